@@ -0,0 +1,324 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Woody1193/goutils/collections"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/cenkalti/backoff/v4"
+)
+
+// BatchGetRequest describes a set of items that should be read from a single table by a batch-get
+type BatchGetRequest struct {
+
+	// TableName is the name of the table from which the items should be read
+	TableName string
+
+	// Keys contains the primary keys of the items that should be read. Every key should contain the
+	// same attributes, which must be the key attributes of the table
+	Keys []map[string]types.AttributeValue
+
+	// Projection contains the names of the attributes that should be retrieved for each item. If this
+	// is empty then all attributes will be retrieved. The key attributes will always be retrieved so
+	// that each item can be matched back to the key that requested it
+	Projection []string
+
+	// ConsistentRead determines whether strongly consistent reads should be used for the table
+	ConsistentRead bool
+}
+
+// Helper type that associates a key requested by a batch-get with the request that contains it
+type batchGetKey struct {
+	request     int
+	fingerprint string
+	key         map[string]types.AttributeValue
+}
+
+// Helper type that associates a request made by a batch-write with the table it should be written to
+type batchWriteEntry struct {
+	tableName string
+	request   types.WriteRequest
+}
+
+// BatchGet reads a number of items from a table in DynamoDB by their keys. The items will be returned
+// in the same order as the keys that were requested, with a nil entry for every key that could not
+// be found. See BatchGetMany for more information
+func (conn *DatabaseConnection) BatchGet(ctx context.Context, tableName string,
+	keys ...map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+
+	// Attempt to read the keys with a single batch-get request; if this fails then return an error
+	results, err := conn.BatchGetMany(ctx, &BatchGetRequest{TableName: tableName, Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+
+	return results[0], nil
+}
+
+// BatchGetMany reads items from one or more tables in DynamoDB by their keys. The keys will be chunked
+// into requests of no more than 100 keys, which is the limit imposed by AWS, and any unprocessed keys
+// returned by DynamoDB will be resubmitted, with an exponential backoff between each submission, until
+// the unprocessed retry limit set on the connection is reached. The results will be returned in the same
+// order as the requests, with the items for each request in the same order as its keys. If an item could
+// not be found then its entry will be nil. Each table may only appear in one request. This function does
+// not return capacity statistics.
+func (conn *DatabaseConnection) BatchGetMany(ctx context.Context,
+	requests ...*BatchGetRequest) ([][]map[string]types.AttributeValue, error) {
+
+	// First, create our results and iterate over all the requests, creating a lookup from the
+	// fingerprint of each key to the positions in the results where the item should be written.
+	// Duplicate keys will only be requested once as DynamoDB rejects batches that contain them
+	results := make([][]map[string]types.AttributeValue, len(requests))
+	positions := make([]map[string][]int, len(requests))
+	keyNames := make([][]string, len(requests))
+	tableKeyNames := make(map[string][]string)
+	tables := make(map[string]int)
+	pending := make([]*batchGetKey, 0)
+	for i, request := range requests {
+
+		// First, ensure that we haven't seen this table before as DynamoDB only allows one set of
+		// keys and attributes per table in each request
+		if _, ok := tables[request.TableName]; ok {
+			return nil, conn.NewError(nil, request.TableName,
+				"Table %s appeared in more than one batch-get request", request.TableName)
+		}
+
+		tables[request.TableName] = i
+
+		// Next, create the results for the request and get the names of the key attributes
+		results[i] = make([]map[string]types.AttributeValue, len(request.Keys))
+		positions[i] = make(map[string][]int)
+		if len(request.Keys) > 0 {
+			keyNames[i] = collections.Keys(request.Keys[0])
+			sort.Strings(keyNames[i])
+			tableKeyNames[request.TableName] = keyNames[i]
+		}
+
+		// Finally, iterate over each key and associate it with its position in the results
+		for j, key := range request.Keys {
+			fingerprint := keyFingerprint(keyNames[i], key)
+			if _, ok := positions[i][fingerprint]; !ok {
+				pending = append(pending, &batchGetKey{request: i, fingerprint: fingerprint, key: key})
+			}
+
+			positions[i][fingerprint] = append(positions[i][fingerprint], j)
+		}
+	}
+
+	// Next, if the connection has an item cache then read as many of the keys as we can from it so
+	// that we only request the keys it doesn't contain
+	pending = conn.batchGetCached(requests, results, positions, pending)
+	conn.logger.Log("Attempting batch-get of %d keys from %d tables...", len(pending), len(requests))
+
+	// Now, iterate until we have no more keys to request, taking up to 100 keys at a time
+	timer := conn.createUnprocessedBackoff()
+	for len(pending) > 0 {
+
+		// First, take the next chunk of keys from our pending keys
+		size := len(pending)
+		if size > 100 {
+			size = 100
+		}
+
+		chunk := pending[:size]
+		pending = pending[size:]
+
+		// Next, attempt to read the chunk of keys from DynamoDB, saving the items we receive to the
+		// cache; if this fails then return an error
+		tickets := conn.startCacheBatchGet(requests, chunk)
+		responses, unprocessed, err := conn.batchGetInner(ctx, requests, keyNames, chunk)
+		conn.cacheBatchGet(tickets, tableKeyNames, responses)
+		if err != nil {
+			return nil, err
+		}
+
+		// Now, iterate over all the items we received and write each to every position in the
+		// results that requested it
+		for tableName, items := range responses {
+			index := tables[tableName]
+			for _, item := range items {
+				for _, position := range positions[index][keyFingerprint(keyNames[index], item)] {
+					results[index][position] = item
+				}
+			}
+		}
+
+		// Finally, if we had any unprocessed keys then add them back to the front of our pending
+		// keys and wait for the backoff to expire before requesting them again. If we've already
+		// retried as many times as we're allowed to then return an error
+		if len(unprocessed) > 0 {
+			pending = append(unprocessed, pending...)
+			if err := waitForBackoff(ctx, timer); err != nil {
+				return nil, conn.NewError(err, batchTableNames(chunk, requests),
+					"Batch-get failed with %d unprocessed keys", len(pending))
+			}
+		} else {
+			timer.Reset()
+		}
+	}
+
+	conn.logger.Log("Batch-get from %d tables completed", len(requests))
+	return results, nil
+}
+
+// Helper function that reads a single batch (no more than a single page) of keys from DynamoDB,
+// returning the items that were read, by table, and the keys that were not processed
+func (conn *DatabaseConnection) batchGetInner(ctx context.Context, requests []*BatchGetRequest,
+	keyNames [][]string, chunk []*batchGetKey) (map[string][]map[string]types.AttributeValue, []*batchGetKey, error) {
+
+	// First, group the keys in the chunk by the request they belong to, creating the keys and
+	// attributes for each table as we go
+	items := make(map[string]types.KeysAndAttributes)
+	indexes := make(map[string]int)
+	lookup := make(map[string]map[string]*batchGetKey)
+	for _, key := range chunk {
+		request := requests[key.request]
+
+		// If we haven't seen the table yet then create its keys and attributes from the request
+		entry, ok := items[request.TableName]
+		if !ok {
+			entry = types.KeysAndAttributes{ConsistentRead: aws.Bool(request.ConsistentRead)}
+			if len(request.Projection) > 0 {
+				entry.ProjectionExpression, entry.ExpressionAttributeNames =
+					projectionExpression(request.Projection, keyNames[key.request])
+			}
+
+			indexes[request.TableName] = key.request
+			lookup[request.TableName] = make(map[string]*batchGetKey)
+		}
+
+		entry.Keys = append(entry.Keys, key.key)
+		items[request.TableName] = entry
+		lookup[request.TableName][key.fingerprint] = key
+	}
+
+	// Next, attempt to retry the operation to batch-get the items from the tables; if this
+	// fails then we'll return the associated error
+	var output *dynamodb.BatchGetItemOutput
+	err := conn.doRetry(ctx, batchTableNames(chunk, requests), "BATCH GET", func() error {
+		var inner error
+		output, inner = conn.db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems:           items,
+			ReturnConsumedCapacity: conn.capacityMode(types.ReturnConsumedCapacityNone),
+		})
+
+		if inner == nil {
+			conn.recordCapacity("BATCH GET", output.ConsumedCapacity...)
+		}
+
+		return inner
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Finally, map any unprocessed keys back to the keys we requested and return them with the responses.
+	// If DynamoDB returned a key that we didn't request then return an error
+	unprocessed := make([]*batchGetKey, 0)
+	for tableName, entry := range output.UnprocessedKeys {
+		for _, key := range entry.Keys {
+			fingerprint := keyFingerprint(keyNames[indexes[tableName]], key)
+			requested, ok := lookup[tableName][fingerprint]
+			if !ok {
+				return nil, nil, conn.NewError(nil, tableName,
+					"Batch-get returned an unprocessed key for %s that was not requested", tableName)
+			}
+
+			unprocessed = append(unprocessed, requested)
+		}
+	}
+
+	return output.Responses, unprocessed, nil
+}
+
+// Helper function that creates a projection expression, and its associated attribute names, from a
+// list of attribute names and the names of the key attributes, which will always be included
+func projectionExpression(attributes []string, keys []string) (*string, map[string]string) {
+
+	// First, combine the attribute names and key names, removing any duplicates
+	seen := make(map[string]bool)
+	parts := make([]string, 0, len(attributes)+len(keys))
+	names := make(map[string]string)
+	for _, name := range append(append([]string{}, attributes...), keys...) {
+		if seen[name] {
+			continue
+		}
+
+		// Create a placeholder for the attribute name so we don't conflict with reserved words
+		placeholder := fmt.Sprintf("#p%d", len(parts))
+		names[placeholder] = name
+		parts = append(parts, placeholder)
+		seen[name] = true
+	}
+
+	// Finally, join the placeholders into a projection expression and return it with the names
+	return aws.String(strings.Join(parts, ", ")), names
+}
+
+// Helper function that creates a string uniquely identifying an item by the values of its key attributes
+func keyFingerprint(names []string, item map[string]types.AttributeValue) string {
+	var builder strings.Builder
+	for _, name := range names {
+		builder.WriteString(name)
+		switch casted := item[name].(type) {
+		case *types.AttributeValueMemberS:
+			builder.WriteString("|S|" + casted.Value)
+		case *types.AttributeValueMemberN:
+			builder.WriteString("|N|" + casted.Value)
+		case *types.AttributeValueMemberB:
+			builder.WriteString("|B|" + base64.StdEncoding.EncodeToString(casted.Value))
+		default:
+			builder.WriteString("|?|")
+		}
+
+		builder.WriteString("|")
+	}
+
+	return builder.String()
+}
+
+// Helper function that creates a description of the tables included in a chunk of batch-get keys
+func batchTableNames(chunk []*batchGetKey, requests []*BatchGetRequest) string {
+	names := make(map[string]bool)
+	for _, key := range chunk {
+		names[requests[key.request].TableName] = true
+	}
+
+	keys := collections.Keys(names)
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
+
+// Helper function that creates the backoff timer used to wait between resubmissions of unprocessed
+// batch requests, limited to the number of unprocessed retries set on the connection
+func (conn *DatabaseConnection) createUnprocessedBackoff() backoff.BackOff {
+	return backoff.WithMaxRetries(conn.createExponentialBackoff(), uint64(conn.unprocessedRetries))
+}
+
+// Helper function that waits for the next interval on a backoff timer before unprocessed requests are
+// resubmitted to DynamoDB. An error will be returned if the timer has expired or if the context is
+// cancelled while we are waiting
+func waitForBackoff(ctx context.Context, timer backoff.BackOff) error {
+
+	// First, get the next interval from the timer; if the timer has expired then return an error
+	wait := timer.NextBackOff()
+	if wait == backoff.Stop {
+		return fmt.Errorf("backoff expired")
+	}
+
+	// Next, wait for the interval to elapse or for the context to be cancelled
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
@@ -0,0 +1,382 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Batch Operation Tests", func() {
+
+	// Test that, if more than 100 keys are requested, then BatchGet will chunk the keys and return
+	// the items in the order they were requested, with nil entries for missing items
+	It("BatchGet - Multiple chunks - Items returned in order", func() {
+
+		// First, create our test connection with a client that contains every even-numbered item
+		client := newBatchDynamoDBClient()
+		for i := 0; i < 250; i += 2 {
+			client.add("TEST_TABLE", createBatchItem(i))
+		}
+
+		conn := createMockConnection(client)
+
+		// Next, create our keys in reverse order so that we can verify ordering
+		keys := make([]map[string]types.AttributeValue, 250)
+		for i := 0; i < 250; i++ {
+			keys[i] = createBatchKey(249 - i)
+		}
+
+		// Now, attempt to read the keys from the table; this should not fail
+		items, err := conn.BatchGet(context.Background(), "TEST_TABLE", keys...)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the items that were returned and the chunks that were requested
+		Expect(items).Should(HaveLen(250))
+		for i, item := range items {
+			if (249-i)%2 == 0 {
+				Expect(item).Should(Equal(createBatchItem(249 - i)))
+			} else {
+				Expect(item).Should(BeNil())
+			}
+		}
+
+		Expect(client.chunks).Should(Equal([]int{100, 100, 50}))
+	})
+
+	// Test that, if DynamoDB returns unprocessed keys, then BatchGet will resubmit them
+	It("BatchGet - Unprocessed keys - Retried", func() {
+
+		// First, create our test connection with a client that fails to process some keys twice
+		client := newBatchDynamoDBClient()
+		client.unprocessed = []int{7, 3}
+		for i := 0; i < 20; i++ {
+			client.add("TEST_TABLE", createBatchItem(i))
+		}
+
+		conn := createMockConnection(client, WithBackoffMaxElapsed(1000))
+
+		// Next, attempt to read the keys from the table, including a duplicate; this should not fail
+		keys := make([]map[string]types.AttributeValue, 21)
+		for i := 0; i < 20; i++ {
+			keys[i] = createBatchKey(i)
+		}
+
+		keys[20] = createBatchKey(0)
+		items, err := conn.BatchGet(context.Background(), "TEST_TABLE", keys...)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the items that were returned and the chunks that were requested
+		for i := 0; i < 20; i++ {
+			Expect(items[i]).Should(Equal(createBatchItem(i)))
+		}
+
+		Expect(items[20]).Should(Equal(createBatchItem(0)))
+		Expect(client.chunks).Should(Equal([]int{20, 7, 3}))
+	})
+
+	// Test that, if DynamoDB never processes some keys, then BatchGet will eventually return an error
+	It("BatchGet - Unprocessed keys remain - Error", func() {
+
+		// First, create our test connection with a client that never processes a key
+		client := newBatchDynamoDBClient()
+		client.unprocessed = []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+		conn := createMockConnection(client)
+
+		// Next, attempt to read the key from the table; this should fail
+		items, err := conn.BatchGet(context.Background(), "TEST_TABLE", createBatchKey(0))
+
+		// Finally, verify the details of the error
+		casted := err.(*Error)
+		Expect(items).Should(BeNil())
+		Expect(casted.TableName).Should(Equal("TEST_TABLE"))
+		Expect(casted.Message).Should(Equal("Batch-get failed with 1 unprocessed keys"))
+	})
+
+	// Test that, if DynamoDB returns an unprocessed key that was not requested, then BatchGet will return
+	// an error rather than resubmitting it
+	It("BatchGet - Unrequested key unprocessed - Error", func() {
+
+		// First, create our test connection with a client that returns a key that wasn't requested
+		client := newBatchDynamoDBClient()
+		client.unrequested = createBatchKey(99)
+		conn := createMockConnection(client)
+
+		// Next, attempt to read a key from the table; this should fail
+		items, err := conn.BatchGet(context.Background(), "TEST_TABLE", createBatchKey(0))
+
+		// Finally, verify the details of the error and that the key was only requested once
+		casted := err.(*Error)
+		Expect(items).Should(BeNil())
+		Expect(casted.TableName).Should(Equal("TEST_TABLE"))
+		Expect(casted.Message).Should(Equal("Batch-get returned an unprocessed key for TEST_TABLE that was not requested"))
+		Expect(client.chunks).Should(Equal([]int{1}))
+	})
+
+	// Test that BatchGetMany can read from multiple tables with different projections and consistency
+	It("BatchGetMany - Multiple tables - Items returned by request", func() {
+
+		// First, create our test connection with a client that contains items in two tables
+		client := newBatchDynamoDBClient()
+		client.add("TABLE_A", createBatchItem(1))
+		client.add("TABLE_B", createBatchItem(2))
+		conn := createMockConnection(client)
+
+		// Next, attempt to read items from both tables; this should not fail
+		results, err := conn.BatchGetMany(context.Background(),
+			&BatchGetRequest{TableName: "TABLE_A", Keys: []map[string]types.AttributeValue{
+				createBatchKey(1), createBatchKey(2)}, ConsistentRead: true},
+			&BatchGetRequest{TableName: "TABLE_B", Keys: []map[string]types.AttributeValue{
+				createBatchKey(2)}, Projection: []string{"data", "id"}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, verify the items that were returned
+		Expect(results).Should(HaveLen(2))
+		Expect(results[0]).Should(Equal([]map[string]types.AttributeValue{createBatchItem(1), nil}))
+		Expect(results[1]).Should(Equal([]map[string]types.AttributeValue{createBatchItem(2)}))
+
+		// Finally, verify the requests that were sent for each table
+		Expect(*client.requests[0]["TABLE_A"].ConsistentRead).Should(BeTrue())
+		Expect(client.requests[0]["TABLE_A"].ProjectionExpression).Should(BeNil())
+		Expect(*client.requests[0]["TABLE_B"].ConsistentRead).Should(BeFalse())
+		Expect(*client.requests[0]["TABLE_B"].ProjectionExpression).Should(Equal("#p0, #p1"))
+		Expect(client.requests[0]["TABLE_B"].ExpressionAttributeNames).Should(Equal(
+			map[string]string{"#p0": "data", "#p1": "id"}))
+	})
+
+	// Test that, if the same table appears in multiple requests, then BatchGetMany will return an error
+	It("BatchGetMany - Duplicate table - Error", func() {
+
+		// First, create our test connection with an empty client
+		client := newBatchDynamoDBClient()
+		conn := createMockConnection(client)
+
+		// Next, attempt to read items from the same table twice; this should fail
+		results, err := conn.BatchGetMany(context.Background(),
+			&BatchGetRequest{TableName: "TEST_TABLE", Keys: []map[string]types.AttributeValue{createBatchKey(1)}},
+			&BatchGetRequest{TableName: "TEST_TABLE", Keys: []map[string]types.AttributeValue{createBatchKey(2)}})
+
+		// Finally, verify the details of the error and that no requests were made
+		casted := err.(*Error)
+		Expect(results).Should(BeNil())
+		Expect(casted.TableName).Should(Equal("TEST_TABLE"))
+		Expect(casted.Message).Should(Equal("Table TEST_TABLE appeared in more than one batch-get request"))
+		Expect(client.chunks).Should(BeEmpty())
+	})
+
+	// Test that BatchWriteMany will chunk the requests for multiple tables and write all of them
+	It("BatchWriteMany - Multiple tables, parallel - All items written", func() {
+
+		// First, create our test connection with an empty client and a batch parallelism of three
+		client := newBatchDynamoDBClient()
+		conn := createMockConnection(client, WithBatchParallelism(3))
+
+		// Next, create our write requests for two tables
+		requests := make(map[string][]types.WriteRequest)
+		for i := 0; i < 60; i++ {
+			tableName := fmt.Sprintf("TABLE_%d", i%2)
+			requests[tableName] = append(requests[tableName],
+				types.WriteRequest{PutRequest: &types.PutRequest{Item: createBatchItem(i)}})
+		}
+
+		// Now, attempt to write the requests to DynamoDB; this should not fail
+		err := conn.BatchWriteMany(context.Background(), requests)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that every item was written to the correct table in chunks of no more than 25
+		Expect(client.chunks).Should(ConsistOf(25, 25, 10))
+		Expect(client.items["TABLE_0"]).Should(HaveLen(30))
+		Expect(client.items["TABLE_1"]).Should(HaveLen(30))
+		for i := 0; i < 60; i++ {
+			Expect(client.items[fmt.Sprintf("TABLE_%d", i%2)][fmt.Sprintf("test_id|%d", i)]).
+				Should(Equal(createBatchItem(i)))
+		}
+	})
+
+	// Test that, if DynamoDB returns unprocessed items, then BatchWrite will resubmit them
+	It("BatchWrite - Unprocessed items - Retried", func() {
+
+		// First, create our test connection with a client that fails to process some items twice
+		client := newBatchDynamoDBClient()
+		client.unprocessed = []int{5, 2}
+		conn := createMockConnection(client, WithBackoffMaxElapsed(1000))
+
+		// Next, attempt to write our requests to DynamoDB; this should not fail
+		requests := make([]types.WriteRequest, 10)
+		for i := 0; i < 10; i++ {
+			requests[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: createBatchItem(i)}}
+		}
+
+		err := conn.BatchWrite(context.Background(), "TEST_TABLE", requests...)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that every item was written and the chunks that were sent
+		Expect(client.items["TEST_TABLE"]).Should(HaveLen(10))
+		Expect(client.chunks).Should(Equal([]int{10, 5, 2}))
+	})
+
+	// Test that, if DynamoDB never processes some items, then BatchWrite will stop once the retry
+	// limit has been reached and return the items that could not be written
+	It("BatchWrite - Unprocessed items remain - Error", func() {
+
+		// First, create our test connection with a client that never processes three items
+		client := newBatchDynamoDBClient()
+		client.unprocessed = []int{3, 3, 3, 3, 3, 3}
+		conn := createMockConnection(client, WithBackoffMaxElapsed(1000), WithUnprocessedRetries(2))
+
+		// Next, attempt to write our requests to DynamoDB; this should fail
+		requests := make([]types.WriteRequest, 10)
+		for i := 0; i < 10; i++ {
+			requests[i] = types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: createBatchKey(i)}}
+		}
+
+		err := conn.BatchWrite(context.Background(), "TEST_TABLE", requests...)
+
+		// Finally, verify the details of the error and the chunks that were sent
+		casted := err.(*BatchWriteError)
+		Expect(casted.TableName).Should(Equal("TEST_TABLE"))
+		Expect(casted.Message).Should(Equal("Batch-write failed with 3 unprocessed requests"))
+		Expect(casted.Unprocessed).Should(Equal(map[string][]types.WriteRequest{
+			"TEST_TABLE": requests[:3]}))
+		Expect(client.chunks).Should(Equal([]int{10, 3, 3}))
+	})
+})
+
+// Helper function that creates the key of a test item for batch operations
+func createBatchKey(index int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id": &types.AttributeValueMemberS{Value: fmt.Sprintf("test_id|%d", index)}}
+}
+
+// Helper function that creates a test item for batch operations
+func createBatchItem(index int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":   &types.AttributeValueMemberS{Value: fmt.Sprintf("test_id|%d", index)},
+		"data": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", index)}}
+}
+
+// Helper type that stores items by table and ID so that batch operations can be tested without a
+// DynamoDB instance. The unprocessed list determines how many keys will be returned as unprocessed
+// on each successive request. If the unrequested key is set then it will also be returned as unprocessed
+type batchDynamoDBClient struct {
+	DynamoDBAPI
+	items       map[string]map[string]map[string]types.AttributeValue
+	unprocessed []int
+	unrequested map[string]types.AttributeValue
+	chunks      []int
+	requests    []map[string]types.KeysAndAttributes
+	lock        sync.Mutex
+}
+
+// Helper function that creates a new, empty batch client
+func newBatchDynamoDBClient() *batchDynamoDBClient {
+	return &batchDynamoDBClient{items: make(map[string]map[string]map[string]types.AttributeValue)}
+}
+
+// Helper function that adds an item to a table on the batch client
+func (client *batchDynamoDBClient) add(tableName string, item map[string]types.AttributeValue) {
+	if _, ok := client.items[tableName]; !ok {
+		client.items[tableName] = make(map[string]map[string]types.AttributeValue)
+	}
+
+	client.items[tableName][item["id"].(*types.AttributeValueMemberS).Value] = item
+}
+
+// Helper function that gets the number of requests that should be returned as unprocessed
+func (client *batchDynamoDBClient) nextUnprocessed() int {
+	if len(client.unprocessed) == 0 {
+		return 0
+	}
+
+	count := client.unprocessed[0]
+	client.unprocessed = client.unprocessed[1:]
+	return count
+}
+
+// Mocks out the BatchGetItem function so that it returns items from the client, leaving the
+// requested number of keys unprocessed
+func (client *batchDynamoDBClient) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	// First, record the request and the number of keys that were requested
+	client.requests = append(client.requests, params.RequestItems)
+	count := 0
+	for _, entry := range params.RequestItems {
+		count += len(entry.Keys)
+	}
+
+	client.chunks = append(client.chunks, count)
+
+	// Next, iterate over all the keys and either read each item or mark it as unprocessed
+	remaining := client.nextUnprocessed()
+	output := dynamodb.BatchGetItemOutput{
+		Responses:       make(map[string][]map[string]types.AttributeValue),
+		UnprocessedKeys: make(map[string]types.KeysAndAttributes),
+	}
+
+	for tableName, entry := range params.RequestItems {
+		if client.unrequested != nil {
+			unprocessed := output.UnprocessedKeys[tableName]
+			unprocessed.Keys = append(unprocessed.Keys, client.unrequested)
+			output.UnprocessedKeys[tableName] = unprocessed
+		}
+
+		for _, key := range entry.Keys {
+			if remaining > 0 {
+				unprocessed := output.UnprocessedKeys[tableName]
+				unprocessed.Keys = append(unprocessed.Keys, key)
+				output.UnprocessedKeys[tableName] = unprocessed
+				remaining--
+			} else if item, ok := client.items[tableName][key["id"].(*types.AttributeValueMemberS).Value]; ok {
+				output.Responses[tableName] = append(output.Responses[tableName], item)
+			}
+		}
+	}
+
+	return &output, nil
+}
+
+// Mocks out the BatchWriteItem function so that it writes items to, or deletes items from, the
+// client, leaving the requested number of items unprocessed
+func (client *batchDynamoDBClient) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	// First, record the number of requests that were made
+	count := 0
+	for _, requests := range params.RequestItems {
+		count += len(requests)
+	}
+
+	client.chunks = append(client.chunks, count)
+
+	// Next, iterate over all the requests and either apply each or mark it as unprocessed
+	remaining := client.nextUnprocessed()
+	output := dynamodb.BatchWriteItemOutput{UnprocessedItems: make(map[string][]types.WriteRequest)}
+	for tableName, requests := range params.RequestItems {
+		if _, ok := client.items[tableName]; !ok {
+			client.items[tableName] = make(map[string]map[string]types.AttributeValue)
+		}
+
+		for _, request := range requests {
+			if remaining > 0 {
+				output.UnprocessedItems[tableName] = append(output.UnprocessedItems[tableName], request)
+				remaining--
+			} else if request.PutRequest != nil {
+				item := request.PutRequest.Item
+				client.items[tableName][item["id"].(*types.AttributeValueMemberS).Value] = item
+			} else {
+				delete(client.items[tableName], request.DeleteRequest.Key["id"].(*types.AttributeValueMemberS).Value)
+			}
+		}
+	}
+
+	return &output, nil
+}
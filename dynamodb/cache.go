@@ -0,0 +1,562 @@
+package dynamodb
+
+import (
+	"container/list"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Woody1193/goutils/collections"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DefaultCacheSize is the maximum number of items that will be held by an item cache if no size is provided
+const DefaultCacheSize = 1000
+
+// CacheStats describes the performance of the item cache on a connection. Hits and misses are only counted
+// for reads that could have been served from the cache, so projected and strongly consistent reads are not
+// included. Evictions counts the items removed to keep the cache within its size and Expirations counts the
+// items that were found to be older than the time-to-live of the cache
+type CacheStats struct {
+	Hits        int64
+	Misses      int64
+	Evictions   int64
+	Expirations int64
+	Entries     int
+}
+
+// Helper type that describes a single item held by the item cache
+type cacheEntry struct {
+	fingerprint string
+	tableName   string
+	item        map[string]types.AttributeValue
+	expires     time.Time
+}
+
+// Helper type that tracks the writes made to an item while it is being read from DynamoDB. The generation
+// is incremented by each write so that a read which overlapped a write can avoid caching a stale item
+type cacheRead struct {
+	readers    int
+	generation uint64
+}
+
+// Helper type that describes a read from DynamoDB whose result may be saved to the cache, recording the
+// generations of the item and its table when the read started
+type cacheTicket struct {
+	tableName       string
+	fingerprint     string
+	names           []string
+	generation      uint64
+	tableGeneration uint64
+}
+
+// Helper type that caches items read from DynamoDB, by table and key, so that frequently read items can
+// be served without a request. The cache holds a bounded number of items, evicting the least recently
+// used item when it is full, and items expire once they are older than its time-to-live. Since items can
+// be written to DynamoDB by PutItem without their key being known, the cache learns the names of the key
+// attributes for each table from the keys used to read it; items written to tables that haven't been read
+// will not be cached, which is fine because they have no entries to update. Writes made while an item is
+// being read are tracked by generation, per key or per table if the key can't be determined, so that the
+// result of the read is only cached if nothing was written to the item in the meantime
+type itemCache struct {
+	size        int
+	ttl         time.Duration
+	tables      map[string]bool
+	keyNames    map[string][]string
+	entries     map[string]*list.Element
+	reads       map[string]*cacheRead
+	generations map[string]uint64
+	order       *list.List
+	stats       CacheStats
+	now         func() time.Time
+	lock        *sync.Mutex
+}
+
+// Helper function that creates a new, empty item cache that will hold no more than the number of items
+// provided, for no longer than the time-to-live provided. If no table names are provided then items from
+// every table will be cached; otherwise, only items from the tables named will be cached
+func newItemCache(size int, ttl time.Duration, tableNames ...string) *itemCache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+
+	tables := make(map[string]bool, len(tableNames))
+	for _, tableName := range tableNames {
+		tables[tableName] = true
+	}
+
+	return &itemCache{
+		size:        size,
+		ttl:         ttl,
+		tables:      tables,
+		keyNames:    make(map[string][]string),
+		entries:     make(map[string]*list.Element),
+		reads:       make(map[string]*cacheRead),
+		generations: make(map[string]uint64),
+		order:       list.New(),
+		now:         time.Now,
+		lock:        new(sync.Mutex),
+	}
+}
+
+// CacheStats returns the hits, misses, evictions and expirations recorded by the item cache on the
+// connection, along with the number of items it currently holds. If the connection does not have an
+// item cache then empty stats will be returned
+func (conn *DatabaseConnection) CacheStats() CacheStats {
+	if conn.cache == nil {
+		return CacheStats{}
+	}
+
+	conn.cache.lock.Lock()
+	defer conn.cache.lock.Unlock()
+	stats := conn.cache.stats
+	stats.Entries = conn.cache.order.Len()
+	return stats
+}
+
+// InvalidateCache removes every item belonging to the table from the item cache on the connection. This
+// should be called after the table has been modified without using the connection, or with PartiQL
+// statements whose effects cannot be tracked. If the connection does not have an item cache then this
+// function does nothing
+func (conn *DatabaseConnection) InvalidateCache(tableName string) {
+	if conn.cache != nil {
+		conn.cache.invalidateTable(tableName)
+	}
+}
+
+// Helper function that determines whether items from a table should be cached
+func (cache *itemCache) enabled(tableName string) bool {
+	return cache != nil && (len(cache.tables) == 0 || cache.tables[tableName])
+}
+
+// Helper function that gets an item from the cache by its table and key. The item returned will be a
+// copy so that the caller may modify it without affecting the cache. If the item was not found, or it
+// has expired, then false will be returned
+func (cache *itemCache) get(tableName string, key map[string]types.AttributeValue) (map[string]types.AttributeValue, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	// First, attempt to find the entry for the key; if there isn't one then record a miss and return
+	fingerprint := cacheFingerprint(tableName, sortedNames(key), key)
+	element, ok := cache.entries[fingerprint]
+	if !ok {
+		cache.stats.Misses++
+		return nil, false
+	}
+
+	// Next, if the entry has expired then remove it and record a miss
+	entry := element.Value.(*cacheEntry)
+	if cache.ttl > 0 && !cache.now().Before(entry.expires) {
+		cache.remove(element)
+		cache.stats.Expirations++
+		cache.stats.Misses++
+		return nil, false
+	}
+
+	// Finally, mark the entry as the most recently used and return a copy of its item
+	cache.order.MoveToFront(element)
+	cache.stats.Hits++
+	return copyItem(entry.item), true
+}
+
+// Helper function that records the start of a read of the item with the key provided, returning a ticket
+// that must be passed to finishRead once the read has completed
+func (cache *itemCache) startRead(tableName string, key map[string]types.AttributeValue) *cacheTicket {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	names := sortedNames(key)
+	fingerprint := cacheFingerprint(tableName, names, key)
+	read, ok := cache.reads[fingerprint]
+	if !ok {
+		read = new(cacheRead)
+		cache.reads[fingerprint] = read
+	}
+
+	read.readers++
+	return &cacheTicket{
+		tableName:       tableName,
+		fingerprint:     fingerprint,
+		names:           names,
+		generation:      read.generation,
+		tableGeneration: cache.generations[tableName],
+	}
+}
+
+// Helper function that records the end of a read and saves the item that was read to the cache, using the
+// names of the key attributes that were used to read it. These names will be saved so that the item can be
+// found again when it is written. If the item, or its table, was written while it was being read then the
+// item may be stale so it will not be saved. If the item is nil then the read will only be ended
+func (cache *itemCache) finishRead(ticket *cacheTicket, item map[string]types.AttributeValue) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	// First, check whether anything was written to the item while it was being read and stop tracking
+	// writes to the item if there are no other reads of it in progress
+	read := cache.reads[ticket.fingerprint]
+	current := read.generation == ticket.generation && cache.generations[ticket.tableName] == ticket.tableGeneration
+	if read.readers--; read.readers == 0 {
+		delete(cache.reads, ticket.fingerprint)
+	}
+
+	// Next, if we have an item and nothing was written to it then save it to the cache
+	if current && len(item) > 0 {
+		cache.keyNames[ticket.tableName] = ticket.names
+		cache.store(ticket.tableName, ticket.names, item)
+	}
+}
+
+// Helper function that saves an item written to a table to the cache, using the key attribute names
+// learned from previous reads of the table. If the table hasn't been read yet then the item will be ignored
+func (cache *itemCache) setItem(tableName string, item map[string]types.AttributeValue) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if names, ok := cache.written(tableName, item); ok {
+		cache.store(tableName, names, item)
+	}
+}
+
+// Helper function that removes the item with the key provided from the cache. The key may be a full item,
+// so long as the names of the key attributes for the table have been learned
+func (cache *itemCache) invalidate(tableName string, key map[string]types.AttributeValue) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	names, ok := cache.written(tableName, key)
+	if !ok {
+		names = sortedNames(key)
+	}
+
+	if element, ok := cache.entries[cacheFingerprint(tableName, names, key)]; ok {
+		cache.remove(element)
+	}
+}
+
+// Helper function that records a write to the item with the key provided so that any read of the item
+// in progress will not be cached. If the names of the key attributes for the table haven't been learned
+// then the key can't be identified, so every read of the table in progress will be prevented from being
+// cached instead. The key names will be returned if they are known. This function should be called under lock
+func (cache *itemCache) written(tableName string, key map[string]types.AttributeValue) ([]string, bool) {
+	names, ok := cache.keyNames[tableName]
+	if !ok {
+		cache.generations[tableName]++
+		return nil, false
+	}
+
+	if read, ok := cache.reads[cacheFingerprint(tableName, names, key)]; ok {
+		read.generation++
+	}
+
+	return names, true
+}
+
+// Helper function that removes every item belonging to a table from the cache and prevents any read of the
+// table in progress from being cached
+func (cache *itemCache) invalidateTable(tableName string) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.generations[tableName]++
+
+	for element := cache.order.Front(); element != nil; {
+		next := element.Next()
+		if element.Value.(*cacheEntry).tableName == tableName {
+			cache.remove(element)
+		}
+
+		element = next
+	}
+}
+
+// Helper function that saves a copy of an item to the cache, as the most recently used item, evicting
+// the least recently used items if the cache is full. This function should be called under lock
+func (cache *itemCache) store(tableName string, names []string, item map[string]types.AttributeValue) {
+
+	// First, create the entry for the item; we'll copy the item so that changes made to it by the
+	// caller will not affect the cache
+	fingerprint := cacheFingerprint(tableName, names, item)
+	entry := cacheEntry{
+		fingerprint: fingerprint,
+		tableName:   tableName,
+		item:        copyItem(item),
+		expires:     cache.now().Add(cache.ttl),
+	}
+
+	// Next, if we already have an entry for the item then replace it and mark it as the most recently used
+	if element, ok := cache.entries[fingerprint]; ok {
+		element.Value = &entry
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	// Finally, add the entry and evict the least recently used entries until the cache is within its size
+	cache.entries[fingerprint] = cache.order.PushFront(&entry)
+	for cache.order.Len() > cache.size {
+		cache.remove(cache.order.Back())
+		cache.stats.Evictions++
+	}
+}
+
+// Helper function that removes an entry from the cache. This function should be called under lock
+func (cache *itemCache) remove(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*cacheEntry).fingerprint)
+}
+
+// Helper function that attempts to read the item requested by a GetItem request from the cache. Requests
+// which project attributes or require strongly consistent reads cannot be served from the cache
+func (conn *DatabaseConnection) getCached(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, bool) {
+	if !cacheableGet(input) || !conn.cache.enabled(*input.TableName) {
+		return nil, false
+	}
+
+	if item, ok := conn.cache.get(*input.TableName, input.Key); ok {
+		return &dynamodb.GetItemOutput{Item: item}, true
+	}
+
+	return nil, false
+}
+
+// Helper function that records the start of a GetItem request whose result may be saved to the cache,
+// returning nil if it can't be. Requests which project attributes will not be saved as they may not
+// contain the full item
+func (conn *DatabaseConnection) startCacheGet(input *dynamodb.GetItemInput) *cacheTicket {
+	if input.ProjectionExpression == nil && len(input.AttributesToGet) == 0 && conn.cache.enabled(*input.TableName) {
+		return conn.cache.startRead(*input.TableName, input.Key)
+	}
+
+	return nil
+}
+
+// Helper function that saves the item returned by a GetItem request to the cache, if the request was
+// recorded by startCacheGet and succeeded
+func (conn *DatabaseConnection) cacheGet(ticket *cacheTicket, output *dynamodb.GetItemOutput, err error) {
+	if ticket == nil {
+		return
+	}
+
+	var item map[string]types.AttributeValue
+	if err == nil {
+		item = output.Item
+	}
+
+	conn.cache.finishRead(ticket, item)
+}
+
+// Helper function that reads the keys requested by a batch-get from the cache, where possible, writing each
+// item found to every position in the results that requested it. The keys that could not be read from the
+// cache will be returned so that they can be requested from DynamoDB
+func (conn *DatabaseConnection) batchGetCached(requests []*BatchGetRequest, results [][]map[string]types.AttributeValue,
+	positions []map[string][]int, pending []*batchGetKey) []*batchGetKey {
+	if conn.cache == nil {
+		return pending
+	}
+
+	remaining := make([]*batchGetKey, 0, len(pending))
+	for _, key := range pending {
+		request := requests[key.request]
+		if cacheableBatchGet(request) && conn.cache.enabled(request.TableName) {
+			if item, ok := conn.cache.get(request.TableName, key.key); ok {
+				for _, position := range positions[key.request][key.fingerprint] {
+					results[key.request][position] = item
+				}
+
+				continue
+			}
+		}
+
+		remaining = append(remaining, key)
+	}
+
+	return remaining
+}
+
+// Helper function that records the start of a batch-get of a chunk of keys whose results may be saved to
+// the cache, returning the tickets for the reads by the fingerprint of their keys. Keys requested with a
+// projection will not be saved as they may not contain the full item
+func (conn *DatabaseConnection) startCacheBatchGet(requests []*BatchGetRequest,
+	chunk []*batchGetKey) map[string]*cacheTicket {
+	if conn.cache == nil {
+		return nil
+	}
+
+	tickets := make(map[string]*cacheTicket)
+	for _, key := range chunk {
+		request := requests[key.request]
+		if len(request.Projection) == 0 && conn.cache.enabled(request.TableName) {
+			ticket := conn.cache.startRead(request.TableName, key.key)
+			tickets[ticket.fingerprint] = ticket
+		}
+	}
+
+	return tickets
+}
+
+// Helper function that saves the items returned by a batch-get to the cache, if their reads were recorded
+// by startCacheBatchGet, and ends the reads of every other key in the chunk
+func (conn *DatabaseConnection) cacheBatchGet(tickets map[string]*cacheTicket, keyNames map[string][]string,
+	responses map[string][]map[string]types.AttributeValue) {
+	if len(tickets) == 0 {
+		return
+	}
+
+	for tableName, items := range responses {
+		for _, item := range items {
+			fingerprint := cacheFingerprint(tableName, keyNames[tableName], item)
+			if ticket, ok := tickets[fingerprint]; ok {
+				conn.cache.finishRead(ticket, item)
+				delete(tickets, fingerprint)
+			}
+		}
+	}
+
+	for _, ticket := range tickets {
+		conn.cache.finishRead(ticket, nil)
+	}
+}
+
+// Helper function that updates the cache after a PutItem request. If the request succeeded then the item
+// written will replace the cached item. Otherwise, we can't be sure whether the item was written so the
+// cached item will be removed
+func (conn *DatabaseConnection) cachePut(input *dynamodb.PutItemInput, err error) {
+	if !conn.cache.enabled(*input.TableName) {
+		return
+	} else if err != nil {
+		conn.cache.invalidate(*input.TableName, input.Item)
+	} else {
+		conn.cache.setItem(*input.TableName, input.Item)
+	}
+}
+
+// Helper function that updates the cache after an UpdateItem request. If the request succeeded and returned
+// the entire updated item then it will replace the cached item. Otherwise, the cached item will be removed
+func (conn *DatabaseConnection) cacheUpdate(input *dynamodb.UpdateItemInput,
+	output *dynamodb.UpdateItemOutput, err error) {
+	if !conn.cache.enabled(*input.TableName) {
+		return
+	} else if err == nil && input.ReturnValues == types.ReturnValueAllNew && len(output.Attributes) > 0 {
+		conn.cache.setItem(*input.TableName, output.Attributes)
+	} else {
+		conn.cache.invalidate(*input.TableName, input.Key)
+	}
+}
+
+// Helper function that removes the items affected by a number of write requests from the cache
+func (conn *DatabaseConnection) cacheWrites(tableName string, requests ...types.WriteRequest) {
+	if !conn.cache.enabled(tableName) {
+		return
+	}
+
+	for _, request := range requests {
+		if request.PutRequest != nil {
+			conn.cache.invalidate(tableName, request.PutRequest.Item)
+		} else if request.DeleteRequest != nil {
+			conn.cache.invalidate(tableName, request.DeleteRequest.Key)
+		}
+	}
+}
+
+// Helper function that removes the items affected by the operations in a write transaction from the cache
+func (conn *DatabaseConnection) cacheTransaction(items []types.TransactWriteItem) {
+	if conn.cache == nil {
+		return
+	}
+
+	for _, item := range items {
+		if item.Put != nil && conn.cache.enabled(*item.Put.TableName) {
+			conn.cache.invalidate(*item.Put.TableName, item.Put.Item)
+		} else if item.Update != nil && conn.cache.enabled(*item.Update.TableName) {
+			conn.cache.invalidate(*item.Update.TableName, item.Update.Key)
+		} else if item.Delete != nil && conn.cache.enabled(*item.Delete.TableName) {
+			conn.cache.invalidate(*item.Delete.TableName, item.Delete.Key)
+		}
+	}
+}
+
+// Helper function that removes every item belonging to the table referenced by a PartiQL statement from
+// the cache, if the statement could have modified the table. We can't determine which items a statement
+// modifies so the entire table must be invalidated
+func (conn *DatabaseConnection) cacheStatement(statement *string) {
+	if conn.cache == nil {
+		return
+	}
+
+	if tableName, verb := describeStatement(aws.ToString(statement)); verb != "SELECT" {
+		conn.cache.invalidateTable(tableName)
+	}
+}
+
+// Helper function that determines whether a GetItem request can be served from the cache
+func cacheableGet(input *dynamodb.GetItemInput) bool {
+	return input.ProjectionExpression == nil && len(input.AttributesToGet) == 0 && !aws.ToBool(input.ConsistentRead)
+}
+
+// Helper function that determines whether the keys requested by a batch-get can be served from the cache
+func cacheableBatchGet(request *BatchGetRequest) bool {
+	return len(request.Projection) == 0 && !request.ConsistentRead
+}
+
+// Helper function that creates the string identifying an item in the cache from its table and the values
+// of its key attributes
+func cacheFingerprint(tableName string, names []string, item map[string]types.AttributeValue) string {
+	return tableName + "|" + keyFingerprint(names, item)
+}
+
+// Helper function that gets the names of the attributes in a key, in sorted order
+func sortedNames(key map[string]types.AttributeValue) []string {
+	names := collections.Keys(key)
+	sort.Strings(names)
+	return names
+}
+
+// Helper function that creates a deep copy of an item, so that neither the item nor any of the maps,
+// lists, sets or binary values within it are shared with the copy
+func copyItem(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	copied := make(map[string]types.AttributeValue, len(item))
+	for name, value := range item {
+		copied[name] = copyValue(value)
+	}
+
+	return copied
+}
+
+// Helper function that creates a deep copy of an attribute value
+func copyValue(value types.AttributeValue) types.AttributeValue {
+	switch casted := value.(type) {
+	case *types.AttributeValueMemberS:
+		return &types.AttributeValueMemberS{Value: casted.Value}
+	case *types.AttributeValueMemberN:
+		return &types.AttributeValueMemberN{Value: casted.Value}
+	case *types.AttributeValueMemberB:
+		return &types.AttributeValueMemberB{Value: append([]byte(nil), casted.Value...)}
+	case *types.AttributeValueMemberBOOL:
+		return &types.AttributeValueMemberBOOL{Value: casted.Value}
+	case *types.AttributeValueMemberNULL:
+		return &types.AttributeValueMemberNULL{Value: casted.Value}
+	case *types.AttributeValueMemberSS:
+		return &types.AttributeValueMemberSS{Value: append([]string(nil), casted.Value...)}
+	case *types.AttributeValueMemberNS:
+		return &types.AttributeValueMemberNS{Value: append([]string(nil), casted.Value...)}
+	case *types.AttributeValueMemberBS:
+		copied := make([][]byte, len(casted.Value))
+		for i, data := range casted.Value {
+			copied[i] = append([]byte(nil), data...)
+		}
+
+		return &types.AttributeValueMemberBS{Value: copied}
+	case *types.AttributeValueMemberL:
+		copied := make([]types.AttributeValue, len(casted.Value))
+		for i, inner := range casted.Value {
+			copied[i] = copyValue(inner)
+		}
+
+		return &types.AttributeValueMemberL{Value: copied}
+	case *types.AttributeValueMemberM:
+		return &types.AttributeValueMemberM{Value: copyItem(casted.Value)}
+	default:
+		return value
+	}
+}
@@ -0,0 +1,342 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	fake "github.com/Woody1193/goutils/dynamodb/testing"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Item Cache Tests", func() {
+
+	// Test that, if the connection has an item cache, then repeated reads of an item will be served
+	// from the cache and that changes to the items returned do not affect the cache
+	It("GetItem - Cached - Served from cache", func() {
+
+		// First, create our test connection with an item cache and write an item to the table
+		conn, client := createCacheConnection(WithItemCache{Size: 10, TTL: time.Minute})
+		writeCacheItem(client, "a", 1)
+
+		// Next, read the item twice, modifying the first item returned
+		first := getCacheItem(conn, "a", false)
+		first["count"] = &types.AttributeValueMemberN{Value: "100"}
+		second := getCacheItem(conn, "a", false)
+
+		// Finally, verify that DynamoDB was only called once and that the cached item was not modified
+		Expect(client.gets).Should(Equal(1))
+		Expect(second["count"]).Should(Equal(&types.AttributeValueMemberN{Value: "1"}))
+		Expect(conn.CacheStats()).Should(Equal(CacheStats{Hits: 1, Misses: 1, Entries: 1}))
+	})
+
+	// Test that, if an item containing nested attributes is cached, then changes to the nested attributes
+	// of the items returned do not affect the cache
+	It("GetItem - Nested attributes modified - Cache unchanged", func() {
+
+		// First, create our test connection with an item cache and write an item with nested attributes
+		conn, client := createCacheConnection(WithItemCache{Size: 10, TTL: time.Minute})
+		item := cacheItem("a", 1)
+		item["tags"] = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"list": &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "x"}}},
+			"set":  &types.AttributeValueMemberSS{Value: []string{"y"}}}}
+		_, err := client.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName: aws.String("CACHE_TABLE"),
+			Item:      item,
+		})
+
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Next, read the item twice, modifying the nested attributes of the first item returned
+		first := getCacheItem(conn, "a", false)
+		tags := first["tags"].(*types.AttributeValueMemberM).Value
+		tags["list"].(*types.AttributeValueMemberL).Value[0] = &types.AttributeValueMemberS{Value: "changed"}
+		tags["set"].(*types.AttributeValueMemberSS).Value[0] = "changed"
+		tags["added"] = &types.AttributeValueMemberBOOL{Value: true}
+		second := getCacheItem(conn, "a", false)
+
+		// Finally, verify that the second item was served from the cache and was not modified
+		Expect(client.gets).Should(Equal(1))
+		Expect(second).Should(Equal(item))
+	})
+
+	// Test that, if an item is written through the connection while it is being read, then the item that
+	// was read will not be cached because it may be older than the item that was written
+	It("GetItem - Written during read - Not cached", func() {
+
+		// First, create our test connection with an item cache and read an item so that the names of
+		// the key attributes are known
+		conn, client := createCacheConnection(WithItemCache{Size: 10, TTL: time.Minute})
+		writeCacheItem(client, "a", 1)
+		writeCacheItem(client, "b", 1)
+		getCacheItem(conn, "b", false)
+
+		// Next, read the other item, updating it through the connection after DynamoDB has returned it
+		// but before the read has completed
+		client.afterGet = func() {
+			client.afterGet = nil
+			_, err := conn.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+				TableName:                 aws.String("CACHE_TABLE"),
+				Key:                       cacheKey("a"),
+				UpdateExpression:          aws.String("SET #c = :c"),
+				ExpressionAttributeNames:  map[string]string{"#c": "count"},
+				ExpressionAttributeValues: map[string]types.AttributeValue{":c": &types.AttributeValueMemberN{Value: "2"}},
+			})
+
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		stale := getCacheItem(conn, "a", false)
+
+		// Finally, verify that the stale item was not cached, so the next read was sent to DynamoDB and
+		// returned the updated item
+		Expect(stale).Should(Equal(cacheItem("a", 1)))
+		Expect(getCacheItem(conn, "a", false)).Should(Equal(cacheItem("a", 2)))
+		Expect(client.gets).Should(Equal(3))
+		Expect(getCacheItem(conn, "a", false)).Should(Equal(cacheItem("a", 2)))
+		Expect(client.gets).Should(Equal(3))
+	})
+
+	// Test that, if a cached item is older than the TTL of the cache, then it will be read from DynamoDB again
+	It("GetItem - Expired - Read from DynamoDB", func() {
+
+		// First, create our test connection with an item cache whose clock we control
+		conn, client := createCacheConnection(WithItemCache{Size: 10, TTL: time.Minute})
+		now := time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC)
+		conn.cache.now = func() time.Time { return now }
+		writeCacheItem(client, "a", 1)
+
+		// Next, read the item, update it without using the connection and read it again before and after
+		// the TTL has elapsed
+		getCacheItem(conn, "a", false)
+		writeCacheItem(client, "a", 2)
+		now = now.Add(59 * time.Second)
+		before := getCacheItem(conn, "a", false)
+		now = now.Add(time.Second)
+		after := getCacheItem(conn, "a", false)
+
+		// Finally, verify that the stale item was only returned until it expired
+		Expect(before["count"]).Should(Equal(&types.AttributeValueMemberN{Value: "1"}))
+		Expect(after["count"]).Should(Equal(&types.AttributeValueMemberN{Value: "2"}))
+		Expect(client.gets).Should(Equal(2))
+		Expect(conn.CacheStats()).Should(Equal(CacheStats{Hits: 1, Misses: 2, Expirations: 1, Entries: 1}))
+	})
+
+	// Test that reads which project attributes or require strong consistency are not served from the cache
+	It("GetItem - Projected or consistent - Not served from cache", func() {
+
+		// First, create our test connection with an item cache and write an item to the table
+		conn, client := createCacheConnection(WithItemCache{Size: 10, TTL: time.Minute})
+		writeCacheItem(client, "a", 1)
+
+		// Next, read the item with a projection, then with a consistent read, twice each
+		for i := 0; i < 2; i++ {
+			_, err := conn.GetItem(context.Background(), &dynamodb.GetItemInput{
+				TableName:            aws.String("CACHE_TABLE"),
+				Key:                  cacheKey("a"),
+				ProjectionExpression: aws.String("id"),
+			})
+
+			Expect(err).ShouldNot(HaveOccurred())
+			getCacheItem(conn, "a", true)
+		}
+
+		// Finally, verify that every read was sent to DynamoDB and that only the full item was cached
+		Expect(client.gets).Should(Equal(4))
+		Expect(conn.CacheStats()).Should(Equal(CacheStats{Entries: 1}))
+	})
+
+	// Test that writes made through the connection update or remove the items in the cache
+	It("PutItem, UpdateItem, DeleteItem - Cached - Cache updated", func() {
+
+		// First, create our test connection with an item cache and cache an item
+		conn, client := createCacheConnection(WithItemCache{Size: 10, TTL: time.Minute})
+		writeCacheItem(client, "a", 1)
+		getCacheItem(conn, "a", false)
+
+		// Next, put a new version of the item and verify that it replaced the cached item
+		_, err := conn.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName: aws.String("CACHE_TABLE"),
+			Item:      cacheItem("a", 2),
+		})
+
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(getCacheItem(conn, "a", false)).Should(Equal(cacheItem("a", 2)))
+		Expect(client.gets).Should(Equal(1))
+
+		// Now, update the item and verify that it was removed from the cache
+		_, err = conn.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+			TableName:                 aws.String("CACHE_TABLE"),
+			Key:                       cacheKey("a"),
+			UpdateExpression:          aws.String("SET #c = :c"),
+			ExpressionAttributeNames:  map[string]string{"#c": "count"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":c": &types.AttributeValueMemberN{Value: "3"}},
+		})
+
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(getCacheItem(conn, "a", false)).Should(Equal(cacheItem("a", 3)))
+		Expect(client.gets).Should(Equal(2))
+
+		// Finally, delete the item and verify that it was removed from the cache
+		_, err = conn.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
+			TableName: aws.String("CACHE_TABLE"),
+			Key:       cacheKey("a"),
+		})
+
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(getCacheItem(conn, "a", false)).Should(BeEmpty())
+		Expect(client.gets).Should(Equal(3))
+	})
+
+	// Test that, if the cache is full, then the least recently used item will be evicted
+	It("GetItem - Cache full - Least recently used evicted", func() {
+
+		// First, create our test connection with an item cache that can hold two items
+		conn, client := createCacheConnection(WithItemCache{Size: 2, TTL: time.Minute})
+		for _, id := range []string{"a", "b", "c"} {
+			writeCacheItem(client, id, 1)
+		}
+
+		// Next, read the first two items, then the first item again so that the second item is the
+		// least recently used, and then read the third item
+		getCacheItem(conn, "a", false)
+		getCacheItem(conn, "b", false)
+		getCacheItem(conn, "a", false)
+		getCacheItem(conn, "c", false)
+
+		// Finally, verify that the second item was evicted but the first was not
+		getCacheItem(conn, "a", false)
+		Expect(client.gets).Should(Equal(3))
+		getCacheItem(conn, "b", false)
+		Expect(client.gets).Should(Equal(4))
+		Expect(conn.CacheStats()).Should(Equal(CacheStats{Hits: 2, Misses: 4, Evictions: 2, Entries: 2}))
+	})
+
+	// Test that, if some of the keys requested by a batch-get are cached, then only the remaining keys will
+	// be requested from DynamoDB and the items returned will be cached
+	It("BatchGet - Partially cached - Remaining keys requested", func() {
+
+		// First, create our test connection with an item cache, write some items and cache one of them
+		conn, client := createCacheConnection(WithItemCache{Size: 10, TTL: time.Minute})
+		for i, id := range []string{"a", "b", "c"} {
+			writeCacheItem(client, id, i)
+		}
+
+		getCacheItem(conn, "b", false)
+
+		// Next, read all the items, and an item that doesn't exist, with a batch-get; this should not fail
+		items, err := conn.BatchGet(context.Background(), "CACHE_TABLE",
+			cacheKey("a"), cacheKey("b"), cacheKey("c"), cacheKey("d"))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, verify the items and that only the uncached keys were requested
+		Expect(items).Should(Equal([]map[string]types.AttributeValue{
+			cacheItem("a", 0), cacheItem("b", 1), cacheItem("c", 2), nil}))
+		Expect(client.batchKeys).Should(Equal(3))
+
+		// Finally, read the items that exist again and verify that they were all served from the cache
+		items, err = conn.BatchGet(context.Background(), "CACHE_TABLE", cacheKey("a"), cacheKey("c"))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(items).Should(Equal([]map[string]types.AttributeValue{cacheItem("a", 0), cacheItem("c", 2)}))
+		Expect(client.batchKeys).Should(Equal(3))
+	})
+
+	// Test that, if the item cache is limited to certain tables, then items from other tables will not be cached
+	It("GetItem - Table not cached - Read from DynamoDB", func() {
+
+		// First, create our test connection with an item cache for a different table
+		conn, client := createCacheConnection(WithItemCache{Size: 10, TTL: time.Minute, Tables: []string{"OTHER"}})
+		writeCacheItem(client, "a", 1)
+
+		// Next, read the item twice
+		getCacheItem(conn, "a", false)
+		getCacheItem(conn, "a", false)
+
+		// Finally, verify that both reads were sent to DynamoDB and that nothing was cached
+		Expect(client.gets).Should(Equal(2))
+		Expect(conn.CacheStats()).Should(Equal(CacheStats{}))
+	})
+})
+
+// Helper function that creates a connection to a fake DynamoDB with a cache table, returning the client
+// so that the requests sent to it can be counted
+func createCacheConnection(opts ...IDynamoDBOption) (*DatabaseConnection, *cachingDynamoDBClient) {
+	def, err := NewTableDefinition[counterObject]("CACHE_TABLE", WithTableTagKey("json"))
+	Expect(err).ShouldNot(HaveOccurred())
+
+	client := &cachingDynamoDBClient{FakeDynamoDB: fake.NewFakeDynamoDB()}
+	_, err = client.CreateTable(context.Background(), def.Input)
+	Expect(err).ShouldNot(HaveOccurred())
+	return createMockConnection(client, opts...), client
+}
+
+// Helper function that writes an item to the cache table without using the connection
+func writeCacheItem(client *cachingDynamoDBClient, id string, count int) {
+	_, err := client.PutItem(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String("CACHE_TABLE"),
+		Item:      cacheItem(id, count),
+	})
+
+	Expect(err).ShouldNot(HaveOccurred())
+}
+
+// Helper function that reads an item from the cache table using the connection
+func getCacheItem(conn *DatabaseConnection, id string, consistent bool) map[string]types.AttributeValue {
+	output, err := conn.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName:      aws.String("CACHE_TABLE"),
+		Key:            cacheKey(id),
+		ConsistentRead: aws.Bool(consistent),
+	})
+
+	Expect(err).ShouldNot(HaveOccurred())
+	return output.Item
+}
+
+// Helper function that creates the key of an item in the cache table
+func cacheKey(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: id}}
+}
+
+// Helper function that creates an item in the cache table
+func cacheItem(id string, count int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":    &types.AttributeValueMemberS{Value: id},
+		"count": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", count)},
+	}
+}
+
+// Helper type that counts the reads sent to a fake DynamoDB so that we can verify which reads were
+// served from the item cache
+type cachingDynamoDBClient struct {
+	*fake.FakeDynamoDB
+	gets      int
+	batchKeys int
+	afterGet  func()
+}
+
+// Mocks out the GetItem function so that it counts the number of requests and calls the after-get
+// function, if there is one, once the item has been read
+func (client *cachingDynamoDBClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	client.gets++
+	output, err := client.FakeDynamoDB.GetItem(ctx, params, optFns...)
+	if client.afterGet != nil {
+		client.afterGet()
+	}
+
+	return output, err
+}
+
+// Mocks out the BatchGetItem function so that it counts the number of keys requested
+func (client *cachingDynamoDBClient) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	for _, entry := range params.RequestItems {
+		client.batchKeys += len(entry.Keys)
+	}
+
+	return client.FakeDynamoDB.BatchGetItem(ctx, params, optFns...)
+}
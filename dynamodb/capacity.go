@@ -0,0 +1,197 @@
+package dynamodb
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// CapacityCallback describes a function that will be called with the capacity consumed by each request
+// made to DynamoDB, along with the verb describing the request (e.g. PUT, QUERY or BATCH WRITE)
+type CapacityCallback func(verb string, consumed *types.ConsumedCapacity)
+
+// CapacityKey describes the table, index and verb against which consumed capacity is aggregated. The
+// index name will be empty for the capacity consumed by requests as a whole, including their indexes
+type CapacityKey struct {
+	TableName string
+	IndexName string
+	Verb      string
+}
+
+// CapacityUnits describes the read, write and total capacity units consumed by a number of requests
+type CapacityUnits struct {
+	Requests int
+	Read     float64
+	Write    float64
+	Total    float64
+}
+
+// CapacityStats aggregates the capacity consumed by requests made to DynamoDB by table, index and verb
+// so that the cost of those requests can be attributed. For each request, the total capacity consumed
+// is added to the entry for its table and verb with no index name. If DynamoDB returned a breakdown by
+// index then the capacity consumed by each index is also added to the entry for that index. When DynamoDB
+// only returns the total capacity consumed, it is counted as read or write capacity based on the verb.
+// The stats are safe for concurrent use
+type CapacityStats struct {
+	units map[CapacityKey]CapacityUnits
+	lock  *sync.RWMutex
+}
+
+// NewCapacityStats creates new, empty consumed capacity stats
+func NewCapacityStats() *CapacityStats {
+	return &CapacityStats{
+		units: make(map[CapacityKey]CapacityUnits),
+		lock:  new(sync.RWMutex),
+	}
+}
+
+// Get returns the capacity consumed against a table, index and verb. The index name should be empty to
+// get the capacity consumed by requests against the table as a whole
+func (stats *CapacityStats) Get(tableName string, indexName string, verb string) CapacityUnits {
+	stats.lock.RLock()
+	defer stats.lock.RUnlock()
+	return stats.units[CapacityKey{TableName: tableName, IndexName: indexName, Verb: verb}]
+}
+
+// Table returns the total capacity consumed by all requests against a table, regardless of their verb
+func (stats *CapacityStats) Table(tableName string) CapacityUnits {
+	stats.lock.RLock()
+	defer stats.lock.RUnlock()
+
+	var total CapacityUnits
+	for key, units := range stats.units {
+		if key.TableName == tableName && key.IndexName == "" {
+			total = total.plus(units)
+		}
+	}
+
+	return total
+}
+
+// Snapshot returns a copy of the capacity consumed against every table, index and verb seen so far
+func (stats *CapacityStats) Snapshot() map[CapacityKey]CapacityUnits {
+	stats.lock.RLock()
+	defer stats.lock.RUnlock()
+
+	snapshot := make(map[CapacityKey]CapacityUnits, len(stats.units))
+	for key, units := range stats.units {
+		snapshot[key] = units
+	}
+
+	return snapshot
+}
+
+// Reset removes all the consumed capacity that has been aggregated so far
+func (stats *CapacityStats) Reset() {
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+	stats.units = make(map[CapacityKey]CapacityUnits)
+}
+
+// Helper function that adds the capacity consumed by a request to the stats
+func (stats *CapacityStats) add(verb string, consumed *types.ConsumedCapacity) {
+	tableName := aws.ToString(consumed.TableName)
+
+	stats.lock.Lock()
+	defer stats.lock.Unlock()
+
+	// First, add the total capacity consumed by the request to the entry for the table
+	total := CapacityUnits{
+		Requests: 1,
+		Read:     aws.ToFloat64(consumed.ReadCapacityUnits),
+		Write:    aws.ToFloat64(consumed.WriteCapacityUnits),
+		Total:    aws.ToFloat64(consumed.CapacityUnits),
+	}
+
+	key := CapacityKey{TableName: tableName, Verb: verb}
+	stats.units[key] = stats.units[key].plus(attributeUnits(verb, total))
+
+	// Next, add the capacity consumed by each of the indexes to the entries for those indexes
+	indexes := []map[string]types.Capacity{consumed.GlobalSecondaryIndexes, consumed.LocalSecondaryIndexes}
+	for _, capacities := range indexes {
+		for indexName, capacity := range capacities {
+			units := attributeUnits(verb, CapacityUnits{
+				Requests: 1,
+				Read:     aws.ToFloat64(capacity.ReadCapacityUnits),
+				Write:    aws.ToFloat64(capacity.WriteCapacityUnits),
+				Total:    aws.ToFloat64(capacity.CapacityUnits),
+			})
+
+			key := CapacityKey{TableName: tableName, IndexName: indexName, Verb: verb}
+			stats.units[key] = stats.units[key].plus(units)
+		}
+	}
+}
+
+// Helper function that adds two sets of capacity units together
+func (units CapacityUnits) plus(other CapacityUnits) CapacityUnits {
+	return CapacityUnits{
+		Requests: units.Requests + other.Requests,
+		Read:     units.Read + other.Read,
+		Write:    units.Write + other.Write,
+		Total:    units.Total + other.Total,
+	}
+}
+
+// Helper function that, if DynamoDB did not break the capacity consumed by a request into read and write
+// capacity, counts the total capacity as read or write capacity depending on the verb of the request
+func attributeUnits(verb string, units CapacityUnits) CapacityUnits {
+	if units.Read != 0 || units.Write != 0 {
+		return units
+	}
+
+	if isReadVerb(verb) {
+		units.Read = units.Total
+	} else {
+		units.Write = units.Total
+	}
+
+	return units
+}
+
+// ConsumedCapacity returns the stats describing the capacity consumed by requests made through the connection
+func (conn *DatabaseConnection) ConsumedCapacity() *CapacityStats {
+	return conn.capacity
+}
+
+// Helper function that determines the consumed capacity that should be requested from DynamoDB. If the
+// request doesn't ask for consumed capacity then the mode set on the connection will be used instead or,
+// if the connection has no mode but limits its throughput, the total capacity will be requested
+func (conn *DatabaseConnection) capacityMode(requested types.ReturnConsumedCapacity) types.ReturnConsumedCapacity {
+	if requested == "" || requested == types.ReturnConsumedCapacityNone {
+		if conn.returnCapacity != "" {
+			return conn.returnCapacity
+		} else if conn.limiter != nil {
+			return types.ReturnConsumedCapacityTotal
+		}
+	}
+
+	return requested
+}
+
+// Helper function that records the capacity consumed by a request, if DynamoDB returned it, takes it
+// from the throughput limits on the connection and sends it to the capacity callback set on the
+// connection, if there is one
+func (conn *DatabaseConnection) recordCapacity(verb string, consumed ...types.ConsumedCapacity) {
+	for i := range consumed {
+		conn.capacity.add(verb, &consumed[i])
+		if conn.limiter != nil {
+			conn.limiter.consume(verb, &consumed[i])
+		}
+
+		if conn.capacityCallback != nil {
+			conn.capacityCallback(verb, &consumed[i])
+		}
+	}
+}
+
+// Helper function that converts a pointer to the capacity consumed by a request, which may be nil,
+// to a list of consumed capacity so that it can be recorded
+func capacityOf(consumed *types.ConsumedCapacity) []types.ConsumedCapacity {
+	if consumed == nil {
+		return nil
+	}
+
+	return []types.ConsumedCapacity{*consumed}
+}
@@ -0,0 +1,128 @@
+package dynamodb
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Consumed Capacity Tests", func() {
+
+	// Test that, if the connection requests consumed capacity by index, then every request will ask for
+	// it and the capacity returned will be aggregated by table, index and verb
+	It("WithConsumedCapacity - Indexes requested - Capacity aggregated", func() {
+
+		// First, create our test connection with a client that returns consumed capacity and a callback
+		// that records the capacity it receives
+		client := &capacityDynamoDBClient{}
+		verbs := make([]string, 0)
+		conn := createMockConnection(client, WithConsumedCapacity(types.ReturnConsumedCapacityIndexes),
+			WithCapacityCallback(func(verb string, consumed *types.ConsumedCapacity) {
+				verbs = append(verbs, verb)
+			}))
+
+		// Next, write an item, query the table twice and batch-write to it; none of these should fail
+		_, err := conn.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		for i := 0; i < 2; i++ {
+			_, err = conn.Query(context.Background(), &dynamodb.QueryInput{TableName: aws.String("TEST_TABLE")})
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		err = conn.BatchWrite(context.Background(), "TEST_TABLE",
+			types.WriteRequest{PutRequest: &types.PutRequest{Item: createBatchItem(0)}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, verify that every request asked for consumed capacity by index and that the callback
+		// received the capacity consumed by each of them
+		Expect(client.requested).Should(Equal([]types.ReturnConsumedCapacity{
+			types.ReturnConsumedCapacityIndexes, types.ReturnConsumedCapacityIndexes,
+			types.ReturnConsumedCapacityIndexes, types.ReturnConsumedCapacityIndexes}))
+		Expect(verbs).Should(Equal([]string{"PUT", "QUERY", "QUERY", "BATCH WRITE"}))
+
+		// Finally, verify the capacity that was aggregated
+		stats := conn.ConsumedCapacity()
+		Expect(stats.Get("TEST_TABLE", "", "PUT")).Should(Equal(CapacityUnits{Requests: 1, Write: 3, Total: 3}))
+		Expect(stats.Get("TEST_TABLE", "ByOwner", "PUT")).Should(Equal(CapacityUnits{Requests: 1, Write: 2, Total: 2}))
+		Expect(stats.Get("TEST_TABLE", "", "QUERY")).Should(Equal(CapacityUnits{Requests: 2, Read: 1, Total: 1}))
+		Expect(stats.Get("TEST_TABLE", "", "BATCH WRITE")).Should(Equal(CapacityUnits{Requests: 1, Write: 1, Total: 1}))
+		Expect(stats.Table("TEST_TABLE")).Should(Equal(CapacityUnits{Requests: 4, Read: 1, Write: 4, Total: 5}))
+		Expect(stats.Snapshot()).Should(HaveLen(4))
+
+		// Reset the stats and verify that they are empty
+		stats.Reset()
+		Expect(stats.Snapshot()).Should(BeEmpty())
+	})
+
+	// Test that, if the connection doesn't request consumed capacity, then requests will be sent as they
+	// were provided and capacity will only be aggregated for those that asked for it
+	It("ConsumedCapacity - Not requested - Only explicit requests aggregated", func() {
+
+		// First, create our test connection with a client that returns consumed capacity when asked
+		client := &capacityDynamoDBClient{}
+		conn := createMockConnection(client)
+
+		// Next, write two items, only one of which asks for the total consumed capacity
+		_, err := conn.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		_, err = conn.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE"),
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the requests and the capacity that was aggregated
+		Expect(client.requested).Should(Equal([]types.ReturnConsumedCapacity{"", types.ReturnConsumedCapacityTotal}))
+		Expect(conn.ConsumedCapacity().Snapshot()).Should(Equal(map[CapacityKey]CapacityUnits{
+			{TableName: "TEST_TABLE", Verb: "PUT"}: {Requests: 1, Write: 3, Total: 3}}))
+	})
+})
+
+// Helper type that returns consumed capacity, whenever it is requested, and records the consumed
+// capacity that was requested
+type capacityDynamoDBClient struct {
+	DynamoDBAPI
+	requested []types.ReturnConsumedCapacity
+}
+
+// PutItem returns the capacity consumed by writing to the table and its index
+func (client *capacityDynamoDBClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	client.requested = append(client.requested, params.ReturnConsumedCapacity)
+	output := dynamodb.PutItemOutput{}
+	switch params.ReturnConsumedCapacity {
+	case types.ReturnConsumedCapacityTotal:
+		output.ConsumedCapacity = &types.ConsumedCapacity{TableName: params.TableName, CapacityUnits: aws.Float64(3)}
+	case types.ReturnConsumedCapacityIndexes:
+		output.ConsumedCapacity = &types.ConsumedCapacity{TableName: params.TableName, CapacityUnits: aws.Float64(3),
+			Table:                  &types.Capacity{CapacityUnits: aws.Float64(1)},
+			GlobalSecondaryIndexes: map[string]types.Capacity{"ByOwner": {CapacityUnits: aws.Float64(2)}}}
+	}
+
+	return &output, nil
+}
+
+// Query returns an empty page and the read capacity consumed by the query
+func (client *capacityDynamoDBClient) Query(ctx context.Context, params *dynamodb.QueryInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	client.requested = append(client.requested, params.ReturnConsumedCapacity)
+	return &dynamodb.QueryOutput{ConsumedCapacity: &types.ConsumedCapacity{TableName: params.TableName,
+		CapacityUnits: aws.Float64(0.5), ReadCapacityUnits: aws.Float64(0.5)}}, nil
+}
+
+// BatchWriteItem processes every request and returns the capacity consumed by each table written to
+func (client *capacityDynamoDBClient) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	client.requested = append(client.requested, params.ReturnConsumedCapacity)
+	output := dynamodb.BatchWriteItemOutput{}
+	for tableName, requests := range params.RequestItems {
+		output.ConsumedCapacity = append(output.ConsumedCapacity, types.ConsumedCapacity{
+			TableName: aws.String(tableName), CapacityUnits: aws.Float64(float64(len(requests)))})
+	}
+
+	return &output, nil
+}
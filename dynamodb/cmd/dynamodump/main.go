@@ -0,0 +1,230 @@
+// Command dynamodump exports a DynamoDB table to a file of JSON lines in the DynamoDB JSON format, or restores
+// such a file to a table. Dumps are written with a parallel scan and restores with batch writes. If a checkpoint
+// file is provided then progress will be saved to it as the command runs, and an interrupted dump or restore
+// will be resumed from it when the command is run again with the same arguments.
+//
+// Usage:
+//
+//	dynamodump -mode dump -table NAME -file PATH [-segments N] [-checkpoint PATH] [-region REGION] [-endpoint URL]
+//	dynamodump -mode restore -table NAME -file PATH [-batch N] [-checkpoint PATH] [-region REGION] [-endpoint URL]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/Woody1193/goutils/dynamodb"
+	"github.com/Woody1193/goutils/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// Helper type that describes the progress of a restore, as saved to a checkpoint file
+type restoreCheckpoint struct {
+	Items int64 `json:"items"`
+}
+
+func main() {
+
+	// First, parse our command-line arguments and check that the required ones were provided
+	mode := flag.String("mode", "", "Whether to dump the table to the file or restore the file to the table (dump|restore)")
+	table := flag.String("table", "", "The name of the DynamoDB table")
+	file := flag.String("file", "", "The path of the dump file")
+	checkpoint := flag.String("checkpoint", "", "The path of a file to which progress should be saved so that the "+
+		"command can be resumed if it is interrupted")
+	segments := flag.Int("segments", 4, "The number of segments to scan concurrently when dumping the table")
+	batch := flag.Int("batch", 100, "The number of items to read from the file before writing them when restoring")
+	region := flag.String("region", "", "The AWS region of the table; if not provided, the default region is used")
+	endpoint := flag.String("endpoint", "", "The endpoint URL of DynamoDB, for use with local DynamoDB")
+	flag.Parse()
+
+	if *table == "" || *file == "" {
+		fail(fmt.Errorf("the -table and -file arguments are required"))
+	}
+
+	// Next, create our connection to DynamoDB, cancelling any requests if the command is interrupted
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	conn, err := connect(ctx, *region, *endpoint)
+	if err != nil {
+		fail(err)
+	}
+
+	// Finally, dump or restore the table depending on the mode requested
+	switch *mode {
+	case "dump":
+		err = dump(ctx, conn, *table, *file, *checkpoint, *segments)
+	case "restore":
+		err = restore(ctx, conn, *table, *file, *checkpoint, *batch)
+	default:
+		err = fmt.Errorf("mode must be dump or restore but was %q", *mode)
+	}
+
+	if err != nil {
+		fail(err)
+	}
+}
+
+// Helper function that creates a connection to DynamoDB from the default AWS config, with the region and
+// endpoint overridden if they were provided
+func connect(ctx context.Context, region string, endpoint string) (*dynamodb.DatabaseConnection, error) {
+	opts := make([]func(*config.LoadOptions) error, 0)
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+
+	if endpoint != "" {
+		opts = append(opts, config.WithEndpointResolver(aws.EndpointResolverFunc(
+			func(service, region string) (aws.Endpoint, error) {
+				return aws.Endpoint{URL: endpoint}, nil
+			})))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return dynamodb.NewDatabaseConnection(cfg, utils.NewLogger("dynamodump", "cli")), nil
+}
+
+// Helper function that dumps the table to the file, resuming from the checkpoint file if it exists
+func dump(ctx context.Context, conn *dynamodb.DatabaseConnection, table string, file string,
+	checkpointFile string, segments int) error {
+
+	// First, load the checkpoint if we have one. If we do then we're resuming so we'll append to the
+	// dump file from the offset of the checkpoint rather than truncating it entirely
+	opts := []dynamodb.IDumpOption{dynamodb.WithDumpSegments(segments)}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	var offset int64
+	if checkpointFile != "" {
+		var checkpoint dynamodb.DumpCheckpoint
+		if found, err := loadCheckpoint(checkpointFile, &checkpoint); err != nil {
+			return err
+		} else if found {
+			opts = append(opts, dynamodb.WithDumpCheckpoint{Checkpoint: &checkpoint})
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+			offset = checkpoint.Offset
+		}
+
+		opts = append(opts, dynamodb.WithDumpProgress(func(progress *dynamodb.DumpCheckpoint) {
+			if err := saveCheckpoint(checkpointFile, progress); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to save checkpoint: %v\n", err)
+			}
+
+			fmt.Printf("\r%d items dumped", progress.Items)
+		}))
+	}
+
+	// Next, open the dump file, discarding anything written after the checkpoint, including any line that
+	// was only partially written when the dump was interrupted, and dump the table to it
+	output, err := os.OpenFile(file, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer output.Close()
+	if err := output.Truncate(offset); err != nil {
+		return err
+	}
+
+	checkpoint, err := conn.DumpTable(ctx, table, output, opts...)
+	if err != nil {
+		return err
+	}
+
+	// Finally, the dump is complete so the checkpoint is no longer needed
+	fmt.Printf("\rDumped %d items from %s to %s\n", checkpoint.Items, table, file)
+	return removeCheckpoint(checkpointFile)
+}
+
+// Helper function that restores the file to the table, resuming from the checkpoint file if it exists
+func restore(ctx context.Context, conn *dynamodb.DatabaseConnection, table string, file string,
+	checkpointFile string, batch int) error {
+
+	// First, load the checkpoint if we have one and skip the items that were already restored
+	opts := []dynamodb.IRestoreOption{dynamodb.WithRestoreBatchSize(batch)}
+	if checkpointFile != "" {
+		var checkpoint restoreCheckpoint
+		if _, err := loadCheckpoint(checkpointFile, &checkpoint); err != nil {
+			return err
+		}
+
+		opts = append(opts, dynamodb.WithRestoreSkip(checkpoint.Items),
+			dynamodb.WithRestoreProgress(func(progress dynamodb.RestoreProgress) {
+				if err := saveCheckpoint(checkpointFile, restoreCheckpoint{Items: progress.Items}); err != nil {
+					fmt.Fprintf(os.Stderr, "failed to save checkpoint: %v\n", err)
+				}
+
+				fmt.Printf("\r%d items restored", progress.Items)
+			}))
+	}
+
+	// Next, open the dump file and restore it to the table
+	input, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+
+	defer input.Close()
+	restored, err := conn.RestoreTable(ctx, table, input, opts...)
+	if err != nil {
+		return err
+	}
+
+	// Finally, the restore is complete so the checkpoint is no longer needed
+	fmt.Printf("\rRestored %d items from %s to %s\n", restored, file, table)
+	return removeCheckpoint(checkpointFile)
+}
+
+// Helper function that loads a checkpoint from a file, returning false if the file doesn't exist
+func loadCheckpoint(path string, checkpoint interface{}) (bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, json.Unmarshal(data, checkpoint)
+}
+
+// Helper function that saves a checkpoint to a file. The checkpoint is written to a temporary file first
+// so that an interruption cannot leave a partially-written checkpoint behind
+func saveCheckpoint(path string, checkpoint interface{}) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+// Helper function that removes a checkpoint file, if one was used
+func removeCheckpoint(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// Helper function that prints an error and exits
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "dynamodump: %v\n", err)
+	os.Exit(1)
+}
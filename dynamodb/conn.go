@@ -3,13 +3,17 @@ package dynamodb
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/Woody1193/goutils/collections"
+	"github.com/Woody1193/goutils/concurrency"
 	"github.com/Woody1193/goutils/utils"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
-	"github.com/aws/smithy-go"
 	"github.com/cenkalti/backoff/v4"
 )
 
@@ -19,7 +23,23 @@ type DatabaseConnection struct {
 	startInterval time.Duration
 	endInterval   time.Duration
 	maxElapsed    time.Duration
+	tagKey        string
 	logger        *utils.Logger
+
+	batchParallelism   int
+	unprocessedRetries int
+
+	returnCapacity   types.ReturnConsumedCapacity
+	capacity         *CapacityStats
+	capacityCallback CapacityCallback
+	limiter          *throughputLimiter
+
+	cursorSecret []byte
+
+	retryPredicate  RetryPredicate
+	backoffPolicies map[string]BackoffFactory
+
+	cache *itemCache
 }
 
 // NewDatabaseConnection creates a new DynamoDB database connection from an AWS session and logger
@@ -36,7 +56,15 @@ func FromClient(inner DynamoDBAPI, logger *utils.Logger, opts ...IDynamoDBOption
 		startInterval: 500,
 		endInterval:   60000,
 		maxElapsed:    900000,
+		tagKey:        "dynamodbav",
 		logger:        logger.ChangeFrame(4),
+
+		batchParallelism:   1,
+		unprocessedRetries: 10,
+
+		capacity: NewCapacityStats(),
+
+		backoffPolicies: make(map[string]BackoffFactory),
 	}
 
 	// Next, iterate over the options provided and update the associated values in the connection
@@ -53,30 +81,50 @@ func (conn *DatabaseConnection) PutItem(ctx context.Context,
 	input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
 
 	// Attempt to retry the operation to put the item in the table; if this
-	// fails then we'll return the associated error. Otherwise, return the output
+	// fails then we'll return the associated error. Otherwise, record the
+	// capacity consumed by the operation and return the output
+	input.ReturnConsumedCapacity = conn.capacityMode(input.ReturnConsumedCapacity)
 	var output *dynamodb.PutItemOutput
 	err := conn.doRetry(ctx, *input.TableName, "PUT", func() error {
 		var inner error
-		output, inner = conn.db.PutItem(ctx, input)
+		if output, inner = conn.db.PutItem(ctx, input); inner == nil {
+			conn.recordCapacity("PUT", capacityOf(output.ConsumedCapacity)...)
+		}
+
 		return inner
 	})
 
+	conn.cachePut(input, err)
 	return output, err
 }
 
-// GetItem retrieves an item from DynamoDB
+// GetItem retrieves an item from DynamoDB. If the connection has an item cache then the item will be read
+// from the cache, if it is there, and saved to the cache after it is read from DynamoDB
 func (conn *DatabaseConnection) GetItem(ctx context.Context,
 	input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
 
-	// Attempt to retry the operation to get the item from the table; if this
-	// fails then we'll return the associated error. Otherwise, return the output
+	// First, if the connection has an item cache and it contains the item then return it
+	if output, ok := conn.getCached(input); ok {
+		return output, nil
+	}
+
+	// Next, attempt to retry the operation to get the item from the table; if this
+	// fails then we'll return the associated error. Otherwise, record the
+	// capacity consumed by the operation and save the item to the cache, so long
+	// as it wasn't written while we were reading it
+	ticket := conn.startCacheGet(input)
+	input.ReturnConsumedCapacity = conn.capacityMode(input.ReturnConsumedCapacity)
 	var output *dynamodb.GetItemOutput
 	err := conn.doRetry(ctx, *input.TableName, "GET", func() error {
 		var inner error
-		output, inner = conn.db.GetItem(ctx, input)
+		if output, inner = conn.db.GetItem(ctx, input); inner == nil {
+			conn.recordCapacity("GET", capacityOf(output.ConsumedCapacity)...)
+		}
+
 		return inner
 	})
 
+	conn.cacheGet(ticket, output, err)
 	return output, err
 }
 
@@ -85,14 +133,20 @@ func (conn *DatabaseConnection) UpdateItem(ctx context.Context,
 	input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
 
 	// Attempt to retry the operation to update the item in the table; if this
-	// fails then we'll return the associated error. Otherwise, return the output
+	// fails then we'll return the associated error. Otherwise, record the
+	// capacity consumed by the operation and return the output
+	input.ReturnConsumedCapacity = conn.capacityMode(input.ReturnConsumedCapacity)
 	var output *dynamodb.UpdateItemOutput
 	err := conn.doRetry(ctx, *input.TableName, "UPDATE", func() error {
 		var inner error
-		output, inner = conn.db.UpdateItem(ctx, input)
+		if output, inner = conn.db.UpdateItem(ctx, input); inner == nil {
+			conn.recordCapacity("UPDATE", capacityOf(output.ConsumedCapacity)...)
+		}
+
 		return inner
 	})
 
+	conn.cacheUpdate(input, output, err)
 	return output, err
 }
 
@@ -101,65 +155,150 @@ func (conn *DatabaseConnection) DeleteItem(ctx context.Context,
 	input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
 
 	// Attempt to retry the operation to delete the item from the table; if this
-	// fails then we'll return the associated error. Otherwise, return the output
+	// fails then we'll return the associated error. Otherwise, record the
+	// capacity consumed by the operation and return the output
+	input.ReturnConsumedCapacity = conn.capacityMode(input.ReturnConsumedCapacity)
 	var output *dynamodb.DeleteItemOutput
 	err := conn.doRetry(ctx, *input.TableName, "DELETE", func() error {
 		var inner error
-		output, inner = conn.db.DeleteItem(ctx, input)
+		if output, inner = conn.db.DeleteItem(ctx, input); inner == nil {
+			conn.recordCapacity("DELETE", capacityOf(output.ConsumedCapacity)...)
+		}
+
 		return inner
 	})
 
+	if conn.cache.enabled(*input.TableName) {
+		conn.cache.invalidate(*input.TableName, input.Key)
+	}
+
 	return output, err
 }
 
 // BatchWrite makes a number of write requests against a table in DynamoDB. This
-// function does not return collection or capacity statistics.
+// function does not return collection or capacity statistics, although consumed capacity
+// is recorded on the connection. See BatchWriteMany for more information on how the
+// requests are processed
 func (conn *DatabaseConnection) BatchWrite(ctx context.Context, tableName string,
 	requests ...types.WriteRequest) error {
+	return conn.BatchWriteMany(ctx, map[string][]types.WriteRequest{tableName: requests})
+}
 
-	// First, calculate the length from the number of requests. If there were no requests
-	// then exit (this also serves as the base case for our recursion)
-	length := len(requests)
+// BatchWriteMany makes a number of write requests against one or more tables in DynamoDB.
+// The requests will be chunked into pages of no more than 25 requests, which is the limit
+// imposed by AWS, and the pages will be sent concurrently, up to the batch parallelism set
+// on the connection. Any unprocessed items returned by DynamoDB will be resubmitted, with an
+// exponential backoff between each submission, until the unprocessed retry limit set on the
+// connection is reached. If any requests remain unprocessed after that then a BatchWriteError
+// containing them will be returned. This function does not return collection or capacity statistics,
+// although consumed capacity is recorded on the connection.
+func (conn *DatabaseConnection) BatchWriteMany(ctx context.Context,
+	requests map[string][]types.WriteRequest) error {
+
+	// First, flatten all the requests into a single list; sort the table names first so that the
+	// chunks we create are deterministic. If there were no requests then exit
+	tableNames := collections.Keys(requests)
+	sort.Strings(tableNames)
+	entries := make([]*batchWriteEntry, 0)
+	for _, tableName := range tableNames {
+		for _, request := range requests[tableName] {
+			entries = append(entries, &batchWriteEntry{tableName: tableName, request: request})
+		}
+	}
+
+	length := len(entries)
 	if length == 0 {
 		return nil
 	}
 
-	conn.logger.Log("Attempting batch-write of %d entries to %s...", length, tableName)
+	conn.logger.Log("Attempting batch-write of %d entries to %s...", length, strings.Join(tableNames, ", "))
 
-	// Next, iterate over all the requests and chunk them so we don't have issues with the AWS
-	// batch size and request limits; accumulate any unprocessed items
-	retries := make([]types.WriteRequest, 0)
+	// Next, iterate over all the entries and chunk them so we don't have issues with the AWS
+	// batch size and request limits; write each chunk to a channel that our workers will read from
+	chunks := make(chan map[string][]types.WriteRequest, length/25+1)
 	for current := 0; current < length; current += 25 {
 		next := current + 25
-
-		// First, get the chunk of data we'll attempt to write. If we have 25 or more items left in
-		// the chunk then we'll take the whole page; otherwise, we'll take the remainder of the list
-		var chunk []types.WriteRequest
 		if next > length {
-			chunk = requests[current:]
-		} else {
-			chunk = requests[current:next]
+			next = length
 		}
 
-		// Next, attempt to write the items to DynamoDB; if this fails then return an error
-		unprocessed, err := conn.batchWriteInner(ctx, tableName, chunk)
-		if err != nil {
-			return err
+		chunk := make(map[string][]types.WriteRequest)
+		for _, entry := range entries[current:next] {
+			chunk[entry.tableName] = append(chunk[entry.tableName], entry.request)
+		}
+
+		chunks <- chunk
+	}
+
+	close(chunks)
+
+	// Now, determine the number of workers we'll use to write the chunks; we don't need more
+	// workers than we have chunks so limit the number here
+	workers := conn.batchParallelism
+	if workers > len(chunks) {
+		workers = len(chunks)
+	} else if workers < 1 {
+		workers = 1
+	}
+
+	// Write all the chunks to DynamoDB, accumulating any items that could not be processed. If
+	// any of the chunks fails then we'll cancel the remaining workers and return the error
+	lock := new(sync.Mutex)
+	failed := make(map[string][]types.WriteRequest)
+	count := 0
+	err := concurrency.ForAllAsync(ctx, workers, true, func(ctx context.Context, _ int, _ context.CancelFunc) error {
+		for chunk := range chunks {
+
+			// First, attempt to write the chunk, resubmitting any unprocessed items; if this
+			// fails then return an error
+			unprocessed, err := conn.batchWriteChunk(ctx, chunk)
+			if err != nil {
+				return err
+			}
+
+			// Next, save any items that could not be processed so they can be reported
+			lock.Lock()
+			for tableName, items := range unprocessed {
+				failed[tableName] = append(failed[tableName], items...)
+				count += len(items)
+			}
+
+			lock.Unlock()
 		}
 
-		// Finally, save any unprocessed items so they can be written later
-		retries = append(retries, unprocessed...)
+		return nil
+	})
+
+	// Remove the items we wrote from the item cache, if there is one. We do this whether or not the
+	// writes succeeded as we can't be sure which of them were applied
+	for _, tableName := range tableNames {
+		conn.cacheWrites(tableName, requests[tableName]...)
 	}
 
-	// Finally, again attempt to do a batch write to the table with our unprocessed data
-	conn.logger.Log("Batch-write to %s completed. Retries? %t", len(retries) == 0)
-	return conn.BatchWrite(ctx, tableName, retries...)
+	// If one of the workers failed then return the error
+	if err != nil {
+		return err
+	}
+
+	// Finally, if we had any items that could not be processed then return an error containing them
+	conn.logger.Log("Batch-write to %s completed. Unprocessed: %d", strings.Join(tableNames, ", "), count)
+	if count > 0 {
+		failedNames := collections.Keys(failed)
+		sort.Strings(failedNames)
+		err := conn.NewError(nil, strings.Join(failedNames, ", "),
+			"Batch-write failed with %d unprocessed requests", count)
+		return &BatchWriteError{GError: err.GError, TableName: err.TableName, Unprocessed: failed}
+	}
+
+	return nil
 }
 
 // Query makes a search on a DynamoDB table and returns the results. This function does not
-// return capacity statistics, just the queried results.
+// return capacity statistics, just the queried results, although consumed capacity is recorded
+// on the connection.
 func (conn *DatabaseConnection) Query(ctx context.Context,
 	input *dynamodb.QueryInput) ([]map[string]types.AttributeValue, error) {
+	input.ReturnConsumedCapacity = conn.capacityMode(input.ReturnConsumedCapacity)
 	results := make([]map[string]types.AttributeValue, 0)
 
 	// We'll start a loop that will query each page of results until all the pages have been retrieved
@@ -169,7 +308,10 @@ func (conn *DatabaseConnection) Query(ctx context.Context,
 		var output *dynamodb.QueryOutput
 		err := conn.doRetry(ctx, *input.TableName, fmt.Sprintf("QUERY(%d)", index), func() error {
 			var inner error
-			output, inner = conn.db.Query(ctx, input)
+			if output, inner = conn.db.Query(ctx, input); inner == nil {
+				conn.recordCapacity("QUERY", capacityOf(output.ConsumedCapacity)...)
+			}
+
 			return inner
 		})
 
@@ -195,26 +337,111 @@ func (conn *DatabaseConnection) Query(ctx context.Context,
 	return results, nil
 }
 
+// Scan reads every item from a DynamoDB table or index and returns the results. This function does
+// not return capacity statistics, just the scanned results, although consumed capacity is recorded
+// on the connection.
+func (conn *DatabaseConnection) Scan(ctx context.Context,
+	input *dynamodb.ScanInput) ([]map[string]types.AttributeValue, error) {
+	input.ReturnConsumedCapacity = conn.capacityMode(input.ReturnConsumedCapacity)
+	results := make([]map[string]types.AttributeValue, 0)
+
+	// We'll start a loop that will scan each page of results until all the pages have been retrieved
+	for index := 0; ; index++ {
+
+		// First, attempt the scan with a backoff-retry loop
+		var output *dynamodb.ScanOutput
+		err := conn.doRetry(ctx, *input.TableName, fmt.Sprintf("SCAN(%d)", index), func() error {
+			var inner error
+			if output, inner = conn.db.Scan(ctx, input); inner == nil {
+				conn.recordCapacity("SCAN", capacityOf(output.ConsumedCapacity)...)
+			}
+
+			return inner
+		})
+
+		// If the scan failed then pass the error back up
+		if err != nil {
+			return nil, err
+		}
+
+		// Next, append the results from the scan to our accumulated list of results
+		results = append(results, output.Items...)
+
+		// Finally, check if the last-evaluated key is nil. If it is then we've finished our scan so
+		// we can break out of the loop. Otherwise, we'll use it to set the exclusive start key on the
+		// input so we can get the next page
+		if output.LastEvaluatedKey != nil {
+			input.ExclusiveStartKey = output.LastEvaluatedKey
+		} else {
+			break
+		}
+	}
+
+	// Return the accumulated results
+	return results, nil
+}
+
+// Helper function that writes a single chunk of write requests to DynamoDB, resubmitting any
+// unprocessed items with an exponential backoff until they are all processed or the unprocessed
+// retry limit set on the connection is reached. Any items that remain unprocessed will be returned
+func (conn *DatabaseConnection) batchWriteChunk(ctx context.Context,
+	chunk map[string][]types.WriteRequest) (map[string][]types.WriteRequest, error) {
+
+	// Attempt to write the chunk to DynamoDB until there are no unprocessed items left
+	timer := conn.createUnprocessedBackoff()
+	for {
+
+		// First, attempt to write the items to DynamoDB; if this fails then return an error
+		unprocessed, err := conn.batchWriteInner(ctx, chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		// Next, if all the items were processed then we're done so return here
+		if len(unprocessed) == 0 {
+			return nil, nil
+		}
+
+		// Finally, wait for the backoff to expire before resubmitting the unprocessed items. If the
+		// context was cancelled then return that error; otherwise, if we've run out of retries then
+		// return the unprocessed items so they can be reported
+		if err := waitForBackoff(ctx, timer); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+
+			return unprocessed, nil
+		}
+
+		chunk = unprocessed
+	}
+}
+
 // Helper function that writes a single batch (no more than a single page) of write requests to
-// a single table in DynamoDB
-func (conn *DatabaseConnection) batchWriteInner(ctx context.Context, tableName string,
-	inputs []types.WriteRequest) ([]types.WriteRequest, error) {
+// one or more tables in DynamoDB
+func (conn *DatabaseConnection) batchWriteInner(ctx context.Context,
+	inputs map[string][]types.WriteRequest) (map[string][]types.WriteRequest, error) {
 
-	// Create our batch write input from the inputs
+	// Create our batch write input from the inputs and a description of the tables we're writing to
 	request := dynamodb.BatchWriteItemInput{
-		ReturnConsumedCapacity:      types.ReturnConsumedCapacityNone,
+		ReturnConsumedCapacity:      conn.capacityMode(types.ReturnConsumedCapacityNone),
 		ReturnItemCollectionMetrics: types.ReturnItemCollectionMetricsNone,
-		RequestItems: map[string][]types.WriteRequest{
-			tableName: inputs,
-		},
+		RequestItems:                inputs,
 	}
 
+	tableNames := collections.Keys(inputs)
+	sort.Strings(tableNames)
+	tableName := strings.Join(tableNames, ", ")
+
 	// Attempt to retry the operation to batch-write the items to the table; if this
 	// fails then we'll return the associated error. Otherwise, return the output
 	var output *dynamodb.BatchWriteItemOutput
 	err := conn.doRetry(ctx, tableName, "BATCH WRITE", func() error {
 		var inner error
-		output, inner = conn.db.BatchWriteItem(ctx, &request)
+		if output, inner = conn.db.BatchWriteItem(ctx, &request); inner == nil {
+			conn.recordCapacity("BATCH WRITE", output.ConsumedCapacity...)
+		}
+
 		return inner
 	})
 
@@ -224,7 +451,7 @@ func (conn *DatabaseConnection) batchWriteInner(ctx context.Context, tableName s
 	}
 
 	// The backoff did not return an error so return any unprocessed items
-	return output.UnprocessedItems[tableName], nil
+	return output.UnprocessedItems, nil
 }
 
 // Helper function that does a retry operation to handle a number of common AWS DynamoDB retry cases
@@ -234,29 +461,28 @@ func (conn *DatabaseConnection) doRetry(ctx context.Context, tableName string, v
 
 	// Attempt the operation with a backoff in the case where an intermittent failure occurs
 	err := backoff.Retry(func() error {
+
+		// If the connection limits its throughput then wait until it can send the request without
+		// exceeding its limits; this can only fail if the context was cancelled so don't retry
+		if err := conn.throttle(ctx, tableName, verb); err != nil {
+			return backoff.Permanent(err)
+		}
+
 		if err := operation(); err != nil {
-			var message string
-
-			// Check that the error type is one that we'd want to retry on. For throughput or request
-			// limit exceptions, waiting a bit may allow the request to succeed. For internal server
-			// errors, since we're not sure of the cause, we'll wait to see if the service manages
-			// to fix itself. Otherwise, we'll return the error wrapped in a permanent failure
-			inner := err.(*smithy.OperationError)
-			switch casted := inner.Err.(type) {
-			case *types.ProvisionedThroughputExceededException:
-				message = *casted.Message
-			case *types.RequestLimitExceeded:
-				message = *casted.Message
-			case *types.InternalServerError:
-				message = *casted.Message
-			default:
+
+			// Check that the error is one that we'd want to retry on. For throughput, throttling or request
+			// limit exceptions, waiting a bit may allow the request to succeed. For internal server errors
+			// and network errors, since we're not sure of the cause, we'll wait to see if the problem fixes
+			// itself. If the user provided their own retry predicate then we'll use that instead. Otherwise,
+			// we'll return the error wrapped in a permanent failure
+			if !conn.shouldRetry(verb, err) {
 				return backoff.Permanent(err)
 			}
 
 			// If we reached this point then we want to retry so log a message stating that there was
 			// a failure and we're going to retry
 			conn.logger.Log("DynamoDB request to %s failed: %s. Retrying...",
-				tableName, message)
+				tableName, retryMessage(err))
 			return err
 		}
 
@@ -264,7 +490,7 @@ func (conn *DatabaseConnection) doRetry(ctx context.Context, tableName string, v
 		// there's nothing else to do here
 		conn.logger.Log("Completed %s operation to %s in DynamoDB", verb, tableName)
 		return nil
-	}, backoff.WithContext(conn.createExponentialBackoff(), ctx))
+	}, backoff.WithContext(conn.createBackoff(verb), ctx))
 
 	// For whatever reason, the operation failed so create an error and return it
 	if err != nil {
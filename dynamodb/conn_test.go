@@ -167,10 +167,10 @@ var _ = Describe("Database Connection Tests", Ordered, func() {
 		Expect(err).Should(HaveOccurred())
 		Expect(casted.TableName).Should(Equal("FAKE_TABLE"))
 		testutils.ErrorVerifier("test", "dynamodb", "/goutils/dynamodb/conn.go", "DatabaseConnection",
-			"PutItem", 58, testutils.InnerErrorPrefixSuffixVerifier("operation error DynamoDB: PutItem, "+
+			"PutItem", 88, testutils.InnerErrorPrefixSuffixVerifier("operation error DynamoDB: PutItem, "+
 				"https response error StatusCode: 400, RequestID: ", ", ResourceNotFoundException: "),
 			"PUT request to FAKE_TABLE in DynamoDB failed", "[test] dynamodb.DatabaseConnection.PutItem "+
-				"(/goutils/dynamodb/conn.go 58): PUT request to FAKE_TABLE in DynamoDB failed, Inner: "+
+				"(/goutils/dynamodb/conn.go 88): PUT request to FAKE_TABLE in DynamoDB failed, Inner: "+
 				"operation error DynamoDB: PutItem, https response error StatusCode: 400, RequestID: ",
 			", ResourceNotFoundException: .")(casted.GError)
 	})
@@ -269,10 +269,10 @@ var _ = Describe("Database Connection Tests", Ordered, func() {
 		Expect(err).Should(HaveOccurred())
 		Expect(casted.TableName).Should(Equal("FAKE_TABLE"))
 		testutils.ErrorVerifier("test", "dynamodb", "/goutils/dynamodb/conn.go", "DatabaseConnection",
-			"GetItem", 74, testutils.InnerErrorPrefixSuffixVerifier("operation error DynamoDB: GetItem, "+
+			"GetItem", 118, testutils.InnerErrorPrefixSuffixVerifier("operation error DynamoDB: GetItem, "+
 				"https response error StatusCode: 400, RequestID: ", ", ResourceNotFoundException: "),
 			"GET request to FAKE_TABLE in DynamoDB failed", "[test] dynamodb.DatabaseConnection.GetItem "+
-				"(/goutils/dynamodb/conn.go 74): GET request to FAKE_TABLE in DynamoDB failed, Inner: "+
+				"(/goutils/dynamodb/conn.go 118): GET request to FAKE_TABLE in DynamoDB failed, Inner: "+
 				"operation error DynamoDB: GetItem, https response error StatusCode: 400, RequestID: ",
 			", ResourceNotFoundException: .")(casted.GError)
 	})
@@ -370,10 +370,10 @@ var _ = Describe("Database Connection Tests", Ordered, func() {
 		Expect(err).Should(HaveOccurred())
 		Expect(casted.TableName).Should(Equal("FAKE_TABLE"))
 		testutils.ErrorVerifier("test", "dynamodb", "/goutils/dynamodb/conn.go", "DatabaseConnection",
-			"UpdateItem", 90, testutils.InnerErrorPrefixSuffixVerifier("operation error DynamoDB: UpdateItem, "+
+			"UpdateItem", 140, testutils.InnerErrorPrefixSuffixVerifier("operation error DynamoDB: UpdateItem, "+
 				"https response error StatusCode: 400, RequestID: ", ", ResourceNotFoundException: "),
 			"UPDATE request to FAKE_TABLE in DynamoDB failed", "[test] dynamodb.DatabaseConnection.UpdateItem "+
-				"(/goutils/dynamodb/conn.go 90): UPDATE request to FAKE_TABLE in DynamoDB failed, Inner: "+
+				"(/goutils/dynamodb/conn.go 140): UPDATE request to FAKE_TABLE in DynamoDB failed, Inner: "+
 				"operation error DynamoDB: UpdateItem, https response error StatusCode: 400, RequestID: ",
 			", ResourceNotFoundException: .")(casted.GError)
 	})
@@ -475,10 +475,10 @@ var _ = Describe("Database Connection Tests", Ordered, func() {
 		Expect(err).Should(HaveOccurred())
 		Expect(casted.TableName).Should(Equal("FAKE_TABLE"))
 		testutils.ErrorVerifier("test", "dynamodb", "/goutils/dynamodb/conn.go", "DatabaseConnection",
-			"DeleteItem", 106, testutils.InnerErrorPrefixSuffixVerifier("operation error DynamoDB: DeleteItem, "+
+			"DeleteItem", 162, testutils.InnerErrorPrefixSuffixVerifier("operation error DynamoDB: DeleteItem, "+
 				"https response error StatusCode: 400, RequestID: ", ", ResourceNotFoundException: "),
 			"DELETE request to FAKE_TABLE in DynamoDB failed", "[test] dynamodb.DatabaseConnection.DeleteItem "+
-				"(/goutils/dynamodb/conn.go 106): DELETE request to FAKE_TABLE in DynamoDB failed, Inner: "+
+				"(/goutils/dynamodb/conn.go 162): DELETE request to FAKE_TABLE in DynamoDB failed, Inner: "+
 				"operation error DynamoDB: DeleteItem, https response error StatusCode: 400, RequestID: ",
 			", ResourceNotFoundException: .")(casted.GError)
 	})
@@ -573,10 +573,10 @@ var _ = Describe("Database Connection Tests", Ordered, func() {
 		Expect(err).Should(HaveOccurred())
 		Expect(casted.TableName).Should(Equal("FAKE_TABLE"))
 		testutils.ErrorVerifier("test", "dynamodb", "/goutils/dynamodb/conn.go", "DatabaseConnection",
-			"batchWriteInner", 215, testutils.InnerErrorPrefixSuffixVerifier("operation error DynamoDB: BatchWriteItem, "+
+			"batchWriteInner", 439, testutils.InnerErrorPrefixSuffixVerifier("operation error DynamoDB: BatchWriteItem, "+
 				"https response error StatusCode: 400, RequestID: ", ", ResourceNotFoundException: "),
 			"BATCH WRITE request to FAKE_TABLE in DynamoDB failed", "[test] dynamodb.DatabaseConnection.batchWriteInner "+
-				"(/goutils/dynamodb/conn.go 215): BATCH WRITE request to FAKE_TABLE in DynamoDB failed, Inner: "+
+				"(/goutils/dynamodb/conn.go 439): BATCH WRITE request to FAKE_TABLE in DynamoDB failed, Inner: "+
 				"operation error DynamoDB: BatchWriteItem, https response error StatusCode: 400, RequestID: ",
 			", ResourceNotFoundException: .")(casted.GError)
 	})
@@ -671,10 +671,10 @@ var _ = Describe("Database Connection Tests", Ordered, func() {
 		Expect(err).Should(HaveOccurred())
 		Expect(casted.TableName).Should(Equal("FAKE_TABLE"))
 		testutils.ErrorVerifier("test", "dynamodb", "/goutils/dynamodb/conn.go", "DatabaseConnection",
-			"Query", 170, testutils.InnerErrorPrefixSuffixVerifier("operation error DynamoDB: Query, "+
+			"Query", 309, testutils.InnerErrorPrefixSuffixVerifier("operation error DynamoDB: Query, "+
 				"https response error StatusCode: 400, RequestID: ", ", ResourceNotFoundException: "),
 			"QUERY(0) request to FAKE_TABLE in DynamoDB failed", "[test] dynamodb.DatabaseConnection.Query "+
-				"(/goutils/dynamodb/conn.go 170): QUERY(0) request to FAKE_TABLE in DynamoDB failed, Inner: "+
+				"(/goutils/dynamodb/conn.go 309): QUERY(0) request to FAKE_TABLE in DynamoDB failed, Inner: "+
 				"operation error DynamoDB: Query, https response error StatusCode: 400, RequestID: ",
 			", ResourceNotFoundException: .")(casted.GError)
 	})
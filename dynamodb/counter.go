@@ -0,0 +1,205 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Counter describes a numeric attribute on a DynamoDB item that can be modified atomically. If the item, or
+// the attribute, doesn't exist then the counter will be treated as having a value of zero
+type Counter struct {
+	TableName string
+	Key       map[string]types.AttributeValue
+	Attribute string
+}
+
+// ICounterOption defines the functionality that will allow the limits on a counter update to be modified
+type ICounterOption interface {
+	Apply(*counterLimits)
+}
+
+// WithCounterFloor allows the user to set the lowest value that a counter may have after it is updated. If
+// the update would take the counter below this value then it will not be applied
+type WithCounterFloor int64
+
+// Apply modifies the counter limits so that they have the floor defined by this object
+func (w WithCounterFloor) Apply(limits *counterLimits) {
+	limits.floor = aws.Int64(int64(w))
+}
+
+// WithCounterCeiling allows the user to set the highest value that a counter may have after it is updated. If
+// the update would take the counter above this value then it will not be applied
+type WithCounterCeiling int64
+
+// Apply modifies the counter limits so that they have the ceiling defined by this object
+func (w WithCounterCeiling) Apply(limits *counterLimits) {
+	limits.ceiling = aws.Int64(int64(w))
+}
+
+// Helper type that describes the limits on the value of a counter after it is updated
+type counterLimits struct {
+	floor   *int64
+	ceiling *int64
+}
+
+// Increment atomically adds the delta to the counter, which may be negative, and returns the new value of the
+// counter. If a floor or ceiling is provided then the update will only be applied if the new value of the
+// counter would be within those limits. Otherwise, a CounterLimitError will be returned
+func (conn *DatabaseConnection) Increment(ctx context.Context, counter Counter, delta int64,
+	opts ...ICounterOption) (int64, error) {
+
+	// First, create our update request that adds the delta to the counter and returns the new value
+	input := dynamodb.UpdateItemInput{
+		TableName:                aws.String(counter.TableName),
+		Key:                      counter.Key,
+		UpdateExpression:         aws.String("ADD #counter :delta"),
+		ExpressionAttributeNames: map[string]string{"#counter": counter.Attribute},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":delta": &types.AttributeValueMemberN{Value: strconv.FormatInt(delta, 10)}},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	}
+
+	// Next, apply our options to get the limits on the counter and add a condition enforcing them
+	var limits counterLimits
+	for _, opt := range opts {
+		opt.Apply(&limits)
+	}
+
+	if condition := limits.condition(delta, input.ExpressionAttributeValues); condition != "" {
+		input.ConditionExpression = aws.String(condition)
+	}
+
+	// Now, attempt to update the counter; if the condition failed then return an error describing the
+	// limit that would have been exceeded
+	output, err := conn.UpdateItem(ctx, &input)
+	if err != nil {
+		if casted, ok := isConditionFailed(err); ok {
+			return 0, &CounterLimitError{
+				GError:    casted.GError,
+				TableName: counter.TableName,
+				Attribute: counter.Attribute,
+				Delta:     delta,
+			}
+		}
+
+		return 0, err
+	}
+
+	// Finally, read the new value of the counter from the output and return it
+	value, ok := output.Attributes[counter.Attribute].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, conn.NewError(fmt.Errorf("counter was not returned as a number"), counter.TableName,
+			"Failed to read counter %s from %s", counter.Attribute, counter.TableName)
+	}
+
+	updated, err := strconv.ParseInt(value.Value, 10, 64)
+	if err != nil {
+		return 0, conn.NewError(err, counter.TableName, "Failed to read counter %s from %s",
+			counter.Attribute, counter.TableName)
+	}
+
+	return updated, nil
+}
+
+// Decrement atomically subtracts the delta from the counter and returns the new value of the counter. This
+// function behaves in the same way as Increment with the delta negated
+func (conn *DatabaseConnection) Decrement(ctx context.Context, counter Counter, delta int64,
+	opts ...ICounterOption) (int64, error) {
+	return conn.Increment(ctx, counter, -delta, opts...)
+}
+
+// Helper function that creates a condition expression requiring that the counter is within the limits after
+// the delta is added to it, adding the attribute values it uses to the values provided. If the counter doesn't
+// exist then it is treated as zero so the condition will allow the update if the delta is within the limits.
+// If there are no limits then an empty condition will be returned
+func (limits counterLimits) condition(delta int64, values map[string]types.AttributeValue) string {
+	if limits.floor == nil && limits.ceiling == nil {
+		return ""
+	}
+
+	// First, create a check for each of the limits, adjusted by the delta so that it can be compared
+	// against the current value of the counter
+	checks := make([]string, 0, 2)
+	missing := true
+	if limits.floor != nil {
+		checks = append(checks, "#counter >= :floor")
+		values[":floor"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(*limits.floor-delta, 10)}
+		missing = missing && delta >= *limits.floor
+	}
+
+	if limits.ceiling != nil {
+		checks = append(checks, "#counter <= :ceiling")
+		values[":ceiling"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(*limits.ceiling-delta, 10)}
+		missing = missing && delta <= *limits.ceiling
+	}
+
+	// Next, if a missing counter would be within the limits after the update then allow it as well
+	condition := strings.Join(checks, " AND ")
+	if missing {
+		condition = fmt.Sprintf("attribute_not_exists(#counter) OR (%s)", condition)
+	}
+
+	return condition
+}
+
+// SequenceGenerator generates unique, increasing IDs from a counter stored in DynamoDB. To minimise the number
+// of requests made, the generator reserves a block of IDs at a time by incrementing the counter by the block
+// size, and then hands out IDs from that block until it is exhausted. As each process reserves its own blocks,
+// IDs will be unique across processes but will not be strictly ordered between them, and any IDs left in a
+// block when a process exits will never be used. The first ID generated from a new counter will be 1. The
+// generator is safe for concurrent use
+type SequenceGenerator struct {
+	conn      *DatabaseConnection
+	counter   Counter
+	blockSize int64
+	next      int64
+	last      int64
+	lock      *sync.Mutex
+}
+
+// NewSequenceGenerator creates a new sequence generator that reserves blocks of IDs, of the size provided,
+// from the counter. If the block size is less than one then IDs will be reserved one at a time
+func (conn *DatabaseConnection) NewSequenceGenerator(counter Counter, blockSize int64) *SequenceGenerator {
+	if blockSize < 1 {
+		blockSize = 1
+	}
+
+	return &SequenceGenerator{
+		conn:      conn,
+		counter:   counter,
+		blockSize: blockSize,
+		next:      1,
+		lock:      new(sync.Mutex),
+	}
+}
+
+// Next returns the next ID from the generator, reserving a new block of IDs from DynamoDB if the current
+// block has been exhausted
+func (gen *SequenceGenerator) Next(ctx context.Context) (int64, error) {
+	gen.lock.Lock()
+	defer gen.lock.Unlock()
+
+	// First, if we've used every ID in the current block then reserve a new block by incrementing the
+	// counter by the block size. The counter holds the last ID in the block that was reserved
+	if gen.next > gen.last {
+		last, err := gen.conn.Increment(ctx, gen.counter, gen.blockSize)
+		if err != nil {
+			return 0, err
+		}
+
+		gen.next = last - gen.blockSize + 1
+		gen.last = last
+	}
+
+	// Next, take the next ID from the block and return it
+	id := gen.next
+	gen.next++
+	return id, nil
+}
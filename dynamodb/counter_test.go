@@ -0,0 +1,161 @@
+package dynamodb
+
+import (
+	"context"
+	"sync"
+
+	fake "github.com/Woody1193/goutils/dynamodb/testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Counter Tests", func() {
+
+	// Test that Increment and Decrement atomically modify the counter, treating a missing counter as zero
+	It("Increment - No limits - Counter updated", func() {
+
+		// First, create our test connection with a counter table
+		conn := createCounterConnection()
+		counter := createTestCounter("test_id")
+
+		// Next, increment the counter twice and decrement it once; none of these should fail
+		value, err := conn.Increment(context.Background(), counter, 5)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(value).Should(Equal(int64(5)))
+
+		value, err = conn.Increment(context.Background(), counter, 3)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(value).Should(Equal(int64(8)))
+
+		value, err = conn.Decrement(context.Background(), counter, 10)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(value).Should(Equal(int64(-2)))
+
+		// Finally, verify the value stored in DynamoDB
+		obj, err := GetAs[counterObject](context.Background(), conn, &dynamodb.GetItemInput{
+			TableName: aws.String("COUNTER_TABLE"), Key: counter.Key})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(obj.Count).Should(Equal(int64(-2)))
+	})
+
+	// Test that, if the update would take the counter outside its limits, then a CounterLimitError will
+	// be returned and the counter will not be modified
+	DescribeTable("Increment - Limits provided - Enforced",
+		func(initial int64, delta int64, opts []ICounterOption, expected int64, exceeded bool) {
+
+			// First, create our test connection with a counter table and set the initial value of the
+			// counter, if it should have one
+			conn := createCounterConnection()
+			counter := createTestCounter("test_id")
+			if initial != 0 {
+				_, err := conn.Increment(context.Background(), counter, initial)
+				Expect(err).ShouldNot(HaveOccurred())
+			}
+
+			// Next, attempt to update the counter with the limits provided
+			value, err := conn.Increment(context.Background(), counter, delta, opts...)
+
+			// Finally, verify the new value or the details of the error
+			if exceeded {
+				casted := err.(*CounterLimitError)
+				Expect(casted.TableName).Should(Equal("COUNTER_TABLE"))
+				Expect(casted.Attribute).Should(Equal("count"))
+				Expect(casted.Delta).Should(Equal(delta))
+			} else {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(value).Should(Equal(expected))
+			}
+		},
+		Entry("Missing, within ceiling - Updated", int64(0), int64(3),
+			[]ICounterOption{WithCounterCeiling(3)}, int64(3), false),
+		Entry("Missing, above ceiling - Error", int64(0), int64(4),
+			[]ICounterOption{WithCounterCeiling(3)}, int64(0), true),
+		Entry("Missing, below floor - Error", int64(0), int64(-1),
+			[]ICounterOption{WithCounterFloor(0)}, int64(0), true),
+		Entry("Exists, within limits - Updated", int64(5), int64(-5),
+			[]ICounterOption{WithCounterFloor(0), WithCounterCeiling(10)}, int64(0), false),
+		Entry("Exists, below floor - Error", int64(5), int64(-6),
+			[]ICounterOption{WithCounterFloor(0), WithCounterCeiling(10)}, int64(0), true),
+		Entry("Exists, above ceiling - Error", int64(5), int64(6),
+			[]ICounterOption{WithCounterFloor(0), WithCounterCeiling(10)}, int64(0), true))
+
+	// Test that the sequence generator hands out consecutive IDs from each block it reserves and that
+	// generators sharing a counter never hand out the same ID
+	It("SequenceGenerator - Multiple generators - Unique IDs", func() {
+
+		// First, create our test connection with a counter table and two generators sharing a counter
+		conn := createCounterConnection()
+		first := conn.NewSequenceGenerator(createTestCounter("sequence"), 10)
+		second := conn.NewSequenceGenerator(createTestCounter("sequence"), 10)
+
+		// Next, generate an ID from each generator; these should come from different blocks
+		id, err := first.Next(context.Background())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(id).Should(Equal(int64(1)))
+
+		id, err = second.Next(context.Background())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(id).Should(Equal(int64(11)))
+
+		// Now, generate IDs concurrently from both generators until several blocks have been reserved
+		ids := make(chan int64, 100)
+		wg := new(sync.WaitGroup)
+		for _, gen := range []*SequenceGenerator{first, second, first, second} {
+			wg.Add(1)
+			go func(gen *SequenceGenerator) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for i := 0; i < 20; i++ {
+					id, err := gen.Next(context.Background())
+					Expect(err).ShouldNot(HaveOccurred())
+					ids <- id
+				}
+			}(gen)
+		}
+
+		wg.Wait()
+		close(ids)
+
+		// Finally, verify that every ID was unique and that the counter holds the end of the last block
+		seen := map[int64]bool{1: true, 11: true}
+		for id := range ids {
+			Expect(seen).ShouldNot(HaveKey(id))
+			seen[id] = true
+		}
+
+		Expect(seen).Should(HaveLen(82))
+		value, err := conn.Increment(context.Background(), createTestCounter("sequence"), 0)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(value).Should(Equal(int64(100)))
+	})
+})
+
+// Helper type that describes an item holding a counter
+type counterObject struct {
+	ID    string `json:"id" dynamodb:"hash"`
+	Count int64  `json:"count"`
+}
+
+// Helper function that creates a connection to a fake DynamoDB with a counter table
+func createCounterConnection() *DatabaseConnection {
+	def, err := NewTableDefinition[counterObject]("COUNTER_TABLE", WithTableTagKey("json"))
+	Expect(err).ShouldNot(HaveOccurred())
+
+	client := fake.NewFakeDynamoDB()
+	_, err = client.CreateTable(context.Background(), def.Input)
+	Expect(err).ShouldNot(HaveOccurred())
+	return createMockConnection(client)
+}
+
+// Helper function that creates a counter on the item with the ID provided
+func createTestCounter(id string) Counter {
+	return Counter{
+		TableName: "COUNTER_TABLE",
+		Key:       map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: id}},
+		Attribute: "count",
+	}
+}
@@ -0,0 +1,254 @@
+package dynamodb
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Woody1193/goutils/collections"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Helper type that describes a single key attribute as it is serialized into a cursor. DynamoDB only
+// allows string, number and binary attributes to be used in keys so no other types are supported
+type cursorValue struct {
+	S *string `json:"S,omitempty"`
+	N *string `json:"N,omitempty"`
+	B []byte  `json:"B,omitempty"`
+}
+
+// EncodeCursor converts a last-evaluated key, returned by DynamoDB, into an opaque cursor string that is
+// safe to include in a URL. If a cursor secret was set on the connection then the cursor will be signed
+// with it, along with the table name, so that DecodeCursor can verify that the cursor was created by this
+// service for the same table. If the key is empty, indicating that there are no more results, then an
+// empty cursor will be returned
+func (conn *DatabaseConnection) EncodeCursor(tableName string, key map[string]types.AttributeValue) (string, error) {
+	return conn.encodeCursor(tableName, tableName, key)
+}
+
+// Helper function that encodes a cursor, signing it with the scope provided. See EncodeCursor for
+// more information
+func (conn *DatabaseConnection) encodeCursor(tableName string, scope string,
+	key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+
+	// First, convert each of the key attributes to its serializable form; if any of them has a type
+	// that cannot be used in a key then return an error
+	values := make(map[string]cursorValue, len(key))
+	for name, value := range key {
+		switch casted := value.(type) {
+		case *types.AttributeValueMemberS:
+			values[name] = cursorValue{S: aws.String(casted.Value)}
+		case *types.AttributeValueMemberN:
+			values[name] = cursorValue{N: aws.String(casted.Value)}
+		case *types.AttributeValueMemberB:
+			values[name] = cursorValue{B: casted.Value}
+		default:
+			return "", conn.NewError(fmt.Errorf("attribute %s has type %T which cannot be used in a key",
+				name, value), tableName, "Failed to encode cursor for %s", tableName)
+		}
+	}
+
+	// Next, serialize the key attributes to JSON; this should never fail
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", conn.NewError(err, tableName, "Failed to encode cursor for %s", tableName)
+	}
+
+	// Finally, encode the payload and, if we have a secret, append the signature of the payload
+	cursor := base64.RawURLEncoding.EncodeToString(data)
+	if len(conn.cursorSecret) > 0 {
+		cursor += "." + base64.RawURLEncoding.EncodeToString(conn.signCursor(scope, cursor))
+	}
+
+	return cursor, nil
+}
+
+// DecodeCursor converts a cursor string, created by EncodeCursor, back into a key that may be set as the
+// exclusive start key on a query or scan. If a cursor secret was set on the connection then the signature
+// on the cursor will be verified, which requires that the cursor was created for the same table. If the
+// cursor is malformed, or its signature is missing or invalid, then an InvalidCursorError will be returned.
+// An empty cursor will be decoded to a nil key
+func (conn *DatabaseConnection) DecodeCursor(tableName string, cursor string) (map[string]types.AttributeValue, error) {
+	return conn.decodeCursor(tableName, tableName, cursor)
+}
+
+// Helper function that decodes a cursor, verifying that it was signed with the scope provided. See
+// DecodeCursor for more information
+func (conn *DatabaseConnection) decodeCursor(tableName string, scope string,
+	cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	// First, split the signature from the payload. If we have a secret then the cursor must have a
+	// signature that matches it; otherwise, the cursor should not have a signature at all
+	payload, signature, signed := strings.Cut(cursor, ".")
+	if len(conn.cursorSecret) > 0 {
+		if !signed {
+			return nil, conn.invalidCursor(fmt.Errorf("cursor is not signed"), tableName, cursor)
+		}
+
+		decoded, err := base64.RawURLEncoding.DecodeString(signature)
+		if err != nil || !hmac.Equal(decoded, conn.signCursor(scope, payload)) {
+			return nil, conn.invalidCursor(fmt.Errorf("cursor signature is invalid"), tableName, cursor)
+		}
+	} else if signed {
+		return nil, conn.invalidCursor(fmt.Errorf("cursor is signed but no secret was provided"), tableName, cursor)
+	}
+
+	// Next, decode the payload and deserialize the key attributes from it; if either of these fails
+	// then return an error
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, conn.invalidCursor(err, tableName, cursor)
+	}
+
+	var values map[string]cursorValue
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, conn.invalidCursor(err, tableName, cursor)
+	}
+
+	// Finally, convert each of the key attributes back to an attribute value. Each attribute must
+	// have exactly one type set on it
+	key := make(map[string]types.AttributeValue, len(values))
+	for name, value := range values {
+		switch {
+		case value.S != nil && value.N == nil && value.B == nil:
+			key[name] = &types.AttributeValueMemberS{Value: *value.S}
+		case value.N != nil && value.S == nil && value.B == nil:
+			key[name] = &types.AttributeValueMemberN{Value: *value.N}
+		case value.B != nil && value.S == nil && value.N == nil:
+			key[name] = &types.AttributeValueMemberB{Value: value.B}
+		default:
+			return nil, conn.invalidCursor(fmt.Errorf("attribute %s does not have exactly one type", name),
+				tableName, cursor)
+		}
+	}
+
+	return key, nil
+}
+
+// QueryPage reads a single page of results from a DynamoDB query, starting from the position described by
+// the cursor, and returns the items along with the cursor that should be provided to read the next page.
+// An empty cursor will start reading from the beginning of the query and an empty cursor will be returned
+// when there are no more results. If the size is greater than zero then no more than that many items will
+// be returned; otherwise, the query will be read to completion. Note that DynamoDB may stop evaluating a
+// query before the page is full, for instance when a filter expression removes items, so this function
+// will continue reading until the page is full or the query is exhausted. If a cursor secret was set on the
+// connection then the cursor will be signed with the table, index and key condition of the query so that
+// it cannot be used to read from a different query. The input is not modified
+func (conn *DatabaseConnection) QueryPage(ctx context.Context, input *dynamodb.QueryInput, cursor string,
+	size int) ([]map[string]types.AttributeValue, string, error) {
+	tableName := aws.ToString(input.TableName)
+
+	// First, attempt to decode the cursor into the key from which we should start reading; if this
+	// fails then return an error
+	scope := queryScope(input)
+	start, err := conn.decodeCursor(tableName, scope, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Next, create an iterator that starts from the key and read pages from it until we have enough
+	// items or there are no more results
+	copied := *input
+	copied.ExclusiveStartKey = start
+	iter := conn.NewQueryIterator(&copied, size)
+
+	items := make([]map[string]types.AttributeValue, 0)
+	for iter.HasNext() {
+		page, err := iter.NextPage(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+
+		items = append(items, page...)
+	}
+
+	// Finally, encode the position at which the iterator stopped as the cursor for the next page
+	next, err := conn.encodeCursor(tableName, scope, iter.Cursor())
+	if err != nil {
+		return nil, "", err
+	}
+
+	return items, next, nil
+}
+
+// QueryPageAs reads a single page of results from a DynamoDB query, as QueryPage does, and unmarshals the
+// items into a list of objects of the type provided. The cursor for the next page will also be returned
+func QueryPageAs[T any](ctx context.Context, conn *DatabaseConnection, input *dynamodb.QueryInput,
+	cursor string, size int) ([]*T, string, error) {
+
+	// First, attempt to read the page of results; if this fails then return an error
+	items, next, err := conn.QueryPage(ctx, input, cursor, size)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Next, attempt to unmarshal the items into our list of objects and return it with the cursor
+	objs, err := unmarshalList[T](conn, *input.TableName, items)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return objs, next, nil
+}
+
+// Helper function that calculates the signature of a cursor payload, and the scope in which the cursor may
+// be used, using the secret set on the connection
+func (conn *DatabaseConnection) signCursor(scope string, payload string) []byte {
+	mac := hmac.New(sha256.New, conn.cursorSecret)
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// Helper function that describes the scope of a query cursor, from the table and index being queried and a
+// hash of the key condition, so that a cursor can't be used to read from another table, index or partition
+func queryScope(input *dynamodb.QueryInput) string {
+
+	// First, write the key condition to the hash along with the attribute names it may reference
+	hash := sha256.New()
+	hash.Write([]byte(aws.ToString(input.KeyConditionExpression)))
+	names := collections.Keys(input.ExpressionAttributeNames)
+	sort.Strings(names)
+	for _, placeholder := range names {
+		fmt.Fprintf(hash, "\x00%s=%s", placeholder, input.ExpressionAttributeNames[placeholder])
+	}
+
+	// Next, write the scalar attribute values to the hash. Key conditions can only compare key attributes,
+	// which must be strings, numbers or binary, so any other values belong to other expressions
+	for _, placeholder := range sortedNames(input.ExpressionAttributeValues) {
+		switch casted := input.ExpressionAttributeValues[placeholder].(type) {
+		case *types.AttributeValueMemberS:
+			fmt.Fprintf(hash, "\x00%s=S:%s", placeholder, casted.Value)
+		case *types.AttributeValueMemberN:
+			fmt.Fprintf(hash, "\x00%s=N:%s", placeholder, casted.Value)
+		case *types.AttributeValueMemberB:
+			fmt.Fprintf(hash, "\x00%s=B:%x", placeholder, casted.Value)
+		}
+	}
+
+	// Finally, combine the table and index names with the hash
+	return fmt.Sprintf("%s\x00%s\x00%x", aws.ToString(input.TableName), aws.ToString(input.IndexName), hash.Sum(nil))
+}
+
+// Helper function that creates an error describing a cursor that could not be decoded
+func (conn *DatabaseConnection) invalidCursor(inner error, tableName string, cursor string) *InvalidCursorError {
+	return &InvalidCursorError{
+		GError:    conn.NewError(inner, tableName, "Failed to decode cursor for %s", tableName).GError,
+		TableName: tableName,
+		Cursor:    cursor,
+	}
+}
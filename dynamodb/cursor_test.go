@@ -0,0 +1,243 @@
+package dynamodb
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cursor Tests", func() {
+
+	// Test that a key encoded by EncodeCursor is URL-safe and can be decoded by DecodeCursor
+	It("EncodeCursor - Not signed - Decoded", func() {
+
+		// First, create our test connection with no cursor secret
+		conn := createMockConnection(&listDynamoDBClient{})
+
+		// Next, encode a key with string, number and binary attributes; this should not fail
+		key := map[string]types.AttributeValue{
+			"id":       &types.AttributeValueMemberS{Value: "test_id+/="},
+			"sort_key": &types.AttributeValueMemberN{Value: "42"},
+			"data":     &types.AttributeValueMemberB{Value: []byte{0xfb, 0xff}}}
+		cursor, err := conn.EncodeCursor("TEST_TABLE", key)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, verify that the cursor doesn't need to be escaped to be included in a URL
+		Expect(cursor).ShouldNot(BeEmpty())
+		Expect(url.QueryEscape(cursor)).Should(Equal(cursor))
+
+		// Finally, decode the cursor and verify that the key was returned
+		decoded, err := conn.DecodeCursor("TEST_TABLE", cursor)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(decoded).Should(Equal(key))
+	})
+
+	// Test that an empty key is encoded to an empty cursor, which is decoded to a nil key
+	It("EncodeCursor - Empty key - Empty cursor", func() {
+		conn := createMockConnection(&listDynamoDBClient{}, WithCursorSecret("secret"))
+
+		cursor, err := conn.EncodeCursor("TEST_TABLE", nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(cursor).Should(BeEmpty())
+
+		key, err := conn.DecodeCursor("TEST_TABLE", cursor)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(key).Should(BeNil())
+	})
+
+	// Test that, if the key contains an attribute that cannot be used in a key, then EncodeCursor will fail
+	It("EncodeCursor - Unsupported attribute - Error", func() {
+
+		// First, create our test connection
+		conn := createMockConnection(&listDynamoDBClient{})
+
+		// Next, attempt to encode a key containing a boolean attribute; this should fail
+		cursor, err := conn.EncodeCursor("TEST_TABLE", map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberBOOL{Value: true}})
+
+		// Finally, verify the details of the error
+		casted := err.(*Error)
+		Expect(cursor).Should(BeEmpty())
+		Expect(casted.TableName).Should(Equal("TEST_TABLE"))
+		Expect(casted.Message).Should(Equal("Failed to encode cursor for TEST_TABLE"))
+		Expect(casted.Inner.Error()).Should(Equal(
+			"attribute id has type *types.AttributeValueMemberBOOL which cannot be used in a key"))
+	})
+
+	// Test that, if a cursor secret is set, then cursors will be signed and any cursor whose signature
+	// does not match the secret will be rejected
+	DescribeTable("DecodeCursor - Signed - Verified",
+		func(encodeSecret string, decodeSecret string, modify func(string) string, message string) {
+
+			// First, create our test connections with the secrets to use to encode and decode the cursor
+			encoder := createMockConnection(&listDynamoDBClient{}, WithCursorSecret(encodeSecret))
+			decoder := createMockConnection(&listDynamoDBClient{}, WithCursorSecret(decodeSecret))
+
+			// Next, encode a key and modify the cursor; this should not fail
+			key := map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "test_id"}}
+			cursor, err := encoder.EncodeCursor("TEST_TABLE", key)
+			Expect(err).ShouldNot(HaveOccurred())
+			cursor = modify(cursor)
+
+			// Now, attempt to decode the cursor
+			decoded, err := decoder.DecodeCursor("TEST_TABLE", cursor)
+
+			// Finally, verify the key or the details of the error
+			if message == "" {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(decoded).Should(Equal(key))
+			} else {
+				casted := err.(*InvalidCursorError)
+				Expect(decoded).Should(BeNil())
+				Expect(casted.TableName).Should(Equal("TEST_TABLE"))
+				Expect(casted.Cursor).Should(Equal(cursor))
+				Expect(casted.Message).Should(Equal("Failed to decode cursor for TEST_TABLE"))
+				Expect(casted.Inner.Error()).Should(Equal(message))
+			}
+		},
+		Entry("Same secret - Key returned", "secret", "secret",
+			func(cursor string) string { return cursor }, ""),
+		Entry("Different secret - Error", "secret", "other",
+			func(cursor string) string { return cursor }, "cursor signature is invalid"),
+		Entry("Payload modified - Error", "secret", "secret",
+			func(cursor string) string { return "e30" + cursor[3:] }, "cursor signature is invalid"),
+		Entry("Signature removed - Error", "secret", "secret",
+			func(cursor string) string { return cursor[:len(cursor)-44] }, "cursor is not signed"),
+		Entry("Not signed - Error", "", "secret",
+			func(cursor string) string { return cursor }, "cursor is not signed"),
+		Entry("No secret - Error", "secret", "",
+			func(cursor string) string { return cursor }, "cursor is signed but no secret was provided"))
+
+	// Test that, if a signed cursor was created for a different table, then DecodeCursor will reject it
+	It("DecodeCursor - Different table - Error", func() {
+
+		// First, create our test connection with a cursor secret and encode a key for another table
+		conn := createMockConnection(&listDynamoDBClient{}, WithCursorSecret("secret"))
+		cursor, err := conn.EncodeCursor("OTHER_TABLE",
+			map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "test_id"}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Next, attempt to decode the cursor for our table; this should fail
+		key, err := conn.DecodeCursor("TEST_TABLE", cursor)
+
+		// Finally, verify the details of the error
+		Expect(key).Should(BeNil())
+		Expect(err.(*InvalidCursorError).Inner.Error()).Should(Equal("cursor signature is invalid"))
+	})
+
+	// Test that, if the cursor is malformed, then DecodeCursor will return an error
+	It("DecodeCursor - Malformed - Error", func() {
+		conn := createMockConnection(&listDynamoDBClient{})
+
+		key, err := conn.DecodeCursor("TEST_TABLE", "not*base64")
+
+		casted := err.(*InvalidCursorError)
+		Expect(key).Should(BeNil())
+		Expect(casted.Cursor).Should(Equal("not*base64"))
+	})
+
+	// Test that the cursor returned by QueryPage can be used to read every page of a query in turn
+	It("QueryPage - Multiple pages - All items read", func() {
+
+		// First, create our test connection with a client that returns pages of four items
+		client := &listDynamoDBClient{items: createTestPages(1, 10)[0], pageSize: 4}
+		conn := createMockConnection(client, WithCursorSecret("secret"))
+
+		// Next, read pages of six items from the query until there is no cursor returned
+		input := dynamodb.QueryInput{TableName: aws.String("TEST_TABLE")}
+		sizes := make([]int, 0)
+		cursor := ""
+		for {
+			items, next, err := conn.QueryPage(context.Background(), &input, cursor, 6)
+			Expect(err).ShouldNot(HaveOccurred())
+			sizes = append(sizes, len(items))
+			if cursor = next; cursor == "" {
+				break
+			}
+		}
+
+		// Finally, verify the pages we read and that the input was not modified
+		Expect(sizes).Should(Equal([]int{6, 4}))
+		Expect(client.limits).Should(Equal([]int32{6, 2, 6}))
+		Expect(input.ExclusiveStartKey).Should(BeNil())
+		Expect(input.Limit).Should(BeNil())
+	})
+
+	// Test that, if a signed cursor returned by QueryPage is used with a query against a different index or
+	// partition, then QueryPage will reject it without querying DynamoDB
+	It("QueryPage - Cursor from different query - Error", func() {
+
+		// First, create our test connection with a cursor secret and read the first page of a query
+		client := &listDynamoDBClient{items: createTestPages(1, 10)[0], pageSize: 4}
+		conn := createMockConnection(client, WithCursorSecret("secret"))
+		input := dynamodb.QueryInput{
+			TableName:                 aws.String("TEST_TABLE"),
+			KeyConditionExpression:    aws.String("#owner = :owner"),
+			ExpressionAttributeNames:  map[string]string{"#owner": "owner"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":owner": &types.AttributeValueMemberS{Value: "a"}},
+		}
+
+		_, next, err := conn.QueryPage(context.Background(), &input, "", 4)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(next).ShouldNot(BeEmpty())
+
+		// Next, attempt to use the cursor against another partition and another index; both should fail
+		other := input
+		other.ExpressionAttributeValues = map[string]types.AttributeValue{":owner": &types.AttributeValueMemberS{Value: "b"}}
+		_, _, partitionErr := conn.QueryPage(context.Background(), &other, next, 4)
+
+		other = input
+		other.IndexName = aws.String("ByOwner")
+		_, _, indexErr := conn.QueryPage(context.Background(), &other, next, 4)
+
+		// Finally, verify the errors and that only the first query was sent
+		Expect(partitionErr).Should(BeAssignableToTypeOf(&InvalidCursorError{}))
+		Expect(indexErr).Should(BeAssignableToTypeOf(&InvalidCursorError{}))
+		Expect(client.calls).Should(Equal(1))
+	})
+
+	// Test that QueryPageAs will unmarshal the page of results into typed objects
+	It("QueryPageAs - First page - Objects returned", func() {
+
+		// First, create our test connection with a client that returns pages of four items
+		conn := createMockConnection(&listDynamoDBClient{items: createTestPages(1, 10)[0], pageSize: 4})
+
+		// Next, attempt to read the first page of three items as test objects; this should not fail
+		objs, next, err := QueryPageAs[testObject](context.Background(), conn,
+			&dynamodb.QueryInput{TableName: aws.String("TEST_TABLE")}, "", 3)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the objects and that the cursor points to the next item
+		Expect(objs).Should(HaveLen(3))
+		for i, obj := range objs {
+			Expect(obj.Data).Should(Equal(i))
+		}
+
+		key, err := conn.DecodeCursor("TEST_TABLE", next)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(key).Should(Equal(map[string]types.AttributeValue{"index": &types.AttributeValueMemberN{Value: "3"}}))
+	})
+
+	// Test that, if the cursor is invalid, then QueryPage will return an error without querying DynamoDB
+	It("QueryPage - Invalid cursor - Error", func() {
+
+		// First, create our test connection with a cursor secret
+		client := &listDynamoDBClient{items: createTestPages(1, 10)[0], pageSize: 4}
+		conn := createMockConnection(client, WithCursorSecret("secret"))
+
+		// Next, attempt to read a page with a cursor that isn't signed; this should fail
+		items, next, err := conn.QueryPage(context.Background(),
+			&dynamodb.QueryInput{TableName: aws.String("TEST_TABLE")}, "e30", 6)
+
+		// Finally, verify the error and that no request was sent
+		Expect(err).Should(BeAssignableToTypeOf(&InvalidCursorError{}))
+		Expect(items).Should(BeNil())
+		Expect(next).Should(BeEmpty())
+		Expect(client.calls).Should(BeZero())
+	})
+})
@@ -0,0 +1,102 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Helper variable containing the regular expression used to find the attribute name placeholders in an expression
+var namePlaceholderRegex = regexp.MustCompile(`#[A-Za-z0-9_]+`)
+
+// DeleteWhere removes every item matching a query from DynamoDB and returns the number of items that were
+// deleted. The query will be read one page at a time, retrieving only the key attributes of each item, and
+// the items on each page will be deleted with batch-writes before the next page is read so that partitions
+// of any size can be purged without holding them in memory. If the query is made against a secondary index
+// then the matching items will be deleted from the table. Any projection on the query will be replaced. If
+// some of the deletes could not be processed then a BatchWriteError will be returned along with the number
+// of items that were deleted before it occurred. If the deletes fail for any other reason then the count
+// will only include the items on the pages that were deleted before the failure, although some of the items
+// on the page that failed may also have been deleted. Note that the input is copied so it will not be modified
+func (conn *DatabaseConnection) DeleteWhere(ctx context.Context, input *dynamodb.QueryInput) (int, error) {
+	tableName := aws.ToString(input.TableName)
+
+	// First, describe the table so that we know the names of its key attributes; if this fails or the
+	// table doesn't exist then return an error
+	table, err := conn.describeTable(ctx, tableName)
+	if err != nil {
+		return 0, err
+	} else if table == nil {
+		return 0, conn.NewError(nil, tableName, "Cannot delete items from %s because it does not exist", tableName)
+	}
+
+	// Next, create a copy of the input that only retrieves the key attributes of each item. We'll keep
+	// the names used by the key condition and filter, dropping any that were only used by the projection
+	// we're replacing as DynamoDB rejects unused names, and add placeholders for the key attributes to
+	// them, ensuring that they don't conflict with any of the names we kept
+	projected := *input
+	projected.Select = types.SelectSpecificAttributes
+	projected.AttributesToGet = nil
+	projected.ExpressionAttributeNames = make(map[string]string, len(input.ExpressionAttributeNames)+2)
+	used := namePlaceholderRegex.FindAllString(aws.ToString(input.KeyConditionExpression)+" "+
+		aws.ToString(input.FilterExpression), -1)
+	for _, placeholder := range used {
+		if name, ok := input.ExpressionAttributeNames[placeholder]; ok {
+			projected.ExpressionAttributeNames[placeholder] = name
+		}
+	}
+
+	keys := make([]string, len(table.KeySchema))
+	expression := ""
+	for i, element := range table.KeySchema {
+		keys[i] = aws.ToString(element.AttributeName)
+		placeholder := fmt.Sprintf("#key%d", i)
+		if name, ok := projected.ExpressionAttributeNames[placeholder]; ok && name != keys[i] {
+			return 0, conn.NewError(nil, tableName, "Cannot delete items from %s because the query uses the "+
+				"attribute name placeholder %s, which is reserved for key attributes", tableName, placeholder)
+		}
+
+		projected.ExpressionAttributeNames[placeholder] = keys[i]
+		if i > 0 {
+			expression += ", "
+		}
+
+		expression += placeholder
+	}
+
+	projected.ProjectionExpression = aws.String(expression)
+
+	// Now, read each page of keys returned by the query and delete the items they belong to. If some
+	// of the deletes could not be processed then we'll count those that were before stopping
+	conn.logger.Log("Attempting to delete items matching query from %s...", tableName)
+	deleted := 0
+	_, err = conn.QueryPages(ctx, &projected, 0,
+		func(ctx context.Context, page []map[string]types.AttributeValue) (bool, error) {
+			requests := make([]types.WriteRequest, len(page))
+			for i, item := range page {
+				key := make(map[string]types.AttributeValue, len(keys))
+				for _, name := range keys {
+					key[name] = item[name]
+				}
+
+				requests[i] = types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}}
+			}
+
+			err := conn.BatchWrite(ctx, tableName, requests...)
+			if casted, ok := err.(*BatchWriteError); ok {
+				deleted += len(requests) - len(casted.Unprocessed[tableName])
+			} else if err == nil {
+				deleted += len(requests)
+			}
+
+			return err == nil, err
+		})
+
+	// Finally, return the number of items that were deleted along with any error that occurred
+	conn.logger.Log("Deleted %d items from %s", deleted, tableName)
+	return deleted, err
+}
@@ -0,0 +1,175 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	fake "github.com/Woody1193/goutils/dynamodb/testing"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Delete Where Tests", func() {
+
+	// Test that, if a query matches every item in a partition, then DeleteWhere will delete all of them,
+	// over multiple pages and batches, without affecting other partitions
+	It("DeleteWhere - Partition - All items deleted", func() {
+
+		// First, create our test connection with two partitions
+		conn := createDeleteConnection(map[string]int{"user1": 60, "user2": 5})
+
+		// Next, delete every item in the first partition, reading ten keys at a time; this should not fail
+		deleted, err := conn.DeleteWhere(context.Background(), &dynamodb.QueryInput{
+			TableName:                 aws.String("DELETE_TABLE"),
+			KeyConditionExpression:    aws.String("#owner = :owner"),
+			ExpressionAttributeNames:  map[string]string{"#owner": "owner"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":owner": &types.AttributeValueMemberS{Value: "user1"}},
+			Limit:                     aws.Int32(10),
+		})
+
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that only the items in the first partition were deleted
+		Expect(deleted).Should(Equal(60))
+		Expect(countDeletePartition(conn, "user1")).Should(BeZero())
+		Expect(countDeletePartition(conn, "user2")).Should(Equal(5))
+	})
+
+	// Test that, if the query has a filter, then DeleteWhere will only delete the items that match the filter
+	It("DeleteWhere - Filtered - Matching items deleted", func() {
+
+		// First, create our test connection with a single partition
+		conn := createDeleteConnection(map[string]int{"user1": 10})
+
+		// Next, delete the items in the partition whose data is less than four; this should not fail
+		deleted, err := conn.DeleteWhere(context.Background(), &dynamodb.QueryInput{
+			TableName:                aws.String("DELETE_TABLE"),
+			KeyConditionExpression:   aws.String("#owner = :owner"),
+			FilterExpression:         aws.String("#data < :data"),
+			ExpressionAttributeNames: map[string]string{"#owner": "owner", "#data": "data"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":owner": &types.AttributeValueMemberS{Value: "user1"},
+				":data":  &types.AttributeValueMemberN{Value: "4"},
+			},
+		})
+
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that only the matching items were deleted
+		Expect(deleted).Should(Equal(4))
+		Expect(countDeletePartition(conn, "user1")).Should(Equal(6))
+	})
+
+	// Test that, if the query has a projection with names that aren't used anywhere else in the query, then
+	// DeleteWhere will drop those names when it replaces the projection
+	It("DeleteWhere - Projection names - Matching items deleted", func() {
+
+		// First, create our test connection with a single partition
+		conn := createDeleteConnection(map[string]int{"user1": 3})
+
+		// Next, delete the items in the partition with a query that projects its data; this should not fail
+		deleted, err := conn.DeleteWhere(context.Background(), &dynamodb.QueryInput{
+			TableName:                 aws.String("DELETE_TABLE"),
+			KeyConditionExpression:    aws.String("#owner = :owner"),
+			ProjectionExpression:      aws.String("#data"),
+			ExpressionAttributeNames:  map[string]string{"#owner": "owner", "#data": "data"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":owner": &types.AttributeValueMemberS{Value: "user1"}},
+		})
+
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that all the items were deleted
+		Expect(deleted).Should(Equal(3))
+		Expect(countDeletePartition(conn, "user1")).Should(BeZero())
+	})
+
+	// Test that, if the query uses a placeholder reserved for key attributes, then DeleteWhere will fail
+	It("DeleteWhere - Reserved placeholder - Error", func() {
+
+		// First, create our test connection with a single partition
+		conn := createDeleteConnection(map[string]int{"user1": 1})
+
+		// Next, attempt to delete items with a query that uses a reserved placeholder; this should fail
+		deleted, err := conn.DeleteWhere(context.Background(), &dynamodb.QueryInput{
+			TableName:                 aws.String("DELETE_TABLE"),
+			KeyConditionExpression:    aws.String("#key0 = :owner"),
+			ExpressionAttributeNames:  map[string]string{"#key0": "data"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":owner": &types.AttributeValueMemberS{Value: "user1"}},
+		})
+
+		// Finally, verify the details of the error and that nothing was deleted
+		Expect(deleted).Should(BeZero())
+		Expect(err.(*Error).Message).Should(Equal("Cannot delete items from DELETE_TABLE because the query uses " +
+			"the attribute name placeholder #key0, which is reserved for key attributes"))
+		Expect(countDeletePartition(conn, "user1")).Should(Equal(1))
+	})
+
+	// Test that, if the table doesn't exist, then DeleteWhere will fail
+	It("DeleteWhere - Table missing - Error", func() {
+
+		// First, create our test connection
+		conn := createDeleteConnection(nil)
+
+		// Next, attempt to delete items from a table that doesn't exist; this should fail
+		deleted, err := conn.DeleteWhere(context.Background(), &dynamodb.QueryInput{
+			TableName:              aws.String("MISSING_TABLE"),
+			KeyConditionExpression: aws.String("owner = :owner"),
+		})
+
+		// Finally, verify the details of the error
+		Expect(deleted).Should(BeZero())
+		Expect(err.(*Error).Message).Should(Equal("Cannot delete items from MISSING_TABLE because it does not exist"))
+	})
+})
+
+// Helper function that creates a connection to a fake DynamoDB with a table containing the number of items
+// requested in each partition
+func createDeleteConnection(partitions map[string]int) *DatabaseConnection {
+	client := fake.NewFakeDynamoDB()
+	_, err := client.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+		TableName: aws.String("DELETE_TABLE"),
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("owner"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("id"), KeyType: types.KeyTypeRange},
+		},
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("owner"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS},
+		},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+
+	Expect(err).ShouldNot(HaveOccurred())
+
+	conn := createMockConnection(client)
+	for owner, count := range partitions {
+		requests := make([]types.WriteRequest, count)
+		for i := range requests {
+			requests[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{
+				"owner": &types.AttributeValueMemberS{Value: owner},
+				"id":    &types.AttributeValueMemberS{Value: fmt.Sprintf("item|%03d", i)},
+				"data":  &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", i)},
+			}}}
+		}
+
+		Expect(conn.BatchWrite(context.Background(), "DELETE_TABLE", requests...)).ShouldNot(HaveOccurred())
+	}
+
+	return conn
+}
+
+// Helper function that counts the items remaining in a partition of the delete table
+func countDeletePartition(conn *DatabaseConnection, owner string) int {
+	items, err := conn.Query(context.Background(), &dynamodb.QueryInput{
+		TableName:                 aws.String("DELETE_TABLE"),
+		KeyConditionExpression:    aws.String("#owner = :owner"),
+		ExpressionAttributeNames:  map[string]string{"#owner": "owner"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":owner": &types.AttributeValueMemberS{Value: owner}},
+	})
+
+	Expect(err).ShouldNot(HaveOccurred())
+	return len(items)
+}
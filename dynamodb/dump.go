@@ -0,0 +1,356 @@
+package dynamodb
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/Woody1193/goutils/concurrency"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DumpCheckpoint records the progress of a table dump so that it can be resumed if it is interrupted. The
+// checkpoint is JSON-serializable so it may be saved to a file whenever the progress callback is called and
+// then loaded and passed to the next dump with WithDumpCheckpoint. The offset is the number of bytes written
+// to the output for the items recorded by the checkpoint, so the output may be truncated to it before a dump
+// is resumed to discard anything written after the checkpoint, including a partially-written line
+type DumpCheckpoint struct {
+	Items    int64          `json:"items"`
+	Offset   int64          `json:"offset"`
+	Segments []*DumpSegment `json:"segments"`
+}
+
+// DumpSegment records the progress of a single segment of a table dump. The last key is the key of the last
+// item written by the segment, encoded in the DynamoDB JSON format, and will be empty if the segment has not
+// yet written any pages
+type DumpSegment struct {
+	Complete bool            `json:"complete"`
+	LastKey  json.RawMessage `json:"last_key,omitempty"`
+}
+
+// RestoreProgress records the progress of a table restore. The number of items is the number of lines read
+// from the dump that have been written to the table, so a restore may be resumed by passing it to
+// WithRestoreSkip
+type RestoreProgress struct {
+	Items int64
+}
+
+// Helper type that contains the settings that may be modified on a table dump
+type dumpSettings struct {
+	segments   int
+	checkpoint *DumpCheckpoint
+	progress   func(*DumpCheckpoint)
+}
+
+// Helper type that contains the settings that may be modified on a table restore
+type restoreSettings struct {
+	batchSize int
+	skip      int64
+	progress  func(RestoreProgress)
+}
+
+// IDumpOption defines the functionality that will allow a table dump to be modified
+type IDumpOption interface {
+	Apply(*dumpSettings)
+}
+
+// IRestoreOption defines the functionality that will allow a table restore to be modified
+type IRestoreOption interface {
+	Apply(*restoreSettings)
+}
+
+// WithDumpSegments allows the user to set the number of segments into which the table will be split so that
+// they can be scanned concurrently. If this option is not provided then the table will be scanned in a single
+// segment. This option is ignored when resuming from a checkpoint, which records its own segments
+type WithDumpSegments int
+
+// Apply modifies the dump settings so that they have the number of segments defined by this object
+func (w WithDumpSegments) Apply(settings *dumpSettings) {
+	settings.segments = int(w)
+}
+
+// WithDumpCheckpoint allows the user to resume a dump from a checkpoint returned by an earlier, incomplete
+// dump of the same table. The output should be the output of the earlier dump, truncated to the offset of the
+// checkpoint and opened for appending
+type WithDumpCheckpoint struct {
+	Checkpoint *DumpCheckpoint
+}
+
+// Apply modifies the dump settings so that they resume from the checkpoint defined by this object
+func (w WithDumpCheckpoint) Apply(settings *dumpSettings) {
+	settings.checkpoint = w.Checkpoint
+}
+
+// WithDumpProgress allows the user to set a function that will be called with a copy of the checkpoint of
+// the dump each time a page of items has been written. The function will not be called concurrently
+type WithDumpProgress func(*DumpCheckpoint)
+
+// Apply modifies the dump settings so that they report progress to the function defined by this object
+func (w WithDumpProgress) Apply(settings *dumpSettings) {
+	settings.progress = w
+}
+
+// WithRestoreBatchSize allows the user to set the number of items that will be read from the dump before
+// they are written to the table. If this option is not provided then items will be written 100 at a time
+type WithRestoreBatchSize int
+
+// Apply modifies the restore settings so that they have the batch size defined by this object
+func (w WithRestoreBatchSize) Apply(settings *restoreSettings) {
+	settings.batchSize = int(w)
+}
+
+// WithRestoreSkip allows the user to resume a restore by skipping the number of items provided, which should
+// be the number of items reported as restored by the progress of an earlier, incomplete restore
+type WithRestoreSkip int64
+
+// Apply modifies the restore settings so that they skip the number of items defined by this object
+func (w WithRestoreSkip) Apply(settings *restoreSettings) {
+	settings.skip = int64(w)
+}
+
+// WithRestoreProgress allows the user to set a function that will be called with the progress of the restore
+// each time a batch of items has been written to the table
+type WithRestoreProgress func(RestoreProgress)
+
+// Apply modifies the restore settings so that they report progress to the function defined by this object
+func (w WithRestoreProgress) Apply(settings *restoreSettings) {
+	settings.progress = w
+}
+
+// Helper type that represents a single line of a table dump. This matches the format used by DynamoDB
+// when exporting a table to S3
+type dumpLine struct {
+	Item json.RawMessage `json:"Item"`
+}
+
+// DumpTable reads every item from the table with a parallel scan and writes them to the output as JSON lines,
+// each containing an object with an Item field holding the item in the DynamoDB JSON format. This is the format
+// used by DynamoDB when exporting a table to S3. The checkpoint of the dump is returned, even if the dump fails,
+// so that it can be resumed with WithDumpCheckpoint. Note that items written after the last checkpoint that was
+// saved will be written again when the dump is resumed so the output should be truncated to the offset of the
+// checkpoint first, which also removes any line that was only partially written when the dump was interrupted
+func (conn *DatabaseConnection) DumpTable(ctx context.Context, tableName string, output io.Writer,
+	opts ...IDumpOption) (*DumpCheckpoint, error) {
+
+	// First, apply our options to the default dump settings and create the checkpoint, if we aren't
+	// resuming from one
+	settings := dumpSettings{segments: 1}
+	for _, opt := range opts {
+		opt.Apply(&settings)
+	}
+
+	checkpoint := settings.checkpoint
+	if checkpoint == nil {
+		if settings.segments < 1 {
+			settings.segments = 1
+		}
+
+		checkpoint = &DumpCheckpoint{Segments: make([]*DumpSegment, settings.segments)}
+		for i := range checkpoint.Segments {
+			checkpoint.Segments[i] = new(DumpSegment)
+		}
+	}
+
+	conn.logger.Log("Attempting dump of %s with %d segments...", tableName, len(checkpoint.Segments))
+
+	// Next, create the handler that will write each page of items to the output and update the checkpoint.
+	// The lock ensures that lines from different segments are not interleaved and that the checkpoint is
+	// only reported after its items have been written
+	lock := new(sync.Mutex)
+	handler := func(ctx context.Context, page *ScanPage) error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		// First, encode each item as a line and write them all to the output, keeping track of the number
+		// of bytes written so that the checkpoint only includes them once the whole page has been written
+		var written int64
+		for _, item := range page.Items {
+			line, err := encodeDumpLine(item)
+			if err != nil {
+				return conn.NewError(err, tableName, "Failed to encode item from %s", tableName)
+			}
+
+			if _, err := output.Write(line); err != nil {
+				return conn.NewError(err, tableName, "Failed to write dump of %s", tableName)
+			}
+
+			written += int64(len(line))
+		}
+
+		// Next, update the checkpoint of the segment with the last key read
+		segment := checkpoint.Segments[page.Segment]
+		if page.LastEvaluatedKey == nil {
+			segment.Complete = true
+		} else {
+			key, err := MarshalItemJSON(page.LastEvaluatedKey)
+			if err != nil {
+				return conn.NewError(err, tableName, "Failed to encode checkpoint of %s", tableName)
+			}
+
+			segment.LastKey = key
+		}
+
+		checkpoint.Items += int64(len(page.Items))
+		checkpoint.Offset += written
+
+		// Finally, report our progress, if we've been asked to
+		if settings.progress != nil {
+			settings.progress(checkpoint.copy())
+		}
+
+		return nil
+	}
+
+	// Now, scan each of the incomplete segments concurrently, starting from the last key in the checkpoint
+	total := len(checkpoint.Segments)
+	err := concurrency.ForAllAsync(ctx, total, true,
+		func(ctx context.Context, index int, _ context.CancelFunc) error {
+			segment := checkpoint.Segments[index]
+			if segment.Complete {
+				return nil
+			}
+
+			input := dynamodb.ScanInput{TableName: aws.String(tableName), ConsistentRead: aws.Bool(true)}
+			if len(segment.LastKey) > 0 {
+				key, err := UnmarshalItemJSON(segment.LastKey)
+				if err != nil {
+					return conn.NewError(err, tableName, "Failed to decode checkpoint of %s", tableName)
+				}
+
+				input.ExclusiveStartKey = key
+			}
+
+			return conn.scanSegment(ctx, &input, index, total, handler)
+		})
+
+	// Finally, if no segment failed but the context was cancelled before all the segments could finish
+	// then the dump is incomplete so return the cancellation error
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	return checkpoint.copy(), err
+}
+
+// RestoreTable reads items from a dump, in the format written by DumpTable, and writes them to the table with
+// batch writes. Existing items with the same keys will be overwritten. The number of items restored will be
+// returned, even if the restore fails, so that it can be resumed with WithRestoreSkip. Blank lines in the dump
+// will be ignored but will still be counted as items
+func (conn *DatabaseConnection) RestoreTable(ctx context.Context, tableName string, input io.Reader,
+	opts ...IRestoreOption) (int64, error) {
+
+	// First, apply our options to the default restore settings
+	settings := restoreSettings{batchSize: 100}
+	for _, opt := range opts {
+		opt.Apply(&settings)
+	}
+
+	if settings.batchSize < 1 {
+		settings.batchSize = 1
+	}
+
+	conn.logger.Log("Attempting restore of %s, skipping %d items...", tableName, settings.skip)
+
+	// Next, create a function that will write the current batch to the table and report our progress
+	restored := settings.skip
+	var read int64
+	batch := make([]types.WriteRequest, 0, settings.batchSize)
+	flush := func() error {
+		if err := conn.BatchWrite(ctx, tableName, batch...); err != nil {
+			return err
+		}
+
+		restored, batch = read, batch[:0]
+		if settings.progress != nil {
+			settings.progress(RestoreProgress{Items: restored})
+		}
+
+		return nil
+	}
+
+	// Now, read each line from the dump, skipping those that were restored already, and add the items
+	// to the batch, writing the batch whenever it is full
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if read++; read <= settings.skip {
+			continue
+		}
+
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		item, err := decodeDumpLine(scanner.Bytes())
+		if err != nil {
+			return restored, conn.NewError(err, tableName, "Failed to decode line %d of dump for %s",
+				read, tableName)
+		}
+
+		batch = append(batch, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+		if len(batch) >= settings.batchSize {
+			if err := flush(); err != nil {
+				return restored, err
+			}
+		}
+	}
+
+	// Finally, check whether we stopped because the dump could not be read and write any remaining items
+	if err := scanner.Err(); err != nil {
+		return restored, conn.NewError(err, tableName, "Failed to read dump for %s", tableName)
+	}
+
+	if read > restored {
+		if err := flush(); err != nil {
+			return restored, err
+		}
+	}
+
+	return restored, nil
+}
+
+// Helper function that creates a deep copy of the checkpoint so that it can be reported while the dump
+// continues to modify the original
+func (checkpoint *DumpCheckpoint) copy() *DumpCheckpoint {
+	copied := DumpCheckpoint{Items: checkpoint.Items, Offset: checkpoint.Offset,
+		Segments: make([]*DumpSegment, len(checkpoint.Segments))}
+	for i, segment := range checkpoint.Segments {
+		copied.Segments[i] = &DumpSegment{Complete: segment.Complete, LastKey: segment.LastKey}
+	}
+
+	return &copied
+}
+
+// Helper function that encodes an item as a single line of a dump, including the trailing newline
+func encodeDumpLine(item map[string]types.AttributeValue) ([]byte, error) {
+	encoded, err := MarshalItemJSON(item)
+	if err != nil {
+		return nil, err
+	}
+
+	line, err := json.Marshal(dumpLine{Item: encoded})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(line, '\n'), nil
+}
+
+// Helper function that decodes an item from a single line of a dump
+func decodeDumpLine(data []byte) (map[string]types.AttributeValue, error) {
+	var line dumpLine
+	if err := json.Unmarshal(data, &line); err != nil {
+		return nil, err
+	} else if len(line.Item) == 0 {
+		return nil, fmt.Errorf("line has no Item field")
+	}
+
+	return UnmarshalItemJSON(line.Item)
+}
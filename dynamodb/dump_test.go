@@ -0,0 +1,178 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	fake "github.com/Woody1193/goutils/dynamodb/testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dump and Restore Tests", func() {
+
+	// Test that a table can be dumped with a parallel scan and then restored to another table
+	It("DumpTable, RestoreTable - Round trip - Items copied", func() {
+
+		// First, create our test connection with a source table containing some items
+		conn := createDumpConnection(50)
+
+		// Next, dump the source table with multiple segments, recording the progress reported
+		var output bytes.Buffer
+		reported := make([]*DumpCheckpoint, 0)
+		checkpoint, err := conn.DumpTable(context.Background(), "SOURCE_TABLE", &output, WithDumpSegments(3),
+			WithDumpProgress(func(progress *DumpCheckpoint) { reported = append(reported, progress) }))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, verify the checkpoint and the dump that were produced
+		Expect(checkpoint.Items).Should(Equal(int64(50)))
+		Expect(checkpoint.Offset).Should(Equal(int64(output.Len())))
+		Expect(checkpoint.Segments).Should(HaveLen(3))
+		for _, segment := range checkpoint.Segments {
+			Expect(segment.Complete).Should(BeTrue())
+		}
+
+		Expect(reported).ShouldNot(BeEmpty())
+		Expect(reported[len(reported)-1]).Should(Equal(checkpoint))
+		lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+		Expect(lines).Should(HaveLen(50))
+		Expect(lines).Should(ContainElement(`{"Item":{"data":{"N":"7"},"id":{"S":"test_id|7"}}}`))
+
+		// Finally, restore the dump to the target table and verify that it contains every item
+		restored, err := conn.RestoreTable(context.Background(), "TARGET_TABLE", &output, WithRestoreBatchSize(20))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(restored).Should(Equal(int64(50)))
+		Expect(scanDumpTable(conn, "TARGET_TABLE")).Should(ConsistOf(scanDumpTable(conn, "SOURCE_TABLE")))
+	})
+
+	// Test that, if a dump is resumed from a checkpoint, then completed segments will not be scanned again
+	It("DumpTable - Resumed from checkpoint - Incomplete segments dumped", func() {
+
+		// First, create our test connection with a source table containing some items and dump it with two
+		// segments, recording the first checkpoint and how much had been written when it was reported
+		conn := createDumpConnection(20)
+		var first bytes.Buffer
+		var checkpoint *DumpCheckpoint
+		written := 0
+		_, err := conn.DumpTable(context.Background(), "SOURCE_TABLE", &first, WithDumpSegments(2),
+			WithDumpProgress(func(progress *DumpCheckpoint) {
+				if checkpoint == nil {
+					checkpoint, written = progress, first.Len()
+				}
+			}))
+
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(checkpoint.Items).Should(BeNumerically(">", 0))
+		Expect(checkpoint.Items).Should(BeNumerically("<", 20))
+
+		// Next, simulate the dump being interrupted after the first checkpoint while writing a line and
+		// verify that truncating the dump to the offset of the checkpoint discards the partial line
+		first.Truncate(written)
+		first.WriteString(`{"Item":{"id":`)
+		Expect(checkpoint.Offset).Should(Equal(int64(written)))
+		first.Truncate(int(checkpoint.Offset))
+
+		// Now, resume the dump from the checkpoint; this should not fail
+		var second bytes.Buffer
+		resumed, err := conn.DumpTable(context.Background(), "SOURCE_TABLE", &second,
+			WithDumpCheckpoint{Checkpoint: checkpoint})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that the two dumps together contain every item exactly once
+		Expect(resumed.Items).Should(Equal(int64(20)))
+		Expect(resumed.Offset).Should(Equal(int64(first.Len() + second.Len())))
+		lines := strings.Split(strings.TrimSpace(first.String()+second.String()), "\n")
+		Expect(lines).Should(HaveLen(20))
+
+		restored, err := conn.RestoreTable(context.Background(), "TARGET_TABLE",
+			strings.NewReader(first.String()+second.String()))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(restored).Should(Equal(int64(20)))
+		Expect(scanDumpTable(conn, "TARGET_TABLE")).Should(ConsistOf(scanDumpTable(conn, "SOURCE_TABLE")))
+	})
+
+	// Test that, if a restore is resumed, then the items that were already restored will be skipped
+	It("RestoreTable - Skip provided - Remaining items restored", func() {
+
+		// First, create our test connection and a dump containing some items and a blank line
+		conn := createDumpConnection(0)
+		dump := `{"Item":{"id":{"S":"test_id|0"}}}` + "\n" +
+			`{"Item":{"id":{"S":"test_id|1"}}}` + "\n\n" +
+			`{"Item":{"id":{"S":"test_id|2"}}}` + "\n" +
+			`{"Item":{"id":{"S":"test_id|3"}}}` + "\n"
+
+		// Next, restore the dump, skipping the first item and recording the progress reported
+		reported := make([]int64, 0)
+		restored, err := conn.RestoreTable(context.Background(), "TARGET_TABLE", strings.NewReader(dump),
+			WithRestoreSkip(1), WithRestoreBatchSize(2), WithRestoreProgress(func(progress RestoreProgress) {
+				reported = append(reported, progress.Items)
+			}))
+
+		// Finally, verify the progress and that only the remaining items were restored
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(restored).Should(Equal(int64(5)))
+		Expect(reported).Should(Equal([]int64{4, 5}))
+		Expect(scanDumpTable(conn, "TARGET_TABLE")).Should(ConsistOf(
+			map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "test_id|1"}},
+			map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "test_id|2"}},
+			map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "test_id|3"}}))
+	})
+
+	// Test that, if a line of the dump cannot be decoded, then the restore will stop and return the number of
+	// items that were restored before the invalid line
+	It("RestoreTable - Invalid line - Error", func() {
+
+		// First, create our test connection and a dump containing an invalid line
+		conn := createDumpConnection(0)
+		dump := `{"Item":{"id":{"S":"test_id|0"}}}` + "\n" +
+			`{"Item":{"id":{"S":"test_id|1"}}}` + "\n" +
+			`{"Item":{"id":{"X":"test_id|2"}}}` + "\n"
+
+		// Next, attempt to restore the dump; this should fail
+		restored, err := conn.RestoreTable(context.Background(), "TARGET_TABLE", strings.NewReader(dump),
+			WithRestoreBatchSize(2))
+
+		// Finally, verify the details of the error
+		Expect(restored).Should(Equal(int64(2)))
+		casted := err.(*Error)
+		Expect(casted.Message).Should(Equal("Failed to decode line 3 of dump for TARGET_TABLE"))
+		Expect(casted.Inner.Error()).Should(Equal("attribute id: attribute value type X is not valid"))
+	})
+})
+
+// Helper function that creates a connection to a fake DynamoDB with source and target tables, writing the
+// number of items requested to the source table
+func createDumpConnection(items int) *DatabaseConnection {
+	client := fake.NewFakeDynamoDB()
+	for _, tableName := range []string{"SOURCE_TABLE", "TARGET_TABLE"} {
+		_, err := client.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+			TableName:            aws.String(tableName),
+			KeySchema:            []types.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash}},
+			AttributeDefinitions: []types.AttributeDefinition{{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS}},
+			BillingMode:          types.BillingModePayPerRequest,
+		})
+
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+
+	conn := createMockConnection(client)
+	requests := make([]types.WriteRequest, items)
+	for i := range requests {
+		requests[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: createBatchItem(i)}}
+	}
+
+	Expect(conn.BatchWrite(context.Background(), "SOURCE_TABLE", requests...)).ShouldNot(HaveOccurred())
+	return conn
+}
+
+// Helper function that reads every item from a table
+func scanDumpTable(conn *DatabaseConnection, tableName string) []map[string]types.AttributeValue {
+	items, err := conn.Scan(context.Background(), &dynamodb.ScanInput{TableName: aws.String(tableName)})
+	Expect(err).ShouldNot(HaveOccurred())
+	return items
+}
@@ -0,0 +1,474 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// EntityRegistry allows multiple types of entity to be stored in a single DynamoDB table, in what is
+// commonly referred to as single-table design. Each entity type is registered with a name and templates
+// describing how its partition and sort keys are built from its attributes, such as "USER#{id}", where the
+// names in braces refer to attributes of the marshalled entity. When an entity is written, the registry
+// will set its composite keys and an attribute containing the name of its entity type, which is then used
+// to decode items returned by queries into the correct Go types. Entity types should be registered with
+// RegisterEntity before the registry is used
+type EntityRegistry struct {
+	conn          *DatabaseConnection
+	tableName     string
+	partitionKey  string
+	sortKey       string
+	typeAttribute string
+	byName        map[string]*entityType
+	byType        map[reflect.Type]*entityType
+	lock          *sync.RWMutex
+}
+
+// Helper type that describes an entity type that has been registered with an EntityRegistry
+type entityType struct {
+	name      string
+	goType    reflect.Type
+	partition *keyTemplate
+	sort      *keyTemplate
+}
+
+// Helper type that describes a template from which a composite key can be built. The template is stored as
+// the literal text surrounding each of its placeholders so there will always be one more literal than there
+// are placeholders
+type keyTemplate struct {
+	raw      string
+	literals []string
+	names    []string
+}
+
+// IEntityRegistryOption defines the functionality that will allow an EntityRegistry to be modified as it is created
+type IEntityRegistryOption interface {
+	Apply(*EntityRegistry)
+}
+
+// WithPartitionKeyAttribute allows the user to set the name of the attribute in which the registry will store
+// the partition key of each entity. If this option is not provided then "pk" will be used
+type WithPartitionKeyAttribute string
+
+// Apply modifies the EntityRegistry so that it uses the partition key attribute defined by this object
+func (w WithPartitionKeyAttribute) Apply(registry *EntityRegistry) {
+	registry.partitionKey = string(w)
+}
+
+// WithSortKeyAttribute allows the user to set the name of the attribute in which the registry will store the
+// sort key of each entity. If this option is not provided then "sk" will be used
+type WithSortKeyAttribute string
+
+// Apply modifies the EntityRegistry so that it uses the sort key attribute defined by this object
+func (w WithSortKeyAttribute) Apply(registry *EntityRegistry) {
+	registry.sortKey = string(w)
+}
+
+// WithEntityTypeAttribute allows the user to set the name of the attribute in which the registry will store the
+// name of the entity type of each item. If this option is not provided then "entity_type" will be used
+type WithEntityTypeAttribute string
+
+// Apply modifies the EntityRegistry so that it uses the entity type attribute defined by this object
+func (w WithEntityTypeAttribute) Apply(registry *EntityRegistry) {
+	registry.typeAttribute = string(w)
+}
+
+// NewEntityRegistry creates a new entity registry for the table with the name provided. Entities will be
+// marshalled and unmarshalled using the tag key set on the connection
+func (conn *DatabaseConnection) NewEntityRegistry(tableName string, opts ...IEntityRegistryOption) *EntityRegistry {
+
+	// First, create our registry with its default settings
+	registry := EntityRegistry{
+		conn:          conn,
+		tableName:     tableName,
+		partitionKey:  "pk",
+		sortKey:       "sk",
+		typeAttribute: "entity_type",
+		byName:        make(map[string]*entityType),
+		byType:        make(map[reflect.Type]*entityType),
+		lock:          new(sync.RWMutex),
+	}
+
+	// Next, apply our options to the registry and return it
+	for _, opt := range opts {
+		opt.Apply(&registry)
+	}
+
+	return &registry
+}
+
+// RegisterEntity registers the type provided with the registry under the entity name provided, with templates
+// describing how its partition and sort keys should be built. The sort key template may be empty if the table
+// has no sort key. An error will be returned if the name or type has already been registered or if either of
+// the templates is invalid
+func RegisterEntity[T any](registry *EntityRegistry, name string, partition string, sort string) error {
+
+	// First, parse the key templates; if either is invalid then return an error
+	entity := entityType{name: name, goType: reflect.TypeOf((*T)(nil)).Elem()}
+	var err error
+	if entity.partition, err = parseKeyTemplate(partition); err != nil {
+		return fmt.Errorf("partition key template of entity %s: %v", name, err)
+	}
+
+	if sort != "" {
+		if entity.sort, err = parseKeyTemplate(sort); err != nil {
+			return fmt.Errorf("sort key template of entity %s: %v", name, err)
+		}
+	}
+
+	// Next, check that neither the name nor the type have already been registered
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if _, ok := registry.byName[name]; ok {
+		return fmt.Errorf("entity %s was already registered", name)
+	} else if existing, ok := registry.byType[entity.goType]; ok {
+		return fmt.Errorf("type %v was already registered as entity %s", entity.goType, existing.name)
+	}
+
+	// Finally, add the entity type to the registry
+	registry.byName[name] = &entity
+	registry.byType[entity.goType] = &entity
+	return nil
+}
+
+// Key builds the key of an item of the entity type with the name provided from the values provided, which
+// should contain a value for every placeholder in the key templates of the entity type. The key may then be
+// used to get, update or delete the item. Each value is marshalled in the same way as the attributes of an
+// entity are by MarshalEntity so the key will match that of the entity. An error will be returned if the
+// entity type has not been registered, if a value is missing or if a value cannot be marshalled
+func (registry *EntityRegistry) Key(name string, values map[string]interface{}) (map[string]types.AttributeValue, error) {
+
+	// First, get the entity type with the name provided; if it doesn't exist then return an error
+	entity, err := registry.entityByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Next, marshal each of the values as an attribute; if any of them fail then return an error
+	attributes := make(map[string]types.AttributeValue, len(values))
+	for placeholder, value := range values {
+		attribute, err := attributevalue.MarshalWithOptions(value, registry.conn.encoderOptions)
+		if err != nil {
+			return nil, registry.conn.NewError(err, registry.tableName,
+				"Failed to marshal value of %s for entity %s", placeholder, entity.name)
+		}
+
+		attributes[placeholder] = attribute
+	}
+
+	// Finally, build the key from the attributes
+	return registry.buildKey(entity, attributeLookup(attributes))
+}
+
+// ParseKey extracts the values of the placeholders in the key templates of the entity type with the name
+// provided from a key, or an item, of that entity type. An error will be returned if the entity type has not
+// been registered or if the key doesn't match its templates
+func (registry *EntityRegistry) ParseKey(name string, key map[string]types.AttributeValue) (map[string]string, error) {
+
+	// First, get the entity type with the name provided; if it doesn't exist then return an error
+	entity, err := registry.entityByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Next, extract the values from the partition key and, if the entity type has one, the sort key
+	values := make(map[string]string)
+	if err := registry.parseKey(entity.partition, registry.partitionKey, key, values); err != nil {
+		return nil, err
+	}
+
+	if entity.sort != nil {
+		if err := registry.parseKey(entity.sort, registry.sortKey, key, values); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// MarshalEntity marshals an entity into a DynamoDB item, adding its composite keys and the name of its entity
+// type. The placeholders in the key templates will be replaced by the values of the corresponding attributes
+// of the marshalled entity, which must be strings or numbers. An error will be returned if the type of the
+// entity has not been registered, if it could not be marshalled or if one of its keys could not be built
+func MarshalEntity[T any](registry *EntityRegistry, obj *T) (map[string]types.AttributeValue, error) {
+
+	// First, get the entity type associated with the Go type; if it doesn't exist then return an error
+	registry.lock.RLock()
+	entity, ok := registry.byType[reflect.TypeOf(obj).Elem()]
+	registry.lock.RUnlock()
+	if !ok {
+		return nil, registry.conn.NewError(fmt.Errorf("type %T was not registered", obj), registry.tableName,
+			"Failed to marshal entity to a DynamoDB item")
+	}
+
+	// Next, attempt to marshal the object into a DynamoDB item; if this fails then return an error
+	item, err := marshalItem(registry.conn, registry.tableName, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	// Now, build the key of the entity from the attributes of the item
+	key, err := registry.buildKey(entity, attributeLookup(item))
+	if err != nil {
+		return nil, err
+	}
+
+	// Finally, add the key and entity type to the item and return it
+	for name, value := range key {
+		item[name] = value
+	}
+
+	item[registry.typeAttribute] = &types.AttributeValueMemberS{Value: entity.name}
+	return item, nil
+}
+
+// PutEntity marshals an entity, with its composite keys and entity type, and writes it to the table,
+// overwriting an existing item if there is one
+func PutEntity[T any](ctx context.Context, registry *EntityRegistry, obj *T) error {
+
+	// First, attempt to marshal the entity into a DynamoDB item; if this fails then return an error
+	item, err := MarshalEntity(registry, obj)
+	if err != nil {
+		return err
+	}
+
+	// Next, attempt to write the item to the table
+	_, err = registry.conn.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(registry.tableName),
+		Item:      item,
+	})
+
+	return err
+}
+
+// Decode unmarshals a list of DynamoDB items, which may be of different entity types, into a list of objects.
+// Each object will be a pointer to the Go type registered for the entity type recorded on the item, so the
+// results should be examined with a type switch. An error will be returned if an item has no entity type, if
+// its entity type has not been registered or if it could not be unmarshalled
+func (registry *EntityRegistry) Decode(items []map[string]types.AttributeValue) ([]interface{}, error) {
+	objs := make([]interface{}, 0, len(items))
+	for _, item := range items {
+
+		// First, read the entity type from the item; if it doesn't have one then return an error
+		name, ok := item[registry.typeAttribute].(*types.AttributeValueMemberS)
+		if !ok {
+			return nil, registry.conn.NewError(fmt.Errorf("item has no %s attribute", registry.typeAttribute),
+				registry.tableName, "Failed to decode DynamoDB item to an entity")
+		}
+
+		// Next, get the entity type associated with the name; if it doesn't exist then return an error
+		entity, err := registry.entityByName(name.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		// Finally, attempt to unmarshal the item into a new object of the registered type
+		obj := reflect.New(entity.goType).Interface()
+		if err := attributevalue.UnmarshalMapWithOptions(item, obj, registry.conn.decoderOptions); err != nil {
+			return nil, registry.conn.NewError(err, registry.tableName,
+				"Failed to unmarshal DynamoDB item to entity %s", entity.name)
+		}
+
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+// Query makes a search on the table, retrieving every page of results, and decodes the items returned into a
+// list of entities. If the table name is not set on the input then the table of the registry will be used
+func (registry *EntityRegistry) Query(ctx context.Context, input *dynamodb.QueryInput) ([]interface{}, error) {
+
+	// First, set the table name on the input if it wasn't provided
+	if input.TableName == nil {
+		input.TableName = aws.String(registry.tableName)
+	}
+
+	// Next, attempt to query the table; if this fails then return an error
+	items, err := registry.conn.Query(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Finally, decode the items into entities and return them
+	return registry.Decode(items)
+}
+
+// Helper function that gets the entity type registered under the name provided
+func (registry *EntityRegistry) entityByName(name string) (*entityType, error) {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	entity, ok := registry.byName[name]
+	if !ok {
+		return nil, registry.conn.NewError(fmt.Errorf("entity %s was not registered", name),
+			registry.tableName, "Entity type %s is unknown", name)
+	}
+
+	return entity, nil
+}
+
+// Helper function that builds the key of an entity from its templates, using the lookup function to get the
+// value of each placeholder
+func (registry *EntityRegistry) buildKey(entity *entityType,
+	lookup func(string) (string, bool)) (map[string]types.AttributeValue, error) {
+
+	// First, build the partition key from its template
+	partition, err := entity.partition.format(lookup)
+	if err != nil {
+		return nil, registry.conn.NewError(err, registry.tableName,
+			"Failed to build partition key for entity %s", entity.name)
+	}
+
+	key := map[string]types.AttributeValue{registry.partitionKey: &types.AttributeValueMemberS{Value: partition}}
+
+	// Next, if the entity has a sort key then build it from its template as well
+	if entity.sort != nil {
+		sort, err := entity.sort.format(lookup)
+		if err != nil {
+			return nil, registry.conn.NewError(err, registry.tableName,
+				"Failed to build sort key for entity %s", entity.name)
+		}
+
+		key[registry.sortKey] = &types.AttributeValueMemberS{Value: sort}
+	}
+
+	return key, nil
+}
+
+// Helper function that creates a function which looks up the value of each placeholder in a key template from
+// the attributes provided. Only string and number attributes may be used in a key
+func attributeLookup(attributes map[string]types.AttributeValue) func(string) (string, bool) {
+	return func(placeholder string) (string, bool) {
+		switch casted := attributes[placeholder].(type) {
+		case *types.AttributeValueMemberS:
+			return casted.Value, true
+		case *types.AttributeValueMemberN:
+			return casted.Value, true
+		default:
+			return "", false
+		}
+	}
+}
+
+// Helper function that extracts the values of the placeholders in a key template from the key attribute with
+// the name provided, adding them to the values provided
+func (registry *EntityRegistry) parseKey(template *keyTemplate, attribute string,
+	key map[string]types.AttributeValue, values map[string]string) error {
+
+	// First, get the value of the key attribute; if it isn't a string then return an error
+	value, ok := key[attribute].(*types.AttributeValueMemberS)
+	if !ok {
+		return registry.conn.NewError(fmt.Errorf("key has no string attribute %s", attribute),
+			registry.tableName, "Failed to parse key attribute %s", attribute)
+	}
+
+	// Next, match the value against the template; if it doesn't match then return an error
+	if !template.parse(value.Value, values) {
+		return registry.conn.NewError(fmt.Errorf("%q does not match template %q", value.Value, template.raw),
+			registry.tableName, "Failed to parse key attribute %s", attribute)
+	}
+
+	return nil
+}
+
+// Helper function that parses a key template, such as "USER#{id}", into its literals and placeholders. An
+// error will be returned if a placeholder is unterminated or empty, or if two placeholders are adjacent as
+// the boundary between their values could not be determined when the key was parsed
+func parseKeyTemplate(raw string) (*keyTemplate, error) {
+	template := keyTemplate{raw: raw}
+	rest := raw
+	for {
+
+		// First, find the start of the next placeholder; if there isn't one then the rest of the template
+		// is the last literal so add it and return
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			template.literals = append(template.literals, rest)
+			return &template, nil
+		}
+
+		// Next, find the end of the placeholder and verify that it has a name
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("placeholder in %q is not terminated", raw)
+		}
+
+		name := rest[start+1 : start+end]
+		if name == "" || strings.Contains(name, "{") {
+			return nil, fmt.Errorf("placeholder %q in %q is invalid", rest[start:start+end+1], raw)
+		}
+
+		// Finally, add the literal preceding the placeholder and the placeholder to the template. If the
+		// placeholder immediately follows another one then return an error
+		if start == 0 && len(template.names) > 0 {
+			return nil, fmt.Errorf("placeholders {%s} and {%s} in %q must be separated by a literal",
+				template.names[len(template.names)-1], name, raw)
+		}
+
+		template.literals = append(template.literals, rest[:start])
+		template.names = append(template.names, name)
+		rest = rest[start+end+1:]
+	}
+}
+
+// Helper function that builds a key from the template, using the lookup function to get the value of each
+// placeholder. An error will be returned if no value could be found for a placeholder
+func (template *keyTemplate) format(lookup func(string) (string, bool)) (string, error) {
+	var builder strings.Builder
+	for i, name := range template.names {
+		value, ok := lookup(name)
+		if !ok {
+			return "", fmt.Errorf("no string or number value was found for {%s} in %q", name, template.raw)
+		}
+
+		builder.WriteString(template.literals[i])
+		builder.WriteString(value)
+	}
+
+	builder.WriteString(template.literals[len(template.literals)-1])
+	return builder.String(), nil
+}
+
+// Helper function that matches a key against the template, adding the value of each placeholder to the
+// values provided. This function returns false if the key does not match the template
+func (template *keyTemplate) parse(key string, values map[string]string) bool {
+
+	// First, check that the key starts with the first literal
+	if !strings.HasPrefix(key, template.literals[0]) {
+		return false
+	}
+
+	// Next, read the value of each placeholder up to the literal that follows it. The last placeholder
+	// takes everything up to the final literal, which must end the key
+	rest := key[len(template.literals[0]):]
+	for i, name := range template.names {
+		next := template.literals[i+1]
+		if i == len(template.names)-1 {
+			if !strings.HasSuffix(rest, next) {
+				return false
+			}
+
+			values[name] = rest[:len(rest)-len(next)]
+			return true
+		}
+
+		end := strings.Index(rest, next)
+		if end < 0 {
+			return false
+		}
+
+		values[name] = rest[:end]
+		rest = rest[end+len(next):]
+	}
+
+	// Finally, if the template had no placeholders then the key must match the literal exactly
+	return rest == ""
+}
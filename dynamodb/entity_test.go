@@ -0,0 +1,185 @@
+package dynamodb
+
+import (
+	"context"
+
+	fake "github.com/Woody1193/goutils/dynamodb/testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Entity Registry Tests", func() {
+
+	// Test that entities of different types written to the same partition can be queried together and
+	// that each item will be decoded into the type registered for its entity type
+	It("Query - Multiple entity types - Decoded to registered types", func() {
+
+		// First, create our test registry and write a user and some orders to the table
+		registry := createEntityRegistry()
+		Expect(PutEntity(context.Background(), registry, &userEntity{ID: "123", Name: "Test User"})).
+			ShouldNot(HaveOccurred())
+		Expect(PutEntity(context.Background(), registry, &orderEntity{UserID: "123", OrderID: 1, Total: 50})).
+			ShouldNot(HaveOccurred())
+		Expect(PutEntity(context.Background(), registry, &orderEntity{UserID: "123", OrderID: 2, Total: 75})).
+			ShouldNot(HaveOccurred())
+		Expect(PutEntity(context.Background(), registry, &userEntity{ID: "456", Name: "Other User"})).
+			ShouldNot(HaveOccurred())
+
+		// Next, query the partition of the first user; this should not fail
+		input, err := NewQuery("ENTITY_TABLE").PartitionKey("pk", "USER#123").Build()
+		Expect(err).ShouldNot(HaveOccurred())
+		entities, err := registry.Query(context.Background(), input)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that each entity was decoded into the correct type
+		Expect(entities).Should(Equal([]interface{}{
+			&orderEntity{UserID: "123", OrderID: 1, Total: 50},
+			&orderEntity{UserID: "123", OrderID: 2, Total: 75},
+			&userEntity{ID: "123", Name: "Test User"}}))
+	})
+
+	// Test that the item written for an entity contains its composite keys and entity type, and that the
+	// values used to build the keys can be parsed back out of them
+	It("MarshalEntity - Registered type - Keys and type added", func() {
+
+		// First, create our test registry and marshal an order; this should not fail
+		registry := createEntityRegistry()
+		item, err := MarshalEntity(registry, &orderEntity{UserID: "123", OrderID: 42, Total: 10})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Next, verify the attributes that were added to the item
+		Expect(item["pk"]).Should(Equal(&types.AttributeValueMemberS{Value: "USER#123"}))
+		Expect(item["sk"]).Should(Equal(&types.AttributeValueMemberS{Value: "ORDER#42"}))
+		Expect(item["entity_type"]).Should(Equal(&types.AttributeValueMemberS{Value: "Order"}))
+
+		// Now, verify that building the key from the same values produces the same key
+		key, err := registry.Key("Order", map[string]interface{}{"user_id": "123", "order_id": 42})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(key).Should(Equal(map[string]types.AttributeValue{"pk": item["pk"], "sk": item["sk"]}))
+
+		// Finally, parse the key and verify the values extracted from it
+		values, err := registry.ParseKey("Order", item)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(values).Should(Equal(map[string]string{"user_id": "123", "order_id": "42"}))
+	})
+
+	// Test that Key marshals each value in the same way as MarshalEntity so that values which are not formatted
+	// the same way as their attributes, such as pointers, produce the same key as the entity
+	It("Key - Values marshalled - Key matches entity", func() {
+
+		// First, create our test registry and marshal an order to get its key
+		registry := createEntityRegistry()
+		item, err := MarshalEntity(registry, &orderEntity{UserID: "123", OrderID: 42, Total: 10})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Next, build the key from pointers to the values; this should not fail
+		key, err := registry.Key("Order", map[string]interface{}{"user_id": aws.String("123"), "order_id": aws.Int(42)})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that the key matches that of the entity
+		Expect(key).Should(Equal(map[string]types.AttributeValue{"pk": item["pk"], "sk": item["sk"]}))
+	})
+
+	// Test that, if a value cannot be marshalled, then Key will return an error
+	It("Key - Value cannot be marshalled - Error", func() {
+		registry := createEntityRegistry()
+		key, err := registry.Key("Order", map[string]interface{}{"user_id": "123", "order_id": failingParameter{}})
+		Expect(key).Should(BeNil())
+		Expect(err.(*Error).Message).Should(Equal("Failed to marshal value of order_id for entity Order"))
+	})
+
+	// Test that, if an entity cannot be written or decoded, then an error will be returned
+	It("MarshalEntity, Decode - Unknown entity - Error", func() {
+
+		// First, create our test registry
+		registry := createEntityRegistry()
+
+		// Next, attempt to marshal an object whose type was not registered; this should fail
+		item, err := MarshalEntity(registry, &testObject{ID: "test_id"})
+		Expect(item).Should(BeNil())
+		Expect(err.(*Error).Message).Should(Equal("Failed to marshal entity to a DynamoDB item"))
+		Expect(err.(*Error).Inner.Error()).Should(Equal("type *dynamodb.testObject was not registered"))
+
+		// Finally, attempt to decode an item with an unknown entity type; this should fail
+		entities, err := registry.Decode([]map[string]types.AttributeValue{
+			{"entity_type": &types.AttributeValueMemberS{Value: "Invoice"}}})
+		Expect(entities).Should(BeNil())
+		Expect(err.(*Error).Message).Should(Equal("Entity type Invoice is unknown"))
+	})
+
+	// Test that, if the key doesn't match the templates of the entity type, then ParseKey will return an error
+	It("ParseKey - Key does not match - Error", func() {
+		registry := createEntityRegistry()
+		values, err := registry.ParseKey("Order", map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: "USER#123"},
+			"sk": &types.AttributeValueMemberS{Value: "PROFILE"}})
+		Expect(values).Should(BeNil())
+		Expect(err.(*Error).Message).Should(Equal("Failed to parse key attribute sk"))
+		Expect(err.(*Error).Inner.Error()).Should(Equal(`"PROFILE" does not match template "ORDER#{order_id}"`))
+	})
+
+	// Test that, if the entity type cannot be registered, then RegisterEntity will return an error
+	DescribeTable("RegisterEntity - Invalid - Error",
+		func(register func(*EntityRegistry) error, message string) {
+			Expect(register(createEntityRegistry())).Should(MatchError(message))
+		},
+		Entry("Duplicate name", func(registry *EntityRegistry) error {
+			return RegisterEntity[testObject](registry, "User", "TEST#{id}", "")
+		}, "entity User was already registered"),
+		Entry("Duplicate type", func(registry *EntityRegistry) error {
+			return RegisterEntity[userEntity](registry, "Member", "MEMBER#{id}", "")
+		}, "type dynamodb.userEntity was already registered as entity User"),
+		Entry("Placeholder not terminated", func(registry *EntityRegistry) error {
+			return RegisterEntity[testObject](registry, "Test", "TEST#{id", "")
+		}, `partition key template of entity Test: placeholder in "TEST#{id" is not terminated`),
+		Entry("Placeholder empty", func(registry *EntityRegistry) error {
+			return RegisterEntity[testObject](registry, "Test", "TEST#{id}", "SORT#{}")
+		}, `sort key template of entity Test: placeholder "{}" in "SORT#{}" is invalid`),
+		Entry("Placeholders adjacent", func(registry *EntityRegistry) error {
+			return RegisterEntity[testObject](registry, "Test", "TEST#{id}{sort_key}", "")
+		}, `partition key template of entity Test: placeholders {id} and {sort_key} in "TEST#{id}{sort_key}" `+
+			`must be separated by a literal`))
+})
+
+// Helper type that describes a user stored in the entity test table
+type userEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Helper type that describes an order stored in the same partition as its user in the entity test table
+type orderEntity struct {
+	UserID  string `json:"user_id"`
+	OrderID int    `json:"order_id"`
+	Total   int    `json:"total"`
+}
+
+// Helper function that creates an entity registry, with users and orders registered, on a fake DynamoDB
+func createEntityRegistry() *EntityRegistry {
+
+	// First, create the table on our fake DynamoDB
+	client := fake.NewFakeDynamoDB()
+	_, err := client.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+		TableName: aws.String("ENTITY_TABLE"),
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("sk"), KeyType: types.KeyTypeRange}},
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("pk"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("sk"), AttributeType: types.ScalarAttributeTypeS}},
+		BillingMode: types.BillingModePayPerRequest,
+	})
+
+	Expect(err).ShouldNot(HaveOccurred())
+
+	// Next, create the registry and register our entity types with it
+	registry := createMockConnection(client).NewEntityRegistry("ENTITY_TABLE")
+	Expect(RegisterEntity[userEntity](registry, "User", "USER#{id}", "PROFILE")).ShouldNot(HaveOccurred())
+	Expect(RegisterEntity[orderEntity](registry, "Order", "USER#{user_id}", "ORDER#{order_id}")).
+		ShouldNot(HaveOccurred())
+	return registry
+}
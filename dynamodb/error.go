@@ -2,6 +2,7 @@ package dynamodb
 
 import (
 	"github.com/Woody1193/goutils/utils"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 // Error describes an error returned by the DynamoDB database connection
@@ -18,3 +19,102 @@ func (conn *DatabaseConnection) NewError(inner error, tableName string,
 		TableName: tableName,
 	}
 }
+
+// BatchWriteError describes an error returned when some of the requests in a batch-write could not
+// be processed by DynamoDB before the unprocessed retry limit was reached
+type BatchWriteError struct {
+	*utils.GError
+	TableName   string
+	Unprocessed map[string][]types.WriteRequest
+}
+
+// CancellationReason describes why a single operation in a transaction caused DynamoDB to cancel it
+type CancellationReason struct {
+	Index     int
+	TableName string
+	Operation string
+	Code      string
+	Message   string
+	Item      map[string]types.AttributeValue
+}
+
+// TransactionCanceledError describes an error returned when DynamoDB cancels a transaction. The
+// reasons contain an entry for each operation in the transaction that caused the cancellation
+type TransactionCanceledError struct {
+	*utils.GError
+	TableName string
+	Reasons   []*CancellationReason
+}
+
+// ConditionFailed returns the reasons associated with operations whose condition expression failed
+func (err *TransactionCanceledError) ConditionFailed() []*CancellationReason {
+	failed := make([]*CancellationReason, 0)
+	for _, reason := range err.Reasons {
+		if reason.Code == "ConditionalCheckFailed" {
+			failed = append(failed, reason)
+		}
+	}
+
+	return failed
+}
+
+// VersionConflictError describes an error returned when a versioned object could not be written to
+// DynamoDB because the version stored on the table did not match the version that was expected. This
+// typically means that the object was modified by another writer after it was read, so the object
+// should be read again and the change reapplied
+type VersionConflictError struct {
+	*utils.GError
+	TableName string
+	Expected  int64
+}
+
+// InvalidCursorError describes an error returned when a pagination cursor could not be decoded, either
+// because it was malformed or because its signature did not match the secret set on the connection. This
+// typically means that the cursor was modified, or created by another service, so it should be rejected
+type InvalidCursorError struct {
+	*utils.GError
+	TableName string
+	Cursor    string
+}
+
+// LockHeldError describes an error returned when a lock could not be acquired because it is held by
+// another client whose lease has not yet expired
+type LockHeldError struct {
+	*utils.GError
+	TableName string
+	LockName  string
+}
+
+// LockLostError describes an error returned when the lease on a lock has been lost, either because it
+// expired before it could be renewed or because another client stole the lock after it expired. Any
+// work protected by the lock should be abandoned when this error is returned
+type LockLostError struct {
+	*utils.GError
+	TableName string
+	LockName  string
+}
+
+// IdempotencyInProgressError describes an error returned when a request could not be processed because
+// another request with the same idempotency key is already being processed
+type IdempotencyInProgressError struct {
+	*utils.GError
+	TableName string
+	Key       string
+}
+
+// IdempotencyMismatchError describes an error returned when an idempotency key is reused for a request
+// whose fingerprint differs from that of the request which first used the key
+type IdempotencyMismatchError struct {
+	*utils.GError
+	TableName string
+	Key       string
+}
+
+// CounterLimitError describes an error returned when a counter could not be updated because its new value
+// would have been outside the floor or ceiling provided with the update
+type CounterLimitError struct {
+	*utils.GError
+	TableName string
+	Attribute string
+	Delta     int64
+}
@@ -0,0 +1,61 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+
+	fake "github.com/Woody1193/goutils/dynamodb/testing"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fake DynamoDB Tests", func() {
+
+	// Test that the table definition can be used to create a table and that versioned objects written
+	// to that table can be queried from its indexes
+	It("NewTableDefinition - Table created - Objects written and queried", func() {
+
+		// First, create our table definition and a connection to an in-memory database
+		def, err := NewTableDefinition[schemaObject]("TEST_TABLE", WithTableTagKey("json"))
+		Expect(err).ShouldNot(HaveOccurred())
+		client := fake.NewFakeDynamoDB()
+		conn := createMockConnection(client)
+
+		// Next, attempt to create the table and enable its TTL; this should not fail
+		_, err = client.CreateTable(context.Background(), def.Input)
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = client.UpdateTimeToLive(context.Background(), def.TimeToLive())
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, attempt to write an object to the table twice; this should not fail and the version
+		// should be incremented each time
+		created := time.Date(2022, time.July, 1, 0, 0, 0, 0, time.UTC)
+		obj := schemaObject{ID: "test_id", SortKey: 1, Owner: "owner", Created: created,
+			Data: "data", Hash: []byte("hash"), Expires: 42}
+		input := &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")}
+		_, err = PutObject(context.Background(), conn, input, &obj)
+		Expect(err).ShouldNot(HaveOccurred())
+		input = &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")}
+		_, err = PutObject(context.Background(), conn, input, &obj)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(obj.Version).Should(Equal(2))
+
+		// Finally, attempt to query the object from the owner index; this should not fail and only the
+		// attributes projected into the index should be returned
+		results, err := QueryAs[schemaObject](context.Background(), conn, &dynamodb.QueryInput{
+			TableName:                aws.String("TEST_TABLE"),
+			IndexName:                aws.String("ByOwner"),
+			KeyConditionExpression:   aws.String("#o = :o AND created <= :c"),
+			ExpressionAttributeNames: map[string]string{"#o": "owner"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":o": &types.AttributeValueMemberS{Value: "owner"},
+				":c": &types.AttributeValueMemberS{Value: "2022-07-01T00:00:00Z"}}})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(results).Should(HaveLen(1))
+		Expect(*results[0]).Should(Equal(schemaObject{ID: "test_id", SortKey: 1, Owner: "owner",
+			Created: created, Data: "data"}))
+	})
+})
@@ -0,0 +1,362 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// IdempotencyStatus describes the state of a request associated with an idempotency key
+type IdempotencyStatus string
+
+const (
+
+	// IdempotencyInProgress indicates that a request with the idempotency key is being processed
+	IdempotencyInProgress IdempotencyStatus = "IN_PROGRESS"
+
+	// IdempotencyCompleted indicates that a request with the idempotency key was processed successfully
+	// and that its response has been stored
+	IdempotencyCompleted IdempotencyStatus = "COMPLETED"
+)
+
+// Helper constant containing the number of times Begin will attempt to create a record for an idempotency
+// key when the existing record keeps being removed before it can be read
+const beginAttempts = 5
+
+// IdempotencyStore records the idempotency keys of requests, along with their responses, on a DynamoDB table
+// so that a request which is retried by a client will only be processed once. Before a request is processed,
+// Begin records its key as being in progress, failing if another request with the same key is already in
+// progress. When the request completes, Complete stores its response so that it can be returned to any
+// duplicate requests. If the request fails then Abort removes the key so that the request may be retried.
+// Completed records expire after the record expiry so the table should have its TTL enabled, using the
+// definition returned by NewIdempotencyTableDefinition
+type IdempotencyStore struct {
+	conn      *DatabaseConnection
+	tableName string
+	expiry    time.Duration
+	timeout   time.Duration
+	now       func() time.Time
+}
+
+// IdempotencyRecord describes the state of a request associated with an idempotency key, as it is stored
+// in DynamoDB. The fingerprint should describe the content of the request, such as a hash of its body, so
+// that a client reusing a key for a different request can be detected. The token identifies the attempt
+// to process the request that created the record
+type IdempotencyRecord struct {
+	Key         string            `dynamodbav:"idempotency_key" dynamodb:"hash"`
+	Status      IdempotencyStatus `dynamodbav:"status"`
+	Fingerprint string            `dynamodbav:"fingerprint,omitempty"`
+	Token       string            `dynamodbav:"token"`
+	Response    []byte            `dynamodbav:"response,omitempty"`
+	LockedUntil int64             `dynamodbav:"locked_until"`
+	Expires     int64             `dynamodbav:"expires" dynamodb:"ttl"`
+}
+
+// IIdempotencyOption defines the functionality that will allow an IdempotencyStore to be modified as it is created
+type IIdempotencyOption interface {
+	Apply(*IdempotencyStore)
+}
+
+// WithRecordExpiry allows the user to set how long an idempotency record will be kept after it was created.
+// Duplicate requests received after this time will be processed again. If this option is not provided then
+// records will be kept for 24 hours
+type WithRecordExpiry time.Duration
+
+// Apply modifies the IdempotencyStore so that it has the record expiry defined by this object
+func (w WithRecordExpiry) Apply(store *IdempotencyStore) {
+	store.expiry = time.Duration(w)
+}
+
+// WithInProgressTimeout allows the user to set how long a request may be in progress before it is considered
+// abandoned, at which point a duplicate request may take over processing it. This should be longer than the
+// longest time a request can take to process. If this option is not provided then requests will be considered
+// abandoned after 1 minute
+type WithInProgressTimeout time.Duration
+
+// Apply modifies the IdempotencyStore so that it has the in-progress timeout defined by this object
+func (w WithInProgressTimeout) Apply(store *IdempotencyStore) {
+	store.timeout = time.Duration(w)
+}
+
+// NewIdempotencyTableDefinition creates a definition describing a table that can be used to store idempotency
+// records. Options may be provided to modify the definition, such as its provisioned throughput, but any tag
+// key provided will be ignored as the names of the record attributes are fixed
+func NewIdempotencyTableDefinition(tableName string, opts ...ITableOption) (*TableDefinition, error) {
+	return NewTableDefinition[IdempotencyRecord](tableName, append(opts, WithTableTagKey("dynamodbav"))...)
+}
+
+// NewIdempotencyStore creates a new idempotency store that will record idempotency keys on the table provided
+func (conn *DatabaseConnection) NewIdempotencyStore(tableName string, opts ...IIdempotencyOption) *IdempotencyStore {
+
+	// First, create our store with default values
+	store := IdempotencyStore{
+		conn:      conn,
+		tableName: tableName,
+		expiry:    24 * time.Hour,
+		timeout:   time.Minute,
+		now:       time.Now,
+	}
+
+	// Next, iterate over the options provided and update the associated values on the store
+	for _, opt := range opts {
+		opt.Apply(&store)
+	}
+
+	return &store
+}
+
+// Begin records that a request with the idempotency key and fingerprint provided is in progress. If no request
+// with the key has been seen, or a previous request was abandoned or has expired, then a new in-progress record
+// will be returned and the request should be processed. If a previous request with the key was completed then
+// its record will be returned, with the stored response, and the request should not be processed again. If a
+// request with the key is in progress then an IdempotencyInProgressError will be returned and, if the record has
+// a different fingerprint, an IdempotencyMismatchError will be returned. If the existing record is repeatedly
+// removed before it can be read then Begin will give up and return an error
+func (store *IdempotencyStore) Begin(ctx context.Context, key string, fingerprint string) (*IdempotencyRecord, error) {
+
+	// First, create a token identifying this attempt to process the request and attempt to create the
+	// in-progress record. If the record for the key is deleted after our write fails but before we can
+	// read it then we'll try again, up to a limit, so that a key which is being repeatedly created and
+	// removed can't keep us here indefinitely
+	token := newToken()
+	for attempt := 0; attempt < beginAttempts; attempt++ {
+		record, existing, err := store.tryBegin(ctx, key, fingerprint, token)
+		if err != nil {
+			return nil, err
+		} else if record != nil {
+			return record, nil
+		} else if existing == nil {
+			continue
+		}
+
+		// Next, check that the existing record describes the same request and return it if it has been
+		// completed. Otherwise, the request is still in progress so return an error
+		if existing.Fingerprint != fingerprint {
+			return nil, &IdempotencyMismatchError{
+				GError: store.conn.NewError(fmt.Errorf("expected fingerprint %q but found %q", fingerprint,
+					existing.Fingerprint), store.tableName, "Idempotency key %s was used for a different request", key).GError,
+				TableName: store.tableName,
+				Key:       key,
+			}
+		} else if existing.Status == IdempotencyCompleted {
+			return existing, nil
+		}
+
+		return nil, &IdempotencyInProgressError{
+			GError: store.conn.NewError(fmt.Errorf("request is locked until %s", time.UnixMilli(existing.LockedUntil)),
+				store.tableName, "Request with idempotency key %s is already in progress", key).GError,
+			TableName: store.tableName,
+			Key:       key,
+		}
+	}
+
+	// Finally, if we get here then the record kept being removed before we could read it so return an error
+	return nil, store.conn.NewError(nil, store.tableName, "Failed to begin request with idempotency key %s "+
+		"after %d attempts because its record was repeatedly removed", key, beginAttempts)
+}
+
+// Complete stores the response to the request described by the in-progress record, returned by Begin, and marks
+// it as completed so that the response will be returned to duplicate requests. If the request was abandoned and
+// taken over by a duplicate request then the condition on the write will fail and an error will be returned
+func (store *IdempotencyStore) Complete(ctx context.Context, record *IdempotencyRecord, response []byte) error {
+
+	// Attempt to update the record with the response, on the condition that it was created by the same attempt
+	// to process the request; if this fails then return an error
+	update := "SET #status = :completed, #expires = :expires"
+	names := map[string]string{"#status": "status", "#expires": "expires", "#token": "token"}
+	values := map[string]types.AttributeValue{
+		":completed": &types.AttributeValueMemberS{Value: string(IdempotencyCompleted)},
+		":expires":   &types.AttributeValueMemberN{Value: strconv.FormatInt(store.now().Add(store.expiry).Unix(), 10)},
+		":token":     &types.AttributeValueMemberS{Value: record.Token}}
+	if len(response) > 0 {
+		update += ", #response = :response"
+		names["#response"] = "response"
+		values[":response"] = &types.AttributeValueMemberB{Value: response}
+	}
+
+	_, err := store.conn.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(store.tableName),
+		Key:                       idempotencyKey(record.Key),
+		UpdateExpression:          aws.String(update),
+		ConditionExpression:       aws.String("#token = :token"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	record.Status = IdempotencyCompleted
+	record.Response = response
+	return nil
+}
+
+// Abort removes the in-progress record, returned by Begin, so that the request may be retried. This should be
+// called when processing the request fails in a way that the client should be allowed to retry. If the request
+// was abandoned and taken over by a duplicate request then the record will not be removed
+func (store *IdempotencyStore) Abort(ctx context.Context, record *IdempotencyRecord) error {
+	_, err := store.conn.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                aws.String(store.tableName),
+		Key:                      idempotencyKey(record.Key),
+		ConditionExpression:      aws.String("#token = :token"),
+		ExpressionAttributeNames: map[string]string{"#token": "token"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":token": &types.AttributeValueMemberS{Value: record.Token}},
+	})
+
+	if _, ok := isConditionFailed(err); err != nil && !ok {
+		return err
+	}
+
+	return nil
+}
+
+// Get reads the idempotency record associated with a key, returning nil if there is no such record
+func (store *IdempotencyStore) Get(ctx context.Context, key string) (*IdempotencyRecord, error) {
+
+	// First, attempt to read the record with a strongly-consistent read; if this fails then return an error
+	output, err := store.conn.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(store.tableName),
+		Key:            idempotencyKey(key),
+		ConsistentRead: aws.Bool(true),
+	})
+
+	if err != nil {
+		return nil, err
+	} else if output.Item == nil {
+		return nil, nil
+	}
+
+	// Next, attempt to unmarshal the record; if this fails then return an error
+	var record IdempotencyRecord
+	if err := attributevalue.UnmarshalMap(output.Item, &record); err != nil {
+		return nil, store.conn.NewError(err, store.tableName, "Failed to unmarshal idempotency record %s", key)
+	}
+
+	return &record, nil
+}
+
+// Idempotent calls the handler at most once for each idempotency key, storing its response so that it can be
+// returned to duplicate requests. The response will be serialized to JSON when it is stored. If a request with
+// the key is already in progress, or the key was used with a different fingerprint, then the errors described
+// by Begin will be returned. If the handler fails then its error will be returned and the key will be removed
+// so that the request can be retried
+func Idempotent[T any](ctx context.Context, store *IdempotencyStore, key string, fingerprint string,
+	handler func(context.Context) (*T, error)) (*T, error) {
+
+	// First, attempt to begin the request; if this fails then return an error
+	record, err := store.Begin(ctx, key, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	// Next, if the request has already been completed then deserialize the stored response and return it
+	if record.Status == IdempotencyCompleted {
+		var response *T
+		if err := json.Unmarshal(record.Response, &response); err != nil {
+			return nil, store.conn.NewError(err, store.tableName,
+				"Failed to unmarshal response for idempotency key %s to %T", key, response)
+		}
+
+		return response, nil
+	}
+
+	// Now, call the handler; if it fails then remove the in-progress record and return the error
+	response, err := handler(ctx)
+	if err != nil {
+		if inner := store.Abort(ctx, record); inner != nil {
+			store.conn.logger.Log("Failed to abort request with idempotency key %s: %v", key, inner)
+		}
+
+		return nil, err
+	}
+
+	// Finally, serialize the response and store it with the record
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, store.conn.NewError(err, store.tableName,
+			"Failed to marshal response for idempotency key %s", key)
+	}
+
+	if err := store.Complete(ctx, record, data); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// Helper function that creates the key of an idempotency record from the idempotency key
+func idempotencyKey(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"idempotency_key": &types.AttributeValueMemberS{Value: key}}
+}
+
+// Helper function that attempts to write an in-progress record, identified by the token provided, for the
+// idempotency key and fingerprint provided. If the record was written then it will be returned as the first
+// value. Otherwise, the existing record for the key will be returned as the second value, or neither record
+// will be returned if the existing record was removed before it could be read
+func (store *IdempotencyStore) tryBegin(ctx context.Context, key string, fingerprint string,
+	token string) (*IdempotencyRecord, *IdempotencyRecord, error) {
+
+	// First, create the in-progress record
+	now := store.now()
+	record := IdempotencyRecord{
+		Key:         key,
+		Status:      IdempotencyInProgress,
+		Fingerprint: fingerprint,
+		Token:       token,
+		LockedUntil: now.Add(store.timeout).UnixMilli(),
+		Expires:     now.Add(store.expiry).Unix(),
+	}
+
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return nil, nil, store.conn.NewError(err, store.tableName, "Failed to marshal idempotency record %s", key)
+	}
+
+	// Next, attempt to write the record to the table, on the condition that there's no record for the
+	// key, the existing record has expired but hasn't been deleted yet or the request that created it
+	// was abandoned while it was in progress
+	_, err = store.conn.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(store.tableName),
+		Item:      item,
+		ConditionExpression: aws.String("attribute_not_exists(#key) OR #expires < :seconds OR " +
+			"(#status = :progress AND #locked < :millis)"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":     "idempotency_key",
+			"#expires": "expires",
+			"#status":  "status",
+			"#locked":  "locked_until"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":seconds":  &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+			":millis":   &types.AttributeValueMemberN{Value: strconv.FormatInt(now.UnixMilli(), 10)},
+			":progress": &types.AttributeValueMemberS{Value: string(IdempotencyInProgress)}},
+	})
+
+	// If the write succeeded then return the new record. Otherwise, if the write failed for any reason
+	// other than the condition then return the error
+	if err == nil {
+		return &record, nil, nil
+	} else if _, ok := isConditionFailed(err); !ok {
+		return nil, nil, err
+	}
+
+	// Finally, read the existing record from the table so we can determine why the condition failed. If the
+	// record has our token then the write was retried after an earlier attempt that succeeded, so the record
+	// is ours and should be returned as the new record
+	existing, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	} else if existing != nil && existing.Token == token {
+		return existing, nil, nil
+	}
+
+	return nil, existing, nil
+}
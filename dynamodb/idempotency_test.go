@@ -0,0 +1,267 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	fake "github.com/Woody1193/goutils/dynamodb/testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Idempotency Store Tests", func() {
+
+	// Test that, if a request is repeated with the same idempotency key, then the handler will only be
+	// called once and the stored response will be returned for the duplicate request
+	It("Idempotent - Duplicate request - Stored response returned", func() {
+
+		// First, create our test store and a handler that counts how many times it was called
+		store := createIdempotencyStore()
+		calls := 0
+		handler := func(ctx context.Context) (*testObject, error) {
+			calls++
+			return &testObject{ID: "test_id", SortKey: "test|sort|key", Data: calls}, nil
+		}
+
+		// Next, call the handler twice with the same key; neither call should fail
+		first, err := Idempotent(context.Background(), store, "test_key", "fingerprint", handler)
+		Expect(err).ShouldNot(HaveOccurred())
+		second, err := Idempotent(context.Background(), store, "test_key", "fingerprint", handler)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, verify that the handler was only called once and both calls returned the same response
+		Expect(calls).Should(Equal(1))
+		Expect(second).Should(Equal(first))
+
+		// Finally, verify the record that was stored
+		record, err := store.Get(context.Background(), "test_key")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(record.Status).Should(Equal(IdempotencyCompleted))
+		Expect(record.Fingerprint).Should(Equal("fingerprint"))
+		Expect(string(record.Response)).Should(Equal(`{"id":"test_id","sort_key":"test|sort|key","data":1}`))
+		Expect(record.Expires).Should(Equal(idempotencyTime.Add(24 * time.Hour).Unix()))
+	})
+
+	// Test that, if the handler fails, then its error will be returned and the request may be retried
+	It("Idempotent - Handler fails - Request can be retried", func() {
+
+		// First, create our test store and a handler that fails the first time it is called
+		store := createIdempotencyStore()
+		calls := 0
+		handler := func(ctx context.Context) (*testObject, error) {
+			if calls++; calls == 1 {
+				return nil, fmt.Errorf("handler failed")
+			}
+
+			return &testObject{ID: "test_id", Data: calls}, nil
+		}
+
+		// Next, call the handler; this should fail and the record should be removed
+		obj, err := Idempotent(context.Background(), store, "test_key", "", handler)
+		Expect(obj).Should(BeNil())
+		Expect(err).Should(MatchError("handler failed"))
+
+		record, err := store.Get(context.Background(), "test_key")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(record).Should(BeNil())
+
+		// Finally, retry the request; this should not fail and the handler should be called again
+		obj, err = Idempotent(context.Background(), store, "test_key", "", handler)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(obj.Data).Should(Equal(2))
+	})
+
+	// Test that, if a request with the same key is in progress, then Begin will reject the duplicate
+	// request until the in-progress timeout has elapsed, after which it may take over the request
+	It("Begin - In progress - Rejected until abandoned", func() {
+
+		// First, create our test store and begin a request; this should not fail
+		store := createIdempotencyStore()
+		record, err := store.Begin(context.Background(), "test_key", "fingerprint")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(record.Status).Should(Equal(IdempotencyInProgress))
+
+		// Next, attempt to begin a duplicate request; this should fail
+		duplicate, err := store.Begin(context.Background(), "test_key", "fingerprint")
+		Expect(duplicate).Should(BeNil())
+		casted := err.(*IdempotencyInProgressError)
+		Expect(casted.TableName).Should(Equal("IDEMPOTENCY_TABLE"))
+		Expect(casted.Key).Should(Equal("test_key"))
+		Expect(casted.Message).Should(Equal("Request with idempotency key test_key is already in progress"))
+
+		// Now, advance the clock past the in-progress timeout and begin the duplicate request again;
+		// this should not fail as the original request is considered abandoned
+		store.now = func() time.Time { return idempotencyTime.Add(2 * time.Minute) }
+		duplicate, err = store.Begin(context.Background(), "test_key", "fingerprint")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(duplicate.Token).ShouldNot(Equal(record.Token))
+
+		// Finally, verify that the original request can no longer be completed or aborted and that the
+		// duplicate request can be completed
+		Expect(store.Complete(context.Background(), record, []byte("original"))).Should(HaveOccurred())
+		Expect(store.Abort(context.Background(), record)).ShouldNot(HaveOccurred())
+		Expect(store.Complete(context.Background(), duplicate, []byte("duplicate"))).ShouldNot(HaveOccurred())
+
+		stored, err := store.Get(context.Background(), "test_key")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stored.Response).Should(Equal([]byte("duplicate")))
+	})
+
+	// Test that, if an idempotency key is reused for a different request, then Begin will return an error
+	It("Begin - Different fingerprint - Error", func() {
+
+		// First, create our test store and complete a request; this should not fail
+		store := createIdempotencyStore()
+		record, err := store.Begin(context.Background(), "test_key", "fingerprint")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(store.Complete(context.Background(), record, []byte("response"))).ShouldNot(HaveOccurred())
+
+		// Next, attempt to begin a request with the same key but a different fingerprint; this should fail
+		duplicate, err := store.Begin(context.Background(), "test_key", "other")
+
+		// Finally, verify the details of the error
+		Expect(duplicate).Should(BeNil())
+		casted := err.(*IdempotencyMismatchError)
+		Expect(casted.Key).Should(Equal("test_key"))
+		Expect(casted.Message).Should(Equal("Idempotency key test_key was used for a different request"))
+		Expect(casted.Inner.Error()).Should(Equal(`expected fingerprint "other" but found "fingerprint"`))
+	})
+
+	// Test that, if the request has no response, then Complete will still mark the request as completed
+	It("Complete - Empty response - Completed", func() {
+
+		// First, create our test store and begin a request; this should not fail
+		store := createIdempotencyStore()
+		record, err := store.Begin(context.Background(), "test_key", "fingerprint")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Next, complete the request without a response; this should not fail
+		Expect(store.Complete(context.Background(), record, nil)).ShouldNot(HaveOccurred())
+
+		// Finally, verify that a duplicate request receives the completed record with no response
+		duplicate, err := store.Begin(context.Background(), "test_key", "fingerprint")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(duplicate.Status).Should(Equal(IdempotencyCompleted))
+		Expect(duplicate.Response).Should(BeEmpty())
+	})
+
+	// Test that, if the record has expired but has not yet been deleted, then Begin will start a new request
+	It("Begin - Record expired - New request started", func() {
+
+		// First, create our test store and complete a request; this should not fail
+		store := createIdempotencyStore()
+		record, err := store.Begin(context.Background(), "test_key", "fingerprint")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(store.Complete(context.Background(), record, []byte("response"))).ShouldNot(HaveOccurred())
+
+		// Next, advance the clock past the record expiry and begin the request again; this should not fail
+		store.now = func() time.Time { return idempotencyTime.Add(25 * time.Hour) }
+		duplicate, err := store.Begin(context.Background(), "test_key", "other")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that a new request was started
+		Expect(duplicate.Status).Should(Equal(IdempotencyInProgress))
+		Expect(duplicate.Response).Should(BeNil())
+	})
+
+	// Test that, if the write of the record is retried after an earlier attempt that succeeded, then Begin
+	// will recognise the record as its own rather than reporting that the request is already in progress
+	It("Begin - Retried after write - New request started", func() {
+
+		// First, create our test store with a client that writes the first record it is sent but reports
+		// that the write failed, so that it will be retried
+		store := createIdempotencyStore()
+		client := &faultyIdempotencyDynamoDB{DynamoDBAPI: store.conn.db, putFailures: 1}
+		store.conn.db = client
+
+		// Next, begin the request; this should not fail
+		record, err := store.Begin(context.Background(), "test_key", "fingerprint")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that the request was started with the record that was written
+		Expect(client.puts).Should(Equal(2))
+		Expect(record.Status).Should(Equal(IdempotencyInProgress))
+		stored, err := store.Get(context.Background(), "test_key")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(stored).Should(Equal(record))
+	})
+
+	// Test that, if the existing record keeps disappearing before it can be read, then Begin will give up
+	// after a limited number of attempts
+	It("Begin - Record repeatedly removed - Error", func() {
+
+		// First, create our test store and begin a request so that later writes will fail
+		store := createIdempotencyStore()
+		_, err := store.Begin(context.Background(), "test_key", "fingerprint")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Next, hide the record from reads so that it appears to be removed after each failed write
+		client := &faultyIdempotencyDynamoDB{DynamoDBAPI: store.conn.db, hideItems: true}
+		store.conn.db = client
+
+		// Now, attempt to begin the request again; this should fail
+		record, err := store.Begin(context.Background(), "test_key", "fingerprint")
+
+		// Finally, verify the details of the error and the number of attempts that were made
+		Expect(record).Should(BeNil())
+		Expect(err.(*Error).Message).Should(Equal("Failed to begin request with idempotency key test_key " +
+			"after 5 attempts because its record was repeatedly removed"))
+		Expect(client.puts).Should(Equal(beginAttempts))
+	})
+})
+
+// Helper variable containing the time used as the clock by test idempotency stores
+var idempotencyTime = time.Date(2022, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+// Helper function that creates an idempotency store on a fake DynamoDB with a fixed clock
+func createIdempotencyStore() *IdempotencyStore {
+	def, err := NewIdempotencyTableDefinition("IDEMPOTENCY_TABLE")
+	Expect(err).ShouldNot(HaveOccurred())
+
+	client := fake.NewFakeDynamoDB()
+	_, err = client.CreateTable(context.Background(), def.Input)
+	Expect(err).ShouldNot(HaveOccurred())
+
+	store := createMockConnection(client).NewIdempotencyStore("IDEMPOTENCY_TABLE")
+	store.now = func() time.Time { return idempotencyTime }
+	return store
+}
+
+// Helper type that wraps a DynamoDB client so that idempotency tests can simulate writes that fail after
+// they were applied and records that are removed before they can be read
+type faultyIdempotencyDynamoDB struct {
+	DynamoDBAPI
+	puts        int
+	putFailures int
+	hideItems   bool
+}
+
+// Mocks out the PutItem function so that it counts the requests made and, for the number of requests set by
+// putFailures, writes the item and then reports a retryable failure
+func (client *faultyIdempotencyDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	client.puts++
+	output, err := client.DynamoDBAPI.PutItem(ctx, params, optFns...)
+	if err == nil && client.putFailures > 0 {
+		client.putFailures--
+		return nil, &smithy.OperationError{Err: &types.InternalServerError{Message: aws.String("failed")}}
+	}
+
+	return output, err
+}
+
+// Mocks out the GetItem function so that, if items should be hidden, it reports that no item was found
+func (client *faultyIdempotencyDynamoDB) GetItem(ctx context.Context, params *dynamodb.GetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if client.hideItems {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+
+	return client.DynamoDBAPI.GetItem(ctx, params, optFns...)
+}
@@ -0,0 +1,183 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MarshalItemJSON encodes a DynamoDB item in the DynamoDB JSON format, in which each attribute value is an
+// object with a single key describing its type (e.g. {"id": {"S": "value"}}). This is the format used by the
+// DynamoDB API, the AWS CLI and exports to S3, so items encoded by this function can be exchanged with them
+func MarshalItemJSON(item map[string]types.AttributeValue) ([]byte, error) {
+	tree, err := encodeItemJSON(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(tree)
+}
+
+// UnmarshalItemJSON decodes a DynamoDB item from the DynamoDB JSON format. See MarshalItemJSON for more
+// information on the format. An error will be returned if the data is not valid JSON or if any of the
+// attribute values does not have exactly one valid type
+func UnmarshalItemJSON(data []byte) (map[string]types.AttributeValue, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	return decodeItemJSON(raw)
+}
+
+// Helper function that encodes each of the attributes of an item into a tree of JSON-compatible values
+func encodeItemJSON(item map[string]types.AttributeValue) (map[string]interface{}, error) {
+	tree := make(map[string]interface{}, len(item))
+	for name, value := range item {
+		encoded, err := encodeAttributeJSON(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %v", name, err)
+		}
+
+		tree[name] = encoded
+	}
+
+	return tree, nil
+}
+
+// Helper function that encodes an attribute value into an object with a single key describing its type.
+// Binary values are encoded by the JSON package as base64 strings, as DynamoDB expects
+func encodeAttributeJSON(value types.AttributeValue) (interface{}, error) {
+	switch casted := value.(type) {
+	case *types.AttributeValueMemberB:
+		return map[string]interface{}{"B": casted.Value}, nil
+	case *types.AttributeValueMemberBOOL:
+		return map[string]interface{}{"BOOL": casted.Value}, nil
+	case *types.AttributeValueMemberBS:
+		return map[string]interface{}{"BS": casted.Value}, nil
+	case *types.AttributeValueMemberL:
+		list := make([]interface{}, len(casted.Value))
+		for i, inner := range casted.Value {
+			encoded, err := encodeAttributeJSON(inner)
+			if err != nil {
+				return nil, err
+			}
+
+			list[i] = encoded
+		}
+
+		return map[string]interface{}{"L": list}, nil
+	case *types.AttributeValueMemberM:
+		entries, err := encodeItemJSON(casted.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"M": entries}, nil
+	case *types.AttributeValueMemberN:
+		return map[string]interface{}{"N": casted.Value}, nil
+	case *types.AttributeValueMemberNS:
+		return map[string]interface{}{"NS": casted.Value}, nil
+	case *types.AttributeValueMemberNULL:
+		return map[string]interface{}{"NULL": casted.Value}, nil
+	case *types.AttributeValueMemberS:
+		return map[string]interface{}{"S": casted.Value}, nil
+	case *types.AttributeValueMemberSS:
+		return map[string]interface{}{"SS": casted.Value}, nil
+	default:
+		return nil, fmt.Errorf("attribute value of type %T cannot be encoded", value)
+	}
+}
+
+// Helper function that decodes each of the attributes of an item from their raw JSON
+func decodeItemJSON(raw map[string]json.RawMessage) (map[string]types.AttributeValue, error) {
+	item := make(map[string]types.AttributeValue, len(raw))
+	for name, data := range raw {
+		value, err := decodeAttributeJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %v", name, err)
+		}
+
+		item[name] = value
+	}
+
+	return item, nil
+}
+
+// Helper function that decodes an attribute value from an object with a single key describing its type
+func decodeAttributeJSON(data json.RawMessage) (types.AttributeValue, error) {
+
+	// First, ensure that the object has a single key, which describes the type of the attribute
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	} else if len(entries) != 1 {
+		return nil, fmt.Errorf("attribute value must have exactly one type but had %d", len(entries))
+	}
+
+	var kind string
+	var inner json.RawMessage
+	for key, value := range entries {
+		kind, inner = key, value
+	}
+
+	// Next, decode the value based on the type of the attribute
+	switch kind {
+	case "B":
+		value := new(types.AttributeValueMemberB)
+		return value, json.Unmarshal(inner, &value.Value)
+	case "BOOL":
+		value := new(types.AttributeValueMemberBOOL)
+		return value, json.Unmarshal(inner, &value.Value)
+	case "BS":
+		value := new(types.AttributeValueMemberBS)
+		return value, json.Unmarshal(inner, &value.Value)
+	case "N":
+		value := new(types.AttributeValueMemberN)
+		return value, json.Unmarshal(inner, &value.Value)
+	case "NS":
+		value := new(types.AttributeValueMemberNS)
+		return value, json.Unmarshal(inner, &value.Value)
+	case "NULL":
+		value := new(types.AttributeValueMemberNULL)
+		return value, json.Unmarshal(inner, &value.Value)
+	case "S":
+		value := new(types.AttributeValueMemberS)
+		return value, json.Unmarshal(inner, &value.Value)
+	case "SS":
+		value := new(types.AttributeValueMemberSS)
+		return value, json.Unmarshal(inner, &value.Value)
+	case "L":
+		var list []json.RawMessage
+		if err := json.Unmarshal(inner, &list); err != nil {
+			return nil, err
+		}
+
+		value := &types.AttributeValueMemberL{Value: make([]types.AttributeValue, len(list))}
+		for i, item := range list {
+			decoded, err := decodeAttributeJSON(item)
+			if err != nil {
+				return nil, err
+			}
+
+			value.Value[i] = decoded
+		}
+
+		return value, nil
+	case "M":
+		var mapping map[string]json.RawMessage
+		if err := json.Unmarshal(inner, &mapping); err != nil {
+			return nil, err
+		}
+
+		entries, err := decodeItemJSON(mapping)
+		if err != nil {
+			return nil, err
+		}
+
+		return &types.AttributeValueMemberM{Value: entries}, nil
+	default:
+		return nil, fmt.Errorf("attribute value type %s is not valid", kind)
+	}
+}
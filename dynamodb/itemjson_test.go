@@ -0,0 +1,57 @@
+package dynamodb
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Item JSON Tests", func() {
+
+	// Test that an item containing every type of attribute value can be encoded in the DynamoDB JSON format
+	// and decoded back into the same item
+	It("MarshalItemJSON, UnmarshalItemJSON - All types - Round trip", func() {
+
+		// First, create an item with every type of attribute value
+		item := map[string]types.AttributeValue{
+			"b":    &types.AttributeValueMemberB{Value: []byte("binary")},
+			"bool": &types.AttributeValueMemberBOOL{Value: true},
+			"bs":   &types.AttributeValueMemberBS{Value: [][]byte{[]byte("a"), []byte("b")}},
+			"l": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "inner"}, &types.AttributeValueMemberN{Value: "1"}}},
+			"m": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"inner": &types.AttributeValueMemberNULL{Value: true}}},
+			"n":  &types.AttributeValueMemberN{Value: "42.5"},
+			"ns": &types.AttributeValueMemberNS{Value: []string{"1", "2"}},
+			"s":  &types.AttributeValueMemberS{Value: "string"},
+			"ss": &types.AttributeValueMemberSS{Value: []string{"x", "y"}}}
+
+		// Next, encode the item; this should not fail
+		data, err := MarshalItemJSON(item)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, verify the encoded item
+		Expect(string(data)).Should(Equal(`{"b":{"B":"YmluYXJ5"},"bool":{"BOOL":true},"bs":{"BS":["YQ==","Yg=="]},` +
+			`"l":{"L":[{"S":"inner"},{"N":"1"}]},"m":{"M":{"inner":{"NULL":true}}},"n":{"N":"42.5"},` +
+			`"ns":{"NS":["1","2"]},"s":{"S":"string"},"ss":{"SS":["x","y"]}}`))
+
+		// Finally, decode the item and verify that it matches the original
+		decoded, err := UnmarshalItemJSON(data)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(decoded).Should(Equal(item))
+	})
+
+	// Test that, if the data is not a valid item in the DynamoDB JSON format, then UnmarshalItemJSON will fail
+	DescribeTable("UnmarshalItemJSON - Invalid - Error",
+		func(data string, message string) {
+			item, err := UnmarshalItemJSON([]byte(data))
+			Expect(item).Should(BeNil())
+			Expect(err).Should(MatchError(message))
+		},
+		Entry("Not JSON", `{"id":`, "unexpected end of JSON input"),
+		Entry("Multiple types", `{"id":{"S":"a","N":"1"}}`, "attribute id: attribute value must have exactly one type but had 2"),
+		Entry("Unknown type", `{"id":{"X":"a"}}`, "attribute id: attribute value type X is not valid"),
+		Entry("Wrong value type", `{"id":{"N":1}}`, "attribute id: json: cannot unmarshal number into Go value of type string"),
+		Entry("Nested invalid", `{"m":{"M":{"inner":{"L":[{}]}}}}`,
+			"attribute m: attribute inner: attribute value must have exactly one type but had 0"))
+})
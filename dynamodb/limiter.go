@@ -0,0 +1,285 @@
+package dynamodb
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Helper type that limits the read and write capacity consumed against each table by a connection so
+// that it can throttle itself before DynamoDB throttles it. Each table has a token bucket for read units
+// and another for write units, each of which refills at the rate allowed for the table and can hold no
+// more than a second's worth of units. Before each request, a single unit is taken from the bucket for
+// the type of request, waiting for it to refill if necessary. After the request, the capacity actually
+// consumed is taken from the buckets so that expensive requests delay those that follow them
+type throughputLimiter struct {
+	tables   map[string]*tableLimit
+	fraction float64
+	refresh  time.Duration
+	now      func() time.Time
+	sleep    func(context.Context, time.Duration) error
+	lock     *sync.Mutex
+}
+
+// Helper type that contains the read and write token buckets for a single table, along with a description
+// of where their rates came from
+type tableLimit struct {
+	static  bool
+	updated time.Time
+	read    *tokenBucket
+	write   *tokenBucket
+}
+
+// Helper type that describes a single token bucket. A rate of zero indicates that there is no limit
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// Helper function that creates a new throughput limiter with no limits
+func newThroughputLimiter() *throughputLimiter {
+	return &throughputLimiter{
+		tables: make(map[string]*tableLimit),
+		now:    time.Now,
+		sleep:  sleepContext,
+		lock:   new(sync.Mutex),
+	}
+}
+
+// Helper function that sets the limits for a table. If static is true then the limits will not be
+// replaced with those derived from the throughput provisioned on the table
+func (limiter *throughputLimiter) setLimit(tableName string, read float64, write float64, static bool) {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	// First, if the table already has static limits then we don't want to overwrite them with
+	// limits derived from its provisioned throughput so exit here
+	now := limiter.now()
+	limit, ok := limiter.tables[tableName]
+	if ok && limit.static && !static {
+		return
+	}
+
+	// Next, if we don't have limits for the table then create them with full buckets
+	if !ok {
+		limit = &tableLimit{read: &tokenBucket{last: now}, write: &tokenBucket{last: now}}
+		limiter.tables[tableName] = limit
+	}
+
+	// Finally, update the rates on the buckets, keeping any units they already hold
+	limit.static = static
+	limit.updated = now
+	limit.read.setRate(read, now)
+	limit.write.setRate(write, now)
+}
+
+// Helper function that takes a single unit from the read or write bucket for a table. If the bucket
+// did not have enough units then nothing will be taken and the time to wait before trying again will
+// be returned
+func (limiter *throughputLimiter) take(tableName string, read bool) time.Duration {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	limit, ok := limiter.tables[tableName]
+	if !ok {
+		return 0
+	} else if read {
+		return limit.read.take(limiter.now())
+	}
+
+	return limit.write.take(limiter.now())
+}
+
+// Helper function that takes the capacity consumed by a request from the buckets for its table. As a
+// single unit was already taken from the bucket for the type of request before it was sent, that unit
+// will be returned to the bucket
+func (limiter *throughputLimiter) consume(verb string, consumed *types.ConsumedCapacity) {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	// First, get the limits for the table; if it has none then there's nothing to do
+	limit, ok := limiter.tables[aws.ToString(consumed.TableName)]
+	if !ok {
+		return
+	}
+
+	// Next, determine how many read and write units were consumed by the request
+	units := attributeUnits(verb, CapacityUnits{
+		Read:  aws.ToFloat64(consumed.ReadCapacityUnits),
+		Write: aws.ToFloat64(consumed.WriteCapacityUnits),
+		Total: aws.ToFloat64(consumed.CapacityUnits),
+	})
+
+	// Finally, take the units from the buckets, returning the unit that was taken before the request
+	now := limiter.now()
+	if isReadVerb(verb) {
+		limit.read.spend(units.Read-1, now)
+		limit.write.spend(units.Write, now)
+	} else {
+		limit.read.spend(units.Read, now)
+		limit.write.spend(units.Write-1, now)
+	}
+}
+
+// Helper function that determines whether the limits for a table should be derived from its provisioned
+// throughput, either because it has no limits yet or because they are older than the refresh interval
+func (limiter *throughputLimiter) needsRefresh(tableName string) bool {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	if limiter.fraction <= 0 {
+		return false
+	}
+
+	limit, ok := limiter.tables[tableName]
+	return !ok || (!limit.static && limiter.refresh > 0 && limiter.now().Sub(limit.updated) >= limiter.refresh)
+}
+
+// Helper function that refills the bucket based on the time that has elapsed since it was last refilled.
+// The bucket will never hold more than a second's worth of units, or a single unit if the rate is lower
+func (bucket *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(bucket.last).Seconds(); elapsed > 0 {
+		bucket.tokens = math.Min(bucket.tokens+elapsed*bucket.rate, math.Max(bucket.rate, 1))
+	}
+
+	bucket.last = now
+}
+
+// Helper function that updates the rate at which the bucket refills. If the bucket was previously
+// unlimited then it will start full
+func (bucket *tokenBucket) setRate(rate float64, now time.Time) {
+	bucket.refill(now)
+	if bucket.rate == 0 {
+		bucket.tokens = math.Max(rate, 1)
+	}
+
+	bucket.rate = rate
+}
+
+// Helper function that takes a single unit from the bucket, returning the time to wait before trying
+// again if the bucket did not have enough units
+func (bucket *tokenBucket) take(now time.Time) time.Duration {
+	if bucket.rate == 0 {
+		return 0
+	}
+
+	bucket.refill(now)
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - bucket.tokens) / bucket.rate * float64(time.Second))
+}
+
+// Helper function that removes units from the bucket, which may leave it in debt
+func (bucket *tokenBucket) spend(units float64, now time.Time) {
+	if bucket.rate != 0 {
+		bucket.refill(now)
+		bucket.tokens -= units
+	}
+}
+
+// Helper function that waits until the connection may send a request to the tables described by the
+// table name, which may contain multiple table names separated by commas, without exceeding their limits
+func (conn *DatabaseConnection) throttle(ctx context.Context, tableName string, verb string) error {
+	if conn.limiter == nil || isTableVerb(verb) {
+		return nil
+	}
+
+	read := isReadVerb(verb)
+	for _, name := range strings.Split(tableName, ", ") {
+
+		// First, if the limits for the table should be derived from its provisioned throughput then
+		// refresh them from DynamoDB
+		if conn.limiter.needsRefresh(name) {
+			conn.refreshLimit(ctx, name)
+		}
+
+		// Next, take a unit from the bucket for the table, waiting for it to refill as necessary
+		for {
+			wait := conn.limiter.take(name, read)
+			if wait <= 0 {
+				break
+			}
+
+			conn.logger.Log("Throttling %s operation to %s in DynamoDB for %v...", verb, name, wait)
+			if err := conn.limiter.sleep(ctx, wait); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Helper function that sets the limits for a table from the throughput provisioned on it. If the table
+// could not be described then it will not be limited until the next refresh
+func (conn *DatabaseConnection) refreshLimit(ctx context.Context, tableName string) {
+
+	// First, attempt to describe the table; if this fails then log the error and remove any limits
+	var read, write float64
+	output, err := conn.db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	if err != nil {
+		conn.logger.Log("Failed to describe %s to refresh its throughput limits: %v", tableName, err)
+	} else if throughput := output.Table.ProvisionedThroughput; throughput != nil {
+
+		// Next, derive the limits from the fraction of the provisioned throughput that the connection
+		// is allowed to use; tables billed per request have no provisioned throughput so they will
+		// have no limits
+		read = conn.limiter.fraction * float64(aws.ToInt64(throughput.ReadCapacityUnits))
+		write = conn.limiter.fraction * float64(aws.ToInt64(throughput.WriteCapacityUnits))
+	}
+
+	// Finally, set the limits on the table
+	conn.limiter.setLimit(tableName, read, write, false)
+}
+
+// Helper function that determines whether a verb describes a request that reads from DynamoDB
+func isReadVerb(verb string) bool {
+	for _, prefix := range []string{"GET", "QUERY", "SCAN", "SELECT", "BATCH GET", "BATCH SELECT", "TRANSACT GET"} {
+		if strings.HasPrefix(verb, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Helper function that determines whether a verb describes a request that manages a table, rather than its
+// items, and so does not consume any of its throughput
+func isTableVerb(verb string) bool {
+	switch verb {
+	case "CREATE TABLE", "DESCRIBE TABLE", "UPDATE TABLE", "DESCRIBE TTL", "UPDATE TTL",
+		"DESCRIBE BACKUPS", "UPDATE BACKUPS":
+		return true
+	default:
+		return false
+	}
+}
+
+// Helper function that waits for a duration to elapse or for the context to be cancelled
+func sleepContext(ctx context.Context, wait time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// Helper function that gets the throughput limiter on the connection, creating it if it doesn't exist
+func (conn *DatabaseConnection) ensureLimiter() *throughputLimiter {
+	if conn.limiter == nil {
+		conn.limiter = newThroughputLimiter()
+	}
+
+	return conn.limiter
+}
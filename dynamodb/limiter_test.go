@@ -0,0 +1,132 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Throughput Limiter Tests", func() {
+
+	// Test that, if the connection has a static limit on a table, then it will wait before sending requests
+	// that would exceed the limit, based on the capacity consumed by previous requests
+	It("WithThroughputLimit - Limit exceeded - Requests throttled", func() {
+
+		// First, create our test connection with a client that consumes three write units for every put
+		// and a limit of two write units per second on the table
+		client := &capacityDynamoDBClient{}
+		conn := createMockConnection(client, WithThroughputLimit{TableName: "TEST_TABLE", Write: 2})
+		waits := useFakeClock(conn)
+
+		// Next, write three items to the table; none of these should fail
+		for i := 0; i < 3; i++ {
+			_, err := conn.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")})
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		// Finally, verify that the requests asked for their consumed capacity and that the connection
+		// waited for the capacity consumed by each request to be refilled before sending the next
+		Expect(client.requested).Should(Equal([]types.ReturnConsumedCapacity{types.ReturnConsumedCapacityTotal,
+			types.ReturnConsumedCapacityTotal, types.ReturnConsumedCapacityTotal}))
+		Expect(*waits).Should(Equal([]time.Duration{time.Second, 1500 * time.Millisecond}))
+	})
+
+	// Test that, if the connection limits each table to a fraction of its provisioned throughput, then it
+	// will describe the table to determine its limits and refresh them when the refresh interval elapses
+	It("WithProvisionedLimit - Table provisioned - Limits refreshed", func() {
+
+		// First, create our test connection with a client whose table has four write units provisioned
+		// and a limit of half the provisioned throughput on each table, refreshed every minute
+		client := &limitedDynamoDBClient{capacityDynamoDBClient: &capacityDynamoDBClient{}, write: 4}
+		conn := createMockConnection(client, WithProvisionedLimit{Fraction: 0.5, Refresh: time.Minute})
+		waits := useFakeClock(conn)
+
+		// Next, write two items to the table; the second should wait for the capacity consumed by the first
+		for i := 0; i < 2; i++ {
+			_, err := conn.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")})
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		Expect(*waits).Should(Equal([]time.Duration{time.Second}))
+		Expect(client.describes).Should(Equal(1))
+
+		// Now, increase the provisioned throughput, wait for the refresh interval to elapse and write
+		// another item to the table; this should not fail
+		client.write = 8
+		*waits = append(*waits, 2*time.Minute)
+		_, err := conn.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that the table was described again and that the new limit was applied
+		Expect(client.describes).Should(Equal(2))
+		Expect(conn.limiter.tables["TEST_TABLE"].write.rate).Should(Equal(4.0))
+	})
+
+	// Test that, if the context is cancelled while the connection is throttling a request, then the
+	// request will not be sent and the cancellation error will be returned
+	It("WithThroughputLimit - Context cancelled - Error", func() {
+
+		// First, create our test connection with a client that consumes three write units for every put
+		// and a limit of one write unit per second on the table
+		client := &capacityDynamoDBClient{}
+		conn := createMockConnection(client, WithThroughputLimit{TableName: "TEST_TABLE", Write: 1})
+
+		// Next, write an item to the table; this should not fail
+		_, err := conn.PutItem(context.Background(), &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, attempt to write another item with a context that has been cancelled; this should fail
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = conn.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")})
+
+		// Finally, verify the error and that the second request was not sent
+		Expect(err.(*Error).Inner).Should(Equal(context.Canceled))
+		Expect(client.requested).Should(HaveLen(1))
+	})
+})
+
+// Helper function that replaces the clock used by the throughput limiter on a connection with one that
+// only advances when the limiter sleeps, or when a wait is added to the list, and records each sleep
+func useFakeClock(conn *DatabaseConnection) *[]time.Duration {
+	start := time.Date(2022, time.July, 1, 0, 0, 0, 0, time.UTC)
+	waits := make([]time.Duration, 0)
+	conn.limiter.now = func() time.Time {
+		now := start
+		for _, wait := range waits {
+			now = now.Add(wait)
+		}
+
+		return now
+	}
+
+	conn.limiter.sleep = func(ctx context.Context, wait time.Duration) error {
+		waits = append(waits, wait)
+		return nil
+	}
+
+	return &waits
+}
+
+// Helper type that describes a table with provisioned throughput and records how many times it was described
+type limitedDynamoDBClient struct {
+	*capacityDynamoDBClient
+	write     int64
+	describes int
+}
+
+// DescribeTable describes the table with the write throughput set on the client
+func (client *limitedDynamoDBClient) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	client.describes++
+	return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{
+		TableName: params.TableName,
+		ProvisionedThroughput: &types.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  aws.Int64(10),
+			WriteCapacityUnits: aws.Int64(client.write)}}}, nil
+}
@@ -0,0 +1,516 @@
+package dynamodb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// LockClient acquires named locks, stored as items on a DynamoDB table, so that multiple processes can
+// obtain mutual exclusion over a shared resource. Each lock is held as a lease which expires after the
+// lease duration unless it is renewed, which the client will do automatically from a heartbeat goroutine.
+// If the process holding a lock dies then its lease will expire and another client may steal the lock.
+// Note that expiry is determined by comparing the expiry time written by the holder with the clock of the
+// client attempting to steal the lock, so the lease duration should be much longer than any clock skew
+// between the machines sharing the table. The table should be created from NewLockTableDefinition
+type LockClient struct {
+	conn      *DatabaseConnection
+	tableName string
+	owner     string
+	lease     time.Duration
+	heartbeat time.Duration
+	retry     time.Duration
+	now       func() time.Time
+}
+
+// Lock describes a lock that has been acquired by a LockClient. The lock should be closed when it is no
+// longer needed so that other clients can acquire it. If the lease on the lock is lost, because it could
+// not be renewed before it expired or because another client stole it, then the channel returned by Lost
+// will be closed and any work protected by the lock should be abandoned
+type Lock struct {
+	client   *LockClient
+	name     string
+	version  string
+	expires  time.Time
+	err      error
+	closed   bool
+	lost     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	cancel   context.CancelFunc
+	renewing *sync.Mutex
+	lock     *sync.Mutex
+}
+
+// ILockOption defines the functionality that will allow a LockClient to be modified as it is created
+type ILockOption interface {
+	Apply(*LockClient)
+}
+
+// WithLockOwner allows the user to set the name that the client will record as the owner of the locks it
+// acquires. If this option is not provided then the owner will be the host name followed by a random suffix
+type WithLockOwner string
+
+// Apply modifies the LockClient so that it has the owner defined by this object
+func (w WithLockOwner) Apply(client *LockClient) {
+	client.owner = string(w)
+}
+
+// WithLeaseDuration allows the user to set how long a lock will be held, after it was last acquired or
+// renewed, before other clients may steal it. If this option is not provided then leases will last 20 seconds
+type WithLeaseDuration time.Duration
+
+// Apply modifies the LockClient so that it has the lease duration defined by this object
+func (w WithLeaseDuration) Apply(client *LockClient) {
+	client.lease = time.Duration(w)
+}
+
+// WithHeartbeatInterval allows the user to set how often the client will renew the leases on the locks it
+// holds. This should be considerably shorter than the lease duration so that a failed renewal can be retried
+// before the lease expires. An interval of zero disables the heartbeat, in which case leases must be renewed
+// manually. If this option is not provided then leases will be renewed every 5 seconds
+type WithHeartbeatInterval time.Duration
+
+// Apply modifies the LockClient so that it has the heartbeat interval defined by this object
+func (w WithHeartbeatInterval) Apply(client *LockClient) {
+	client.heartbeat = time.Duration(w)
+}
+
+// WithAcquireInterval allows the user to set how long Acquire will wait, after finding that a lock is held
+// by another client, before attempting to acquire it again. If this option is not provided then Acquire will
+// wait for 1 second between attempts
+type WithAcquireInterval time.Duration
+
+// Apply modifies the LockClient so that it has the acquire interval defined by this object
+func (w WithAcquireInterval) Apply(client *LockClient) {
+	client.retry = time.Duration(w)
+}
+
+// Helper type that describes a lock as it is stored in DynamoDB. The expiry time is stored in epoch
+// milliseconds so that leases shorter than a second can be used
+type lockRecord struct {
+	Name     string `dynamodbav:"lock_name" dynamodb:"hash"`
+	Owner    string `dynamodbav:"owner"`
+	Version  string `dynamodbav:"record_version"`
+	Duration int64  `dynamodbav:"lease_duration"`
+	Expires  int64  `dynamodbav:"expires_at"`
+}
+
+// NewLockTableDefinition creates a definition describing a table that can be used to store locks. Options
+// may be provided to modify the definition, such as its provisioned throughput, but any tag key provided
+// will be ignored as the names of the lock attributes are fixed
+func NewLockTableDefinition(tableName string, opts ...ITableOption) (*TableDefinition, error) {
+	return NewTableDefinition[lockRecord](tableName, append(opts, WithTableTagKey("dynamodbav"))...)
+}
+
+// NewLockClient creates a new lock client that will store the locks it acquires on the table provided
+func (conn *DatabaseConnection) NewLockClient(tableName string, opts ...ILockOption) *LockClient {
+
+	// First, create our client with default values
+	client := LockClient{
+		conn:      conn,
+		tableName: tableName,
+		lease:     20 * time.Second,
+		heartbeat: 5 * time.Second,
+		retry:     time.Second,
+		now:       time.Now,
+	}
+
+	// Next, iterate over the options provided and update the associated values on the client
+	for _, opt := range opts {
+		opt.Apply(&client)
+	}
+
+	// Finally, if no owner was provided then create one from the host name and a random suffix so that
+	// clients running in the same process can be told apart
+	if client.owner == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "unknown"
+		}
+
+		client.owner = fmt.Sprintf("%s-%s", host, newToken()[:8])
+	}
+
+	return &client
+}
+
+// Owner returns the name that the client records as the owner of the locks it acquires
+func (client *LockClient) Owner() string {
+	return client.owner
+}
+
+// TryAcquire attempts to acquire the lock with the name provided, stealing it if its lease has expired. If
+// the lock is held by another client then a LockHeldError will be returned. If the client has a heartbeat
+// interval then the lease on the lock will be renewed automatically until the lock is closed
+func (client *LockClient) TryAcquire(ctx context.Context, name string) (*Lock, error) {
+
+	// First, create the lock record with a new version and an expiry time based on the lease duration
+	now := client.now()
+	expires := now.Add(client.lease)
+	record := lockRecord{
+		Name:     name,
+		Owner:    client.owner,
+		Version:  newToken(),
+		Duration: client.lease.Milliseconds(),
+		Expires:  expires.UnixMilli(),
+	}
+
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return nil, client.conn.NewError(err, client.tableName, "Failed to marshal lock %s", name)
+	}
+
+	// Next, attempt to write the record to the table, on the condition that the lock either doesn't
+	// exist or has expired. If the condition fails then the lock is held by someone else, unless the
+	// request was retried after an earlier attempt that wrote our record, so check for that as well
+	_, err = client.conn.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                aws.String(client.tableName),
+		Item:                     item,
+		ConditionExpression:      aws.String("attribute_not_exists(#name) OR #expires < :now"),
+		ExpressionAttributeNames: map[string]string{"#name": "lock_name", "#expires": "expires_at"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.UnixMilli(), 10)}},
+	})
+
+	if err != nil {
+		casted, ok := isConditionFailed(err)
+		if !ok {
+			return nil, err
+		}
+
+		if owned, err := client.isOwnRecord(ctx, name, record.Version); err != nil {
+			return nil, err
+		} else if !owned {
+			return nil, &LockHeldError{GError: casted.GError, TableName: client.tableName, LockName: name}
+		}
+	}
+
+	// Finally, create the lock and start its heartbeat if we have one
+	hbCtx, cancel := context.WithCancel(context.Background())
+	lock := Lock{
+		client:   client,
+		name:     name,
+		version:  record.Version,
+		expires:  expires,
+		lost:     make(chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		cancel:   cancel,
+		renewing: new(sync.Mutex),
+		lock:     new(sync.Mutex),
+	}
+
+	if client.heartbeat > 0 {
+		go lock.beat(hbCtx)
+	} else {
+		close(lock.done)
+	}
+
+	return &lock, nil
+}
+
+// Acquire attempts to acquire the lock with the name provided, waiting for it to be released or for its
+// lease to expire if it is held by another client. This function will continue trying until the lock is
+// acquired, a request fails or the context is cancelled
+func (client *LockClient) Acquire(ctx context.Context, name string) (*Lock, error) {
+	for {
+
+		// First, attempt to acquire the lock. If we got it, or the attempt failed for any reason other
+		// than the lock being held, then return the result
+		lock, err := client.TryAcquire(ctx, name)
+		var held *LockHeldError
+		if err == nil || !errors.As(err, &held) {
+			return lock, err
+		}
+
+		// Next, wait for the acquire interval before trying again; if the context is cancelled while
+		// we're waiting then return an error
+		client.conn.logger.Log("Lock %s on %s is held. Waiting %v to try again...", name, client.tableName, client.retry)
+		if err := sleepContext(ctx, client.retry); err != nil {
+			return nil, client.conn.NewError(err, client.tableName, "Failed to acquire lock %s", name)
+		}
+	}
+}
+
+// Name returns the name of the lock
+func (lock *Lock) Name() string {
+	return lock.name
+}
+
+// Expires returns the time at which the lease on the lock will expire if it isn't renewed
+func (lock *Lock) Expires() time.Time {
+	lock.lock.Lock()
+	defer lock.lock.Unlock()
+	return lock.expires
+}
+
+// Lost returns a channel that will be closed if the lease on the lock is lost
+func (lock *Lock) Lost() <-chan struct{} {
+	return lock.lost
+}
+
+// Err returns the error describing why the lease on the lock was lost, or nil if it is still held
+func (lock *Lock) Err() error {
+	lock.lock.Lock()
+	defer lock.lock.Unlock()
+	return lock.err
+}
+
+// Renew extends the lease on the lock by the lease duration. This is done automatically if the client
+// has a heartbeat interval. The renewal must complete before the current lease expires; if it doesn't,
+// or the lease has been lost for any other reason, then a LockLostError will be returned and the channel
+// returned by Lost will be closed. Other methods on the lock will not be blocked while a renewal is made
+func (lock *Lock) Renew(ctx context.Context) error {
+
+	// First, ensure that only one renewal is made at a time. Concurrent renewals would both be made
+	// against the same version, so the one that finished second would think the lock had been stolen
+	lock.renewing.Lock()
+	defer lock.renewing.Unlock()
+
+	// Next, if the lease has already been lost, or has expired, then return the error describing why.
+	// If the lock has been closed then it can't be renewed so return an error. Otherwise, take the
+	// version and expiry time of the current lease so that we don't need to hold the mutex while
+	// the lease is being renewed
+	client := lock.client
+	lock.lock.Lock()
+	if err := lock.checkExpired(); err != nil {
+		lock.lock.Unlock()
+		return err
+	} else if lock.closed {
+		lock.lock.Unlock()
+		return client.conn.NewError(fmt.Errorf("lock has been closed"), client.tableName,
+			"Failed to renew lease on lock %s", lock.name)
+	}
+
+	current, deadline := lock.version, lock.expires
+	lock.lock.Unlock()
+
+	// Now, attempt to update the lock record with a new version and expiry time, on the condition that
+	// the version hasn't changed since we last wrote it. There's no point retrying after the current
+	// lease expires so the request will be cancelled at that point
+	now := client.now()
+	version := newToken()
+	expires := now.Add(client.lease)
+	renewCtx, cancel := context.WithTimeout(ctx, deadline.Sub(now))
+	defer cancel()
+
+	_, err := client.conn.UpdateItem(renewCtx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(client.tableName),
+		Key:                 lockKey(lock.name),
+		UpdateExpression:    aws.String("SET #version = :version, #expires = :expires"),
+		ConditionExpression: aws.String("#version = :current"),
+		ExpressionAttributeNames: map[string]string{
+			"#version": "record_version",
+			"#expires": "expires_at"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberS{Value: version},
+			":expires": &types.AttributeValueMemberN{Value: strconv.FormatInt(expires.UnixMilli(), 10)},
+			":current": &types.AttributeValueMemberS{Value: current}},
+	})
+
+	// If the condition failed then another client has stolen the lock, unless the request was retried
+	// after an earlier attempt that renewed the lease, so check for that here
+	casted, stolen := isConditionFailed(err)
+	if stolen {
+		if owned, ownErr := client.isOwnRecord(renewCtx, lock.name, version); ownErr == nil && owned {
+			err, stolen = nil, false
+		}
+	}
+
+	// Finally, re-take the mutex and apply the result of the renewal. If it succeeded then update the
+	// lock with the new version and expiry time. Regardless of the outcome, if the current lease expired
+	// before the renewal completed then we can't be sure that no other client acquired the lock, so the
+	// lease is considered lost
+	lock.lock.Lock()
+	defer lock.lock.Unlock()
+	if err == nil {
+		lock.version = version
+		lock.expires = expires
+	}
+
+	if lock.err != nil {
+		return lock.err
+	} else if stolen {
+		return lock.markLost(client.conn.NewError(casted.Inner, client.tableName,
+			"Lock %s on %s was stolen by another client", lock.name, client.tableName))
+	} else if !client.now().Before(deadline) {
+		return lock.markLost(client.conn.NewError(err, client.tableName,
+			"Lease on lock %s on %s expired before it could be renewed", lock.name, client.tableName))
+	}
+
+	return err
+}
+
+// Close stops renewing the lease on the lock and releases it so that other clients may acquire it. If the
+// lease was lost before the lock could be released then a LockLostError will be returned. Calling Close
+// more than once has no effect
+func (lock *Lock) Close(ctx context.Context) error {
+
+	// First, mark the lock as closed so that we don't release it twice
+	lock.lock.Lock()
+	if lock.closed {
+		lock.lock.Unlock()
+		return nil
+	}
+
+	lock.closed = true
+	lock.lock.Unlock()
+
+	// Next, stop the heartbeat and wait for it to exit, and for any renewal that was requested manually
+	// to finish, so that neither can renew the lease after we release it
+	lock.cancel()
+	close(lock.stop)
+	<-lock.done
+
+	lock.renewing.Lock()
+	defer lock.renewing.Unlock()
+
+	// Now, if the lease was already lost then there's nothing to release so return the error describing
+	// why. Otherwise, take the version of the current lease and attempt to delete the lock record, on the
+	// condition that it is still ours. The mutex isn't held while the record is being deleted so that
+	// other methods on the lock aren't blocked if the request is slow or retried
+	lock.lock.Lock()
+	if lock.err != nil {
+		lock.lock.Unlock()
+		return lock.err
+	}
+
+	current := lock.version
+	lock.lock.Unlock()
+
+	_, err := lock.client.conn.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                aws.String(lock.client.tableName),
+		Key:                      lockKey(lock.name),
+		ConditionExpression:      aws.String("#version = :current"),
+		ExpressionAttributeNames: map[string]string{"#version": "record_version"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":current": &types.AttributeValueMemberS{Value: current}},
+	})
+
+	// Finally, re-take the mutex and apply the result of the release. If the condition failed then another
+	// client stole the lock before we could release it so the lease is considered lost
+	lock.lock.Lock()
+	defer lock.lock.Unlock()
+	if casted, ok := isConditionFailed(err); ok {
+		return lock.markLost(lock.client.conn.NewError(casted.Inner, lock.client.tableName,
+			"Lock %s on %s was stolen by another client", lock.name, lock.client.tableName))
+	}
+
+	return err
+}
+
+// Helper function that renews the lease on the lock every heartbeat interval until the lock is closed
+// or the lease is lost. If the lease expires before it could be renewed then it will be marked as lost
+func (lock *Lock) beat(ctx context.Context) {
+	defer close(lock.done)
+
+	ticker := time.NewTicker(lock.client.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		expiry := time.NewTimer(lock.Expires().Sub(lock.client.now()))
+		select {
+		case <-lock.stop:
+			expiry.Stop()
+			return
+		case <-lock.lost:
+			expiry.Stop()
+			return
+		case <-expiry.C:
+			lock.lock.Lock()
+			lock.checkExpired()
+			lock.lock.Unlock()
+		case <-ticker.C:
+			expiry.Stop()
+			if err := lock.Renew(ctx); err != nil {
+				lock.client.conn.logger.Log("Failed to renew lease on lock %s on %s: %v",
+					lock.name, lock.client.tableName, err)
+			}
+		}
+	}
+}
+
+// Helper function that checks whether the lease on the lock has expired, marking it as lost if it has.
+// The error describing why the lease was lost will be returned, or nil if it is still held. This function
+// assumes that the caller holds the mutex on the lock
+func (lock *Lock) checkExpired() error {
+	if lock.err == nil && !lock.client.now().Before(lock.expires) {
+		return lock.markLost(lock.client.conn.NewError(nil, lock.client.tableName,
+			"Lease on lock %s on %s expired before it could be renewed", lock.name, lock.client.tableName))
+	}
+
+	return lock.err
+}
+
+// Helper function that determines whether the record stored for a lock was written by this client with
+// the version provided. This is used to detect conditional writes that failed only because they were
+// retried after an earlier attempt had already succeeded
+func (client *LockClient) isOwnRecord(ctx context.Context, name string, version string) (bool, error) {
+	output, err := client.conn.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(client.tableName),
+		Key:            lockKey(name),
+		ConsistentRead: aws.Bool(true),
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	var record lockRecord
+	if err := attributevalue.UnmarshalMap(output.Item, &record); err != nil {
+		return false, client.conn.NewError(err, client.tableName, "Failed to unmarshal lock %s", name)
+	}
+
+	return record.Owner == client.owner && record.Version == version, nil
+}
+
+// Helper function that records that the lease on the lock has been lost and closes the lost channel.
+// This function assumes that the caller holds the mutex on the lock
+func (lock *Lock) markLost(err *Error) error {
+	if lock.err == nil {
+		lock.err = &LockLostError{GError: err.GError, TableName: err.TableName, LockName: lock.name}
+
+		close(lock.lost)
+	}
+
+	return lock.err
+}
+
+// Helper function that creates the key of a lock record from the name of the lock
+func lockKey(name string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"lock_name": &types.AttributeValueMemberS{Value: name}}
+}
+
+// Helper function that creates a random token, used to identify lock versions and idempotency attempts
+func newToken() string {
+	data := make([]byte, 16)
+	if _, err := rand.Read(data); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(data)
+}
+
+// Helper function that determines whether an error returned by the connection was caused by a condition
+// expression failing, returning the error cast to our error type if it was
+func isConditionFailed(err error) (*Error, bool) {
+	casted, ok := err.(*Error)
+	if !ok {
+		return nil, false
+	}
+
+	var failed *types.ConditionalCheckFailedException
+	return casted, errors.As(casted.Inner, &failed)
+}
@@ -0,0 +1,362 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+
+	fake "github.com/Woody1193/goutils/dynamodb/testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock Client Tests", func() {
+
+	// Test that, if a lock is held, then no other client may acquire it until it has been released
+	It("TryAcquire - Held by another client - Error", func() {
+
+		// First, create our test connection with a lock table and two clients that don't renew their leases
+		conn := createLockConnection()
+		first := createLockClient(conn, "first", 0)
+		second := createLockClient(conn, "second", 0)
+
+		// Next, acquire the lock with the first client; this should not fail
+		lock, err := first.TryAcquire(context.Background(), "test_lock")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(lock.Name()).Should(Equal("test_lock"))
+		Expect(lock.Expires()).Should(Equal(lockTime.Add(time.Minute)))
+
+		// Now, attempt to acquire the lock with the second client; this should fail
+		other, err := second.TryAcquire(context.Background(), "test_lock")
+		Expect(other).Should(BeNil())
+		casted := err.(*LockHeldError)
+		Expect(casted.TableName).Should(Equal("LOCK_TABLE"))
+		Expect(casted.LockName).Should(Equal("test_lock"))
+
+		// Finally, release the lock with the first client and verify that the second can acquire it
+		Expect(lock.Close(context.Background())).ShouldNot(HaveOccurred())
+		Expect(lock.Close(context.Background())).ShouldNot(HaveOccurred())
+		other, err = second.TryAcquire(context.Background(), "test_lock")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(getLockRecord(conn, "test_lock").Owner).Should(Equal("second"))
+		Expect(other.Close(context.Background())).ShouldNot(HaveOccurred())
+	})
+
+	// Test that, if the lease on a lock has expired, then another client may steal it and the original
+	// holder will detect that the lease was lost when it next attempts to renew it
+	It("TryAcquire - Lease expired - Lock stolen", func() {
+
+		// First, create our test connection with a lock table and two clients, the second of which has
+		// a clock that is two minutes ahead of the first
+		conn := createLockConnection()
+		first := createLockClient(conn, "first", 0)
+		second := createLockClient(conn, "second", 0)
+		second.now = func() time.Time { return lockTime.Add(2 * time.Minute) }
+
+		// Next, acquire the lock with the first client and steal it with the second; neither should fail
+		lock, err := first.TryAcquire(context.Background(), "test_lock")
+		Expect(err).ShouldNot(HaveOccurred())
+		stolen, err := second.TryAcquire(context.Background(), "test_lock")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(getLockRecord(conn, "test_lock").Owner).Should(Equal("second"))
+
+		// Now, attempt to renew the lease with the first client; this should fail
+		err = lock.Renew(context.Background())
+		casted := err.(*LockLostError)
+		Expect(casted.TableName).Should(Equal("LOCK_TABLE"))
+		Expect(casted.LockName).Should(Equal("test_lock"))
+		Expect(casted.Message).Should(Equal("Lock test_lock on LOCK_TABLE was stolen by another client"))
+		Expect(lock.Lost()).Should(BeClosed())
+		Expect(lock.Err()).Should(Equal(err))
+
+		// Finally, verify that closing the lost lock returns the same error and doesn't release the lock
+		Expect(lock.Close(context.Background())).Should(Equal(err))
+		Expect(getLockRecord(conn, "test_lock").Owner).Should(Equal("second"))
+		Expect(stolen.Close(context.Background())).ShouldNot(HaveOccurred())
+		Expect(getLockRecord(conn, "test_lock")).Should(BeNil())
+	})
+
+	// Test that the heartbeat renews the lease on a lock until it is stolen, at which point the lock
+	// will report that the lease was lost
+	It("Heartbeat - Lease renewed - Loss detected", func() {
+
+		// First, create our test connection with a lock table and two clients, the first of which renews
+		// its leases every few milliseconds and the second of which has a clock that is ahead of the first
+		conn := createLockConnection()
+		first := createLockClient(conn, "first", 5*time.Millisecond)
+		second := createLockClient(conn, "second", 0)
+		second.now = func() time.Time { return lockTime.Add(2 * time.Minute) }
+
+		// Next, acquire the lock with the first client and wait for its lease to be renewed
+		lock, err := first.TryAcquire(context.Background(), "test_lock")
+		Expect(err).ShouldNot(HaveOccurred())
+		version := getLockRecord(conn, "test_lock").Version
+		Eventually(func() string {
+			return getLockRecord(conn, "test_lock").Version
+		}).ShouldNot(Equal(version))
+
+		// Now, steal the lock with the second client; this should not fail
+		stolen, err := second.TryAcquire(context.Background(), "test_lock")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that the first client detects that its lease was lost
+		Eventually(lock.Lost()).Should(BeClosed())
+		Expect(lock.Err()).Should(BeAssignableToTypeOf(&LockLostError{}))
+		Expect(stolen.Close(context.Background())).ShouldNot(HaveOccurred())
+	})
+
+	// Test that, if the lock is held, then Acquire will wait until it has been released
+	It("Acquire - Held by another client - Waits for release", func() {
+
+		// First, create our test connection with a lock table and two clients
+		conn := createLockConnection()
+		first := createLockClient(conn, "first", 0)
+		second := createLockClient(conn, "second", 0)
+
+		// Next, acquire the lock with the first client and start acquiring it with the second
+		lock, err := first.TryAcquire(context.Background(), "test_lock")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		acquired := make(chan *Lock)
+		go func() {
+			defer GinkgoRecover()
+			other, err := second.Acquire(context.Background(), "test_lock")
+			Expect(err).ShouldNot(HaveOccurred())
+			acquired <- other
+		}()
+
+		// Now, verify that the second client is waiting and then release the lock with the first
+		Consistently(acquired, 20*time.Millisecond).ShouldNot(Receive())
+		Expect(lock.Close(context.Background())).ShouldNot(HaveOccurred())
+
+		// Finally, verify that the second client acquired the lock
+		var other *Lock
+		Eventually(acquired).Should(Receive(&other))
+		Expect(getLockRecord(conn, "test_lock").Owner).Should(Equal("second"))
+		Expect(other.Close(context.Background())).ShouldNot(HaveOccurred())
+	})
+
+	// Test that, if the context is cancelled while Acquire is waiting, then an error will be returned
+	It("Acquire - Context cancelled - Error", func() {
+
+		// First, create our test connection with a lock table and two clients
+		conn := createLockConnection()
+		first := createLockClient(conn, "first", 0)
+		second := createLockClient(conn, "second", 0)
+
+		// Next, acquire the lock with the first client; this should not fail
+		_, err := first.TryAcquire(context.Background(), "test_lock")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, attempt to acquire the lock with the second client using a context that will time out
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		lock, err := second.Acquire(ctx, "test_lock")
+
+		// Finally, verify the details of the error
+		casted := err.(*Error)
+		Expect(lock).Should(BeNil())
+		Expect(casted.Message).Should(Equal("Failed to acquire lock test_lock"))
+		Expect(casted.Inner).Should(Equal(context.DeadlineExceeded))
+	})
+
+	// Test that, if a write that acquired a lock is retried, then TryAcquire will recognise its own record
+	// rather than reporting that the lock is held by another client
+	It("TryAcquire - Retried after write - Acquired", func() {
+
+		// First, create our test connection with a client that writes the first lock record it is sent but
+		// reports that the write failed, so that it will be retried
+		client := &faultyLockDynamoDB{FakeDynamoDB: createLockTable(), putFailures: 1}
+		conn := createMockConnection(client)
+		first := createLockClient(conn, "first", 0)
+
+		// Next, acquire the lock; this should not fail
+		lock, err := first.TryAcquire(context.Background(), "test_lock")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that the lock is held and can be released
+		Expect(getLockRecord(conn, "test_lock").Owner).Should(Equal("first"))
+		Expect(lock.Close(context.Background())).ShouldNot(HaveOccurred())
+		Expect(getLockRecord(conn, "test_lock")).Should(BeNil())
+	})
+
+	// Test that, if the lease on a lock has expired before it is renewed, then the lease will be lost
+	It("Renew - Lease expired - Lost", func() {
+
+		// First, create our test connection with a lock table and a client whose clock we control
+		conn := createLockConnection()
+		first := createLockClient(conn, "first", 0)
+		now := lockTime
+		first.now = func() time.Time { return now }
+
+		// Next, acquire the lock and then move the clock to the time at which its lease expires
+		lock, err := first.TryAcquire(context.Background(), "test_lock")
+		Expect(err).ShouldNot(HaveOccurred())
+		now = lockTime.Add(time.Minute)
+
+		// Now, attempt to renew the lease; this should fail
+		err = lock.Renew(context.Background())
+
+		// Finally, verify that the lease was lost
+		casted := err.(*LockLostError)
+		Expect(casted.Message).Should(Equal("Lease on lock test_lock on LOCK_TABLE expired before it could be renewed"))
+		Expect(lock.Lost()).Should(BeClosed())
+		Expect(lock.Close(context.Background())).Should(Equal(err))
+	})
+
+	// Test that, while a renewal is waiting on DynamoDB, the other methods on the lock are not blocked
+	// and the lock can still be closed
+	It("Heartbeat - Renewal blocked - Lock usable", func() {
+
+		// First, create our test connection with a client that blocks renewals until they are cancelled
+		client := &faultyLockDynamoDB{FakeDynamoDB: createLockTable(),
+			blockUpdates: true, updating: make(chan struct{}, 1)}
+		conn := createMockConnection(client)
+		first := createLockClient(conn, "first", 5*time.Millisecond)
+
+		// Next, acquire the lock and wait for the heartbeat to start renewing its lease
+		lock, err := first.TryAcquire(context.Background(), "test_lock")
+		Expect(err).ShouldNot(HaveOccurred())
+		Eventually(client.updating).Should(Receive())
+
+		// Finally, verify that the lock can be inspected and closed while the renewal is blocked
+		Expect(lock.Err()).ShouldNot(HaveOccurred())
+		Expect(lock.Expires()).Should(Equal(lockTime.Add(time.Minute)))
+		Expect(lock.Close(context.Background())).ShouldNot(HaveOccurred())
+		Expect(getLockRecord(conn, "test_lock")).Should(BeNil())
+	})
+
+	// Test that, while the lock record is being deleted by Close, the other methods on the lock are not blocked
+	It("Close - Delete blocked - Lock usable", func() {
+
+		// First, create our test connection with a client that blocks deletes until they are cancelled
+		client := &faultyLockDynamoDB{FakeDynamoDB: createLockTable(),
+			blockDeletes: true, deleting: make(chan struct{}, 1)}
+		conn := createMockConnection(client)
+		first := createLockClient(conn, "first", 0)
+
+		// Next, acquire the lock and start closing it in the background, waiting for the delete to start
+		lock, err := first.TryAcquire(context.Background(), "test_lock")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		closed := make(chan error, 1)
+		go func() { closed <- lock.Close(ctx) }()
+		Eventually(client.deleting).Should(Receive())
+
+		// Now, verify that the lock can be inspected while the delete is blocked
+		Expect(lock.Err()).ShouldNot(HaveOccurred())
+		Expect(lock.Expires()).Should(Equal(lockTime.Add(time.Minute)))
+
+		// Finally, cancel the delete and verify that Close returned the error
+		cancel()
+		Eventually(closed).Should(Receive(HaveOccurred()))
+	})
+})
+
+// Helper variable containing the time used as the clock by test lock clients
+var lockTime = time.Date(2022, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+// Helper function that creates a fake DynamoDB with a lock table
+func createLockTable() *fake.FakeDynamoDB {
+	def, err := NewLockTableDefinition("LOCK_TABLE", WithTableTagKey("json"))
+	Expect(err).ShouldNot(HaveOccurred())
+
+	client := fake.NewFakeDynamoDB()
+	_, err = client.CreateTable(context.Background(), def.Input)
+	Expect(err).ShouldNot(HaveOccurred())
+	return client
+}
+
+// Helper function that creates a connection to a fake DynamoDB with a lock table
+func createLockConnection() *DatabaseConnection {
+	return createMockConnection(createLockTable())
+}
+
+// Helper function that creates a lock client with a one-minute lease, a fixed clock and the owner and
+// heartbeat interval provided
+func createLockClient(conn *DatabaseConnection, owner string, heartbeat time.Duration) *LockClient {
+	client := conn.NewLockClient("LOCK_TABLE", WithLockOwner(owner), WithLeaseDuration(time.Minute),
+		WithHeartbeatInterval(heartbeat), WithAcquireInterval(time.Millisecond))
+	client.now = func() time.Time { return lockTime }
+	return client
+}
+
+// Helper function that reads a lock record from the lock table, returning nil if it doesn't exist
+func getLockRecord(conn *DatabaseConnection, name string) *lockRecord {
+	output, err := conn.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName:      aws.String("LOCK_TABLE"),
+		Key:            map[string]types.AttributeValue{"lock_name": &types.AttributeValueMemberS{Value: name}},
+		ConsistentRead: aws.Bool(true)})
+	Expect(err).ShouldNot(HaveOccurred())
+	if output.Item == nil {
+		return nil
+	}
+
+	var record lockRecord
+	Expect(attributevalue.UnmarshalMap(output.Item, &record)).ShouldNot(HaveOccurred())
+	return &record
+}
+
+// Helper type that wraps a fake DynamoDB so that lock tests can simulate requests that fail after they were
+// applied and requests that never complete
+type faultyLockDynamoDB struct {
+	*fake.FakeDynamoDB
+	putFailures  int
+	blockUpdates bool
+	updating     chan struct{}
+	blockDeletes bool
+	deleting     chan struct{}
+}
+
+// Mocks out the PutItem function so that it writes the item and then reports a retryable failure, for the
+// number of requests set by putFailures
+func (client *faultyLockDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	output, err := client.FakeDynamoDB.PutItem(ctx, params, optFns...)
+	if err == nil && client.putFailures > 0 {
+		client.putFailures--
+		return nil, &smithy.OperationError{Err: &types.InternalServerError{Message: aws.String("failed")}}
+	}
+
+	return output, err
+}
+
+// Mocks out the UpdateItem function so that, if updates should be blocked, it signals that an update was
+// requested and waits for the request to be cancelled
+func (client *faultyLockDynamoDB) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if !client.blockUpdates {
+		return client.FakeDynamoDB.UpdateItem(ctx, params, optFns...)
+	}
+
+	select {
+	case client.updating <- struct{}{}:
+	default:
+	}
+
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// Mocks out the DeleteItem function so that, if deletes should be blocked, it signals that a delete was
+// requested and waits for the request to be cancelled
+func (client *faultyLockDynamoDB) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	if !client.blockDeletes {
+		return client.FakeDynamoDB.DeleteItem(ctx, params, optFns...)
+	}
+
+	select {
+	case client.deleting <- struct{}{}:
+	default:
+	}
+
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
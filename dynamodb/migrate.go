@@ -0,0 +1,643 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Woody1193/goutils/collections"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MigrationStep describes a single change that must be made to a table so that it matches its definition.
+// DynamoDB only allows one index to be created or deleted by each request, so each step makes a single change
+type MigrationStep struct {
+	Description string
+	apply       func(context.Context, *migrationSettings) error
+}
+
+// Helper type that contains the settings that may be modified when waiting for a table
+type migrationSettings struct {
+	interval time.Duration
+}
+
+// IMigrationOption defines the functionality that will allow a migration, or a wait for a table, to be modified
+type IMigrationOption interface {
+	Apply(*migrationSettings)
+}
+
+// WithPollInterval allows the user to set how often the table will be described while waiting for it and
+// its indexes to become active, or for changes to its TTL settings to complete. If this option is not provided
+// then the table will be described every 5 seconds
+type WithPollInterval time.Duration
+
+// Apply modifies the migration settings so that they have the poll interval defined by this object
+func (w WithPollInterval) Apply(settings *migrationSettings) {
+	settings.interval = time.Duration(w)
+}
+
+// PlanMigration compares a table definition with the table in DynamoDB and returns the steps that would be
+// required to make the table match the definition, without applying them. If the table doesn't exist then
+// it will be created. Otherwise, global secondary indexes will be added, removed or recreated, and the billing
+// mode, throughput, stream, TTL and point-in-time recovery settings will be updated to match the definition.
+// An error will be returned if the key schema or local secondary indexes of the table differ from the
+// definition, as these cannot be changed once the table has been created
+func (conn *DatabaseConnection) PlanMigration(ctx context.Context, def *TableDefinition) ([]*MigrationStep, error) {
+	tableName := aws.ToString(def.Input.TableName)
+
+	// First, describe the table; if it doesn't exist then we'll create it, followed by the settings that
+	// cannot be set when the table is created
+	table, err := conn.describeTable(ctx, tableName)
+	if err != nil {
+		return nil, err
+	} else if table == nil {
+		steps := []*MigrationStep{conn.createTableStep(def)}
+		if def.TTLAttribute != "" {
+			steps = append(steps, conn.timeToLiveStep(tableName, def.TTLAttribute, true))
+		}
+
+		if def.PointInTimeRecovery {
+			steps = append(steps, conn.recoveryStep(tableName, true))
+		}
+
+		return steps, nil
+	}
+
+	// Next, ensure that the parts of the table that cannot be changed match the definition
+	if !sameKeySchema(table.KeySchema, def.Input.KeySchema) {
+		return nil, conn.NewError(fmt.Errorf("key schema cannot be changed"), tableName,
+			"Table %s cannot be migrated because its key schema differs from the definition", tableName)
+	} else if !sameLocalIndexes(table.LocalSecondaryIndexes, def.Input.LocalSecondaryIndexes) {
+		return nil, conn.NewError(fmt.Errorf("local secondary indexes cannot be changed"), tableName,
+			"Table %s cannot be migrated because its local secondary indexes differ from the definition", tableName)
+	}
+
+	// Now, plan the changes to the global secondary indexes, billing mode and throughput, followed by the
+	// stream, TTL and point-in-time recovery settings
+	steps := append(conn.planIndexes(def, table), conn.planStream(def, table)...)
+	ttl, err := conn.planTimeToLive(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+
+	steps = append(steps, ttl...)
+	recovery, err := conn.planRecovery(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+
+	// Finally, return all the steps we planned
+	return append(steps, recovery...), nil
+}
+
+// MigrateTable compares a table definition with the table in DynamoDB and applies the changes required to make
+// the table match the definition, waiting for the table and its indexes to become active after each change.
+// DynamoDB does not allow TTL to be changed while a previous change is still in progress, which can take up to
+// an hour, so changes to TTL will also wait for any previous change to complete. See PlanMigration for more
+// information on the changes that will be made. The steps that were applied will be returned, even if the
+// migration fails, so the caller can determine how far the migration progressed
+func (conn *DatabaseConnection) MigrateTable(ctx context.Context, def *TableDefinition,
+	opts ...IMigrationOption) ([]*MigrationStep, error) {
+	tableName := aws.ToString(def.Input.TableName)
+
+	// First, plan the steps required to migrate the table; if this fails then return an error
+	steps, err := conn.PlanMigration(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.logger.Log("Attempting migration of %s with %d steps...", tableName, len(steps))
+
+	// Next, apply each step in turn, waiting for the table to become active after each one
+	settings := newMigrationSettings(opts...)
+	applied := make([]*MigrationStep, 0, len(steps))
+	for _, step := range steps {
+		conn.logger.Log("Migrating %s: %s...", tableName, step.Description)
+		if err := step.apply(ctx, settings); err != nil {
+			return applied, err
+		}
+
+		applied = append(applied, step)
+		if err := conn.waitForTable(ctx, tableName, settings); err != nil {
+			return applied, err
+		}
+	}
+
+	// Finally, return the steps that were applied
+	conn.logger.Log("Completed migration of %s", tableName)
+	return applied, nil
+}
+
+// WaitForTable describes the table until it, and all of its global secondary indexes, are active and any change
+// to its TTL settings has completed, waiting for the poll interval between each request. A table that doesn't
+// exist yet is treated as not yet active. An error will be returned if the table could not be described or if
+// the context is cancelled before it becomes active
+func (conn *DatabaseConnection) WaitForTable(ctx context.Context, tableName string, opts ...IMigrationOption) error {
+	return conn.waitForTable(ctx, tableName, newMigrationSettings(opts...))
+}
+
+// Helper function that creates the migration settings from the default settings and the options provided
+func newMigrationSettings(opts ...IMigrationOption) *migrationSettings {
+	settings := migrationSettings{interval: 5 * time.Second}
+	for _, opt := range opts {
+		opt.Apply(&settings)
+	}
+
+	return &settings
+}
+
+// Helper function that waits for a table, and its indexes, to become active and for any change to its TTL
+// settings to complete. See WaitForTable for more information
+func (conn *DatabaseConnection) waitForTable(ctx context.Context, tableName string, settings *migrationSettings) error {
+
+	// Describe the table until it and its indexes are active
+	for {
+
+		// First, describe the table; if this fails then return an error
+		table, err := conn.describeTable(ctx, tableName)
+		if err != nil {
+			return err
+		}
+
+		// Next, if the table and its indexes are active then wait for any change to its TTL settings
+		// to complete, after which we're done
+		if table != nil && isTableActive(table) {
+			return conn.waitForTimeToLive(ctx, tableName, settings)
+		}
+
+		// Finally, wait for the poll interval before describing the table again
+		conn.logger.Log("Waiting for %s to become active...", tableName)
+		if err := sleepContext(ctx, settings.interval); err != nil {
+			return conn.NewError(err, tableName, "Failed waiting for table %s to become active", tableName)
+		}
+	}
+}
+
+// Helper function that plans the changes to the global secondary indexes, billing mode and throughput of the
+// table. Indexes that should be removed, or whose keys or projection have changed, are deleted first so that
+// the table does not exceed its index limit, then the billing mode and throughput are updated and, finally,
+// new indexes are created
+func (conn *DatabaseConnection) planIndexes(def *TableDefinition, table *types.TableDescription) []*MigrationStep {
+	tableName := aws.ToString(def.Input.TableName)
+	steps := make([]*MigrationStep, 0)
+
+	// First, collect the desired indexes by name and plan the deletion of any existing index that isn't
+	// desired or that differs from its definition, keeping track of the indexes that will remain
+	desired := make(map[string]types.GlobalSecondaryIndex)
+	for _, index := range def.Input.GlobalSecondaryIndexes {
+		desired[aws.ToString(index.IndexName)] = index
+	}
+
+	kept := make(map[string]bool)
+	for _, index := range table.GlobalSecondaryIndexes {
+		name := aws.ToString(index.IndexName)
+		if wanted, ok := desired[name]; ok && sameKeySchema(index.KeySchema, wanted.KeySchema) &&
+			sameProjection(index.Projection, wanted.Projection) {
+			kept[name] = true
+			continue
+		}
+
+		steps = append(steps, conn.updateTableStep(fmt.Sprintf("Delete index %s", name),
+			&dynamodb.UpdateTableInput{
+				TableName: aws.String(tableName),
+				GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+					{Delete: &types.DeleteGlobalSecondaryIndexAction{IndexName: aws.String(name)}}},
+			}))
+	}
+
+	// Next, determine whether the billing mode or throughput of the table has changed. If the table will
+	// be provisioned after it was billed per request then each index that remains needs throughput as well
+	current := types.BillingModeProvisioned
+	if table.BillingModeSummary != nil && table.BillingModeSummary.BillingMode != "" {
+		current = table.BillingModeSummary.BillingMode
+	}
+
+	mode := def.Input.BillingMode
+	if mode == "" {
+		mode = types.BillingModeProvisioned
+	}
+
+	provisioned := mode == types.BillingModeProvisioned
+	if mode != current || (provisioned && !sameThroughput(table.ProvisionedThroughput, def.Input.ProvisionedThroughput)) {
+		input := dynamodb.UpdateTableInput{TableName: aws.String(tableName), BillingMode: mode}
+		if provisioned {
+			input.ProvisionedThroughput = def.Input.ProvisionedThroughput
+			if mode != current {
+				for _, name := range sortedKeys(kept) {
+					input.GlobalSecondaryIndexUpdates = append(input.GlobalSecondaryIndexUpdates,
+						types.GlobalSecondaryIndexUpdate{Update: &types.UpdateGlobalSecondaryIndexAction{
+							IndexName:             aws.String(name),
+							ProvisionedThroughput: indexThroughput(desired[name], def),
+						}})
+				}
+			}
+		}
+
+		description := "Update throughput of table"
+		if mode != current {
+			description = fmt.Sprintf("Change billing mode to %s", mode)
+		}
+
+		steps = append(steps, conn.updateTableStep(description, &input))
+	}
+
+	// Now, if the table was, and will remain, provisioned then update the throughput of any index that
+	// remains but whose throughput differs from its definition
+	if provisioned && mode == current {
+		for _, index := range table.GlobalSecondaryIndexes {
+			name := aws.ToString(index.IndexName)
+			throughput := indexThroughput(desired[name], def)
+			if !kept[name] || sameIndexThroughput(index.ProvisionedThroughput, throughput) {
+				continue
+			}
+
+			steps = append(steps, conn.updateTableStep(fmt.Sprintf("Update throughput of index %s", name),
+				&dynamodb.UpdateTableInput{
+					TableName: aws.String(tableName),
+					GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+						{Update: &types.UpdateGlobalSecondaryIndexAction{
+							IndexName:             aws.String(name),
+							ProvisionedThroughput: throughput,
+						}}},
+				}))
+		}
+	}
+
+	// Finally, plan the creation of every desired index that doesn't remain on the table
+	for _, name := range sortedKeys(desired) {
+		if kept[name] {
+			continue
+		}
+
+		index := desired[name]
+		action := types.CreateGlobalSecondaryIndexAction{
+			IndexName:  index.IndexName,
+			KeySchema:  index.KeySchema,
+			Projection: index.Projection,
+		}
+
+		if provisioned {
+			action.ProvisionedThroughput = indexThroughput(index, def)
+		}
+
+		steps = append(steps, conn.updateTableStep(fmt.Sprintf("Create index %s", name),
+			&dynamodb.UpdateTableInput{
+				TableName:                   aws.String(tableName),
+				AttributeDefinitions:        def.Input.AttributeDefinitions,
+				GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{{Create: &action}},
+			}))
+	}
+
+	return steps
+}
+
+// Helper function that plans the changes to the stream of the table. DynamoDB does not allow the view type of
+// a stream to be changed so, if it differs from the definition, the stream will be disabled and re-enabled
+func (conn *DatabaseConnection) planStream(def *TableDefinition, table *types.TableDescription) []*MigrationStep {
+	tableName := aws.ToString(def.Input.TableName)
+
+	// First, determine whether the stream is enabled on the table and in the definition
+	current, wanted := table.StreamSpecification, def.Input.StreamSpecification
+	enabled := current != nil && aws.ToBool(current.StreamEnabled)
+	desired := wanted != nil && aws.ToBool(wanted.StreamEnabled)
+	if (!enabled && !desired) || (enabled && desired && current.StreamViewType == wanted.StreamViewType) {
+		return nil
+	}
+
+	// Next, if the stream is enabled then disable it, as it is either not wanted or has the wrong view type
+	steps := make([]*MigrationStep, 0, 2)
+	if enabled {
+		steps = append(steps, conn.updateTableStep("Disable stream", &dynamodb.UpdateTableInput{
+			TableName:           aws.String(tableName),
+			StreamSpecification: &types.StreamSpecification{StreamEnabled: aws.Bool(false)},
+		}))
+	}
+
+	// Finally, if the stream is wanted then enable it with the view type from the definition
+	if desired {
+		steps = append(steps, conn.updateTableStep(fmt.Sprintf("Enable stream with view type %s", wanted.StreamViewType),
+			&dynamodb.UpdateTableInput{TableName: aws.String(tableName), StreamSpecification: wanted}))
+	}
+
+	return steps
+}
+
+// Helper function that plans the changes to the TTL settings of the table. DynamoDB does not allow the TTL
+// attribute to be changed while TTL is enabled so, if it differs from the definition, TTL will be disabled
+// and re-enabled with the new attribute. Since DynamoDB also rejects changes to TTL while a previous change
+// is in progress, each step will wait for TTL to be enabled or disabled before it is applied
+func (conn *DatabaseConnection) planTimeToLive(ctx context.Context, def *TableDefinition) ([]*MigrationStep, error) {
+	tableName := aws.ToString(def.Input.TableName)
+
+	// First, describe the TTL settings of the table; if this fails then return an error
+	desc, err := conn.describeTimeToLive(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Next, determine whether TTL is enabled, or will be once the change in progress completes, and on
+	// which attribute; if this matches the definition then there's nothing to do
+	var attribute string
+	if desc != nil && (desc.TimeToLiveStatus == types.TimeToLiveStatusEnabled ||
+		desc.TimeToLiveStatus == types.TimeToLiveStatusEnabling) {
+		attribute = aws.ToString(desc.AttributeName)
+	}
+
+	if attribute == def.TTLAttribute {
+		return nil, nil
+	}
+
+	// Finally, disable TTL if it was enabled on another attribute and enable it on the attribute from the
+	// definition, if it has one
+	steps := make([]*MigrationStep, 0, 2)
+	if attribute != "" {
+		steps = append(steps, conn.timeToLiveStep(tableName, attribute, false))
+	}
+
+	if def.TTLAttribute != "" {
+		steps = append(steps, conn.timeToLiveStep(tableName, def.TTLAttribute, true))
+	}
+
+	return steps, nil
+}
+
+// Helper function that plans the change to the point-in-time recovery setting of the table
+func (conn *DatabaseConnection) planRecovery(ctx context.Context, def *TableDefinition) ([]*MigrationStep, error) {
+	tableName := aws.ToString(def.Input.TableName)
+
+	// First, describe the continuous backup settings of the table; if this fails then return an error
+	var output *dynamodb.DescribeContinuousBackupsOutput
+	err := conn.doRetry(ctx, tableName, "DESCRIBE BACKUPS", func() error {
+		var inner error
+		output, inner = conn.db.DescribeContinuousBackups(ctx,
+			&dynamodb.DescribeContinuousBackupsInput{TableName: aws.String(tableName)})
+		return inner
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Next, determine whether point-in-time recovery is enabled; if this matches the definition then
+	// there's nothing to do. Otherwise, plan the change
+	enabled := false
+	if desc := output.ContinuousBackupsDescription; desc != nil && desc.PointInTimeRecoveryDescription != nil {
+		enabled = desc.PointInTimeRecoveryDescription.PointInTimeRecoveryStatus == types.PointInTimeRecoveryStatusEnabled
+	}
+
+	if enabled == def.PointInTimeRecovery {
+		return nil, nil
+	}
+
+	return []*MigrationStep{conn.recoveryStep(tableName, def.PointInTimeRecovery)}, nil
+}
+
+// Helper function that creates a step that creates the table from its definition
+func (conn *DatabaseConnection) createTableStep(def *TableDefinition) *MigrationStep {
+	tableName := aws.ToString(def.Input.TableName)
+	return &MigrationStep{
+		Description: fmt.Sprintf("Create table %s", tableName),
+		apply: func(ctx context.Context, _ *migrationSettings) error {
+			return conn.doRetry(ctx, tableName, "CREATE TABLE", func() error {
+				_, err := conn.db.CreateTable(ctx, def.Input)
+				return err
+			})
+		},
+	}
+}
+
+// Helper function that creates a step that sends an update table request
+func (conn *DatabaseConnection) updateTableStep(description string, input *dynamodb.UpdateTableInput) *MigrationStep {
+	return &MigrationStep{
+		Description: description,
+		apply: func(ctx context.Context, _ *migrationSettings) error {
+			return conn.doRetry(ctx, *input.TableName, "UPDATE TABLE", func() error {
+				_, err := conn.db.UpdateTable(ctx, input)
+				return err
+			})
+		},
+	}
+}
+
+// Helper function that creates a step that enables or disables TTL on the attribute provided, after waiting
+// for any change to TTL that is already in progress to complete
+func (conn *DatabaseConnection) timeToLiveStep(tableName string, attribute string, enabled bool) *MigrationStep {
+	description := fmt.Sprintf("Disable TTL on %s", attribute)
+	if enabled {
+		description = fmt.Sprintf("Enable TTL on %s", attribute)
+	}
+
+	return &MigrationStep{
+		Description: description,
+		apply: func(ctx context.Context, settings *migrationSettings) error {
+			if err := conn.waitForTimeToLive(ctx, tableName, settings); err != nil {
+				return err
+			}
+
+			return conn.doRetry(ctx, tableName, "UPDATE TTL", func() error {
+				_, err := conn.db.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+					TableName: aws.String(tableName),
+					TimeToLiveSpecification: &types.TimeToLiveSpecification{
+						AttributeName: aws.String(attribute),
+						Enabled:       aws.Bool(enabled),
+					},
+				})
+
+				return err
+			})
+		},
+	}
+}
+
+// Helper function that creates a step that enables or disables point-in-time recovery
+func (conn *DatabaseConnection) recoveryStep(tableName string, enabled bool) *MigrationStep {
+	description := "Disable point-in-time recovery"
+	if enabled {
+		description = "Enable point-in-time recovery"
+	}
+
+	return &MigrationStep{
+		Description: description,
+		apply: func(ctx context.Context, _ *migrationSettings) error {
+			return conn.doRetry(ctx, tableName, "UPDATE BACKUPS", func() error {
+				_, err := conn.db.UpdateContinuousBackups(ctx, &dynamodb.UpdateContinuousBackupsInput{
+					TableName: aws.String(tableName),
+					PointInTimeRecoverySpecification: &types.PointInTimeRecoverySpecification{
+						PointInTimeRecoveryEnabled: aws.Bool(enabled),
+					},
+				})
+
+				return err
+			})
+		},
+	}
+}
+
+// Helper function that describes the TTL settings of a table
+func (conn *DatabaseConnection) describeTimeToLive(ctx context.Context,
+	tableName string) (*types.TimeToLiveDescription, error) {
+	var output *dynamodb.DescribeTimeToLiveOutput
+	err := conn.doRetry(ctx, tableName, "DESCRIBE TTL", func() error {
+		var inner error
+		output, inner = conn.db.DescribeTimeToLive(ctx,
+			&dynamodb.DescribeTimeToLiveInput{TableName: aws.String(tableName)})
+		return inner
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return output.TimeToLiveDescription, nil
+}
+
+// Helper function that describes the TTL settings of a table until they are no longer being enabled or
+// disabled, waiting for the poll interval between each request
+func (conn *DatabaseConnection) waitForTimeToLive(ctx context.Context, tableName string,
+	settings *migrationSettings) error {
+	for {
+
+		// First, describe the TTL settings; if this fails then return an error
+		desc, err := conn.describeTimeToLive(ctx, tableName)
+		if err != nil {
+			return err
+		}
+
+		// Next, if TTL isn't being enabled or disabled then we're done
+		if desc == nil || (desc.TimeToLiveStatus != types.TimeToLiveStatusEnabling &&
+			desc.TimeToLiveStatus != types.TimeToLiveStatusDisabling) {
+			return nil
+		}
+
+		// Finally, wait for the poll interval before describing the TTL settings again
+		conn.logger.Log("Waiting for TTL on %s to be %s...", tableName, strings.ToLower(string(desc.TimeToLiveStatus)))
+		if err := sleepContext(ctx, settings.interval); err != nil {
+			return conn.NewError(err, tableName, "Failed waiting for TTL on table %s to change", tableName)
+		}
+	}
+}
+
+// Helper function that describes a table, returning nil if the table doesn't exist
+func (conn *DatabaseConnection) describeTable(ctx context.Context, tableName string) (*types.TableDescription, error) {
+	var output *dynamodb.DescribeTableOutput
+	err := conn.doRetry(ctx, tableName, "DESCRIBE TABLE", func() error {
+		var inner error
+		output, inner = conn.db.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+		return inner
+	})
+
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if casted, ok := err.(*Error); ok && errors.As(casted.Inner, &notFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return output.Table, nil
+}
+
+// Helper function that determines whether a table and all of its global secondary indexes are active
+func isTableActive(table *types.TableDescription) bool {
+	if table.TableStatus != types.TableStatusActive {
+		return false
+	}
+
+	for _, index := range table.GlobalSecondaryIndexes {
+		if index.IndexStatus != types.IndexStatusActive {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Helper function that gets the throughput that an index should have from its definition, or from the table
+// if the index doesn't define its own
+func indexThroughput(index types.GlobalSecondaryIndex, def *TableDefinition) *types.ProvisionedThroughput {
+	if index.ProvisionedThroughput != nil {
+		return index.ProvisionedThroughput
+	}
+
+	return def.Input.ProvisionedThroughput
+}
+
+// Helper function that determines whether two key schemas contain the same keys
+func sameKeySchema(current []types.KeySchemaElement, desired []types.KeySchemaElement) bool {
+	return describeKeySchema(current) == describeKeySchema(desired)
+}
+
+// Helper function that determines whether two projections project the same attributes
+func sameProjection(current *types.Projection, desired *types.Projection) bool {
+	return describeProjection(current) == describeProjection(desired)
+}
+
+// Helper function that determines whether the local secondary indexes on the table match those in the definition
+func sameLocalIndexes(current []types.LocalSecondaryIndexDescription, desired []types.LocalSecondaryIndex) bool {
+	described := make(map[string]string, len(current))
+	for _, index := range current {
+		described[aws.ToString(index.IndexName)] = describeKeySchema(index.KeySchema) + describeProjection(index.Projection)
+	}
+
+	if len(described) != len(desired) {
+		return false
+	}
+
+	for _, index := range desired {
+		if described[aws.ToString(index.IndexName)] != describeKeySchema(index.KeySchema)+describeProjection(index.Projection) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Helper function that determines whether the provisioned throughput of the table matches the throughput desired
+func sameThroughput(current *types.ProvisionedThroughputDescription, desired *types.ProvisionedThroughput) bool {
+	if current == nil || desired == nil {
+		return current == nil && desired == nil
+	}
+
+	return aws.ToInt64(current.ReadCapacityUnits) == aws.ToInt64(desired.ReadCapacityUnits) &&
+		aws.ToInt64(current.WriteCapacityUnits) == aws.ToInt64(desired.WriteCapacityUnits)
+}
+
+// Helper function that determines whether the provisioned throughput of an index matches the throughput desired
+func sameIndexThroughput(current *types.ProvisionedThroughputDescription, desired *types.ProvisionedThroughput) bool {
+	return desired == nil || sameThroughput(current, desired)
+}
+
+// Helper function that creates a string describing a key schema so that key schemas can be compared
+func describeKeySchema(schema []types.KeySchemaElement) string {
+	parts := make([]string, len(schema))
+	for i, element := range schema {
+		parts[i] = fmt.Sprintf("%s=%s", aws.ToString(element.AttributeName), element.KeyType)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Helper function that creates a string describing a projection so that projections can be compared. The
+// non-key attributes are sorted as DynamoDB does not preserve their order
+func describeProjection(projection *types.Projection) string {
+	if projection == nil {
+		return string(types.ProjectionTypeAll)
+	}
+
+	attributes := append([]string{}, projection.NonKeyAttributes...)
+	sort.Strings(attributes)
+	return fmt.Sprintf("%s(%s)", projection.ProjectionType, strings.Join(attributes, ","))
+}
+
+// Helper function that gets the keys of a map in sorted order
+func sortedKeys[T any](mapping map[string]T) []string {
+	keys := collections.Keys(mapping)
+	sort.Strings(keys)
+	return keys
+}
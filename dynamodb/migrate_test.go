@@ -0,0 +1,424 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Migration Tests", func() {
+
+	// Test that, if the table doesn't exist, then MigrateTable will create it and then apply the settings
+	// that cannot be set when a table is created, waiting for the table to become active after each step
+	It("MigrateTable - Table missing - Created", func() {
+
+		// First, create our test connection with no tables and a definition for the table
+		client := &migrationDynamoDBClient{pending: 2}
+		conn := createMockConnection(client)
+		def := createMigrationDefinition(WithPointInTimeRecovery(true))
+
+		// Next, migrate the table; this should not fail
+		steps, err := conn.MigrateTable(context.Background(), def, WithPollInterval(time.Millisecond))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, verify the steps that were applied and that we waited for the table after each of them
+		Expect(stepDescriptions(steps)).Should(Equal([]string{
+			"Create table MIGRATION_TABLE", "Enable TTL on expires", "Enable point-in-time recovery"}))
+		Expect(client.describes).Should(Equal(6))
+
+		// Finally, verify the state of the table
+		Expect(client.table.BillingModeSummary.BillingMode).Should(Equal(types.BillingModeProvisioned))
+		Expect(client.indexNames()).Should(Equal([]string{"ByHash", "ByOwner"}))
+		Expect(*client.ttl.AttributeName).Should(Equal("expires"))
+		Expect(client.ttlWaiting).Should(BeZero())
+		Expect(client.recovery).Should(BeTrue())
+	})
+
+	// Test that, if TTL is still being disabled on the table, then MigrateTable will wait for it to be
+	// disabled before enabling it on the attribute in the definition, since DynamoDB would reject the change
+	It("MigrateTable - TTL being disabled - Enabled after change completes", func() {
+
+		// First, create our test connection with a table whose TTL is being disabled
+		client := &migrationDynamoDBClient{pending: 3}
+		conn := createMockConnection(client)
+		def := createMigrationDefinition()
+
+		_, err := client.CreateTable(context.Background(), def.Input)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		client.ttl = &types.TimeToLiveDescription{
+			AttributeName: aws.String("old_expiry"), TimeToLiveStatus: types.TimeToLiveStatusDisabled}
+		client.ttlWaiting = 3
+
+		// Next, migrate the table; this should not fail
+		steps, err := conn.MigrateTable(context.Background(), def, WithPollInterval(time.Millisecond))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that TTL was enabled once it had been disabled and that we waited for it
+		// to be enabled before returning
+		Expect(stepDescriptions(steps)).Should(Equal([]string{"Enable TTL on expires"}))
+		Expect(client.ttl).Should(Equal(&types.TimeToLiveDescription{
+			AttributeName: aws.String("expires"), TimeToLiveStatus: types.TimeToLiveStatusEnabled}))
+		Expect(client.ttlWaiting).Should(BeZero())
+	})
+
+	// Test that, if TTL is still being enabled on the table when the context is cancelled, then MigrateTable
+	// will return an error without attempting to change it
+	It("MigrateTable - TTL change not completed - Error", func() {
+
+		// First, create our test connection with a table whose TTL is being enabled on another attribute
+		client := &migrationDynamoDBClient{}
+		conn := createMockConnection(client)
+		def := createMigrationDefinition()
+
+		_, err := client.CreateTable(context.Background(), def.Input)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		client.ttl = &types.TimeToLiveDescription{
+			AttributeName: aws.String("old_expiry"), TimeToLiveStatus: types.TimeToLiveStatusEnabled}
+		client.ttlWaiting = 1000
+
+		// Next, attempt to migrate the table with a short deadline; this should fail
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		steps, err := conn.MigrateTable(ctx, def, WithPollInterval(time.Millisecond))
+
+		// Finally, verify the details of the error and that TTL was not changed
+		Expect(steps).Should(BeEmpty())
+		Expect(err.(*Error).Message).Should(Equal("Failed waiting for TTL on table MIGRATION_TABLE to change"))
+		Expect(err.(*Error).Inner).Should(MatchError(context.DeadlineExceeded))
+		Expect(*client.ttl.AttributeName).Should(Equal("old_expiry"))
+	})
+
+	// Test that, if the table differs from its definition, then MigrateTable will apply every change required
+	// to make the table match the definition
+	It("MigrateTable - Table differs - Changes applied", func() {
+
+		// First, create our test connection with a table that differs from our definition in every way
+		// that can be migrated
+		client := &migrationDynamoDBClient{pending: 1}
+		conn := createMockConnection(client)
+		def := createMigrationDefinition()
+
+		old := *def.Input
+		old.BillingMode = types.BillingModePayPerRequest
+		old.ProvisionedThroughput = nil
+		old.StreamSpecification = &types.StreamSpecification{
+			StreamEnabled: aws.Bool(true), StreamViewType: types.StreamViewTypeNewImage}
+		old.GlobalSecondaryIndexes = []types.GlobalSecondaryIndex{
+			{IndexName: aws.String("ByOld"), KeySchema: keySchema("owner", ""),
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll}},
+			{IndexName: aws.String("ByOwner"), KeySchema: keySchema("owner", "created"),
+				Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll}}}
+		_, err := client.CreateTable(context.Background(), &old)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		client.ttl = &types.TimeToLiveDescription{
+			AttributeName: aws.String("old_expiry"), TimeToLiveStatus: types.TimeToLiveStatusEnabled}
+		client.recovery = true
+
+		// Next, migrate the table; this should not fail
+		steps, err := conn.MigrateTable(context.Background(), def, WithPollInterval(time.Millisecond))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, verify the steps that were applied
+		Expect(stepDescriptions(steps)).Should(Equal([]string{
+			"Delete index ByOld", "Delete index ByOwner", "Change billing mode to PROVISIONED",
+			"Create index ByHash", "Create index ByOwner", "Disable stream",
+			"Enable stream with view type NEW_AND_OLD_IMAGES", "Disable TTL on old_expiry",
+			"Enable TTL on expires", "Disable point-in-time recovery"}))
+
+		// Finally, verify the state of the table and that planning again finds nothing left to do
+		Expect(client.table.BillingModeSummary.BillingMode).Should(Equal(types.BillingModeProvisioned))
+		Expect(client.indexNames()).Should(Equal([]string{"ByHash", "ByOwner"}))
+		Expect(client.table.StreamSpecification.StreamViewType).Should(Equal(types.StreamViewTypeNewAndOldImages))
+		Expect(*client.ttl.AttributeName).Should(Equal("expires"))
+		Expect(client.ttlWaiting).Should(BeZero())
+		Expect(client.recovery).Should(BeFalse())
+
+		remaining, err := conn.PlanMigration(context.Background(), def)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(remaining).Should(BeEmpty())
+	})
+
+	// Test that, if the throughput of a provisioned table differs from its definition, then PlanMigration
+	// will plan updates to the throughput of the table and each of its indexes
+	It("PlanMigration - Throughput differs - Updates planned", func() {
+
+		// First, create our test connection with a table that matches our definition
+		client := &migrationDynamoDBClient{}
+		conn := createMockConnection(client)
+		_, err := conn.MigrateTable(context.Background(), createMigrationDefinition(), WithPollInterval(time.Millisecond))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Next, plan a migration to a definition with more throughput; this should not fail
+		steps, err := conn.PlanMigration(context.Background(),
+			createMigrationDefinition(WithProvisionedThroughput{Read: 10, Write: 10}))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the steps that were planned
+		Expect(stepDescriptions(steps)).Should(Equal([]string{"Update throughput of table",
+			"Update throughput of index ByHash", "Update throughput of index ByOwner"}))
+	})
+
+	// Test that, if the key schema of the table differs from its definition, then PlanMigration will return
+	// an error as the key schema cannot be changed
+	It("PlanMigration - Key schema differs - Error", func() {
+
+		// First, create our test connection with a table that has a different key schema
+		client := &migrationDynamoDBClient{}
+		conn := createMockConnection(client)
+		def := createMigrationDefinition()
+
+		old := *def.Input
+		old.KeySchema = keySchema("id", "")
+		_, err := client.CreateTable(context.Background(), &old)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Next, attempt to plan the migration; this should fail
+		steps, err := conn.PlanMigration(context.Background(), def)
+
+		// Finally, verify the details of the error
+		Expect(steps).Should(BeNil())
+		Expect(err.(*Error).Message).Should(Equal(
+			"Table MIGRATION_TABLE cannot be migrated because its key schema differs from the definition"))
+	})
+
+	// Test that, if the context is cancelled before the table becomes active, then WaitForTable will fail
+	It("WaitForTable - Context cancelled - Error", func() {
+
+		// First, create our test connection with a table that never becomes active
+		client := &migrationDynamoDBClient{}
+		conn := createMockConnection(client)
+		_, err := client.CreateTable(context.Background(), createMigrationDefinition().Input)
+		Expect(err).ShouldNot(HaveOccurred())
+		client.waiting = 1000
+
+		// Next, wait for the table with a context that times out
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err = conn.WaitForTable(ctx, "MIGRATION_TABLE", WithPollInterval(time.Millisecond))
+
+		// Finally, verify the details of the error
+		Expect(err.(*Error).Message).Should(Equal("Failed waiting for table MIGRATION_TABLE to become active"))
+		Expect(err.(*Error).Inner).Should(MatchError(context.DeadlineExceeded))
+	})
+})
+
+// Helper function that creates the table definition used by migration tests
+func createMigrationDefinition(opts ...ITableOption) *TableDefinition {
+	opts = append([]ITableOption{WithTableTagKey("json"), WithProvisionedThroughput{Read: 5, Write: 5},
+		WithTableStream(types.StreamViewTypeNewAndOldImages)}, opts...)
+	def, err := NewTableDefinition[schemaObject]("MIGRATION_TABLE", opts...)
+	Expect(err).ShouldNot(HaveOccurred())
+	return def
+}
+
+// Helper function that gets the descriptions of a list of migration steps
+func stepDescriptions(steps []*MigrationStep) []string {
+	descriptions := make([]string, len(steps))
+	for i, step := range steps {
+		descriptions[i] = step.Description
+	}
+
+	return descriptions
+}
+
+// Helper type that stores the description of a single table so that migrations can be tested without a
+// DynamoDB instance. After each change, the table will be described as updating the number of times set
+// by pending before it becomes active
+type migrationDynamoDBClient struct {
+	DynamoDBAPI
+	table      *types.TableDescription
+	ttl        *types.TimeToLiveDescription
+	recovery   bool
+	pending    int
+	waiting    int
+	ttlWaiting int
+	describes  int
+}
+
+// Mocks out the DescribeTable function so that it describes the stored table, if it exists
+func (client *migrationDynamoDBClient) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	client.describes++
+	if client.table == nil {
+		return nil, &smithy.OperationError{Err: &types.ResourceNotFoundException{Message: aws.String("not found")}}
+	}
+
+	copied := *client.table
+	copied.TableStatus = types.TableStatusActive
+	if client.waiting > 0 {
+		client.waiting--
+		copied.TableStatus = types.TableStatusUpdating
+	}
+
+	return &dynamodb.DescribeTableOutput{Table: &copied}, nil
+}
+
+// Mocks out the CreateTable function so that it stores a description of the table requested
+func (client *migrationDynamoDBClient) CreateTable(ctx context.Context, params *dynamodb.CreateTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	client.table = &types.TableDescription{
+		TableName:           params.TableName,
+		KeySchema:           params.KeySchema,
+		BillingModeSummary:  &types.BillingModeSummary{BillingMode: params.BillingMode},
+		StreamSpecification: params.StreamSpecification,
+	}
+
+	client.setThroughput(params.ProvisionedThroughput)
+	for _, index := range params.GlobalSecondaryIndexes {
+		client.addIndex(index.IndexName, index.KeySchema, index.Projection, index.ProvisionedThroughput)
+	}
+
+	for _, index := range params.LocalSecondaryIndexes {
+		client.table.LocalSecondaryIndexes = append(client.table.LocalSecondaryIndexes,
+			types.LocalSecondaryIndexDescription{
+				IndexName: index.IndexName, KeySchema: index.KeySchema, Projection: index.Projection})
+	}
+
+	client.ttl = &types.TimeToLiveDescription{TimeToLiveStatus: types.TimeToLiveStatusDisabled}
+	client.waiting = client.pending
+	return &dynamodb.CreateTableOutput{TableDescription: client.table}, nil
+}
+
+// Mocks out the UpdateTable function so that it applies the changes requested to the stored table
+func (client *migrationDynamoDBClient) UpdateTable(ctx context.Context, params *dynamodb.UpdateTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+	if params.BillingMode != "" {
+		client.table.BillingModeSummary = &types.BillingModeSummary{BillingMode: params.BillingMode}
+		client.setThroughput(params.ProvisionedThroughput)
+	}
+
+	if params.StreamSpecification != nil {
+		client.table.StreamSpecification = params.StreamSpecification
+	}
+
+	for _, update := range params.GlobalSecondaryIndexUpdates {
+		if update.Create != nil {
+			client.addIndex(update.Create.IndexName, update.Create.KeySchema,
+				update.Create.Projection, update.Create.ProvisionedThroughput)
+		}
+
+		indexes := make([]types.GlobalSecondaryIndexDescription, 0)
+		for _, index := range client.table.GlobalSecondaryIndexes {
+			if update.Delete != nil && *update.Delete.IndexName == *index.IndexName {
+				continue
+			} else if update.Update != nil && *update.Update.IndexName == *index.IndexName {
+				index.ProvisionedThroughput = describeThroughput(update.Update.ProvisionedThroughput)
+			}
+
+			indexes = append(indexes, index)
+		}
+
+		client.table.GlobalSecondaryIndexes = indexes
+	}
+
+	client.waiting = client.pending
+	return &dynamodb.UpdateTableOutput{TableDescription: client.table}, nil
+}
+
+// Mocks out the DescribeTimeToLive function so that it describes the stored TTL settings, which will be
+// described as being enabled or disabled until they have been described the number of times requested
+func (client *migrationDynamoDBClient) DescribeTimeToLive(ctx context.Context, params *dynamodb.DescribeTimeToLiveInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	copied := *client.ttl
+	if client.ttlWaiting > 0 {
+		client.ttlWaiting--
+		copied.TimeToLiveStatus = types.TimeToLiveStatusDisabling
+		if client.ttl.TimeToLiveStatus == types.TimeToLiveStatusEnabled {
+			copied.TimeToLiveStatus = types.TimeToLiveStatusEnabling
+		}
+	}
+
+	return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: &copied}, nil
+}
+
+// Mocks out the UpdateTimeToLive function so that it updates the stored TTL settings. As with DynamoDB,
+// this will fail if a previous change to the TTL settings is still in progress
+func (client *migrationDynamoDBClient) UpdateTimeToLive(ctx context.Context, params *dynamodb.UpdateTimeToLiveInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	if client.ttlWaiting > 0 {
+		return nil, &smithy.GenericAPIError{Code: "ValidationException",
+			Message: "Time to live has been modified multiple times within a fixed interval"}
+	}
+
+	client.ttl = &types.TimeToLiveDescription{AttributeName: params.TimeToLiveSpecification.AttributeName,
+		TimeToLiveStatus: types.TimeToLiveStatusDisabled}
+	if *params.TimeToLiveSpecification.Enabled {
+		client.ttl.TimeToLiveStatus = types.TimeToLiveStatusEnabled
+	}
+
+	client.ttlWaiting = client.pending
+	return &dynamodb.UpdateTimeToLiveOutput{}, nil
+}
+
+// Mocks out the DescribeContinuousBackups function so that it describes the stored recovery setting
+func (client *migrationDynamoDBClient) DescribeContinuousBackups(ctx context.Context,
+	params *dynamodb.DescribeContinuousBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+	status := types.PointInTimeRecoveryStatusDisabled
+	if client.recovery {
+		status = types.PointInTimeRecoveryStatusEnabled
+	}
+
+	return &dynamodb.DescribeContinuousBackupsOutput{
+		ContinuousBackupsDescription: &types.ContinuousBackupsDescription{
+			PointInTimeRecoveryDescription: &types.PointInTimeRecoveryDescription{PointInTimeRecoveryStatus: status},
+		},
+	}, nil
+}
+
+// Mocks out the UpdateContinuousBackups function so that it updates the stored recovery setting
+func (client *migrationDynamoDBClient) UpdateContinuousBackups(ctx context.Context,
+	params *dynamodb.UpdateContinuousBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	client.recovery = *params.PointInTimeRecoverySpecification.PointInTimeRecoveryEnabled
+	return &dynamodb.UpdateContinuousBackupsOutput{}, nil
+}
+
+// Helper function that gets the names of the global secondary indexes on the stored table
+func (client *migrationDynamoDBClient) indexNames() []string {
+	names := make([]string, len(client.table.GlobalSecondaryIndexes))
+	for i, index := range client.table.GlobalSecondaryIndexes {
+		names[i] = *index.IndexName
+	}
+
+	return names
+}
+
+// Helper function that adds a global secondary index to the stored table
+func (client *migrationDynamoDBClient) addIndex(name *string, schema []types.KeySchemaElement,
+	projection *types.Projection, throughput *types.ProvisionedThroughput) {
+	client.table.GlobalSecondaryIndexes = append(client.table.GlobalSecondaryIndexes,
+		types.GlobalSecondaryIndexDescription{
+			IndexName:             name,
+			KeySchema:             schema,
+			Projection:            projection,
+			IndexStatus:           types.IndexStatusActive,
+			ProvisionedThroughput: describeThroughput(throughput),
+		})
+}
+
+// Helper function that sets the provisioned throughput of the stored table
+func (client *migrationDynamoDBClient) setThroughput(throughput *types.ProvisionedThroughput) {
+	client.table.ProvisionedThroughput = describeThroughput(throughput)
+}
+
+// Helper function that converts provisioned throughput into its description
+func describeThroughput(throughput *types.ProvisionedThroughput) *types.ProvisionedThroughputDescription {
+	if throughput == nil {
+		return nil
+	}
+
+	return &types.ProvisionedThroughputDescription{
+		ReadCapacityUnits:  throughput.ReadCapacityUnits,
+		WriteCapacityUnits: throughput.WriteCapacityUnits,
+	}
+}
@@ -1,6 +1,10 @@
 package dynamodb
 
-import "time"
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
 
 // IDynamoDBOption defines the functionality that will allow the behavior of a
 // DatabaseConnection to be modified at construction
@@ -34,3 +38,136 @@ type WithBackoffMaxElapsed time.Duration
 func (w WithBackoffMaxElapsed) Apply(conn *DatabaseConnection) {
 	conn.maxElapsed = time.Duration(w)
 }
+
+// WithTagKey allows the user to set the struct tag that should be used to determine the names of
+// attributes when objects are marshalled to, or unmarshalled from, DynamoDB. If this option is not
+// provided then the default tag used by the attributevalue package, dynamodbav, will be used
+type WithTagKey string
+
+// Apply modifies the DatabaseConnection so that it uses the tag key defined by this object
+func (w WithTagKey) Apply(conn *DatabaseConnection) {
+	conn.tagKey = string(w)
+}
+
+// WithBatchParallelism allows the user to set the number of pages of a batch request that may be
+// sent to DynamoDB concurrently. If this option is not provided then pages will be sent one at a time
+type WithBatchParallelism int
+
+// Apply modifies the DatabaseConnection so that it has the batch parallelism defined by this object
+func (w WithBatchParallelism) Apply(conn *DatabaseConnection) {
+	conn.batchParallelism = int(w)
+}
+
+// WithUnprocessedRetries allows the user to set the maximum number of times that unprocessed items
+// returned by a batch request will be resubmitted to DynamoDB before the request is considered failed
+type WithUnprocessedRetries int
+
+// Apply modifies the DatabaseConnection so that it has the unprocessed retry limit defined by this object
+func (w WithUnprocessedRetries) Apply(conn *DatabaseConnection) {
+	conn.unprocessedRetries = int(w)
+}
+
+// WithConsumedCapacity allows the user to request that DynamoDB return the capacity consumed by every
+// request made through the connection, either in total or broken down by index. The capacity returned
+// will be aggregated into the stats returned by ConsumedCapacity. If this option is not provided then
+// capacity will only be aggregated for those requests which ask for it explicitly
+type WithConsumedCapacity types.ReturnConsumedCapacity
+
+// Apply modifies the DatabaseConnection so that it requests the consumed capacity defined by this object
+func (w WithConsumedCapacity) Apply(conn *DatabaseConnection) {
+	conn.returnCapacity = types.ReturnConsumedCapacity(w)
+}
+
+// WithCapacityCallback allows the user to set a function that will be called with the capacity consumed
+// by every request made through the connection, as it is returned by DynamoDB
+type WithCapacityCallback CapacityCallback
+
+// Apply modifies the DatabaseConnection so that it calls the capacity callback defined by this object
+func (w WithCapacityCallback) Apply(conn *DatabaseConnection) {
+	conn.capacityCallback = CapacityCallback(w)
+}
+
+// WithThroughputLimit allows the user to limit the read and write capacity units that the connection
+// will consume against a table each second, so that bulk operations do not starve other clients of the
+// table's throughput. The connection will throttle itself, before sending requests, based on the capacity
+// consumed by previous requests. A limit of zero indicates that the type of request should not be limited.
+// This option may be provided once for each table that should be limited and these limits will take
+// precedence over those set by WithProvisionedLimit
+type WithThroughputLimit struct {
+	TableName string
+	Read      float64
+	Write     float64
+}
+
+// Apply modifies the DatabaseConnection so that it limits the throughput on the table defined by this object
+func (w WithThroughputLimit) Apply(conn *DatabaseConnection) {
+	conn.ensureLimiter().setLimit(w.TableName, w.Read, w.Write, true)
+}
+
+// WithProvisionedLimit allows the user to limit the read and write capacity units that the connection
+// will consume against every table to a fraction of the throughput provisioned on that table. The
+// provisioned throughput will be read from DynamoDB before the first request to each table and refreshed
+// whenever the refresh interval has elapsed. If the refresh interval is zero then it will only be read once.
+// Tables which are billed per request have no provisioned throughput so they will not be limited
+type WithProvisionedLimit struct {
+	Fraction float64
+	Refresh  time.Duration
+}
+
+// Apply modifies the DatabaseConnection so that it limits the throughput on each table as defined by this object
+func (w WithProvisionedLimit) Apply(conn *DatabaseConnection) {
+	limiter := conn.ensureLimiter()
+	limiter.fraction = w.Fraction
+	limiter.refresh = w.Refresh
+}
+
+// WithCursorSecret allows the user to set the secret that will be used to sign the pagination cursors
+// created by the connection, and to verify the cursors it receives, so that clients cannot forge cursors
+// to read from arbitrary positions in a table. If this option is not provided then cursors will not be signed
+type WithCursorSecret []byte
+
+// Apply modifies the DatabaseConnection so that it signs cursors with the secret defined by this object
+func (w WithCursorSecret) Apply(conn *DatabaseConnection) {
+	conn.cursorSecret = []byte(w)
+}
+
+// WithRetryPredicate allows the user to set the function that decides whether a failed DynamoDB request should
+// be retried. If this option is not provided then IsRetryable will be used. Note that the predicate replaces the
+// default classification so it should call IsRetryable if it only needs to extend it
+type WithRetryPredicate RetryPredicate
+
+// Apply modifies the DatabaseConnection so that it uses the retry predicate defined by this object
+func (w WithRetryPredicate) Apply(conn *DatabaseConnection) {
+	conn.retryPredicate = RetryPredicate(w)
+}
+
+// WithOperationBackoff allows the user to set the backoff policy used when retrying a specific operation, such
+// as "GET", "QUERY" or "BATCH WRITE". Operations without a policy will use the exponential backoff settings on
+// the connection
+type WithOperationBackoff struct {
+	Operation string
+	Factory   BackoffFactory
+}
+
+// Apply modifies the DatabaseConnection so that it uses the backoff policy defined by this object for its operation
+func (w WithOperationBackoff) Apply(conn *DatabaseConnection) {
+	conn.backoffPolicies[w.Operation] = w.Factory
+}
+
+// WithItemCache allows the user to cache items read from DynamoDB in memory so that frequently read items,
+// such as configuration, can be served without sending a request. The cache will hold no more than Size
+// items, evicting the least recently used items when it is full, and items will expire once they are older
+// than TTL. If Size is zero then DefaultCacheSize will be used and if TTL is zero then items will not expire.
+// If any table names are provided then only items from those tables will be cached. Items written through
+// the connection will be updated or removed in the cache but writes made by other clients will not be seen
+// until the cached items expire
+type WithItemCache struct {
+	Size   int
+	TTL    time.Duration
+	Tables []string
+}
+
+// Apply modifies the DatabaseConnection so that it caches items as defined by this object
+func (w WithItemCache) Apply(conn *DatabaseConnection) {
+	conn.cache = newItemCache(w.Size, w.TTL, w.Tables...)
+}
@@ -0,0 +1,351 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MaxBatchStatements is the maximum number of PartiQL statements that DynamoDB allows in a single batch
+const MaxBatchStatements = 25
+
+// Helper variable containing the regular expression used to find the table referenced by a PartiQL statement
+var statementTableRegex = regexp.MustCompile(`(?i)\b(?:FROM|INTO|UPDATE)\s+"?([A-Za-z0-9_.\-]+)`)
+
+// NewStatement creates the input for an ExecuteStatement request from a PartiQL statement and the values
+// of its parameters. Each parameter will be marshalled into an attribute value, using the tag key set on
+// the connection, and bound to the corresponding ? placeholder in the statement. Parameters which are
+// already attribute values will be bound as-is. If the number of parameters does not match the number of
+// placeholders in the statement then an error will be returned
+func (conn *DatabaseConnection) NewStatement(statement string,
+	params ...interface{}) (*dynamodb.ExecuteStatementInput, error) {
+	values, err := conn.bindParameters(statement, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.ExecuteStatementInput{Statement: aws.String(statement), Parameters: values}, nil
+}
+
+// NewBatchStatement creates a request that can be sent with BatchExecute from a PartiQL statement and the
+// values of its parameters, which will be bound in the same way as they are by NewStatement
+func (conn *DatabaseConnection) NewBatchStatement(statement string,
+	params ...interface{}) (types.BatchStatementRequest, error) {
+	values, err := conn.bindParameters(statement, params...)
+	if err != nil {
+		return types.BatchStatementRequest{}, err
+	}
+
+	return types.BatchStatementRequest{Statement: aws.String(statement), Parameters: values}, nil
+}
+
+// NewParameterizedStatement creates a statement that can be sent with ExecuteTransaction from a PartiQL
+// statement and the values of its parameters, which will be bound in the same way as they are by NewStatement
+func (conn *DatabaseConnection) NewParameterizedStatement(statement string,
+	params ...interface{}) (types.ParameterizedStatement, error) {
+	values, err := conn.bindParameters(statement, params...)
+	if err != nil {
+		return types.ParameterizedStatement{}, err
+	}
+
+	return types.ParameterizedStatement{Statement: aws.String(statement), Parameters: values}, nil
+}
+
+// ExecuteStatement runs a PartiQL statement against DynamoDB and returns the items it produced. If
+// DynamoDB returns a next token then the statement will be executed again, with that token, until
+// every page of results has been retrieved. If the table referenced by the statement can't be determined
+// then an error will be returned without executing it. This function does not return capacity statistics,
+// just the items, although consumed capacity is recorded on the connection. Note that the input is copied
+// so it will not be modified
+func (conn *DatabaseConnection) ExecuteStatement(ctx context.Context,
+	input *dynamodb.ExecuteStatementInput) ([]map[string]types.AttributeValue, error) {
+	tableName, verb, err := conn.statementTable(aws.ToString(input.Statement))
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the input so that setting the capacity mode and next token doesn't affect the caller
+	copied := *input
+	copied.ReturnConsumedCapacity = conn.capacityMode(input.ReturnConsumedCapacity)
+	results := make([]map[string]types.AttributeValue, 0)
+
+	// We'll start a loop that will execute the statement for each page of results until all the pages
+	// have been retrieved
+	for index := 0; ; index++ {
+
+		// First, attempt to execute the statement with a backoff-retry loop
+		var output *dynamodb.ExecuteStatementOutput
+		err := conn.doRetry(ctx, tableName, fmt.Sprintf("%s(%d)", verb, index), func() error {
+			var inner error
+			if output, inner = conn.db.ExecuteStatement(ctx, &copied); inner == nil {
+				conn.recordCapacity(verb, capacityOf(output.ConsumedCapacity)...)
+			}
+
+			return inner
+		})
+
+		// If the statement failed then pass the error back up. Either way, remove any items it may have
+		// modified from the item cache
+		conn.cacheStatement(copied.Statement)
+		if err != nil {
+			return nil, err
+		}
+
+		// Next, append the items produced by the statement to our accumulated list of results
+		results = append(results, output.Items...)
+
+		// Finally, check if the next token is nil. If it is then we've retrieved every page so we can
+		// break out of the loop. Otherwise, we'll set it on the input so we can get the next page
+		if output.NextToken != nil {
+			copied.NextToken = output.NextToken
+		} else {
+			break
+		}
+	}
+
+	// Return the accumulated results
+	return results, nil
+}
+
+// ExecuteAs runs a PartiQL statement against DynamoDB and unmarshals the items it produced into a list
+// of objects of the type provided. Like ExecuteStatement, this function will retrieve every page of
+// results before returning
+func ExecuteAs[T any](ctx context.Context, conn *DatabaseConnection,
+	input *dynamodb.ExecuteStatementInput) ([]*T, error) {
+
+	// First, attempt to execute the statement; if this fails then return an error
+	items, err := conn.ExecuteStatement(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Next, attempt to unmarshal the items into our list of objects and return it
+	tableName, _ := describeStatement(aws.ToString(input.Statement))
+	return unmarshalList[T](conn, tableName, items)
+}
+
+// BatchExecute runs a number of PartiQL statements against DynamoDB. The statements will be chunked into
+// batches of no more than 25, which is the limit imposed by AWS, and each batch will be sent in turn. The
+// responses will be returned in the same order as the statements. Note that DynamoDB reports failures of
+// individual statements in their responses, rather than failing the request, so the Error field on each
+// response should be checked. Batches containing only SELECT statements will be throttled as reads, and
+// any other batch as writes. If the table referenced by any statement can't be determined then an error
+// will be returned without executing any of them. This function does not return capacity statistics, just
+// the responses, although consumed capacity is recorded on the connection
+func (conn *DatabaseConnection) BatchExecute(ctx context.Context,
+	statements ...types.BatchStatementRequest) ([]types.BatchStatementResponse, error) {
+
+	// First, create a description of each statement so that we know which tables they reference
+	operations := make([]*transactOperation, len(statements))
+	for i, statement := range statements {
+		tableName, verb, err := conn.statementTable(aws.ToString(statement.Statement))
+		if err != nil {
+			return nil, err
+		}
+
+		operations[i] = &transactOperation{tableName: tableName, verb: verb}
+	}
+
+	// Next, iterate over the statements in chunks, sending each in turn
+	responses := make([]types.BatchStatementResponse, 0, len(statements))
+	for start := 0; start < len(statements); start += MaxBatchStatements {
+
+		// First, get the next chunk of statements and determine whether they only read from their tables
+		end := start + MaxBatchStatements
+		if end > len(statements) {
+			end = len(statements)
+		}
+
+		chunk := statements[start:end]
+		verb := "BATCH SELECT"
+		for _, operation := range operations[start:end] {
+			if operation.verb != "SELECT" {
+				verb = "BATCH EXECUTE"
+				break
+			}
+		}
+
+		// Next, attempt to execute the chunk with a backoff-retry loop; if this fails then return an error
+		input := dynamodb.BatchExecuteStatementInput{
+			Statements:             chunk,
+			ReturnConsumedCapacity: conn.capacityMode(types.ReturnConsumedCapacityNone),
+		}
+
+		var output *dynamodb.BatchExecuteStatementOutput
+		err := conn.doRetry(ctx, transactionTableNames(operations[start:end]), verb, func() error {
+			var inner error
+			if output, inner = conn.db.BatchExecuteStatement(ctx, &input); inner == nil {
+				conn.recordCapacity(verb, output.ConsumedCapacity...)
+			}
+
+			return inner
+		})
+
+		for _, statement := range chunk {
+			conn.cacheStatement(statement.Statement)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Finally, add the responses to our list of responses
+		responses = append(responses, output.Responses...)
+	}
+
+	return responses, nil
+}
+
+// ExecuteTransaction atomically runs a number of PartiQL statements against DynamoDB and returns the
+// items produced by each statement, in the same order as the statements. If DynamoDB cancels the
+// transaction then a TransactionCanceledError will be returned, describing which of the statements
+// caused the cancellation and why
+func (conn *DatabaseConnection) ExecuteTransaction(ctx context.Context,
+	statements ...types.ParameterizedStatement) ([]map[string]types.AttributeValue, error) {
+
+	// First, create a description of each statement so that failures can be reported
+	operations := make([]*transactOperation, len(statements))
+	for i, statement := range statements {
+		tableName, verb, err := conn.statementTable(aws.ToString(statement.Statement))
+		if err != nil {
+			return nil, err
+		}
+
+		operations[i] = &transactOperation{tableName: tableName, verb: verb}
+	}
+
+	tableName := transactionTableNames(operations)
+	if err := validateTransactionSize(len(statements)); err != nil {
+		return nil, conn.NewError(err, tableName, "Failed to create transaction for %s", tableName)
+	}
+
+	// Next, attempt to retry the operation to execute the transaction; if this fails then convert
+	// the error so that any cancellation reasons are included
+	input := dynamodb.ExecuteTransactionInput{
+		TransactStatements:     statements,
+		ReturnConsumedCapacity: conn.capacityMode(types.ReturnConsumedCapacityNone),
+	}
+
+	var output *dynamodb.ExecuteTransactionOutput
+	err := conn.doRetry(ctx, tableName, "EXECUTE TRANSACTION", func() error {
+		var inner error
+		if output, inner = conn.db.ExecuteTransaction(ctx, &input); inner == nil {
+			conn.recordCapacity("EXECUTE TRANSACTION", output.ConsumedCapacity...)
+		}
+
+		return inner
+	})
+
+	for _, statement := range statements {
+		conn.cacheStatement(statement.Statement)
+	}
+
+	if err != nil {
+		return nil, fromTransactionError(err, operations)
+	}
+
+	// Finally, collect the items produced by each statement and return them
+	items := make([]map[string]types.AttributeValue, len(output.Responses))
+	for i, response := range output.Responses {
+		items[i] = response.Item
+	}
+
+	return items, nil
+}
+
+// Helper function that marshals the parameters of a PartiQL statement into attribute values. If the number
+// of parameters doesn't match the number of placeholders in the statement then an error will be returned
+func (conn *DatabaseConnection) bindParameters(statement string,
+	params ...interface{}) ([]types.AttributeValue, error) {
+
+	// First, check that we have a parameter for each placeholder in the statement
+	if count := countPlaceholders(statement); count != len(params) {
+		tableName, _ := describeStatement(statement)
+		return nil, conn.NewError(nil, tableName, "PartiQL statement has %d placeholders but %d parameters "+
+			"were provided", count, len(params))
+	}
+
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	values := make([]types.AttributeValue, len(params))
+	for i, param := range params {
+
+		// If the parameter is already an attribute value then bind it directly
+		if value, ok := param.(types.AttributeValue); ok {
+			values[i] = value
+			continue
+		}
+
+		// Otherwise, attempt to marshal the parameter; if this fails then return an error
+		value, err := attributevalue.MarshalWithOptions(param, conn.encoderOptions)
+		if err != nil {
+			tableName, _ := describeStatement(statement)
+			return nil, conn.NewError(err, tableName, "Failed to marshal parameter %d of PartiQL statement", i)
+		}
+
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// Helper function that determines the name of the table referenced by a PartiQL statement and a verb
+// describing the statement, as describeStatement does, returning an error if the table can't be determined.
+// Since the table is used to throttle the statement and to invalidate cached items, statements whose table
+// is unknown can't be executed safely
+func (conn *DatabaseConnection) statementTable(statement string) (string, string, error) {
+	tableName, verb := describeStatement(statement)
+	if tableName == "" {
+		return "", "", conn.NewError(nil, "", "Failed to determine the table referenced by PartiQL statement %q",
+			statement)
+	}
+
+	return tableName, verb, nil
+}
+
+// Helper function that determines the name of the table referenced by a PartiQL statement and a verb
+// describing the statement, which will be its first keyword (e.g. SELECT or INSERT). If the table can't
+// be determined then the table name will be empty
+func describeStatement(statement string) (string, string) {
+	fields := strings.Fields(statement)
+	verb := "EXECUTE"
+	if len(fields) > 0 {
+		verb = strings.ToUpper(fields[0])
+	}
+
+	tableName := ""
+	if matches := statementTableRegex.FindStringSubmatch(statement); matches != nil {
+		tableName = matches[1]
+	}
+
+	return tableName, verb
+}
+
+// Helper function that counts the ? placeholders in a PartiQL statement, ignoring any that appear within
+// string literals or quoted identifiers
+func countPlaceholders(statement string) int {
+	count := 0
+	var quote rune
+	for _, char := range statement {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == '?':
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,375 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PartiQL Tests", func() {
+
+	// Test that, if the statement has parameters, then NewStatement will bind them as attribute values
+	It("NewStatement - Parameters provided - Bound to statement", func() {
+
+		// First, create our test connection
+		conn := createMockConnection(&partiqlDynamoDBClient{})
+
+		// Next, create a statement with a string, a number, a list and an attribute value; this should not fail
+		input, err := conn.NewStatement(`SELECT * FROM "TEST_TABLE" WHERE id = ? AND data > ? AND tags = ? AND flag = ?`,
+			"test_id", 42, []string{"a", "b"}, &types.AttributeValueMemberBOOL{Value: true})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the statement and its parameters
+		Expect(*input.Statement).Should(Equal(`SELECT * FROM "TEST_TABLE" WHERE id = ? AND data > ? AND tags = ? AND flag = ?`))
+		Expect(input.Parameters).Should(Equal([]types.AttributeValue{
+			&types.AttributeValueMemberS{Value: "test_id"},
+			&types.AttributeValueMemberN{Value: "42"},
+			&types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "a"}, &types.AttributeValueMemberS{Value: "b"}}},
+			&types.AttributeValueMemberBOOL{Value: true}}))
+	})
+
+	// Test that, if a parameter cannot be marshalled, then NewStatement will return an error
+	It("NewStatement - Marshal fails - Error", func() {
+
+		// First, create our test connection
+		conn := createMockConnection(&partiqlDynamoDBClient{})
+
+		// Next, attempt to create a statement with a parameter that cannot be marshalled; this should fail
+		input, err := conn.NewStatement(`SELECT * FROM "TEST_TABLE" WHERE id = ?`, failingParameter{})
+
+		// Finally, verify the details of the error
+		casted := err.(*Error)
+		Expect(input).Should(BeNil())
+		Expect(casted.TableName).Should(Equal("TEST_TABLE"))
+		Expect(casted.Message).Should(Equal("Failed to marshal parameter 0 of PartiQL statement"))
+		Expect(casted.Inner.Error()).Should(Equal("parameter cannot be marshalled"))
+	})
+
+	// Test that, if the number of parameters doesn't match the number of placeholders in the statement, then
+	// NewStatement will return an error. Question marks within quotes should not be counted as placeholders
+	DescribeTable("NewStatement - Parameter count mismatch - Error",
+		func(statement string, params []interface{}, message string) {
+
+			// First, create our test connection
+			conn := createMockConnection(&partiqlDynamoDBClient{})
+
+			// Next, attempt to create the statement
+			input, err := conn.NewStatement(statement, params...)
+
+			// Finally, verify the statement or the details of the error
+			if message == "" {
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(input.Parameters).Should(HaveLen(len(params)))
+			} else {
+				Expect(input).Should(BeNil())
+				Expect(err.(*Error).TableName).Should(Equal("TEST_TABLE"))
+				Expect(err.(*Error).Message).Should(Equal(message))
+			}
+		},
+		Entry("Too few parameters - Error", `SELECT * FROM "TEST_TABLE" WHERE id = ? AND data = ?`,
+			[]interface{}{"test_id"}, "PartiQL statement has 2 placeholders but 1 parameters were provided"),
+		Entry("Too many parameters - Error", `SELECT * FROM "TEST_TABLE" WHERE id = ?`,
+			[]interface{}{"test_id", 1}, "PartiQL statement has 1 placeholders but 2 parameters were provided"),
+		Entry("No parameters - Error", `SELECT * FROM "TEST_TABLE" WHERE id = ?`,
+			[]interface{}{}, "PartiQL statement has 1 placeholders but 0 parameters were provided"),
+		Entry("Quoted question marks - Ignored", `SELECT * FROM "TEST_TABLE" WHERE id = '?' AND "what?" = ?`,
+			[]interface{}{"test_id"}, ""))
+
+	// Test that, if the table referenced by a statement can't be determined, then ExecuteStatement will
+	// return an error without executing it
+	It("ExecuteStatement - Table unknown - Error", func() {
+
+		// First, create our test connection with a client that records next tokens
+		client := &partiqlDynamoDBClient{pages: createTestPages(1, 2)}
+		conn := createMockConnection(client)
+
+		// Next, attempt to execute a statement that doesn't reference a table; this should fail
+		items, err := conn.ExecuteStatement(context.Background(),
+			&dynamodb.ExecuteStatementInput{Statement: aws.String(`EXISTS(SELECT)`)})
+
+		// Finally, verify the details of the error and that nothing was sent to DynamoDB
+		Expect(items).Should(BeNil())
+		Expect(err.(*Error).Message).Should(Equal(
+			`Failed to determine the table referenced by PartiQL statement "EXISTS(SELECT)"`))
+		Expect(client.tokens).Should(BeEmpty())
+	})
+
+	// Test that, if DynamoDB returns a next token, then ExecuteStatement will follow it until every page
+	// of results has been retrieved
+	It("ExecuteStatement - Multiple pages - All items returned", func() {
+
+		// First, create our test connection with a client that returns three pages of results
+		client := &partiqlDynamoDBClient{pages: createTestPages(3, 2)}
+		conn := createMockConnection(client)
+
+		// Next, attempt to execute the statement; this should not fail
+		input, err := conn.NewStatement(`SELECT * FROM "TEST_TABLE" WHERE id = ?`, "test_id")
+		Expect(err).ShouldNot(HaveOccurred())
+		items, err := conn.ExecuteStatement(context.Background(), input)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the items that were returned and the tokens that were sent
+		Expect(items).Should(HaveLen(6))
+		for i, item := range items {
+			Expect(item["data"]).Should(Equal(&types.AttributeValueMemberN{Value: strconv.Itoa(i)}))
+		}
+
+		Expect(client.tokens).Should(Equal([]string{"", "1", "2"}))
+	})
+
+	// Test that ExecuteStatement does not modify its input, so that the same input can be executed again
+	// and will start from the first page of results
+	It("ExecuteStatement - Input reused - Input unchanged", func() {
+
+		// First, create our test connection with a client that returns two pages of results
+		client := &partiqlDynamoDBClient{pages: createTestPages(2, 2)}
+		conn := createMockConnection(client)
+
+		// Next, attempt to execute the same statement twice; neither should fail
+		input, err := conn.NewStatement(`SELECT * FROM "TEST_TABLE" WHERE id = ?`, "test_id")
+		Expect(err).ShouldNot(HaveOccurred())
+		first, err := conn.ExecuteStatement(context.Background(), input)
+		Expect(err).ShouldNot(HaveOccurred())
+		second, err := conn.ExecuteStatement(context.Background(), input)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that both executions started from the first page and that the input is unchanged
+		Expect(second).Should(Equal(first))
+		Expect(client.tokens).Should(Equal([]string{"", "1", "", "1"}))
+		Expect(input.NextToken).Should(BeNil())
+		Expect(input.ReturnConsumedCapacity).Should(BeEmpty())
+	})
+
+	// Test that, if DynamoDB returns an error, then ExecuteStatement will return it
+	It("ExecuteStatement - Request fails - Error", func() {
+
+		// First, create our test connection with a client that fails every statement
+		conn := createMockConnection(&partiqlDynamoDBClient{fail: true})
+
+		// Next, attempt to execute the statement; this should fail
+		items, err := conn.ExecuteStatement(context.Background(),
+			&dynamodb.ExecuteStatementInput{Statement: aws.String(`SELECT * FROM "TEST_TABLE"`)})
+
+		// Finally, verify the details of the error
+		casted := err.(*Error)
+		Expect(items).Should(BeNil())
+		Expect(casted.TableName).Should(Equal("TEST_TABLE"))
+		Expect(casted.Message).Should(Equal("SELECT(0) request to TEST_TABLE in DynamoDB failed"))
+	})
+
+	// Test that ExecuteAs will unmarshal the items returned by the statement into typed objects
+	It("ExecuteAs - Multiple pages - Objects returned", func() {
+
+		// First, create our test connection with a client that returns two pages of results
+		conn := createMockConnection(&partiqlDynamoDBClient{pages: createTestPages(2, 2)})
+
+		// Next, attempt to execute the statement as test objects; this should not fail
+		objs, err := ExecuteAs[testObject](context.Background(), conn,
+			&dynamodb.ExecuteStatementInput{Statement: aws.String(`SELECT * FROM "TEST_TABLE"`)})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the objects that were returned
+		Expect(objs).Should(HaveLen(4))
+		for i, obj := range objs {
+			Expect(obj.ID).Should(Equal("test_id"))
+			Expect(obj.SortKey).Should(Equal("test|sort|key"))
+			Expect(obj.Data).Should(Equal(i))
+		}
+	})
+
+	// Test that BatchExecute will split the statements into chunks that DynamoDB will accept and return
+	// the responses in the same order as the statements
+	It("BatchExecute - Many statements - Chunked and responses returned", func() {
+
+		// First, create our test connection with a client that records batches
+		client := &partiqlDynamoDBClient{}
+		conn := createMockConnection(client)
+
+		// Next, create more statements than can be sent in a single batch
+		statements := make([]types.BatchStatementRequest, 30)
+		for i := range statements {
+			statement, err := conn.NewBatchStatement(`UPDATE "TEST_TABLE" SET data = ? WHERE id = ?`,
+				i, fmt.Sprintf("test_id|%d", i))
+			Expect(err).ShouldNot(HaveOccurred())
+			statements[i] = statement
+		}
+
+		// Now, attempt to execute the statements; this should not fail
+		responses, err := conn.BatchExecute(context.Background(), statements...)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the batches that were sent and the responses that were returned
+		Expect(client.batches).Should(Equal([]int{25, 5}))
+		Expect(responses).Should(HaveLen(30))
+		for i, response := range responses {
+			Expect(response.TableName).Should(Equal(aws.String("TEST_TABLE")))
+			Expect(response.Item).Should(Equal(createBatchItem(i)))
+		}
+	})
+
+	// Test that, if every statement in a batch is a SELECT statement, then BatchExecute will treat the batch
+	// as a read so that it is throttled, and its consumed capacity is recorded, as a read
+	It("BatchExecute - Only SELECT statements - Treated as read", func() {
+
+		// First, create our test connection with a client that records batches
+		conn := createMockConnection(&partiqlDynamoDBClient{})
+
+		// Next, create some SELECT statements and execute them; this should not fail
+		statements := make([]types.BatchStatementRequest, 3)
+		for i := range statements {
+			statement, err := conn.NewBatchStatement(`SELECT * FROM "TEST_TABLE" WHERE data = ?`, i)
+			Expect(err).ShouldNot(HaveOccurred())
+			statements[i] = statement
+		}
+
+		_, err := conn.BatchExecute(context.Background(), statements...)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that the consumed capacity was recorded as a read
+		Expect(conn.ConsumedCapacity().Get("TEST_TABLE", "", "BATCH SELECT")).Should(Equal(
+			CapacityUnits{Requests: 1, Read: 3, Total: 3}))
+		Expect(isReadVerb("BATCH SELECT")).Should(BeTrue())
+	})
+
+	// Test that, if DynamoDB cancels the transaction, then ExecuteTransaction will return an error that
+	// describes which statements caused the cancellation
+	It("ExecuteTransaction - Cancelled - Reasons returned", func() {
+
+		// First, create our test connection with a client that cancels transactions
+		client := &partiqlDynamoDBClient{reasons: []types.CancellationReason{
+			{Code: aws.String("None")},
+			{Code: aws.String("ConditionalCheckFailed"), Message: aws.String("The conditional request failed")}}}
+		conn := createMockConnection(client)
+
+		// Next, create a transaction with two statements against different tables
+		insert, err := conn.NewParameterizedStatement(`INSERT INTO "TABLE_A" VALUE {'id': ?}`, "test_id")
+		Expect(err).ShouldNot(HaveOccurred())
+		remove, err := conn.NewParameterizedStatement(`DELETE FROM TABLE_B WHERE id = ?`, "test_id")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, attempt to execute the transaction; this should fail
+		items, err := conn.ExecuteTransaction(context.Background(), insert, remove)
+		Expect(items).Should(BeNil())
+
+		// Finally, verify the details of the error
+		casted := err.(*TransactionCanceledError)
+		Expect(casted.TableName).Should(Equal("TABLE_A, TABLE_B"))
+		Expect(casted.Message).Should(Equal("EXECUTE TRANSACTION request to TABLE_A, TABLE_B in DynamoDB failed"))
+		Expect(casted.Reasons).Should(Equal([]*CancellationReason{
+			{Index: 1, TableName: "TABLE_B", Operation: "DELETE", Code: "ConditionalCheckFailed",
+				Message: "The conditional request failed"}}))
+	})
+
+	// Test that, if the transaction succeeds, then ExecuteTransaction will return the item produced by
+	// each of the statements
+	It("ExecuteTransaction - No failures - Items returned", func() {
+
+		// First, create our test connection with a client that returns an item for each statement
+		conn := createMockConnection(&partiqlDynamoDBClient{})
+
+		// Next, create a transaction with two statements
+		statements := make([]types.ParameterizedStatement, 2)
+		for i := range statements {
+			statement, err := conn.NewParameterizedStatement(`SELECT * FROM "TEST_TABLE" WHERE id = ?`,
+				fmt.Sprintf("test_id|%d", i))
+			Expect(err).ShouldNot(HaveOccurred())
+			statements[i] = statement
+		}
+
+		// Now, attempt to execute the transaction; this should not fail
+		items, err := conn.ExecuteTransaction(context.Background(), statements...)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the items that were returned
+		Expect(items).Should(Equal([]map[string]types.AttributeValue{createBatchItem(0), createBatchItem(1)}))
+	})
+})
+
+// Helper type that fails to marshal itself so that parameter binding failures can be tested
+type failingParameter struct{}
+
+// MarshalDynamoDBAttributeValue always returns an error
+func (param failingParameter) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	return nil, fmt.Errorf("parameter cannot be marshalled")
+}
+
+// Helper type that executes PartiQL statements by returning canned pages, recording the next tokens
+// and batches it receives, so that the PartiQL functions can be tested without a DynamoDB instance
+type partiqlDynamoDBClient struct {
+	DynamoDBAPI
+	fail    bool
+	pages   [][]map[string]types.AttributeValue
+	reasons []types.CancellationReason
+	tokens  []string
+	batches []int
+}
+
+// Mocks out the ExecuteStatement function so that it returns the page associated with the next token
+func (client *partiqlDynamoDBClient) ExecuteStatement(ctx context.Context, params *dynamodb.ExecuteStatementInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteStatementOutput, error) {
+	if client.fail {
+		return nil, &smithy.OperationError{ServiceID: "DynamoDB", OperationName: "ExecuteStatement",
+			Err: &types.ResourceNotFoundException{Message: aws.String("Requested resource not found")}}
+	}
+
+	token := aws.ToString(params.NextToken)
+	client.tokens = append(client.tokens, token)
+
+	index, _ := strconv.Atoi(token)
+	output := dynamodb.ExecuteStatementOutput{Items: client.pages[index]}
+	if index+1 < len(client.pages) {
+		output.NextToken = aws.String(strconv.Itoa(index + 1))
+	}
+
+	return &output, nil
+}
+
+// Mocks out the BatchExecuteStatement function so that it records the size of the batch and returns
+// an item for each statement, based on the value of its first parameter
+func (client *partiqlDynamoDBClient) BatchExecuteStatement(ctx context.Context,
+	params *dynamodb.BatchExecuteStatementInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchExecuteStatementOutput, error) {
+	client.batches = append(client.batches, len(params.Statements))
+	responses := make([]types.BatchStatementResponse, len(params.Statements))
+	for i, statement := range params.Statements {
+		index, _ := strconv.Atoi(statement.Parameters[0].(*types.AttributeValueMemberN).Value)
+		responses[i] = types.BatchStatementResponse{TableName: aws.String("TEST_TABLE"), Item: createBatchItem(index)}
+	}
+
+	return &dynamodb.BatchExecuteStatementOutput{Responses: responses, ConsumedCapacity: []types.ConsumedCapacity{
+		{TableName: aws.String("TEST_TABLE"), CapacityUnits: aws.Float64(float64(len(params.Statements)))}}}, nil
+}
+
+// Mocks out the ExecuteTransaction function so that, if the client has cancellation reasons, it returns
+// a cancellation error. Otherwise, it returns an item for each statement, based on its first parameter
+func (client *partiqlDynamoDBClient) ExecuteTransaction(ctx context.Context,
+	params *dynamodb.ExecuteTransactionInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteTransactionOutput, error) {
+	if client.reasons != nil {
+		return nil, &smithy.OperationError{
+			ServiceID:     "DynamoDB",
+			OperationName: "ExecuteTransaction",
+			Err: &types.TransactionCanceledException{
+				Message:             aws.String("Transaction cancelled"),
+				CancellationReasons: client.reasons,
+			},
+		}
+	}
+
+	responses := make([]types.ItemResponse, len(params.TransactStatements))
+	for i, statement := range params.TransactStatements {
+		var index int
+		fmt.Sscanf(statement.Parameters[0].(*types.AttributeValueMemberS).Value, "test_id|%d", &index)
+		responses[i].Item = createBatchItem(index)
+	}
+
+	return &dynamodb.ExecuteTransactionOutput{Responses: responses}, nil
+}
@@ -0,0 +1,136 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// QueryIterator allows the results of a DynamoDB query to be read one page at a time so that large
+// partitions do not need to be buffered in memory. The iterator may be stopped at any time and the
+// value returned by Cursor can be set as the exclusive start key on a new query to resume it
+type QueryIterator struct {
+	conn   *DatabaseConnection
+	input  dynamodb.QueryInput
+	limit  int
+	read   int
+	index  int
+	done   bool
+	cursor map[string]types.AttributeValue
+}
+
+// NewQueryIterator creates a new iterator over the results of the query described by the input. If
+// limit is greater than zero then no more than that many items will be returned by the iterator. If
+// the input has an exclusive start key then the iterator will begin reading from that key. Note that
+// the input is copied so it will not be modified by the iterator
+func (conn *DatabaseConnection) NewQueryIterator(input *dynamodb.QueryInput, limit int) *QueryIterator {
+	iter := QueryIterator{
+		conn:   conn,
+		input:  *input,
+		limit:  limit,
+		cursor: input.ExclusiveStartKey,
+	}
+
+	iter.input.ReturnConsumedCapacity = conn.capacityMode(input.ReturnConsumedCapacity)
+	return &iter
+}
+
+// HasNext returns true if there are more pages of results that can be read from the iterator
+func (iter *QueryIterator) HasNext() bool {
+	return !iter.done
+}
+
+// Cursor returns the last-evaluated key from the most recent page read by the iterator. This value
+// may be set as the exclusive start key on another query to resume reading where this iterator left
+// off. If the query has been read to completion then nil will be returned
+func (iter *QueryIterator) Cursor() map[string]types.AttributeValue {
+	return iter.cursor
+}
+
+// Read returns the number of items that have been read by the iterator so far
+func (iter *QueryIterator) Read() int {
+	return iter.read
+}
+
+// NextPage retrieves the next page of results from DynamoDB. If the iterator has already been read
+// to completion then this function will return no items. If an item limit was set on the iterator
+// then the page size will be adjusted so that no more than the remaining number of items are read,
+// ensuring that the cursor always points to the last item returned
+func (iter *QueryIterator) NextPage(ctx context.Context) ([]map[string]types.AttributeValue, error) {
+
+	// First, check if we've already finished reading; if we have then return nothing
+	if iter.done {
+		return nil, nil
+	}
+
+	// Next, set the exclusive start key from our cursor and, if we have a limit, ensure that the
+	// page size won't cause us to read more items than we've been asked for
+	iter.input.ExclusiveStartKey = iter.cursor
+	if iter.limit > 0 {
+		if remaining := int32(iter.limit - iter.read); iter.input.Limit == nil || *iter.input.Limit > remaining {
+			iter.input.Limit = aws.Int32(remaining)
+		}
+	}
+
+	// Now, attempt the query with a backoff-retry loop; if this fails then return an error
+	var output *dynamodb.QueryOutput
+	err := iter.conn.doRetry(ctx, *iter.input.TableName, fmt.Sprintf("QUERY(%d)", iter.index), func() error {
+		var inner error
+		if output, inner = iter.conn.db.Query(ctx, &iter.input); inner == nil {
+			iter.conn.recordCapacity("QUERY", capacityOf(output.ConsumedCapacity)...)
+		}
+
+		return inner
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Finally, update the state of the iterator from the output. We're done if there's no
+	// last-evaluated key or if we've read as many items as we were asked for
+	iter.index++
+	iter.read += len(output.Items)
+	iter.cursor = output.LastEvaluatedKey
+	iter.done = output.LastEvaluatedKey == nil || (iter.limit > 0 && iter.read >= iter.limit)
+	return output.Items, nil
+}
+
+// QueryPages makes a search on a DynamoDB table, calling the handler with each page of results as it
+// is retrieved, rather than accumulating all of them. If limit is greater than zero then no more than
+// that many items will be sent to the handler. The handler may return false to stop the query early
+// or an error, which will be returned by this function. The cursor of the last page read will be
+// returned so that the query can be resumed by setting it as the exclusive start key on the input.
+// A nil cursor indicates that the query was read to completion
+func (conn *DatabaseConnection) QueryPages(ctx context.Context, input *dynamodb.QueryInput, limit int,
+	handler func(context.Context, []map[string]types.AttributeValue) (bool, error)) (
+	map[string]types.AttributeValue, error) {
+
+	// Create an iterator from our input and iterate over all the pages it returns
+	iter := conn.NewQueryIterator(input, limit)
+	for iter.HasNext() {
+
+		// First, check whether cancellation has been requested; if it has then return the cursor
+		// we have now, along with the cancellation error
+		if err := ctx.Err(); err != nil {
+			return iter.Cursor(), err
+		}
+
+		// Next, attempt to read the next page from the iterator; if this fails then return an error
+		page, err := iter.NextPage(ctx)
+		if err != nil {
+			return iter.Cursor(), err
+		}
+
+		// Finally, send the page to the handler. If the handler fails or asks us to stop then
+		// return the cursor so the caller can resume from this point
+		if cont, err := handler(ctx, page); err != nil || !cont {
+			return iter.Cursor(), err
+		}
+	}
+
+	return iter.Cursor(), nil
+}
@@ -0,0 +1,181 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Query Iterator Tests", func() {
+
+	// Test that, if no limit is provided, then the iterator will read every page of results
+	It("NewQueryIterator - No limit - All pages read", func() {
+
+		// First, create our test connection with a client that returns pages of four items
+		client := &listDynamoDBClient{items: createTestPages(1, 10)[0], pageSize: 4}
+		conn := createMockConnection(client)
+
+		// Next, create an iterator over the query and read all the pages from it
+		input := dynamodb.QueryInput{TableName: aws.String("TEST_TABLE")}
+		iter := conn.NewQueryIterator(&input, 0)
+		sizes := make([]int, 0)
+		for iter.HasNext() {
+			page, err := iter.NextPage(context.Background())
+			Expect(err).ShouldNot(HaveOccurred())
+			sizes = append(sizes, len(page))
+		}
+
+		// Finally, verify the pages we read, that the cursor is empty and that the input was not modified
+		Expect(sizes).Should(Equal([]int{4, 4, 2}))
+		Expect(iter.Read()).Should(Equal(10))
+		Expect(iter.Cursor()).Should(BeNil())
+		Expect(input.ExclusiveStartKey).Should(BeNil())
+		Expect(input.Limit).Should(BeNil())
+	})
+
+	// Test that, if a limit is provided, then the iterator will stop once the limit has been reached
+	// and the cursor will point to the last item that was read
+	It("NewQueryIterator - Limit provided - Stops at limit", func() {
+
+		// First, create our test connection with a client that returns pages of four items
+		client := &listDynamoDBClient{items: createTestPages(1, 10)[0], pageSize: 4}
+		conn := createMockConnection(client)
+
+		// Next, create an iterator over the query with a limit and read all the pages from it
+		iter := conn.NewQueryIterator(&dynamodb.QueryInput{TableName: aws.String("TEST_TABLE")}, 6)
+		sizes := make([]int, 0)
+		for iter.HasNext() {
+			page, err := iter.NextPage(context.Background())
+			Expect(err).ShouldNot(HaveOccurred())
+			sizes = append(sizes, len(page))
+		}
+
+		// Finally, verify the pages we read and the cursor that was returned
+		Expect(sizes).Should(Equal([]int{4, 2}))
+		Expect(client.limits).Should(Equal([]int32{6, 2}))
+		Expect(iter.Cursor()).Should(Equal(map[string]types.AttributeValue{
+			"index": &types.AttributeValueMemberN{Value: "6"}}))
+	})
+
+	// Test that the cursor returned by QueryPages can be used to resume the query
+	It("QueryPages - Stopped early - Resumed from cursor", func() {
+
+		// First, create our test connection with a client that returns pages of three items
+		client := &listDynamoDBClient{items: createTestPages(1, 8)[0], pageSize: 3}
+		conn := createMockConnection(client)
+
+		// Next, query the table but stop after the first page
+		input := dynamodb.QueryInput{TableName: aws.String("TEST_TABLE")}
+		read := make([]map[string]types.AttributeValue, 0)
+		cursor, err := conn.QueryPages(context.Background(), &input, 0,
+			func(_ context.Context, page []map[string]types.AttributeValue) (bool, error) {
+				read = append(read, page...)
+				return false, nil
+			})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(read).Should(HaveLen(3))
+
+		// Now, resume the query from the cursor and read the remaining pages
+		input.ExclusiveStartKey = cursor
+		cursor, err = conn.QueryPages(context.Background(), &input, 0,
+			func(_ context.Context, page []map[string]types.AttributeValue) (bool, error) {
+				read = append(read, page...)
+				return true, nil
+			})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that all the items were read exactly once
+		Expect(cursor).Should(BeNil())
+		Expect(read).Should(Equal(client.items))
+	})
+
+	// Test that, if the handler returns an error, then QueryPages will return it
+	It("QueryPages - Handler fails - Error", func() {
+
+		// First, create our test connection with a client that returns pages of three items
+		conn := createMockConnection(&listDynamoDBClient{items: createTestPages(1, 8)[0], pageSize: 3})
+
+		// Next, query the table with a handler that fails
+		cursor, err := conn.QueryPages(context.Background(),
+			&dynamodb.QueryInput{TableName: aws.String("TEST_TABLE")}, 0,
+			func(_ context.Context, page []map[string]types.AttributeValue) (bool, error) {
+				return true, errors.New("derp")
+			})
+
+		// Finally, verify the error and the cursor
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(Equal("derp"))
+		Expect(cursor).Should(Equal(map[string]types.AttributeValue{
+			"index": &types.AttributeValueMemberN{Value: "3"}}))
+	})
+
+	// Test that, if the context has been cancelled, then QueryPages will not read any more pages
+	It("QueryPages - Cancelled - Error", func() {
+
+		// First, create our test connection and a context that has already been cancelled
+		client := &listDynamoDBClient{items: createTestPages(1, 8)[0], pageSize: 3}
+		conn := createMockConnection(client)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Next, attempt to query the table; this should fail
+		_, err := conn.QueryPages(ctx, &dynamodb.QueryInput{TableName: aws.String("TEST_TABLE")}, 0,
+			func(_ context.Context, page []map[string]types.AttributeValue) (bool, error) {
+				return true, nil
+			})
+
+		// Finally, verify that the error was returned and that no requests were made
+		Expect(err).Should(Equal(context.Canceled))
+		Expect(client.calls).Should(BeZero())
+	})
+})
+
+// Helper type that serves a list of items in pages, respecting the limit and exclusive start key
+// on each request, so that pagination can be tested without a DynamoDB instance
+type listDynamoDBClient struct {
+	DynamoDBAPI
+	items    []map[string]types.AttributeValue
+	pageSize int32
+	limits   []int32
+	calls    int
+}
+
+// Mocks out the Query function so that it returns the next page of items
+func (client *listDynamoDBClient) Query(ctx context.Context, params *dynamodb.QueryInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+
+	// First, determine where the page starts and how large it should be
+	client.calls++
+	start := 0
+	if params.ExclusiveStartKey != nil {
+		start, _ = strconv.Atoi(params.ExclusiveStartKey["index"].(*types.AttributeValueMemberN).Value)
+	}
+
+	size := client.pageSize
+	if params.Limit != nil {
+		client.limits = append(client.limits, *params.Limit)
+		if *params.Limit < size {
+			size = *params.Limit
+		}
+	}
+
+	// Next, get the items in the page
+	end := start + int(size)
+	if end > len(client.items) {
+		end = len(client.items)
+	}
+
+	// Finally, if there are more items after the page then create a last-evaluated key pointing to them
+	var last map[string]types.AttributeValue
+	if end < len(client.items) {
+		last = map[string]types.AttributeValue{"index": &types.AttributeValueMemberN{Value: strconv.Itoa(end)}}
+	}
+
+	return &dynamodb.QueryOutput{Items: client.items[start:end], LastEvaluatedKey: last}, nil
+}
@@ -0,0 +1,419 @@
+package dynamodb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// QueryBuilder builds the input for a DynamoDB query from a partition key, an optional sort key condition
+// and other query settings so that key condition expressions don't need to be written by hand. Every
+// attribute name and value referenced by the key condition and projection is replaced with a placeholder,
+// prefixed with "#q" or ":q", so placeholders with these prefixes should not be used in filter expressions.
+// Key values may be any Go value that can be marshalled into a string, number or binary attribute, or an
+// attribute value. If the builder has a table definition then the keys will be checked against the key
+// schema of the table or index being queried when the input is built
+type QueryBuilder struct {
+	tableName  string
+	indexName  string
+	schema     *TableDefinition
+	partition  *queryKey
+	sort       *queryKey
+	filter     string
+	names      map[string]string
+	values     map[string]types.AttributeValue
+	projection []string
+	forward    *bool
+	limit      *int32
+	consistent bool
+	errs       []error
+}
+
+// Helper type that describes a condition on a key attribute in a query
+type queryKey struct {
+	name     string
+	operator string
+	values   []types.AttributeValue
+}
+
+// NewQuery creates a new query builder for the table with the name provided
+func NewQuery(tableName string) *QueryBuilder {
+	return &QueryBuilder{
+		tableName: tableName,
+		names:     make(map[string]string),
+		values:    make(map[string]types.AttributeValue),
+		errs:      make([]error, 0),
+	}
+}
+
+// NewQueryFor creates a new query builder for the table with the name provided, checking the keys used in
+// the query against the key schema described by the schema tags on the type provided. See TableDefinition
+// for more information on how the schema tags are interpreted
+func NewQueryFor[T any](tableName string, opts ...ITableOption) *QueryBuilder {
+	builder := NewQuery(tableName)
+	def, err := NewTableDefinition[T](tableName, opts...)
+	if err != nil {
+		builder.errs = append(builder.errs, err)
+	}
+
+	builder.schema = def
+	return builder
+}
+
+// Index sets the name of the secondary index that should be queried, rather than the table
+func (builder *QueryBuilder) Index(indexName string) *QueryBuilder {
+	builder.indexName = indexName
+	return builder
+}
+
+// PartitionKey sets the name and value of the partition key of the items that should be queried
+func (builder *QueryBuilder) PartitionKey(name string, value interface{}) *QueryBuilder {
+	builder.partition = builder.key(name, "=", value)
+	return builder
+}
+
+// SortKeyEquals adds a condition to the query requiring that the sort key is equal to the value provided
+func (builder *QueryBuilder) SortKeyEquals(name string, value interface{}) *QueryBuilder {
+	return builder.setSortKey(name, "=", value)
+}
+
+// SortKeyLessThan adds a condition to the query requiring that the sort key is less than the value provided
+func (builder *QueryBuilder) SortKeyLessThan(name string, value interface{}) *QueryBuilder {
+	return builder.setSortKey(name, "<", value)
+}
+
+// SortKeyLessThanOrEqual adds a condition to the query requiring that the sort key is less than, or equal
+// to, the value provided
+func (builder *QueryBuilder) SortKeyLessThanOrEqual(name string, value interface{}) *QueryBuilder {
+	return builder.setSortKey(name, "<=", value)
+}
+
+// SortKeyGreaterThan adds a condition to the query requiring that the sort key is greater than the value provided
+func (builder *QueryBuilder) SortKeyGreaterThan(name string, value interface{}) *QueryBuilder {
+	return builder.setSortKey(name, ">", value)
+}
+
+// SortKeyGreaterThanOrEqual adds a condition to the query requiring that the sort key is greater than, or
+// equal to, the value provided
+func (builder *QueryBuilder) SortKeyGreaterThanOrEqual(name string, value interface{}) *QueryBuilder {
+	return builder.setSortKey(name, ">=", value)
+}
+
+// SortKeyBetween adds a condition to the query requiring that the sort key is between the low and high
+// values provided, inclusive
+func (builder *QueryBuilder) SortKeyBetween(name string, low interface{}, high interface{}) *QueryBuilder {
+	return builder.setSortKey(name, "BETWEEN", low, high)
+}
+
+// SortKeyBeginsWith adds a condition to the query requiring that the sort key begins with the prefix
+// provided. The sort key must be a string or binary attribute
+func (builder *QueryBuilder) SortKeyBeginsWith(name string, prefix interface{}) *QueryBuilder {
+	return builder.setSortKey(name, "begins_with", prefix)
+}
+
+// Filter sets the filter expression that will be applied to the items read by the query, along with the
+// attribute names and values it references. Items that do not match the filter will not be returned but
+// will still consume read capacity
+func (builder *QueryBuilder) Filter(expression string, names map[string]string,
+	values map[string]types.AttributeValue) *QueryBuilder {
+	builder.filter = expression
+	for placeholder, name := range names {
+		builder.names[placeholder] = name
+	}
+
+	for placeholder, value := range values {
+		builder.values[placeholder] = value
+	}
+
+	return builder
+}
+
+// Project sets the names of the attributes that should be returned for each item. If this is not called
+// then all the attributes projected into the table or index will be returned
+func (builder *QueryBuilder) Project(names ...string) *QueryBuilder {
+	builder.projection = append(builder.projection, names...)
+	return builder
+}
+
+// Ascending requests that the items be returned in ascending order of their sort key, which is the default
+func (builder *QueryBuilder) Ascending() *QueryBuilder {
+	builder.forward = aws.Bool(true)
+	return builder
+}
+
+// Descending requests that the items be returned in descending order of their sort key
+func (builder *QueryBuilder) Descending() *QueryBuilder {
+	builder.forward = aws.Bool(false)
+	return builder
+}
+
+// Limit sets the maximum number of items that DynamoDB will evaluate for each page of the query
+func (builder *QueryBuilder) Limit(limit int32) *QueryBuilder {
+	builder.limit = aws.Int32(limit)
+	return builder
+}
+
+// ConsistentRead requests that the query use strongly-consistent reads. This cannot be used when querying
+// a global secondary index
+func (builder *QueryBuilder) ConsistentRead() *QueryBuilder {
+	builder.consistent = true
+	return builder
+}
+
+// Build validates the query and creates the input for a Query request from the builder, which may then be
+// sent with DatabaseConnection.Query or any of the other query functions. An error will be returned if a key
+// value could not be marshalled, no partition key was provided, a sort key condition has values of the wrong
+// type or the keys do not match the key schema of the table or index being queried
+func (builder *QueryBuilder) Build() (*dynamodb.QueryInput, error) {
+
+	// First, check that the builder is valid; if it isn't then return an error
+	if len(builder.errs) > 0 {
+		return nil, builder.errs[0]
+	} else if err := builder.validate(); err != nil {
+		return nil, err
+	}
+
+	// Next, create our input from the settings on the builder
+	input := dynamodb.QueryInput{
+		TableName:        aws.String(builder.tableName),
+		ScanIndexForward: builder.forward,
+		Limit:            builder.limit,
+	}
+
+	if builder.indexName != "" {
+		input.IndexName = aws.String(builder.indexName)
+	}
+
+	if builder.consistent {
+		input.ConsistentRead = aws.Bool(true)
+	}
+
+	if builder.filter != "" {
+		input.FilterExpression = aws.String(builder.filter)
+	}
+
+	// Now, create the key condition expression from the partition key and sort key condition, and the
+	// projection expression from the projected attributes
+	names := make(map[string]string)
+	values := make(map[string]types.AttributeValue)
+	conditions := []string{builder.partition.expression(names, values)}
+	if builder.sort != nil {
+		conditions = append(conditions, builder.sort.expression(names, values))
+	}
+
+	input.KeyConditionExpression = aws.String(strings.Join(conditions, " AND "))
+	if len(builder.projection) > 0 {
+		projected := make([]string, len(builder.projection))
+		for i, name := range builder.projection {
+			projected[i] = namePlaceholder(name, names)
+		}
+
+		input.ProjectionExpression = aws.String(strings.Join(projected, ", "))
+	}
+
+	// Finally, add the attribute names and values from the filter to those from the key condition and
+	// set them on the input
+	for placeholder, name := range builder.names {
+		names[placeholder] = name
+	}
+
+	for placeholder, value := range builder.values {
+		values[placeholder] = value
+	}
+
+	input.ExpressionAttributeNames = names
+	input.ExpressionAttributeValues = values
+	return &input, nil
+}
+
+// Helper function that sets the sort key condition on the builder
+func (builder *QueryBuilder) setSortKey(name string, operator string, values ...interface{}) *QueryBuilder {
+	builder.sort = builder.key(name, operator, values...)
+	return builder
+}
+
+// Helper function that creates a key condition from a name, operator and values, marshalling each value
+// into an attribute value. If a value cannot be marshalled then the error will be recorded on the builder
+func (builder *QueryBuilder) key(name string, operator string, values ...interface{}) *queryKey {
+	key := queryKey{name: name, operator: operator, values: make([]types.AttributeValue, len(values))}
+	for i, value := range values {
+
+		// If the value is already an attribute value then use it directly
+		if attr, ok := value.(types.AttributeValue); ok {
+			key.values[i] = attr
+			continue
+		}
+
+		// Otherwise, attempt to marshal the value; if this fails then record the error
+		attr, err := attributevalue.Marshal(value)
+		if err == nil && attr == nil {
+			err = fmt.Errorf("value of type %T could not be marshalled", value)
+		}
+
+		if err != nil {
+			builder.errs = append(builder.errs, fmt.Errorf("key %s: %v", name, err))
+		}
+
+		key.values[i] = attr
+	}
+
+	return &key
+}
+
+// Helper function that checks that the query has a partition key and that its key conditions are valid
+// and, if the builder has a table definition, match the key schema of the table or index being queried
+func (builder *QueryBuilder) validate() error {
+
+	// First, check that we have a partition key and that the values of the key conditions have the
+	// correct types for their operators
+	if builder.partition == nil {
+		return fmt.Errorf("query on %s has no partition key", builder.tableName)
+	} else if builder.limit != nil && *builder.limit <= 0 {
+		return fmt.Errorf("query limit must be positive but was %d", *builder.limit)
+	}
+
+	keys := []*queryKey{builder.partition}
+	if builder.sort != nil {
+		keys = append(keys, builder.sort)
+	}
+
+	for _, key := range keys {
+		if err := key.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Next, if we don't have a table definition then there's nothing more to check
+	if builder.schema == nil {
+		return nil
+	}
+
+	// Now, get the key schema of the table or the index being queried; if the index doesn't exist on the
+	// table then return an error
+	schema, found := builder.schema.Input.KeySchema, builder.indexName == ""
+	for _, index := range builder.schema.Input.GlobalSecondaryIndexes {
+		if aws.ToString(index.IndexName) == builder.indexName {
+			schema, found = index.KeySchema, true
+			if builder.consistent {
+				return fmt.Errorf("consistent reads are not supported on global secondary index %s", builder.indexName)
+			}
+		}
+	}
+
+	for _, index := range builder.schema.Input.LocalSecondaryIndexes {
+		if aws.ToString(index.IndexName) == builder.indexName {
+			schema, found = index.KeySchema, true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("index %s is not defined on %s", builder.indexName, builder.tableName)
+	}
+
+	// Finally, check that the keys on the query match the key schema and that their values have the
+	// types of the key attributes
+	var hash, rangeKey string
+	for _, element := range schema {
+		if element.KeyType == types.KeyTypeHash {
+			hash = aws.ToString(element.AttributeName)
+		} else {
+			rangeKey = aws.ToString(element.AttributeName)
+		}
+	}
+
+	if builder.partition.name != hash {
+		return fmt.Errorf("partition key of %s is %s but %s was provided", builder.target(), hash, builder.partition.name)
+	} else if builder.sort != nil && rangeKey == "" {
+		return fmt.Errorf("%s has no sort key but a condition on %s was provided", builder.target(), builder.sort.name)
+	} else if builder.sort != nil && builder.sort.name != rangeKey {
+		return fmt.Errorf("sort key of %s is %s but %s was provided", builder.target(), rangeKey, builder.sort.name)
+	}
+
+	for _, key := range keys {
+		for _, definition := range builder.schema.Input.AttributeDefinitions {
+			if aws.ToString(definition.AttributeName) == key.name && attributeType(key.values[0]) != definition.AttributeType {
+				return fmt.Errorf("key %s has type %s but a value of type %s was provided",
+					key.name, definition.AttributeType, attributeType(key.values[0]))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Helper function that describes the table or index being queried, for use in error messages
+func (builder *QueryBuilder) target() string {
+	if builder.indexName != "" {
+		return fmt.Sprintf("index %s on %s", builder.indexName, builder.tableName)
+	}
+
+	return builder.tableName
+}
+
+// Helper function that checks that the values of a key condition are scalar key types and that they are
+// valid for the operator of the condition
+func (key *queryKey) validate() error {
+	for _, value := range key.values {
+		if kind := attributeType(value); kind == "" {
+			return fmt.Errorf("key %s must be a string, number or binary value but was %T", key.name, value)
+		} else if kind != attributeType(key.values[0]) {
+			return fmt.Errorf("values of key %s must all have the same type", key.name)
+		} else if key.operator == "begins_with" && kind == types.ScalarAttributeTypeN {
+			return fmt.Errorf("begins_with cannot be used on key %s because its value is a number", key.name)
+		}
+	}
+
+	return nil
+}
+
+// Helper function that creates the expression for a key condition, adding the attribute names and values
+// it references to the maps provided
+func (key *queryKey) expression(names map[string]string, values map[string]types.AttributeValue) string {
+	name := namePlaceholder(key.name, names)
+	placeholders := make([]string, len(key.values))
+	for i, value := range key.values {
+		placeholders[i] = fmt.Sprintf(":q%d", len(values))
+		values[placeholders[i]] = value
+	}
+
+	switch key.operator {
+	case "BETWEEN":
+		return fmt.Sprintf("%s BETWEEN %s AND %s", name, placeholders[0], placeholders[1])
+	case "begins_with":
+		return fmt.Sprintf("begins_with(%s, %s)", name, placeholders[0])
+	default:
+		return fmt.Sprintf("%s %s %s", name, key.operator, placeholders[0])
+	}
+}
+
+// Helper function that gets the placeholder for an attribute name, creating it if it doesn't exist
+func namePlaceholder(name string, names map[string]string) string {
+	for placeholder, existing := range names {
+		if existing == name {
+			return placeholder
+		}
+	}
+
+	placeholder := fmt.Sprintf("#q%d", len(names))
+	names[placeholder] = name
+	return placeholder
+}
+
+// Helper function that gets the scalar type of an attribute value, returning an empty type if the value
+// is not a string, number or binary value
+func attributeType(value types.AttributeValue) types.ScalarAttributeType {
+	switch value.(type) {
+	case *types.AttributeValueMemberS:
+		return types.ScalarAttributeTypeS
+	case *types.AttributeValueMemberN:
+		return types.ScalarAttributeTypeN
+	case *types.AttributeValueMemberB:
+		return types.ScalarAttributeTypeB
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,150 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	fake "github.com/Woody1193/goutils/dynamodb/testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Query Builder Tests", func() {
+
+	// Test that, if all the settings are provided, then Build will create an input containing all of them
+	It("Build - All settings - Input created", func() {
+
+		// First, create a query builder with every setting on it
+		builder := NewQuery("TEST_TABLE").Index("ByOwner").PartitionKey("owner", "test_owner").
+			SortKeyBetween("created", "2022-01-01", "2022-12-31").
+			Filter("#data > :min", map[string]string{"#data": "data"},
+				map[string]types.AttributeValue{":min": &types.AttributeValueMemberN{Value: "3"}}).
+			Project("id", "owner").Descending().Limit(10)
+
+		// Next, build the input from the builder; this should not fail
+		input, err := builder.Build()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the input that was created
+		Expect(*input.TableName).Should(Equal("TEST_TABLE"))
+		Expect(*input.IndexName).Should(Equal("ByOwner"))
+		Expect(*input.KeyConditionExpression).Should(Equal("#q0 = :q0 AND #q1 BETWEEN :q1 AND :q2"))
+		Expect(*input.FilterExpression).Should(Equal("#data > :min"))
+		Expect(*input.ProjectionExpression).Should(Equal("#q2, #q0"))
+		Expect(*input.ScanIndexForward).Should(BeFalse())
+		Expect(*input.Limit).Should(Equal(int32(10)))
+		Expect(input.ConsistentRead).Should(BeNil())
+		Expect(input.ExpressionAttributeNames).Should(Equal(map[string]string{
+			"#q0": "owner", "#q1": "created", "#q2": "id", "#data": "data"}))
+		Expect(input.ExpressionAttributeValues).Should(Equal(map[string]types.AttributeValue{
+			":q0":  &types.AttributeValueMemberS{Value: "test_owner"},
+			":q1":  &types.AttributeValueMemberS{Value: "2022-01-01"},
+			":q2":  &types.AttributeValueMemberS{Value: "2022-12-31"},
+			":min": &types.AttributeValueMemberN{Value: "3"}}))
+	})
+
+	// Test that the input created by the builder can be used to query DynamoDB
+	It("Build - Query sent - Matching items returned", func() {
+
+		// First, create our test connection with a table and write some items to it
+		def, err := NewTableDefinition[queryObject]("TEST_TABLE", WithTableTagKey("json"))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		client := fake.NewFakeDynamoDB()
+		_, err = client.CreateTable(context.Background(), def.Input)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		conn := createMockConnection(client)
+		for i := 0; i < 5; i++ {
+			_, err = PutObject(context.Background(), conn, &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")},
+				&queryObject{ID: "test_id", SortKey: fmt.Sprintf("prefix|%d", i), Data: i})
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		_, err = PutObject(context.Background(), conn, &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")},
+			&queryObject{ID: "test_id", SortKey: "other|0"})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Next, build a query for the items with a sort key prefix in descending order; this should not fail
+		input, err := NewQueryFor[queryObject]("TEST_TABLE", WithTableTagKey("json")).
+			PartitionKey("id", "test_id").SortKeyBeginsWith("sort_key", "prefix|").Descending().Build()
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, send the query to DynamoDB; this should not fail
+		items, err := QueryAs[queryObject](context.Background(), conn, input)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that the matching items were returned in the order requested
+		Expect(items).Should(HaveLen(5))
+		for i, item := range items {
+			Expect(item.SortKey).Should(Equal(fmt.Sprintf("prefix|%d", 4-i)))
+		}
+	})
+
+	// Test that, if the query is invalid, then Build will return an error describing the problem
+	DescribeTable("Build - Invalid query - Error",
+		func(builder *QueryBuilder, message string) {
+			input, err := builder.Build()
+			Expect(input).Should(BeNil())
+			Expect(err).Should(MatchError(message))
+		},
+		Entry("No partition key", NewQuery("TEST_TABLE").SortKeyEquals("sort_key", "value"),
+			"query on TEST_TABLE has no partition key"),
+		Entry("Limit not positive", NewQuery("TEST_TABLE").PartitionKey("id", "test_id").Limit(0),
+			"query limit must be positive but was 0"),
+		Entry("Value not marshalled", NewQuery("TEST_TABLE").PartitionKey("id", func() {}),
+			"key id: value of type func() could not be marshalled"),
+		Entry("Value not scalar", NewQuery("TEST_TABLE").PartitionKey("id", true),
+			"key id must be a string, number or binary value but was *types.AttributeValueMemberBOOL"),
+		Entry("Between types differ", NewQuery("TEST_TABLE").PartitionKey("id", "test_id").
+			SortKeyBetween("sort_key", 1, "2"), "values of key sort_key must all have the same type"),
+		Entry("Begins with number", NewQuery("TEST_TABLE").PartitionKey("id", "test_id").
+			SortKeyBeginsWith("sort_key", 1), "begins_with cannot be used on key sort_key because its value is a number"),
+		Entry("Schema invalid", NewQueryFor[invalidKeySchema]("TEST_TABLE").PartitionKey("ID", true),
+			"field ID on dynamodb.invalidKeySchema has type bool which cannot be used as a key"),
+		Entry("Index not defined", createSchemaQuery().Index("ByOther").PartitionKey("id", "test_id"),
+			"index ByOther is not defined on TEST_TABLE"),
+		Entry("Wrong partition key", createSchemaQuery().Index("ByOwner").PartitionKey("id", "test_id"),
+			"partition key of index ByOwner on TEST_TABLE is owner but id was provided"),
+		Entry("Wrong sort key", createSchemaQuery().Index("ByCreated").PartitionKey("id", "test_id").
+			SortKeyEquals("sort_key", 1), "sort key of index ByCreated on TEST_TABLE is created but sort_key was provided"),
+		Entry("No sort key", createSchemaQuery().Index("ByHash").PartitionKey("Hash", []byte("hash")).
+			SortKeyEquals("id", "test_id"), "index ByHash on TEST_TABLE has no sort key but a condition on id was provided"),
+		Entry("Wrong key type", createSchemaQuery().PartitionKey("id", "test_id").SortKeyLessThan("sort_key", "1"),
+			"key sort_key has type N but a value of type S was provided"),
+		Entry("Consistent read on global index", createSchemaQuery().Index("ByOwner").
+			PartitionKey("owner", "test_owner").ConsistentRead(),
+			"consistent reads are not supported on global secondary index ByOwner"))
+
+	// Test that, if the query matches the key schema of the table or index, then Build will not fail
+	DescribeTable("Build - Query matches schema - Input created",
+		func(builder *QueryBuilder, expression string) {
+			input, err := builder.Build()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(*input.KeyConditionExpression).Should(Equal(expression))
+		},
+		Entry("Table", createSchemaQuery().PartitionKey("id", "test_id").SortKeyGreaterThanOrEqual("sort_key", 1).
+			ConsistentRead(), "#q0 = :q0 AND #q1 >= :q1"),
+		Entry("Local index", createSchemaQuery().Index("ByCreated").PartitionKey("id", "test_id").
+			SortKeyLessThanOrEqual("created", "2022-07-01"), "#q0 = :q0 AND #q1 <= :q1"),
+		Entry("Global index", createSchemaQuery().Index("ByOwner").PartitionKey("owner", "test_owner").
+			SortKeyBeginsWith("created", "2022"), "#q0 = :q0 AND begins_with(#q1, :q1)"),
+		Entry("Attribute value", createSchemaQuery().Index("ByHash").
+			PartitionKey("Hash", &types.AttributeValueMemberB{Value: []byte("hash")}), "#q0 = :q0"))
+})
+
+// Helper function that creates a query builder that validates queries against the schema test type
+func createSchemaQuery() *QueryBuilder {
+	return NewQueryFor[schemaObject]("TEST_TABLE", WithTableTagKey("json"))
+}
+
+// Helper type that describes an item in a table with a hash and range key
+type queryObject struct {
+	ID      string `json:"id" dynamodb:"hash"`
+	SortKey string `json:"sort_key" dynamodb:"range"`
+	Data    int    `json:"data"`
+}
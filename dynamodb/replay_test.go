@@ -0,0 +1,56 @@
+package dynamodb
+
+import (
+	"context"
+
+	fake "github.com/Woody1193/goutils/dynamodb/testing"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Record and Replay Tests", func() {
+
+	// Test that, if conflicting writes to a versioned object are recorded against a database, then replaying
+	// them will produce the same version conflict without the database
+	It("PutObject - Conflict replayed - Error returned", func() {
+
+		// First, create a recorder wrapping an in-memory database with our test table
+		database := fake.NewFakeDynamoDB()
+		_, err := database.CreateTable(context.Background(), &dynamodb.CreateTableInput{
+			TableName: aws.String("TEST_TABLE"),
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS}},
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash}},
+			BillingMode: types.BillingModePayPerRequest})
+		Expect(err).ShouldNot(HaveOccurred())
+		recorder := fake.NewRecorder(database)
+
+		// Next, write two copies of the same object where the second was read before the first was written;
+		// the second write should fail with a version conflict
+		write := func(client DynamoDBAPI) error {
+			conn := createMockConnection(client)
+			first, second := &versionedObject{ID: "test_id", Data: 1}, &versionedObject{ID: "test_id", Data: 2}
+			if _, err := PutObject(context.Background(), conn,
+				&dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")}, first); err != nil {
+				return err
+			}
+
+			_, err := PutObject(context.Background(), conn,
+				&dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE")}, second)
+			return err
+		}
+
+		Expect(write(recorder)).Should(BeAssignableToTypeOf(&VersionConflictError{}))
+
+		// Finally, replay the writes; this should produce the same error and replay every interaction
+		replayer := fake.NewReplayer(recorder.Interactions()...)
+		err = write(replayer)
+		Expect(err).Should(BeAssignableToTypeOf(&VersionConflictError{}))
+		Expect(err.(*VersionConflictError).Expected).Should(Equal(int64(0)))
+		Expect(replayer.Verify()).ShouldNot(HaveOccurred())
+	})
+})
@@ -0,0 +1,133 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/cenkalti/backoff/v4"
+)
+
+// RetryPredicate decides whether a failed DynamoDB request should be retried. It is called with the operation
+// that failed, such as "GET", "QUERY" or "BATCH WRITE", and the error returned by the request, and should
+// return true if the request should be retried
+type RetryPredicate func(operation string, err error) bool
+
+// BackoffFactory creates the backoff timer that will be used to wait between retries of a DynamoDB request.
+// A new timer is created for every request so the factory should not return a shared timer
+type BackoffFactory func() backoff.BackOff
+
+// Helper variable containing the error codes returned by DynamoDB that indicate the request may succeed if
+// it is retried after a short wait
+var retryableCodes = map[string]bool{
+	"ProvisionedThroughputExceededException": true,
+	"RequestLimitExceeded":                   true,
+	"InternalServerError":                    true,
+	"ThrottlingException":                    true,
+	"TransactionConflictException":           true,
+}
+
+// Helper variable containing the transaction cancellation reasons that indicate the transaction may succeed
+// if it is retried after a short wait
+var retryableCancellations = map[string]bool{
+	"None":                          true,
+	"TransactionConflict":           true,
+	"ThrottlingError":               true,
+	"ProvisionedThroughputExceeded": true,
+}
+
+// IsRetryable determines whether a failed DynamoDB request should be retried. Throughput and request limit
+// errors, throttling, transaction conflicts, internal server errors and transient network errors, such as
+// timeouts and dropped connections, will be retried. Transactions that were cancelled only because of
+// conflicts or throttling will also be retried. Cancellation of the request context will never be retried.
+// This is the default classification used by the connection and may be called by a RetryPredicate to
+// extend it
+func IsRetryable(err error) bool {
+
+	// First, check whether the context was cancelled or timed out; neither should be retried. We need to
+	// check this first because the deadline error also satisfies the network error interface
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// Next, check whether the error was returned by DynamoDB. If it was then the request should be retried
+	// if its error code indicates a transient failure or if it was a transaction that was cancelled only
+	// because of transient failures
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		return isRetryableCancellation(canceled)
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return retryableCodes[apiErr.ErrorCode()]
+	}
+
+	// Finally, check whether the request failed because of a transient network error: a timeout, a dropped
+	// or refused connection or a response that was cut short. Other network errors, such as failing to
+	// resolve the endpoint, are unlikely to fix themselves so they won't be retried
+	var netErr net.Error
+	return (errors.As(err, &netErr) && netErr.Timeout()) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE)
+}
+
+// Helper function that determines whether the request should be retried, using the retry predicate set on
+// the connection if there is one and the default classification otherwise
+func (conn *DatabaseConnection) shouldRetry(verb string, err error) bool {
+	if conn.retryPredicate != nil {
+		return conn.retryPredicate(retryOperation(verb), err)
+	}
+
+	return IsRetryable(err)
+}
+
+// Helper function that creates the backoff timer for a request, using the backoff policy set on the
+// connection for the operation if there is one and the exponential backoff settings otherwise
+func (conn *DatabaseConnection) createBackoff(verb string) backoff.BackOff {
+	if factory, ok := conn.backoffPolicies[retryOperation(verb)]; ok {
+		return factory()
+	}
+
+	return conn.createExponentialBackoff()
+}
+
+// Helper function that gets the operation from the verb describing a request by removing any details of
+// the page or segment being requested, so "QUERY(1)" becomes "QUERY"
+func retryOperation(verb string) string {
+	if index := strings.Index(verb, "("); index >= 0 {
+		verb = verb[:index]
+	}
+
+	return strings.TrimSpace(verb)
+}
+
+// Helper function that determines whether a transaction was cancelled only because of transient failures
+func isRetryableCancellation(canceled *types.TransactionCanceledException) bool {
+	transient := false
+	for _, reason := range canceled.CancellationReasons {
+		code := aws.ToString(reason.Code)
+		if !retryableCancellations[code] && code != "" {
+			return false
+		}
+
+		transient = transient || (code != "" && code != "None")
+	}
+
+	return transient
+}
+
+// Helper function that gets a message describing why a request failed, for logging
+func retryMessage(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorMessage() != "" {
+		return apiErr.ErrorMessage()
+	}
+
+	return err.Error()
+}
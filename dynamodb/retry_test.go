@@ -0,0 +1,143 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/Woody1193/goutils/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/cenkalti/backoff/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Retry Tests", func() {
+
+	// Test that IsRetryable classifies errors returned by DynamoDB and the network correctly
+	DescribeTable("IsRetryable - Conditions",
+		func(err error, expected bool) {
+			Expect(IsRetryable(err)).Should(Equal(expected))
+		},
+		Entry("Nil - False", nil, false),
+		Entry("Plain error - False", errors.New("failed"), false),
+		Entry("Context cancelled - False", fmt.Errorf("wrapped: %w", context.Canceled), false),
+		Entry("Deadline exceeded - False", context.DeadlineExceeded, false),
+		Entry("ProvisionedThroughputExceededException - True",
+			&smithy.OperationError{Err: &types.ProvisionedThroughputExceededException{}}, true),
+		Entry("RequestLimitExceeded - True", &smithy.OperationError{Err: &types.RequestLimitExceeded{}}, true),
+		Entry("InternalServerError - True", &smithy.OperationError{Err: &types.InternalServerError{}}, true),
+		Entry("ThrottlingException - True", &smithy.OperationError{
+			Err: &smithy.GenericAPIError{Code: "ThrottlingException"}}, true),
+		Entry("TransactionConflictException - True",
+			&smithy.OperationError{Err: &types.TransactionConflictException{}}, true),
+		Entry("ResourceNotFoundException - False",
+			&smithy.OperationError{Err: &types.ResourceNotFoundException{}}, false),
+		Entry("ConditionalCheckFailedException - False",
+			&smithy.OperationError{Err: &types.ConditionalCheckFailedException{}}, false),
+		Entry("Transaction conflict cancellation - True", &smithy.OperationError{
+			Err: createCancellation("None", "TransactionConflict")}, true),
+		Entry("Condition failed cancellation - False", &smithy.OperationError{
+			Err: createCancellation("TransactionConflict", "ConditionalCheckFailed")}, false),
+		Entry("Cancellation without reasons - False", &smithy.OperationError{Err: createCancellation()}, false),
+		Entry("Connection reset - True", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, true),
+		Entry("Connection refused - True", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect",
+			syscall.ECONNREFUSED)}, true),
+		Entry("Unresolved host - False", &net.OpError{Op: "dial", Err: &net.DNSError{IsNotFound: true}}, false),
+		Entry("Invalid address - False", &net.OpError{Op: "dial", Err: &net.AddrError{Err: "missing port"}}, false),
+		Entry("Unexpected EOF - True", fmt.Errorf("wrapped: %w", io.ErrUnexpectedEOF), true),
+		Entry("Network timeout - True", &net.DNSError{IsTimeout: true}, true),
+		Entry("Network error - False", &net.DNSError{IsNotFound: true}, false))
+
+	// Test that, if the operation returns an error that wasn't returned by DynamoDB, then doRetry will not
+	// retry the request and will return the error
+	It("doRetry - Plain error - Not retried", func() {
+
+		// First, create our test connection
+		conn := createRetryConnection()
+
+		// Next, attempt an operation that fails with a plain error
+		count := 0
+		err := conn.doRetry(context.Background(), "TEST_TABLE", "GET", func() error {
+			count++
+			return errors.New("failed")
+		})
+
+		// Finally, verify that the operation was only attempted once and that the error was returned
+		Expect(count).Should(Equal(1))
+		Expect(err.(*Error).Message).Should(Equal("GET request to TEST_TABLE in DynamoDB failed"))
+		Expect(err.(*Error).Inner).Should(MatchError("failed"))
+	})
+
+	// Test that, if a retry predicate is provided, then doRetry will use it to decide whether to retry
+	It("doRetry - Retry predicate provided - Predicate used", func() {
+
+		// First, create our test connection with a predicate that retries any error until the third attempt
+		operations := make([]string, 0)
+		conn := createRetryConnection(WithRetryPredicate(func(operation string, err error) bool {
+			operations = append(operations, operation)
+			return len(operations) < 3
+		}))
+
+		// Next, attempt an operation that always fails
+		count := 0
+		err := conn.doRetry(context.Background(), "TEST_TABLE", "QUERY(2)", func() error {
+			count++
+			return errors.New("failed")
+		})
+
+		// Finally, verify that the predicate was called with the operation and that it decided how many
+		// times the operation was attempted
+		Expect(err).Should(HaveOccurred())
+		Expect(count).Should(Equal(3))
+		Expect(operations).Should(Equal([]string{"QUERY", "QUERY", "QUERY"}))
+	})
+
+	// Test that, if a backoff policy is provided for an operation, then doRetry will use it for that
+	// operation and the default backoff for others
+	It("doRetry - Operation backoff provided - Policy used", func() {
+
+		// First, create our test connection with a policy that allows two retries for batch writes
+		conn := createRetryConnection(WithOperationBackoff{
+			Operation: "BATCH WRITE",
+			Factory: func() backoff.BackOff {
+				return backoff.WithMaxRetries(&backoff.ZeroBackOff{}, 2)
+			},
+		})
+
+		// Next, attempt a batch write that always fails with a retryable error
+		count := 0
+		err := conn.doRetry(context.Background(), "TEST_TABLE", "BATCH WRITE", func() error {
+			count++
+			return &smithy.OperationError{Err: &types.InternalServerError{Message: aws.String("failed")}}
+		})
+
+		// Finally, verify that the operation was attempted according to the policy
+		Expect(err).Should(HaveOccurred())
+		Expect(count).Should(Equal(3))
+	})
+})
+
+// Helper function that creates a connection with a short backoff and no client, for testing retries
+func createRetryConnection(opts ...IDynamoDBOption) *DatabaseConnection {
+	logger := utils.NewLogger("testd", "test")
+	logger.Discard()
+	opts = append([]IDynamoDBOption{WithBackoffStart(1), WithBackoffEnd(5), WithBackoffMaxElapsed(10)}, opts...)
+	return FromClient(nil, logger, opts...)
+}
+
+// Helper function that creates a transaction cancellation with the reason codes provided
+func createCancellation(codes ...string) *types.TransactionCanceledException {
+	reasons := make([]types.CancellationReason, len(codes))
+	for i, code := range codes {
+		reasons[i] = types.CancellationReason{Code: aws.String(code)}
+	}
+
+	return &types.TransactionCanceledException{CancellationReasons: reasons}
+}
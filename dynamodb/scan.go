@@ -0,0 +1,108 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Woody1193/goutils/concurrency"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ScanPage contains a single page of items read by one segment of a parallel scan
+type ScanPage struct {
+	Segment          int
+	Index            int
+	Items            []map[string]types.AttributeValue
+	LastEvaluatedKey map[string]types.AttributeValue
+}
+
+// ParallelScan reads every item from a DynamoDB table or index by splitting it into a number of
+// segments and scanning each segment concurrently. Each page of results is sent to the handler as it
+// is retrieved so the handler may be called concurrently from multiple segments and should be safe
+// for concurrent use. If the handler returns an error, or any page cannot be read, then the remaining
+// segments will be cancelled and the error returned. Each page request is retried with the backoff
+// settings on the connection. Note that the input is copied so it will not be modified by the scan
+func (conn *DatabaseConnection) ParallelScan(ctx context.Context, input *dynamodb.ScanInput,
+	segments int, handler func(context.Context, *ScanPage) error) error {
+
+	// First, ensure that we have at least one segment to scan
+	if segments < 1 {
+		segments = 1
+	}
+
+	conn.logger.Log("Attempting parallel scan of %s with %d segments...", *input.TableName, segments)
+
+	// Next, scan each of the segments concurrently, short-circuiting if any of them fails
+	err := concurrency.ForAllAsync(ctx, segments, true,
+		func(ctx context.Context, segment int, _ context.CancelFunc) error {
+			return conn.scanSegment(ctx, input, segment, segments, handler)
+		})
+
+	// Finally, if no segment failed but the context was cancelled before all the segments could
+	// finish then the scan is incomplete so return the cancellation error
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	return err
+}
+
+// Helper function that reads every page from a single segment of a parallel scan and sends each
+// page to the handler as it is retrieved
+func (conn *DatabaseConnection) scanSegment(ctx context.Context, input *dynamodb.ScanInput,
+	segment int, total int, handler func(context.Context, *ScanPage) error) error {
+
+	// First, copy the input and set the consumed capacity and segment information on it. DynamoDB will
+	// reject a segment with a total of one so we'll only set these values if we actually have multiple segments
+	segInput := *input
+	segInput.ReturnConsumedCapacity = conn.capacityMode(input.ReturnConsumedCapacity)
+	if total > 1 {
+		segInput.Segment = aws.Int32(int32(segment))
+		segInput.TotalSegments = aws.Int32(int32(total))
+	}
+
+	// Next, we'll start a loop that will scan each page of the segment until all the pages have been read
+	for index := 0; ; index++ {
+
+		// First, check whether cancellation has been requested; if it has then stop here
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Next, attempt the scan with a backoff-retry loop; if this fails then return an error
+		var output *dynamodb.ScanOutput
+		err := conn.doRetry(ctx, *input.TableName, fmt.Sprintf("SCAN(%d/%d, %d)", segment, total, index),
+			func() error {
+				var inner error
+				if output, inner = conn.db.Scan(ctx, &segInput); inner == nil {
+					conn.recordCapacity("SCAN", capacityOf(output.ConsumedCapacity)...)
+				}
+
+				return inner
+			})
+
+		if err != nil {
+			return err
+		}
+
+		// Now, send the page to the handler; if this fails then return the error
+		if err := handler(ctx, &ScanPage{
+			Segment:          segment,
+			Index:            index,
+			Items:            output.Items,
+			LastEvaluatedKey: output.LastEvaluatedKey,
+		}); err != nil {
+			return err
+		}
+
+		// Finally, check if the last-evaluated key is nil. If it is then we've finished scanning the
+		// segment so we can exit. Otherwise, we'll use it as the start key for the next page
+		if output.LastEvaluatedKey == nil {
+			return nil
+		}
+
+		segInput.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
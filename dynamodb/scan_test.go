@@ -0,0 +1,151 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Parallel Scan Tests", func() {
+
+	// Test that, if no failures occur, then every item in every segment will be sent to the handler
+	It("ParallelScan - No failures - All items read", func() {
+
+		// First, create our test connection with a client that splits 40 items into segments
+		client := &segmentDynamoDBClient{items: createTestPages(1, 40)[0], pageSize: 3}
+		conn := createMockConnection(client)
+
+		// Next, scan the table with four segments, collecting the data from each item
+		lock := new(sync.Mutex)
+		data := make([]int, 0)
+		segments := make(map[int]bool)
+		err := conn.ParallelScan(context.Background(), &dynamodb.ScanInput{TableName: aws.String("TEST_TABLE")},
+			4, func(_ context.Context, page *ScanPage) error {
+				lock.Lock()
+				defer lock.Unlock()
+				segments[page.Segment] = true
+				for _, item := range page.Items {
+					value, _ := strconv.Atoi(item["data"].(*types.AttributeValueMemberN).Value)
+					data = append(data, value)
+				}
+
+				return nil
+			})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that every item was read exactly once and that every segment was scanned
+		sort.Ints(data)
+		Expect(data).Should(HaveLen(40))
+		for i, value := range data {
+			Expect(value).Should(Equal(i))
+		}
+
+		Expect(segments).Should(HaveLen(4))
+		Expect(client.totals).Should(ConsistOf(int32(4), int32(4), int32(4), int32(4)))
+	})
+
+	// Test that, if only a single segment is requested, then no segment information is sent
+	It("ParallelScan - Single segment - No segment sent", func() {
+
+		// First, create our test connection with a client that returns pages of five items
+		client := &segmentDynamoDBClient{items: createTestPages(1, 12)[0], pageSize: 5}
+		conn := createMockConnection(client)
+
+		// Next, scan the table with a single segment, counting the pages and items
+		pages, items := 0, 0
+		err := conn.ParallelScan(context.Background(), &dynamodb.ScanInput{TableName: aws.String("TEST_TABLE")},
+			0, func(_ context.Context, page *ScanPage) error {
+				Expect(page.Index).Should(Equal(pages))
+				pages++
+				items += len(page.Items)
+				return nil
+			})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the pages and items that were read
+		Expect(pages).Should(Equal(3))
+		Expect(items).Should(Equal(12))
+		Expect(client.totals).Should(BeEmpty())
+	})
+
+	// Test that, if the handler fails, then ParallelScan will return the error
+	It("ParallelScan - Handler fails - Error", func() {
+
+		// First, create our test connection with a client that splits 40 items into segments
+		conn := createMockConnection(&segmentDynamoDBClient{items: createTestPages(1, 40)[0], pageSize: 3})
+
+		// Next, scan the table with a handler that fails on the first page of the second segment
+		err := conn.ParallelScan(context.Background(), &dynamodb.ScanInput{TableName: aws.String("TEST_TABLE")},
+			4, func(_ context.Context, page *ScanPage) error {
+				if page.Segment == 1 {
+					return errors.New("derp")
+				}
+
+				return nil
+			})
+
+		// Finally, verify the error
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(Equal("derp"))
+	})
+})
+
+// Helper type that serves a list of items split into segments and pages, respecting the segment
+// and exclusive start key on each request, so that scans can be tested without a DynamoDB instance
+type segmentDynamoDBClient struct {
+	DynamoDBAPI
+	items    []map[string]types.AttributeValue
+	pageSize int
+	totals   []int32
+	lock     sync.Mutex
+}
+
+// Mocks out the Scan function so that it returns the next page of items in the segment
+func (client *segmentDynamoDBClient) Scan(ctx context.Context, params *dynamodb.ScanInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+
+	// First, get the items that belong to the segment; the item at index i belongs to the segment
+	// equal to i modulo the total number of segments. We'll also record the total number of segments
+	// on the first request for each segment
+	segment, total := 0, 1
+	if params.TotalSegments != nil {
+		segment, total = int(*params.Segment), int(*params.TotalSegments)
+		if params.ExclusiveStartKey == nil {
+			client.lock.Lock()
+			client.totals = append(client.totals, *params.TotalSegments)
+			client.lock.Unlock()
+		}
+	}
+
+	items := make([]map[string]types.AttributeValue, 0)
+	for i := segment; i < len(client.items); i += total {
+		items = append(items, client.items[i])
+	}
+
+	// Next, determine where the page starts and ends
+	start := 0
+	if params.ExclusiveStartKey != nil {
+		start, _ = strconv.Atoi(params.ExclusiveStartKey["index"].(*types.AttributeValueMemberN).Value)
+	}
+
+	end := start + client.pageSize
+	if end > len(items) {
+		end = len(items)
+	}
+
+	// Finally, if there are more items after the page then create a last-evaluated key pointing to them
+	var last map[string]types.AttributeValue
+	if end < len(items) {
+		last = map[string]types.AttributeValue{"index": &types.AttributeValueMemberN{Value: strconv.Itoa(end)}}
+	}
+
+	return &dynamodb.ScanOutput{Items: items[start:end], LastEvaluatedKey: last}, nil
+}
@@ -0,0 +1,446 @@
+package dynamodb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Woody1193/goutils/collections"
+	"github.com/Woody1193/goutils/reflection"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TableDefinition describes a DynamoDB table derived from the schema tags on a struct type. Fields
+// are described by values in the dynamodb tag, separated by commas:
+//
+//	hash              The field is the hash key of the table
+//	range             The field is the range key of the table
+//	gsi=Name/hash     The field is the hash key of the global secondary index called Name
+//	gsi=Name/range    The field is the range key of the global secondary index called Name
+//	lsi=Name          The field is the range key of the local secondary index called Name
+//	project=Name      The field should be projected into the index called Name
+//	ttl               The field contains the expiry time of the item, in epoch seconds, and must be an integer
+//	version           The field contains the version of the item, used for optimistic locking
+//
+// Index values may end with a projection type, all, keys or include (e.g. gsi=Name/hash/keys). If no
+// projection type is given then indexes with projected fields will include only those fields, in addition
+// to the keys, and all other indexes will project all attributes. Fields embedded in other structs are
+// not examined
+type TableDefinition struct {
+
+	// Input contains the request that can be used to create the table
+	Input *dynamodb.CreateTableInput
+
+	// TTLAttribute contains the name of the attribute that holds the expiry time of items on the table.
+	// If this is empty then the table does not have a TTL attribute
+	TTLAttribute string
+
+	// PointInTimeRecovery determines whether point-in-time recovery should be enabled on the table. This
+	// cannot be set when the table is created so it is only applied when the table is migrated
+	PointInTimeRecovery bool
+
+	tagKey     string
+	throughput *types.ProvisionedThroughput
+}
+
+// ITableOption defines the functionality that will allow a TableDefinition to be modified as it is created
+type ITableOption interface {
+	Apply(*TableDefinition)
+}
+
+// WithTableTagKey allows the user to set the struct tag that should be used to determine the names of
+// attributes on the table. This should be the same tag key used by the connection that writes objects
+// to the table. If this option is not provided then the default tag, dynamodbav, will be used
+type WithTableTagKey string
+
+// Apply modifies the TableDefinition so that it uses the tag key defined by this object
+func (w WithTableTagKey) Apply(def *TableDefinition) {
+	def.tagKey = string(w)
+}
+
+// WithProvisionedThroughput allows the user to set the read and write capacity of the table and all of
+// its global secondary indexes. If this option is not provided then the table will be billed per request
+type WithProvisionedThroughput struct {
+	Read  int64
+	Write int64
+}
+
+// Apply modifies the TableDefinition so that it uses the throughput defined by this object
+func (w WithProvisionedThroughput) Apply(def *TableDefinition) {
+	def.throughput = &types.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(w.Read),
+		WriteCapacityUnits: aws.Int64(w.Write),
+	}
+}
+
+// WithTableStream allows the user to enable a stream on the table, with the view type provided. If this
+// option is not provided then the table will not have a stream
+type WithTableStream types.StreamViewType
+
+// Apply modifies the TableDefinition so that it has the stream defined by this object
+func (w WithTableStream) Apply(def *TableDefinition) {
+	def.Input.StreamSpecification = &types.StreamSpecification{
+		StreamEnabled:  aws.Bool(true),
+		StreamViewType: types.StreamViewType(w),
+	}
+}
+
+// WithPointInTimeRecovery allows the user to set whether point-in-time recovery should be enabled on the
+// table. If this option is not provided then point-in-time recovery will be disabled
+type WithPointInTimeRecovery bool
+
+// Apply modifies the TableDefinition so that it has the point-in-time recovery setting defined by this object
+func (w WithPointInTimeRecovery) Apply(def *TableDefinition) {
+	def.PointInTimeRecovery = bool(w)
+}
+
+// Helper type that collects the description of a secondary index as the fields are examined
+type indexSchema struct {
+	kind       string
+	hash       string
+	rangeKey   string
+	projection string
+	attributes []string
+}
+
+// NewTableDefinition creates a table definition, with the name provided, from the schema tags on the
+// type. See TableDefinition for a description of the tags. An error will be returned if the tags are
+// invalid, if the table has no hash key or if a key field has a type that cannot be used as a key
+func NewTableDefinition[T any](tableName string, opts ...ITableOption) (*TableDefinition, error) {
+
+	// First, create our definition with default values and apply all our options to it
+	def := &TableDefinition{
+		Input:  &dynamodb.CreateTableInput{TableName: aws.String(tableName)},
+		tagKey: "dynamodbav",
+	}
+
+	for _, opt := range opts {
+		opt.Apply(def)
+	}
+
+	// Next, ensure that we have a struct type; if we don't then it can't have any schema tags
+	tType := reflect.TypeOf((*T)(nil)).Elem()
+	if tType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct type", tType)
+	}
+
+	// Now, iterate over all the fields on the type and collect the keys, indexes and definitions of
+	// the attributes used as keys from their schema tags
+	var hash, rangeKey string
+	indexes := make(map[string]*indexSchema)
+	attributes := make([]types.AttributeDefinition, 0)
+	defined := make(map[string]bool)
+	for _, field := range reflection.GetTypeInfo[T]().Fields {
+
+		// First, get the schema tag from the field; if it doesn't have one then skip it
+		tag, ok := field.Tags[SchemaTag]
+		if !ok {
+			continue
+		}
+
+		// Next, get the attribute name of the field from the tag key used by the definition
+		name := field.Name
+		if nameTag, ok := field.Tags[def.tagKey]; ok && nameTag.Name != "" {
+			name = nameTag.Name
+		}
+
+		// Now, iterate over each value in the tag and update the schema with it
+		isKey := false
+		for _, value := range append([]string{tag.Name}, tag.Modifiers...) {
+			var err error
+			value = strings.TrimSpace(value)
+			kind, arg, _ := strings.Cut(value, "=")
+			switch kind {
+			case "", "version":
+			case "hash":
+				err = setKey(&hash, name, "hash key of the table")
+				isKey = true
+			case "range":
+				err = setKey(&rangeKey, name, "range key of the table")
+				isKey = true
+			case "ttl":
+				if !isIntegerType(field.Type) {
+					err = fmt.Errorf("type %s cannot be used as a TTL attribute as it is not an integer", field.Type)
+				} else {
+					err = setKey(&def.TTLAttribute, name, "TTL attribute of the table")
+				}
+			case "gsi", "lsi", "project":
+				isKey = isKey || kind != "project"
+				err = updateIndex(indexes, kind, arg, name)
+			default:
+				err = fmt.Errorf("unknown schema tag value %q", value)
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("field %s on %s: %v", field.Name, tType, err)
+			}
+		}
+
+		// Finally, if the field is a key of the table or any of its indexes then ensure that it
+		// has a valid name and type and add its definition to the table if we haven't already
+		if isKey && !defined[name] {
+			if name == "-" {
+				return nil, fmt.Errorf("field %s on %s is a key but is not marshalled", field.Name, tType)
+			}
+
+			attrType, ok := keyAttributeType(field.Type)
+			if !ok {
+				return nil, fmt.Errorf("field %s on %s has type %s which cannot be used as a key",
+					field.Name, tType, field.Type)
+			}
+
+			attributes = append(attributes, types.AttributeDefinition{
+				AttributeName: aws.String(name),
+				AttributeType: attrType,
+			})
+
+			defined[name] = true
+		}
+	}
+
+	// Ensure that the table has a hash key as DynamoDB requires one
+	if hash == "" {
+		return nil, fmt.Errorf("%s has no field tagged as the hash key", tType)
+	}
+
+	// Set the keys and attribute definitions on the table, along with its billing mode
+	def.Input.AttributeDefinitions = attributes
+	def.Input.KeySchema = keySchema(hash, rangeKey)
+	if def.throughput != nil {
+		def.Input.BillingMode = types.BillingModeProvisioned
+		def.Input.ProvisionedThroughput = def.throughput
+	} else {
+		def.Input.BillingMode = types.BillingModePayPerRequest
+	}
+
+	// Finally, iterate over all the indexes, in order of their names, and add each to the table
+	names := collections.Keys(indexes)
+	sort.Strings(names)
+	for _, name := range names {
+		index := indexes[name]
+
+		// First, create the projection for the index; if this fails then return an error
+		projection, err := index.createProjection()
+		if err != nil {
+			return nil, fmt.Errorf("index %s on %s: %v", name, tType, err)
+		}
+
+		// Next, if the index is global then ensure it has a hash key and add it to the table
+		if index.kind == "" {
+			return nil, fmt.Errorf("fields are projected into index %s on %s but it is not defined", name, tType)
+		} else if index.kind == "gsi" {
+			if index.hash == "" {
+				return nil, fmt.Errorf("index %s on %s has no hash key", name, tType)
+			}
+
+			def.Input.GlobalSecondaryIndexes = append(def.Input.GlobalSecondaryIndexes, types.GlobalSecondaryIndex{
+				IndexName:             aws.String(name),
+				KeySchema:             keySchema(index.hash, index.rangeKey),
+				Projection:            projection,
+				ProvisionedThroughput: def.throughput,
+			})
+
+			continue
+		}
+
+		// Finally, the index is local so ensure that the table has a range key and add it to the table
+		if rangeKey == "" {
+			return nil, fmt.Errorf("index %s on %s is local but the table has no range key", name, tType)
+		}
+
+		def.Input.LocalSecondaryIndexes = append(def.Input.LocalSecondaryIndexes, types.LocalSecondaryIndex{
+			IndexName:  aws.String(name),
+			KeySchema:  keySchema(hash, index.rangeKey),
+			Projection: projection,
+		})
+	}
+
+	return def, nil
+}
+
+// CreateTableInput returns the request that can be used to create the table. This allows the definition to
+// be used wherever a table schema is required, such as by the testing package
+func (def *TableDefinition) CreateTableInput() *dynamodb.CreateTableInput {
+	return def.Input
+}
+
+// TimeToLive creates the request that can be used to enable TTL on the table. If the table does not have
+// a TTL attribute then this function will return nil
+func (def *TableDefinition) TimeToLive() *dynamodb.UpdateTimeToLiveInput {
+	if def.TTLAttribute == "" {
+		return nil
+	}
+
+	return &dynamodb.UpdateTimeToLiveInput{
+		TableName: def.Input.TableName,
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String(def.TTLAttribute),
+			Enabled:       aws.Bool(true),
+		},
+	}
+}
+
+// Helper function that creates the projection for an index from its projection type and the attributes
+// that were tagged as projected into it
+func (index *indexSchema) createProjection() (*types.Projection, error) {
+	projection := index.projection
+	if projection == "" {
+		if len(index.attributes) > 0 {
+			projection = "include"
+		} else {
+			projection = "all"
+		}
+	}
+
+	switch projection {
+	case "all", "keys":
+		if len(index.attributes) > 0 {
+			return nil, fmt.Errorf("fields are projected into the index but its projection type is %s", projection)
+		} else if projection == "all" {
+			return &types.Projection{ProjectionType: types.ProjectionTypeAll}, nil
+		}
+
+		return &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly}, nil
+	case "include":
+		if len(index.attributes) == 0 {
+			return nil, fmt.Errorf("projection type is include but no fields are projected into the index")
+		}
+
+		return &types.Projection{ProjectionType: types.ProjectionTypeInclude, NonKeyAttributes: index.attributes}, nil
+	default:
+		return nil, fmt.Errorf("unknown projection type %q", projection)
+	}
+}
+
+// Helper function that updates the index described by a gsi, lsi or project tag value with the attribute
+func updateIndex(indexes map[string]*indexSchema, kind string, arg string, name string) error {
+
+	// First, split the value into the index name and its parts and get or create the index
+	parts := strings.Split(arg, "/")
+	if parts[0] == "" {
+		return fmt.Errorf("%s tag value has no index name", kind)
+	}
+
+	index, ok := indexes[parts[0]]
+	if !ok {
+		index = new(indexSchema)
+		indexes[parts[0]] = index
+	}
+
+	// Next, if the value projects the attribute into the index then add it to the index
+	if kind == "project" {
+		if len(parts) != 1 {
+			return fmt.Errorf("project tag value should only contain an index name")
+		}
+
+		index.attributes = append(index.attributes, name)
+		return nil
+	}
+
+	// Now, ensure that the index is described consistently; if it isn't then return an error
+	if index.kind == "" {
+		index.kind = kind
+	} else if index.kind != kind {
+		return fmt.Errorf("index %s is described as both global and local", parts[0])
+	}
+
+	// Finally, get the key type and projection type from the value and update the index with them
+	keyType, projection := "range", ""
+	if kind == "gsi" {
+		if len(parts) < 2 || len(parts) > 3 {
+			return fmt.Errorf("gsi tag value should be formatted as Name/hash or Name/range")
+		}
+
+		keyType = parts[1]
+		if len(parts) == 3 {
+			projection = parts[2]
+		}
+	} else if len(parts) > 2 {
+		return fmt.Errorf("lsi tag value should be formatted as Name")
+	} else if len(parts) == 2 {
+		projection = parts[1]
+	}
+
+	if projection != "" {
+		if index.projection != "" && index.projection != projection {
+			return fmt.Errorf("index %s has more than one projection type", parts[0])
+		}
+
+		index.projection = projection
+	}
+
+	switch keyType {
+	case "hash":
+		return setKey(&index.hash, name, fmt.Sprintf("hash key of index %s", parts[0]))
+	case "range":
+		return setKey(&index.rangeKey, name, fmt.Sprintf("range key of index %s", parts[0]))
+	default:
+		return fmt.Errorf("unknown key type %q for index %s", keyType, parts[0])
+	}
+}
+
+// Helper function that sets a key to an attribute name, returning an error if it has already been set
+func setKey(key *string, name string, description string) error {
+	if *key != "" {
+		return fmt.Errorf("%s was already defined as %s", description, *key)
+	}
+
+	*key = name
+	return nil
+}
+
+// Helper function that creates a key schema from a hash key and an optional range key
+func keySchema(hash string, rangeKey string) []types.KeySchemaElement {
+	schema := []types.KeySchemaElement{{AttributeName: aws.String(hash), KeyType: types.KeyTypeHash}}
+	if rangeKey != "" {
+		schema = append(schema, types.KeySchemaElement{AttributeName: aws.String(rangeKey), KeyType: types.KeyTypeRange})
+	}
+
+	return schema
+}
+
+// Helper function that determines the DynamoDB attribute type of a field that is used as a key. Strings
+// and times will be stored as strings, numeric types as numbers and byte slices as binary. All other
+// types cannot be used as keys
+func keyAttributeType(fType reflect.Type) (types.ScalarAttributeType, bool) {
+	for fType.Kind() == reflect.Ptr {
+		fType = fType.Elem()
+	}
+
+	switch fType.Kind() {
+	case reflect.String:
+		return types.ScalarAttributeTypeS, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return types.ScalarAttributeTypeN, true
+	case reflect.Slice, reflect.Array:
+		if fType.Elem().Kind() == reflect.Uint8 {
+			return types.ScalarAttributeTypeB, true
+		}
+	case reflect.Struct:
+		if fType == reflect.TypeOf(time.Time{}) {
+			return types.ScalarAttributeTypeS, true
+		}
+	}
+
+	return "", false
+}
+
+// Helper function that determines whether a field type, or the type it points to, is an integer type
+func isIntegerType(fType reflect.Type) bool {
+	for fType.Kind() == reflect.Ptr {
+		fType = fType.Elem()
+	}
+
+	switch fType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,194 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	fake "github.com/Woody1193/goutils/dynamodb/testing"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Table Definition Tests", func() {
+
+	// Test that, if the type is annotated with keys and indexes, then NewTableDefinition will create
+	// a request describing the table, its attributes and indexes
+	It("NewTableDefinition - Annotated type - Definition created", func() {
+
+		// First, attempt to create the table definition from our annotated type; this should not fail
+		def, err := NewTableDefinition[schemaObject]("TEST_TABLE",
+			WithTableTagKey("json"), WithProvisionedThroughput{Read: 5, Write: 10})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Next, verify the TTL attribute and the request that will enable it
+		Expect(def.TTLAttribute).Should(Equal("expires"))
+		Expect(def.TimeToLive()).Should(Equal(&dynamodb.UpdateTimeToLiveInput{
+			TableName: aws.String("TEST_TABLE"),
+			TimeToLiveSpecification: &types.TimeToLiveSpecification{
+				AttributeName: aws.String("expires"),
+				Enabled:       aws.Bool(true)}}))
+
+		// Finally, verify the request that will create the table
+		throughput := &types.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(5), WriteCapacityUnits: aws.Int64(10)}
+		Expect(def.Input).Should(Equal(&dynamodb.CreateTableInput{
+			TableName:   aws.String("TEST_TABLE"),
+			BillingMode: types.BillingModeProvisioned,
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("sort_key"), AttributeType: types.ScalarAttributeTypeN},
+				{AttributeName: aws.String("owner"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("created"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("Hash"), AttributeType: types.ScalarAttributeTypeB}},
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("sort_key"), KeyType: types.KeyTypeRange}},
+			GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+				{
+					IndexName: aws.String("ByHash"),
+					KeySchema: []types.KeySchemaElement{
+						{AttributeName: aws.String("Hash"), KeyType: types.KeyTypeHash}},
+					Projection:            &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly},
+					ProvisionedThroughput: throughput,
+				},
+				{
+					IndexName: aws.String("ByOwner"),
+					KeySchema: []types.KeySchemaElement{
+						{AttributeName: aws.String("owner"), KeyType: types.KeyTypeHash},
+						{AttributeName: aws.String("created"), KeyType: types.KeyTypeRange}},
+					Projection: &types.Projection{ProjectionType: types.ProjectionTypeInclude,
+						NonKeyAttributes: []string{"data"}},
+					ProvisionedThroughput: throughput,
+				}},
+			LocalSecondaryIndexes: []types.LocalSecondaryIndex{
+				{
+					IndexName: aws.String("ByCreated"),
+					KeySchema: []types.KeySchemaElement{
+						{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash},
+						{AttributeName: aws.String("created"), KeyType: types.KeyTypeRange}},
+					Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+				}},
+			ProvisionedThroughput: throughput,
+		}))
+	})
+
+	// Test that the requests produced by a table definition can be used to create the table it describes
+	// and enable TTL on it, as is done when creating tables for testing
+	It("CreateTableInput, TimeToLive - Requests sent - Table created", func() {
+
+		// First, create the table definition from our annotated type; this should not fail
+		def, err := NewTableDefinition[schemaObject]("TEST_TABLE", WithTableTagKey("json"))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Next, create the table and enable TTL on it with the requests from the definition; neither should fail
+		client := fake.NewFakeDynamoDB()
+		_, err = client.CreateTable(context.Background(), def.CreateTableInput())
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = client.UpdateTimeToLive(context.Background(), def.TimeToLive())
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, verify that the table was created with the keys and indexes of the definition
+		table, err := client.DescribeTable(context.Background(),
+			&dynamodb.DescribeTableInput{TableName: aws.String("TEST_TABLE")})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(table.Table.KeySchema).Should(Equal(def.Input.KeySchema))
+		Expect(table.Table.GlobalSecondaryIndexes).Should(HaveLen(len(def.Input.GlobalSecondaryIndexes)))
+
+		// Finally, verify that TTL was enabled on the attribute of the definition
+		ttl, err := client.DescribeTimeToLive(context.Background(),
+			&dynamodb.DescribeTimeToLiveInput{TableName: aws.String("TEST_TABLE")})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(ttl.TimeToLiveDescription.TimeToLiveStatus).Should(Equal(types.TimeToLiveStatusEnabled))
+		Expect(ttl.TimeToLiveDescription.AttributeName).Should(Equal(aws.String("expires")))
+	})
+
+	// Test that, if no options are provided, then NewTableDefinition will bill the table per request
+	// and name attributes from the default tag key
+	It("NewTableDefinition - No options - Defaults used", func() {
+
+		// First, attempt to create the table definition from our annotated type; this should not fail
+		def, err := NewTableDefinition[schemaObject]("TEST_TABLE")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the billing mode and the names of the keys
+		Expect(def.Input.BillingMode).Should(Equal(types.BillingModePayPerRequest))
+		Expect(def.Input.ProvisionedThroughput).Should(BeNil())
+		Expect(def.Input.GlobalSecondaryIndexes[0].ProvisionedThroughput).Should(BeNil())
+		Expect(*def.Input.KeySchema[0].AttributeName).Should(Equal("ID"))
+		Expect(*def.Input.KeySchema[1].AttributeName).Should(Equal("SortKey"))
+	})
+
+	// Test that, if the tags describe an invalid schema, then NewTableDefinition will return an error
+	DescribeTable("NewTableDefinition - Invalid schema - Error",
+		func(create func() (*TableDefinition, error), message string) {
+			def, err := create()
+			Expect(def).Should(BeNil())
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).Should(Equal(message))
+		},
+		Entry("No hash key", func() (*TableDefinition, error) {
+			return NewTableDefinition[testObject]("TEST_TABLE")
+		}, "dynamodb.testObject has no field tagged as the hash key"),
+		Entry("Invalid key type", func() (*TableDefinition, error) {
+			return NewTableDefinition[invalidKeySchema]("TEST_TABLE")
+		}, "field ID on dynamodb.invalidKeySchema has type bool which cannot be used as a key"),
+		Entry("Unknown tag value", func() (*TableDefinition, error) {
+			return NewTableDefinition[unknownTagSchema]("TEST_TABLE")
+		}, "field ID on dynamodb.unknownTagSchema: unknown schema tag value \"primary\""),
+		Entry("Duplicate hash key", func() (*TableDefinition, error) {
+			return NewTableDefinition[duplicateHashSchema]("TEST_TABLE")
+		}, "field Other on dynamodb.duplicateHashSchema: hash key of the table was already defined as ID"),
+		Entry("Local index without range key", func() (*TableDefinition, error) {
+			return NewTableDefinition[missingRangeSchema]("TEST_TABLE")
+		}, "index ByOther on dynamodb.missingRangeSchema is local but the table has no range key"),
+		Entry("Invalid TTL type", func() (*TableDefinition, error) {
+			return NewTableDefinition[invalidTTLSchema]("TEST_TABLE")
+		}, "field Expires on dynamodb.invalidTTLSchema: type time.Time cannot be used as a TTL attribute "+
+			"as it is not an integer"),
+		Entry("Interface type", func() (*TableDefinition, error) {
+			return NewTableDefinition[fmt.Stringer]("TEST_TABLE")
+		}, "fmt.Stringer is not a struct type"))
+})
+
+// Helper type that can be used to test table definitions
+type schemaObject struct {
+	ID      string    `json:"id" dynamodb:"hash"`
+	SortKey int       `json:"sort_key" dynamodb:"range"`
+	Owner   string    `json:"owner" dynamodb:"gsi=ByOwner/hash"`
+	Created time.Time `json:"created" dynamodb:"gsi=ByOwner/range,lsi=ByCreated"`
+	Data    string    `json:"data" dynamodb:"project=ByOwner"`
+	Hash    []byte    `dynamodb:"gsi=ByHash/hash/keys"`
+	Expires int64     `json:"expires" dynamodb:"ttl"`
+	Version int       `json:"version" dynamodb:"version"`
+}
+
+// Helper type with a key that cannot be stored as a key attribute
+type invalidKeySchema struct {
+	ID bool `dynamodb:"hash"`
+}
+
+// Helper type with a schema tag value that isn't recognised
+type unknownTagSchema struct {
+	ID string `dynamodb:"hash,primary"`
+}
+
+// Helper type with more than one hash key
+type duplicateHashSchema struct {
+	ID    string `dynamodb:"hash"`
+	Other string `dynamodb:"hash"`
+}
+
+// Helper type with a local secondary index but no range key
+type missingRangeSchema struct {
+	ID    string `dynamodb:"hash"`
+	Other string `dynamodb:"lsi=ByOther"`
+}
+
+// Helper type with a TTL attribute that isn't stored in epoch seconds
+type invalidTTLSchema struct {
+	ID      string    `dynamodb:"hash"`
+	Expires time.Time `dynamodb:"ttl"`
+}
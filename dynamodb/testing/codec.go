@@ -0,0 +1,515 @@
+package testing
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// Helper variables containing the types that must be handled specially when encoding requests and responses
+var (
+	attributeValueType = reflect.TypeOf((*types.AttributeValue)(nil)).Elem()
+	metadataType       = reflect.TypeOf(middleware.Metadata{})
+	timeType           = reflect.TypeOf(time.Time{})
+	bytesType          = reflect.TypeOf([]byte{})
+)
+
+// Helper function that encodes a request, response or error from the SDK into a tree of maps, lists and
+// JSON-compatible values. Zero-valued fields are omitted and attribute values are encoded in the same way
+// that DynamoDB would encode them (e.g. {"S": "value"}) so that the type of each is preserved. The tree is
+// then normalized so that it can be compared with a tree read from a fixture file
+func encode(value interface{}) (interface{}, error) {
+
+	// First, encode the value into a tree
+	tree, err := encodeValue(reflect.ValueOf(value))
+	if err != nil {
+		return nil, err
+	}
+
+	// Next, convert the tree to JSON and back so that numbers are represented in the same way as those
+	// read from a fixture file
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeJSON(data)
+}
+
+// Helper function that decodes a tree, created by encode, into the value pointed to by the target
+func decode(tree interface{}, target interface{}) error {
+	return decodeValue(tree, reflect.ValueOf(target).Elem())
+}
+
+// Helper function that decodes JSON data into a tree, preserving the precision of numbers
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+// Helper function that encodes a single value from a request or response into a tree
+func encodeValue(value reflect.Value) (interface{}, error) {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Pointer:
+		if value.IsNil() {
+			return nil, nil
+		}
+
+		return encodeValue(value.Elem())
+	case reflect.Interface:
+		if value.IsNil() {
+			return nil, nil
+		} else if value.Type() != attributeValueType {
+			return nil, fmt.Errorf("values of type %s cannot be encoded", value.Type())
+		}
+
+		return encodeAttribute(value.Interface().(types.AttributeValue))
+	case reflect.Struct:
+		if value.Type() == timeType {
+			return value.Interface().(time.Time).Format(time.RFC3339Nano), nil
+		}
+
+		// Encode each of the exported, non-zero fields on the struct, ignoring the response metadata
+		// as it is only used by the SDK's middleware
+		fields := make(map[string]interface{})
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() || field.Type == metadataType || value.Field(i).IsZero() {
+				continue
+			}
+
+			encoded, err := encodeValue(value.Field(i))
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %v", field.Name, err)
+			}
+
+			fields[field.Name] = encoded
+		}
+
+		return fields, nil
+	case reflect.Slice:
+		if value.IsNil() {
+			return nil, nil
+		} else if value.Type() == bytesType {
+			return base64.StdEncoding.EncodeToString(value.Bytes()), nil
+		}
+
+		list := make([]interface{}, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			encoded, err := encodeValue(value.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %v", i, err)
+			}
+
+			list[i] = encoded
+		}
+
+		return list, nil
+	case reflect.Map:
+		if value.IsNil() {
+			return nil, nil
+		} else if value.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("maps with keys of type %s cannot be encoded", value.Type().Key())
+		}
+
+		entries := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			encoded, err := encodeValue(iter.Value())
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %v", iter.Key().String(), err)
+			}
+
+			entries[iter.Key().String()] = encoded
+		}
+
+		return entries, nil
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Bool:
+		return value.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	default:
+		return nil, fmt.Errorf("values of type %s cannot be encoded", value.Type())
+	}
+}
+
+// Helper function that encodes an attribute value into a tree with a single key describing its type
+func encodeAttribute(value types.AttributeValue) (interface{}, error) {
+	switch casted := value.(type) {
+	case *types.AttributeValueMemberB:
+		return map[string]interface{}{"B": base64.StdEncoding.EncodeToString(casted.Value)}, nil
+	case *types.AttributeValueMemberBOOL:
+		return map[string]interface{}{"BOOL": casted.Value}, nil
+	case *types.AttributeValueMemberBS:
+		set := make([]interface{}, len(casted.Value))
+		for i, inner := range casted.Value {
+			set[i] = base64.StdEncoding.EncodeToString(inner)
+		}
+
+		return map[string]interface{}{"BS": set}, nil
+	case *types.AttributeValueMemberL:
+		list := make([]interface{}, len(casted.Value))
+		for i, inner := range casted.Value {
+			encoded, err := encodeAttribute(inner)
+			if err != nil {
+				return nil, err
+			}
+
+			list[i] = encoded
+		}
+
+		return map[string]interface{}{"L": list}, nil
+	case *types.AttributeValueMemberM:
+		entries := make(map[string]interface{}, len(casted.Value))
+		for key, inner := range casted.Value {
+			encoded, err := encodeAttribute(inner)
+			if err != nil {
+				return nil, err
+			}
+
+			entries[key] = encoded
+		}
+
+		return map[string]interface{}{"M": entries}, nil
+	case *types.AttributeValueMemberN:
+		return map[string]interface{}{"N": casted.Value}, nil
+	case *types.AttributeValueMemberNS:
+		return map[string]interface{}{"NS": casted.Value}, nil
+	case *types.AttributeValueMemberNULL:
+		return map[string]interface{}{"NULL": casted.Value}, nil
+	case *types.AttributeValueMemberS:
+		return map[string]interface{}{"S": casted.Value}, nil
+	case *types.AttributeValueMemberSS:
+		return map[string]interface{}{"SS": casted.Value}, nil
+	default:
+		return nil, fmt.Errorf("attribute value of type %T cannot be encoded", value)
+	}
+}
+
+// Helper function that decodes a tree into a single value of a request or response
+func decodeValue(tree interface{}, target reflect.Value) error {
+	if tree == nil {
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.Pointer:
+		value := reflect.New(target.Type().Elem())
+		if err := decodeValue(tree, value.Elem()); err != nil {
+			return err
+		}
+
+		target.Set(value)
+	case reflect.Interface:
+		if target.Type() != attributeValueType {
+			return fmt.Errorf("values of type %s cannot be decoded", target.Type())
+		}
+
+		value, err := decodeAttribute(tree)
+		if err != nil {
+			return err
+		}
+
+		target.Set(reflect.ValueOf(value))
+	case reflect.Struct:
+		if target.Type() == timeType {
+			str, err := asType[string](tree)
+			if err != nil {
+				return err
+			}
+
+			parsed, err := time.Parse(time.RFC3339Nano, str)
+			if err != nil {
+				return err
+			}
+
+			target.Set(reflect.ValueOf(parsed))
+			return nil
+		}
+
+		// Decode each of the fields in the tree into the field with the same name on the struct
+		fields, err := asType[map[string]interface{}](tree)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range sortedNames(fields) {
+			field, ok := target.Type().FieldByName(name)
+			if !ok || !field.IsExported() {
+				return fmt.Errorf("%s has no field named %s", target.Type(), name)
+			} else if err := decodeValue(fields[name], target.FieldByIndex(field.Index)); err != nil {
+				return fmt.Errorf("field %s: %v", name, err)
+			}
+		}
+	case reflect.Slice:
+		if target.Type() == bytesType {
+			str, err := asType[string](tree)
+			if err != nil {
+				return err
+			}
+
+			data, err := base64.StdEncoding.DecodeString(str)
+			if err != nil {
+				return err
+			}
+
+			target.SetBytes(data)
+			return nil
+		}
+
+		list, err := asType[[]interface{}](tree)
+		if err != nil {
+			return err
+		}
+
+		slice := reflect.MakeSlice(target.Type(), len(list), len(list))
+		for i, inner := range list {
+			if err := decodeValue(inner, slice.Index(i)); err != nil {
+				return fmt.Errorf("index %d: %v", i, err)
+			}
+		}
+
+		target.Set(slice)
+	case reflect.Map:
+		entries, err := asType[map[string]interface{}](tree)
+		if err != nil {
+			return err
+		}
+
+		mapping := reflect.MakeMapWithSize(target.Type(), len(entries))
+		for key, inner := range entries {
+			value := reflect.New(target.Type().Elem()).Elem()
+			if err := decodeValue(inner, value); err != nil {
+				return fmt.Errorf("key %s: %v", key, err)
+			}
+
+			mapping.SetMapIndex(reflect.ValueOf(key).Convert(target.Type().Key()), value)
+		}
+
+		target.Set(mapping)
+	case reflect.String:
+		str, err := asType[string](tree)
+		if err != nil {
+			return err
+		}
+
+		target.SetString(str)
+	case reflect.Bool:
+		value, err := asType[bool](tree)
+		if err != nil {
+			return err
+		}
+
+		target.SetBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, err := asType[json.Number](tree)
+		if err != nil {
+			return err
+		}
+
+		value, err := number.Int64()
+		if err != nil {
+			return err
+		}
+
+		target.SetInt(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, err := asType[json.Number](tree)
+		if err != nil {
+			return err
+		}
+
+		var value uint64
+		if _, err := fmt.Sscan(number.String(), &value); err != nil {
+			return err
+		}
+
+		target.SetUint(value)
+	case reflect.Float32, reflect.Float64:
+		number, err := asType[json.Number](tree)
+		if err != nil {
+			return err
+		}
+
+		value, err := number.Float64()
+		if err != nil {
+			return err
+		}
+
+		target.SetFloat(value)
+	default:
+		return fmt.Errorf("values of type %s cannot be decoded", target.Type())
+	}
+
+	return nil
+}
+
+// Helper function that decodes a tree, with a single key describing its type, into an attribute value
+func decodeAttribute(tree interface{}) (types.AttributeValue, error) {
+
+	// First, ensure that the tree has a single key, which describes the type of the attribute
+	entries, err := asType[map[string]interface{}](tree)
+	if err != nil {
+		return nil, err
+	} else if len(entries) != 1 {
+		return nil, fmt.Errorf("attribute value must have exactly one type but had %d", len(entries))
+	}
+
+	var kind string
+	var inner interface{}
+	for key, value := range entries {
+		kind, inner = key, value
+	}
+
+	// Next, decode the value based on the type of the attribute
+	switch kind {
+	case "B":
+		value := new(types.AttributeValueMemberB)
+		return value, decodeValue(inner, reflect.ValueOf(&value.Value).Elem())
+	case "BOOL":
+		value := new(types.AttributeValueMemberBOOL)
+		return value, decodeValue(inner, reflect.ValueOf(&value.Value).Elem())
+	case "BS":
+		value := new(types.AttributeValueMemberBS)
+		return value, decodeValue(inner, reflect.ValueOf(&value.Value).Elem())
+	case "N":
+		value := new(types.AttributeValueMemberN)
+		return value, decodeValue(inner, reflect.ValueOf(&value.Value).Elem())
+	case "NS":
+		value := new(types.AttributeValueMemberNS)
+		return value, decodeValue(inner, reflect.ValueOf(&value.Value).Elem())
+	case "NULL":
+		value := new(types.AttributeValueMemberNULL)
+		return value, decodeValue(inner, reflect.ValueOf(&value.Value).Elem())
+	case "S":
+		value := new(types.AttributeValueMemberS)
+		return value, decodeValue(inner, reflect.ValueOf(&value.Value).Elem())
+	case "SS":
+		value := new(types.AttributeValueMemberSS)
+		return value, decodeValue(inner, reflect.ValueOf(&value.Value).Elem())
+	case "L":
+		list, err := asType[[]interface{}](inner)
+		if err != nil {
+			return nil, err
+		}
+
+		value := &types.AttributeValueMemberL{Value: make([]types.AttributeValue, len(list))}
+		for i, item := range list {
+			if value.Value[i], err = decodeAttribute(item); err != nil {
+				return nil, err
+			}
+		}
+
+		return value, nil
+	case "M":
+		mapping, err := asType[map[string]interface{}](inner)
+		if err != nil {
+			return nil, err
+		}
+
+		value := &types.AttributeValueMemberM{Value: make(map[string]types.AttributeValue, len(mapping))}
+		for key, item := range mapping {
+			if value.Value[key], err = decodeAttribute(item); err != nil {
+				return nil, err
+			}
+		}
+
+		return value, nil
+	default:
+		return nil, fmt.Errorf("attribute value type %s is not valid", kind)
+	}
+}
+
+// Helper function that casts part of a tree to the type expected, returning an error if it has another type
+func asType[T any](tree interface{}) (T, error) {
+	casted, ok := tree.(T)
+	if !ok {
+		return casted, fmt.Errorf("expected %T but found %T", casted, tree)
+	}
+
+	return casted, nil
+}
+
+// Helper function that finds the first path at which two trees differ. This function will return an empty
+// string if the trees are equal
+func firstDifference(path string, first interface{}, second interface{}) string {
+	switch casted := first.(type) {
+	case map[string]interface{}:
+		other, ok := second.(map[string]interface{})
+		if !ok {
+			return rootPath(path)
+		}
+
+		// Check each of the keys in either map, in order, so that the difference is deterministic
+		keys := make(map[string]interface{}, len(casted)+len(other))
+		for key := range casted {
+			keys[key] = nil
+		}
+
+		for key := range other {
+			keys[key] = nil
+		}
+
+		for _, key := range sortedNames(keys) {
+			inner := key
+			if path != "" {
+				inner = path + "." + key
+			}
+
+			if diff := firstDifference(inner, casted[key], other[key]); diff != "" {
+				return diff
+			}
+		}
+
+		return ""
+	case []interface{}:
+		other, ok := second.([]interface{})
+		if !ok || len(casted) != len(other) {
+			return rootPath(path)
+		}
+
+		for i := range casted {
+			if diff := firstDifference(fmt.Sprintf("%s[%d]", path, i), casted[i], other[i]); diff != "" {
+				return diff
+			}
+		}
+
+		return ""
+	default:
+		if !reflect.DeepEqual(first, second) {
+			return rootPath(path)
+		}
+
+		return ""
+	}
+}
+
+// Helper function that describes the root of a tree with a name that isn't empty
+func rootPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+
+	return path
+}
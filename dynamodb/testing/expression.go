@@ -0,0 +1,1210 @@
+package testing
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Helper type that describes the kind of a token in an expression
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenIdentifier
+	tokenName
+	tokenValue
+	tokenNumber
+	tokenSymbol
+)
+
+// Helper type that describes a single token in an expression
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// Helper type that describes a single element in a document path, which is either the name of an
+// attribute or an index into a list
+type pathElement struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// Helper type that describes a document path
+type documentPath []pathElement
+
+// String converts the path to its string representation, for use in error messages
+func (path documentPath) String() string {
+	var builder strings.Builder
+	for i, element := range path {
+		if element.isIndex {
+			builder.WriteString(fmt.Sprintf("[%d]", element.index))
+		} else {
+			if i > 0 {
+				builder.WriteString(".")
+			}
+
+			builder.WriteString(element.name)
+		}
+	}
+
+	return builder.String()
+}
+
+// Helper type that collects the attribute names and values that may be referenced by the expressions
+// in a single request, and records which of them have been used so that unused names and values can
+// be reported, as DynamoDB does
+type expressionContext struct {
+	names      map[string]string
+	values     map[string]types.AttributeValue
+	usedNames  map[string]bool
+	usedValues map[string]bool
+}
+
+// Helper function that creates a new expression context from the attribute names and values on a request
+func newExpressionContext(names map[string]string, values map[string]types.AttributeValue) *expressionContext {
+	return &expressionContext{
+		names:      names,
+		values:     values,
+		usedNames:  make(map[string]bool),
+		usedValues: make(map[string]bool),
+	}
+}
+
+// Helper function that checks that all the attribute names and values on a request were used by its
+// expressions, returning an error describing those that were not
+func (ctx *expressionContext) checkUnused() error {
+	unusedNames := make([]string, 0)
+	for name := range ctx.names {
+		if !ctx.usedNames[name] {
+			unusedNames = append(unusedNames, name)
+		}
+	}
+
+	if len(unusedNames) > 0 {
+		sort.Strings(unusedNames)
+		return validationError("Value provided in ExpressionAttributeNames unused in expressions: keys: {%s}",
+			strings.Join(unusedNames, ", "))
+	}
+
+	unusedValues := make([]string, 0)
+	for name := range ctx.values {
+		if !ctx.usedValues[name] {
+			unusedValues = append(unusedValues, name)
+		}
+	}
+
+	if len(unusedValues) > 0 {
+		sort.Strings(unusedValues)
+		return validationError("Value provided in ExpressionAttributeValues unused in expressions: keys: {%s}",
+			strings.Join(unusedValues, ", "))
+	}
+
+	return nil
+}
+
+// Helper function that parses a condition, filter or key condition expression. If the expression is
+// empty then a nil condition will be returned
+func (ctx *expressionContext) parseCondition(kind string, expression *string) (condition, error) {
+	if expression == nil {
+		return nil, nil
+	}
+
+	p, err := ctx.newParser(kind, *expression)
+	if err != nil {
+		return nil, err
+	}
+
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectEnd(); err != nil {
+		return nil, err
+	}
+
+	return cond, nil
+}
+
+// Helper function that parses an update expression into its actions
+func (ctx *expressionContext) parseUpdate(expression *string) ([]*updateAction, error) {
+	if expression == nil {
+		return nil, nil
+	}
+
+	p, err := ctx.newParser("UpdateExpression", *expression)
+	if err != nil {
+		return nil, err
+	}
+
+	// Iterate over the clauses in the expression, parsing the actions in each
+	actions := make([]*updateAction, 0)
+	seen := make(map[string]bool)
+	for p.peek().kind != tokenEnd {
+
+		// First, get the clause keyword and ensure that it is valid and hasn't been seen before
+		keyword := strings.ToUpper(p.next().text)
+		if keyword != "SET" && keyword != "REMOVE" && keyword != "ADD" && keyword != "DELETE" {
+			return nil, p.syntaxError(keyword)
+		} else if seen[keyword] {
+			return nil, p.errorf("The \"%s\" section can only be used once in an update expression", keyword)
+		}
+
+		seen[keyword] = true
+
+		// Next, parse each action in the clause until we reach the next clause or the end
+		for {
+			action := &updateAction{kind: keyword}
+			path, err := p.parsePath()
+			if err != nil {
+				return nil, err
+			}
+
+			action.path = path
+			switch keyword {
+			case "SET":
+				if err := p.expectSymbol("="); err != nil {
+					return nil, err
+				}
+
+				if action.value, err = p.parseSetValue(); err != nil {
+					return nil, err
+				}
+			case "ADD", "DELETE":
+				if action.operand, err = p.parseOperand(); err != nil {
+					return nil, err
+				}
+			}
+
+			actions = append(actions, action)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+	}
+
+	if len(actions) == 0 {
+		return nil, p.errorf("The expression can not be empty")
+	}
+
+	return actions, nil
+}
+
+// Helper function that parses a projection expression into the paths it contains
+func (ctx *expressionContext) parseProjection(expression *string) ([]documentPath, error) {
+	if expression == nil {
+		return nil, nil
+	}
+
+	p, err := ctx.newParser("ProjectionExpression", *expression)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]documentPath, 0)
+	for {
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, path)
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+
+	if err := p.expectEnd(); err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// Helper type that parses a single expression
+type parser struct {
+	ctx    *expressionContext
+	kind   string
+	tokens []token
+	pos    int
+}
+
+// Helper function that tokenizes an expression and creates a parser for it
+func (ctx *expressionContext) newParser(kind string, expression string) (*parser, error) {
+	p := &parser{ctx: ctx, kind: kind}
+	if strings.TrimSpace(expression) == "" {
+		return nil, p.errorf("The expression can not be empty")
+	}
+
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '#' || r == ':' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			start := i
+			i++
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+
+			text := string(runes[start:i])
+			kind := tokenIdentifier
+			switch {
+			case r == '#':
+				kind = tokenName
+			case r == ':':
+				kind = tokenValue
+			case unicode.IsDigit(r):
+				kind = tokenNumber
+			}
+
+			if (kind == tokenName || kind == tokenValue) && len(text) == 1 {
+				return nil, p.syntaxError(text)
+			}
+
+			p.tokens = append(p.tokens, token{kind: kind, text: text})
+		default:
+			text := string(r)
+			if i+1 < len(runes) {
+				switch pair := string(runes[i : i+2]); pair {
+				case "<>", "<=", ">=":
+					text = pair
+				}
+			}
+
+			if !strings.Contains("<>=(),.[]+-", string(r)) {
+				return nil, p.syntaxError(text)
+			}
+
+			p.tokens = append(p.tokens, token{kind: tokenSymbol, text: text})
+			i += len(text)
+		}
+	}
+
+	return p, nil
+}
+
+// Helper function that parses a sequence of conditions joined by OR
+func (p *parser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &logicalCondition{and: false, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// Helper function that parses a sequence of conditions joined by AND
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &logicalCondition{and: true, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// Helper function that parses a condition that may be negated
+func (p *parser) parseNot() (condition, error) {
+	if p.acceptKeyword("NOT") {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return &notCondition{inner: inner}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+// Helper function that parses a parenthesized condition, a function or a comparison
+func (p *parser) parsePrimary() (condition, error) {
+
+	// First, if we have an opening parenthesis then parse the condition inside it
+	if p.acceptSymbol("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+
+		return inner, nil
+	}
+
+	// Next, if we have a function (other than size, which is an operand) then parse it
+	if next := p.peek(); next.kind == tokenIdentifier && p.peekAt(1).text == "(" && next.text != "size" {
+		return p.parseFunction()
+	}
+
+	// Now, parse the operand on the left-hand side of the comparison
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	// Finally, determine the kind of comparison being made and parse the rest of it
+	if p.acceptKeyword("BETWEEN") {
+		low, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.acceptKeyword("AND") {
+			return nil, p.syntaxError(p.peek().text)
+		}
+
+		high, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		return &betweenCondition{value: left, low: low, high: high}, nil
+	} else if p.acceptKeyword("IN") {
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+
+		options := make([]operand, 0)
+		for {
+			option, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+
+			options = append(options, option)
+			if !p.acceptSymbol(",") {
+				break
+			}
+		}
+
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+
+		return &inCondition{value: left, options: options}, nil
+	}
+
+	next := p.next()
+	switch next.text {
+	case "=", "<>", "<", "<=", ">", ">=":
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		return &comparison{operator: next.text, left: left, right: right}, nil
+	default:
+		return nil, p.syntaxError(next.text)
+	}
+}
+
+// Helper function that parses a function that returns a boolean
+func (p *parser) parseFunction() (condition, error) {
+
+	// First, get the name of the function and ensure that it's one we support
+	name := p.next().text
+	arguments := 2
+	switch name {
+	case "attribute_exists", "attribute_not_exists":
+		arguments = 1
+	case "attribute_type", "begins_with", "contains":
+	default:
+		return nil, p.errorf("Invalid function name; function: %s", name)
+	}
+
+	// Next, parse the path the function operates on
+	p.next()
+	path, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+
+	// Now, parse the second argument, if the function requires one
+	function := &functionCondition{name: name, path: path}
+	if arguments == 2 {
+		if err := p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+
+		if function.argument, err = p.parseOperand(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Finally, ensure that the function is closed and return it
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+
+	return function, nil
+}
+
+// Helper function that parses the value assigned by a SET action
+func (p *parser) parseSetValue() (setValue, error) {
+	left, err := p.parseSetOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if next := p.peek(); next.kind == tokenSymbol && (next.text == "+" || next.text == "-") {
+		p.next()
+		right, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		return &arithmeticValue{operator: next.text, left: left, right: right}, nil
+	}
+
+	return left, nil
+}
+
+// Helper function that parses an operand, or function, used in the value assigned by a SET action
+func (p *parser) parseSetOperand() (setValue, error) {
+
+	// First, check if we have a function; if we don't then parse the operand
+	next := p.peek()
+	if next.kind != tokenIdentifier || p.peekAt(1).text != "(" || next.text == "size" {
+		inner, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		return &operandValue{inner: inner}, nil
+	}
+
+	// Next, parse the arguments of the function based on its name
+	p.next()
+	p.next()
+	var value setValue
+	switch next.text {
+	case "if_not_exists":
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+
+		fallback, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		value = &ifNotExistsValue{path: path, fallback: fallback}
+	case "list_append":
+		first, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+
+		second, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		value = &listAppendValue{first: first, second: second}
+	default:
+		return nil, p.errorf("Invalid function name; function: %s", next.text)
+	}
+
+	// Finally, ensure that the function is closed and return it
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// Helper function that parses an operand, which is either a path, a value or the size of a path
+func (p *parser) parseOperand() (operand, error) {
+	next := p.peek()
+	switch {
+	case next.kind == tokenValue:
+		p.next()
+		value, ok := p.ctx.values[next.text]
+		if !ok {
+			return nil, p.errorf("An expression attribute value used in expression is not defined; "+
+				"attribute value: %s", next.text)
+		}
+
+		p.ctx.usedValues[next.text] = true
+		return &valueOperand{value: value}, nil
+	case next.kind == tokenIdentifier && next.text == "size" && p.peekAt(1).text == "(":
+		p.next()
+		p.next()
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+
+		return &sizeOperand{path: path}, nil
+	default:
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+
+		return &pathOperand{path: path}, nil
+	}
+}
+
+// Helper function that parses a document path, resolving any attribute name placeholders
+func (p *parser) parsePath() (documentPath, error) {
+	path := make(documentPath, 0)
+	for {
+
+		// First, parse the name of the attribute, resolving it if it's a placeholder
+		next := p.next()
+		switch next.kind {
+		case tokenIdentifier:
+			path = append(path, pathElement{name: next.text})
+		case tokenName:
+			name, ok := p.ctx.names[next.text]
+			if !ok {
+				return nil, p.errorf("An expression attribute name used in the document path is not defined; "+
+					"attribute name: %s", next.text)
+			}
+
+			p.ctx.usedNames[next.text] = true
+			path = append(path, pathElement{name: name})
+		default:
+			return nil, p.syntaxError(next.text)
+		}
+
+		// Next, parse any list indexes that follow the name
+		for p.acceptSymbol("[") {
+			index := p.next()
+			if index.kind != tokenNumber {
+				return nil, p.syntaxError(index.text)
+			}
+
+			value, err := strconv.Atoi(index.text)
+			if err != nil {
+				return nil, p.syntaxError(index.text)
+			}
+
+			if err := p.expectSymbol("]"); err != nil {
+				return nil, err
+			}
+
+			path = append(path, pathElement{index: value, isIndex: true})
+		}
+
+		// Finally, if the path doesn't continue then return it
+		if !p.acceptSymbol(".") {
+			return path, nil
+		}
+	}
+}
+
+// Helper function that gets the next token without consuming it
+func (p *parser) peek() token {
+	return p.peekAt(0)
+}
+
+// Helper function that gets a token ahead of the current position without consuming it
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset < len(p.tokens) {
+		return p.tokens[p.pos+offset]
+	}
+
+	return token{kind: tokenEnd, text: "<EOF>"}
+}
+
+// Helper function that consumes the next token and returns it
+func (p *parser) next() token {
+	next := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+
+	return next
+}
+
+// Helper function that consumes the next token if it is the symbol provided
+func (p *parser) acceptSymbol(symbol string) bool {
+	if next := p.peek(); next.kind == tokenSymbol && next.text == symbol {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+// Helper function that consumes the next token if it is the keyword provided, ignoring case
+func (p *parser) acceptKeyword(keyword string) bool {
+	if next := p.peek(); next.kind == tokenIdentifier && strings.EqualFold(next.text, keyword) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+// Helper function that consumes the next token, returning an error if it isn't the symbol provided
+func (p *parser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return p.syntaxError(p.peek().text)
+	}
+
+	return nil
+}
+
+// Helper function that returns an error if there are any tokens left to consume
+func (p *parser) expectEnd() error {
+	if next := p.peek(); next.kind != tokenEnd {
+		return p.syntaxError(next.text)
+	}
+
+	return nil
+}
+
+// Helper function that creates an error describing an unexpected token in the expression
+func (p *parser) syntaxError(text string) error {
+	return p.errorf("Syntax error; token: \"%s\"", text)
+}
+
+// Helper function that creates an error describing an invalid expression
+func (p *parser) errorf(message string, args ...interface{}) error {
+	return validationError("Invalid %s: %s", p.kind, fmt.Sprintf(message, args...))
+}
+
+// Helper type that describes a boolean condition that can be evaluated against an item
+type condition interface {
+	evaluate(item map[string]types.AttributeValue) bool
+}
+
+// Helper type that describes two conditions joined by AND or OR
+type logicalCondition struct {
+	and   bool
+	left  condition
+	right condition
+}
+
+// Helper function that evaluates the logical condition against an item
+func (cond *logicalCondition) evaluate(item map[string]types.AttributeValue) bool {
+	if cond.and {
+		return cond.left.evaluate(item) && cond.right.evaluate(item)
+	}
+
+	return cond.left.evaluate(item) || cond.right.evaluate(item)
+}
+
+// Helper type that describes a negated condition
+type notCondition struct {
+	inner condition
+}
+
+// Helper function that evaluates the negated condition against an item
+func (cond *notCondition) evaluate(item map[string]types.AttributeValue) bool {
+	return !cond.inner.evaluate(item)
+}
+
+// Helper type that describes a comparison between two operands
+type comparison struct {
+	operator string
+	left     operand
+	right    operand
+}
+
+// Helper function that evaluates the comparison against an item. Comparisons involving attributes
+// that do not exist, or values with different types, are false, except for inequalities
+func (cond *comparison) evaluate(item map[string]types.AttributeValue) bool {
+	left, right := cond.left.evaluate(item), cond.right.evaluate(item)
+	if left == nil || right == nil {
+		return cond.operator == "<>" && (left != nil || right != nil)
+	}
+
+	switch cond.operator {
+	case "=":
+		return valuesEqual(left, right)
+	case "<>":
+		return !valuesEqual(left, right)
+	}
+
+	result, ok := compareValues(left, right)
+	if !ok {
+		return false
+	}
+
+	switch cond.operator {
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	default:
+		return result >= 0
+	}
+}
+
+// Helper type that describes a check that an operand falls within an inclusive range
+type betweenCondition struct {
+	value operand
+	low   operand
+	high  operand
+}
+
+// Helper function that evaluates the range check against an item
+func (cond *betweenCondition) evaluate(item map[string]types.AttributeValue) bool {
+	value := cond.value.evaluate(item)
+	low, lok := compareValues(value, cond.low.evaluate(item))
+	high, hok := compareValues(value, cond.high.evaluate(item))
+	return lok && hok && low >= 0 && high <= 0
+}
+
+// Helper type that describes a check that an operand is equal to one of a number of options
+type inCondition struct {
+	value   operand
+	options []operand
+}
+
+// Helper function that evaluates the membership check against an item
+func (cond *inCondition) evaluate(item map[string]types.AttributeValue) bool {
+	value := cond.value.evaluate(item)
+	for _, option := range cond.options {
+		if value != nil && valuesEqual(value, option.evaluate(item)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Helper type that describes a call to a function that returns a boolean
+type functionCondition struct {
+	name     string
+	path     documentPath
+	argument operand
+}
+
+// Helper function that evaluates the function against an item
+func (cond *functionCondition) evaluate(item map[string]types.AttributeValue) bool {
+	value := resolvePath(item, cond.path)
+	switch cond.name {
+	case "attribute_exists":
+		return value != nil
+	case "attribute_not_exists":
+		return value == nil
+	}
+
+	argument := cond.argument.evaluate(item)
+	if value == nil || argument == nil {
+		return false
+	}
+
+	switch cond.name {
+	case "attribute_type":
+		casted, ok := argument.(*types.AttributeValueMemberS)
+		return ok && typeName(value) == casted.Value
+	case "begins_with":
+		switch casted := value.(type) {
+		case *types.AttributeValueMemberS:
+			prefix, ok := argument.(*types.AttributeValueMemberS)
+			return ok && strings.HasPrefix(casted.Value, prefix.Value)
+		case *types.AttributeValueMemberB:
+			prefix, ok := argument.(*types.AttributeValueMemberB)
+			return ok && bytes.HasPrefix(casted.Value, prefix.Value)
+		}
+
+		return false
+	default:
+		return containsElement(value, argument)
+	}
+}
+
+// Helper type that describes an operand in a condition, which evaluates to an attribute value or to
+// nil if it refers to an attribute that doesn't exist
+type operand interface {
+	evaluate(item map[string]types.AttributeValue) types.AttributeValue
+}
+
+// Helper type that describes an operand that refers to an attribute on the item
+type pathOperand struct {
+	path documentPath
+}
+
+// Helper function that gets the value of the attribute from the item
+func (op *pathOperand) evaluate(item map[string]types.AttributeValue) types.AttributeValue {
+	return resolvePath(item, op.path)
+}
+
+// Helper type that describes an operand that refers to an expression attribute value
+type valueOperand struct {
+	value types.AttributeValue
+}
+
+// Helper function that gets the expression attribute value
+func (op *valueOperand) evaluate(item map[string]types.AttributeValue) types.AttributeValue {
+	return op.value
+}
+
+// Helper type that describes an operand that gets the size of an attribute on the item
+type sizeOperand struct {
+	path documentPath
+}
+
+// Helper function that gets the size of the attribute on the item as a number
+func (op *sizeOperand) evaluate(item map[string]types.AttributeValue) types.AttributeValue {
+	if size, ok := valueSize(resolvePath(item, op.path)); ok {
+		return &types.AttributeValueMemberN{Value: strconv.Itoa(size)}
+	}
+
+	return nil
+}
+
+// Helper type that describes a single action in an update expression
+type updateAction struct {
+	kind    string
+	path    documentPath
+	value   setValue
+	operand operand
+}
+
+// Helper function that applies the update action to an item
+func (action *updateAction) apply(item map[string]types.AttributeValue) error {
+	switch action.kind {
+	case "SET":
+
+		// Evaluate the value against the item and set it at the path
+		value, err := action.value.evaluate(item)
+		if err != nil {
+			return err
+		}
+
+		return setPath(item, action.path, value)
+	case "REMOVE":
+		return removePath(item, action.path)
+	case "ADD":
+
+		// If the attribute doesn't exist then set it to the value. Otherwise, add the value to the
+		// number or set that already exists
+		value, existing := action.operand.evaluate(item), resolvePath(item, action.path)
+		if existing == nil {
+			switch value.(type) {
+			case *types.AttributeValueMemberN, *types.AttributeValueMemberSS,
+				*types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+				return setPath(item, action.path, copyValue(value))
+			default:
+				return invalidUpdate("Incorrect operand type for operator or function; operator: ADD, operand type: %s",
+					typeName(value))
+			}
+		}
+
+		if _, ok := existing.(*types.AttributeValueMemberN); ok {
+			sum, err := addNumbers(existing, value, "+")
+			if err != nil {
+				return err
+			}
+
+			return setPath(item, action.path, sum)
+		}
+
+		combined, ok := combineSets(existing, value, true)
+		if !ok {
+			return invalidUpdate("Incorrect operand type for operator or function; operator: ADD, operand type: %s",
+				typeName(value))
+		}
+
+		return setPath(item, action.path, combined)
+	default:
+
+		// Remove the elements of the value from the set; if the set is empty then remove it
+		value, existing := action.operand.evaluate(item), resolvePath(item, action.path)
+		if existing == nil {
+			return nil
+		}
+
+		combined, ok := combineSets(existing, value, false)
+		if !ok {
+			return invalidUpdate("Incorrect operand type for operator or function; operator: DELETE, operand type: %s",
+				typeName(value))
+		}
+
+		if size, _ := valueSize(combined); size == 0 {
+			return removePath(item, action.path)
+		}
+
+		return setPath(item, action.path, combined)
+	}
+}
+
+// Helper type that describes the value assigned by a SET action
+type setValue interface {
+	evaluate(item map[string]types.AttributeValue) (types.AttributeValue, error)
+}
+
+// Helper type that describes a value assigned by a SET action from an operand
+type operandValue struct {
+	inner operand
+}
+
+// Helper function that evaluates the operand, returning an error if it refers to a missing attribute
+func (value *operandValue) evaluate(item map[string]types.AttributeValue) (types.AttributeValue, error) {
+	result := value.inner.evaluate(item)
+	if result == nil {
+		return nil, invalidUpdate("The provided expression refers to an attribute that does not exist in the item")
+	}
+
+	return copyValue(result), nil
+}
+
+// Helper type that describes the sum or difference of two values
+type arithmeticValue struct {
+	operator string
+	left     setValue
+	right    setValue
+}
+
+// Helper function that evaluates the sum or difference of the two values
+func (value *arithmeticValue) evaluate(item map[string]types.AttributeValue) (types.AttributeValue, error) {
+	left, err := value.left.evaluate(item)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := value.right.evaluate(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return addNumbers(left, right, value.operator)
+}
+
+// Helper type that describes the value of an attribute, or a fallback if the attribute does not exist
+type ifNotExistsValue struct {
+	path     documentPath
+	fallback setValue
+}
+
+// Helper function that gets the value of the attribute or evaluates the fallback
+func (value *ifNotExistsValue) evaluate(item map[string]types.AttributeValue) (types.AttributeValue, error) {
+	if existing := resolvePath(item, value.path); existing != nil {
+		return copyValue(existing), nil
+	}
+
+	return value.fallback.evaluate(item)
+}
+
+// Helper type that describes the concatenation of two lists
+type listAppendValue struct {
+	first  setValue
+	second setValue
+}
+
+// Helper function that concatenates the two lists
+func (value *listAppendValue) evaluate(item map[string]types.AttributeValue) (types.AttributeValue, error) {
+	first, err := value.first.evaluate(item)
+	if err != nil {
+		return nil, err
+	}
+
+	second, err := value.second.evaluate(item)
+	if err != nil {
+		return nil, err
+	}
+
+	left, lok := first.(*types.AttributeValueMemberL)
+	right, rok := second.(*types.AttributeValueMemberL)
+	if !lok || !rok {
+		return nil, invalidUpdate("Incorrect operand type for operator or function; operator or function: list_append")
+	}
+
+	return &types.AttributeValueMemberL{Value: append(append([]types.AttributeValue{}, left.Value...),
+		right.Value...)}, nil
+}
+
+// Helper function that adds, or subtracts, two numbers
+func addNumbers(first types.AttributeValue, second types.AttributeValue, operator string) (types.AttributeValue, error) {
+	left, lok := first.(*types.AttributeValueMemberN)
+	right, rok := second.(*types.AttributeValueMemberN)
+	if !lok || !rok {
+		return nil, invalidUpdate("An operand in the update expression has an incorrect data type")
+	}
+
+	leftNum, lok := parseNumber(left.Value)
+	rightNum, rok := parseNumber(right.Value)
+	if !lok || !rok {
+		return nil, invalidUpdate("An operand in the update expression has an incorrect data type")
+	}
+
+	result := new(big.Float).SetPrec(256)
+	if operator == "+" {
+		result.Add(leftNum, rightNum)
+	} else {
+		result.Sub(leftNum, rightNum)
+	}
+
+	return &types.AttributeValueMemberN{Value: formatNumber(result)}, nil
+}
+
+// Helper function that gets the value at a document path on an item, returning nil if it doesn't exist
+func resolvePath(item map[string]types.AttributeValue, path documentPath) types.AttributeValue {
+	var current types.AttributeValue = &types.AttributeValueMemberM{Value: item}
+	for _, element := range path {
+		switch casted := current.(type) {
+		case *types.AttributeValueMemberM:
+			if element.isIndex {
+				return nil
+			}
+
+			current = casted.Value[element.name]
+		case *types.AttributeValueMemberL:
+			if !element.isIndex || element.index >= len(casted.Value) {
+				return nil
+			}
+
+			current = casted.Value[element.index]
+		default:
+			return nil
+		}
+
+		if current == nil {
+			return nil
+		}
+	}
+
+	return current
+}
+
+// Helper function that sets the value at a document path on an item. The parent of the path must exist
+func setPath(item map[string]types.AttributeValue, path documentPath, value types.AttributeValue) error {
+	parent := resolvePath(item, path[:len(path)-1])
+	last := path[len(path)-1]
+	switch casted := parent.(type) {
+	case *types.AttributeValueMemberM:
+		if !last.isIndex {
+			if casted.Value == nil {
+				casted.Value = make(map[string]types.AttributeValue)
+			}
+
+			casted.Value[last.name] = value
+			return nil
+		}
+	case *types.AttributeValueMemberL:
+		if last.isIndex {
+			if last.index < len(casted.Value) {
+				casted.Value[last.index] = value
+			} else {
+				casted.Value = append(casted.Value, value)
+			}
+
+			return nil
+		}
+	}
+
+	return invalidUpdate("The document path provided in the update expression is invalid for update")
+}
+
+// Helper function that removes the value at a document path on an item
+func removePath(item map[string]types.AttributeValue, path documentPath) error {
+	parent := resolvePath(item, path[:len(path)-1])
+	last := path[len(path)-1]
+	switch casted := parent.(type) {
+	case *types.AttributeValueMemberM:
+		if !last.isIndex {
+			delete(casted.Value, last.name)
+			return nil
+		}
+	case *types.AttributeValueMemberL:
+		if last.isIndex {
+			if last.index < len(casted.Value) {
+				casted.Value = append(casted.Value[:last.index], casted.Value[last.index+1:]...)
+			}
+
+			return nil
+		}
+	case nil:
+		return nil
+	}
+
+	return invalidUpdate("The document path provided in the update expression is invalid for update")
+}
+
+// Helper function that creates a copy of an item containing only the attributes at the paths provided.
+// Paths that index into a list will project the entire list
+func projectItem(item map[string]types.AttributeValue, paths []documentPath) map[string]types.AttributeValue {
+	projected := make(map[string]types.AttributeValue)
+	for _, path := range paths {
+
+		// First, truncate the path at the first list index and get the value at the path
+		for i, element := range path {
+			if element.isIndex {
+				path = path[:i]
+				break
+			}
+		}
+
+		value := resolvePath(item, path)
+		if value == nil {
+			continue
+		}
+
+		// Next, create any maps required to hold the value in the projected item
+		current := projected
+		for _, element := range path[:len(path)-1] {
+			next, ok := current[element.name].(*types.AttributeValueMemberM)
+			if !ok {
+				next = &types.AttributeValueMemberM{Value: make(map[string]types.AttributeValue)}
+				current[element.name] = next
+			}
+
+			current = next.Value
+		}
+
+		// Finally, copy the value into the projected item
+		current[path[len(path)-1].name] = copyValue(value)
+	}
+
+	return projected
+}
+
+// Helper function that creates an error describing an invalid update
+func invalidUpdate(message string, args ...interface{}) error {
+	return validationError("The update expression is invalid: %s", fmt.Sprintf(message, args...))
+}
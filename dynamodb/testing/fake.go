@@ -0,0 +1,502 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// FakeDynamoDB is an in-memory implementation of the DynamoDB client that can be used in unit tests in
+// place of DynamoDB Local. It supports creating, describing and deleting tables with global and local
+// secondary indexes; getting, putting, updating and deleting items with condition expressions; queries
+// with key conditions on tables and indexes; scans with filters and segments; batch gets and writes and
+// transactional gets and writes. Errors are returned with the same types, wrapped in the same operation
+// error, as the SDK client so that they can be handled in the same way. Operations that aren't supported
+// return an error. The legacy request parameters (e.g. KeyConditions, ScanFilter and Expected) are ignored
+// and time-to-live settings are recorded but items are never expired. All operations are serialized so
+// the fake is safe for concurrent use
+type FakeDynamoDB struct {
+	tables map[string]*fakeTable
+	lock   *sync.Mutex
+}
+
+// Helper type that describes a table stored by the fake, along with its items
+type fakeTable struct {
+	description *types.TableDescription
+	hashKey     string
+	rangeKey    string
+	attributes  map[string]types.ScalarAttributeType
+	indexes     map[string]*fakeIndex
+	items       map[string]map[string]types.AttributeValue
+	ttl         *types.TimeToLiveSpecification
+}
+
+// Helper type that describes a secondary index on a table stored by the fake
+type fakeIndex struct {
+	name       string
+	global     bool
+	hashKey    string
+	rangeKey   string
+	projection *types.Projection
+}
+
+// NewFakeDynamoDB creates a new, empty in-memory DynamoDB
+func NewFakeDynamoDB() *FakeDynamoDB {
+	return &FakeDynamoDB{
+		tables: make(map[string]*fakeTable),
+		lock:   new(sync.Mutex),
+	}
+}
+
+// CreateTable creates a new table from the request. The table will be active immediately
+func (fake *FakeDynamoDB) CreateTable(ctx context.Context, params *dynamodb.CreateTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	// First, ensure that the table doesn't already exist
+	tableName := aws.ToString(params.TableName)
+	if _, ok := fake.tables[tableName]; ok {
+		return nil, operationError("CreateTable", &types.ResourceInUseException{
+			Message: aws.String(fmt.Sprintf("Table already exists: %s", tableName))})
+	}
+
+	// Next, collect the attribute definitions so that we can verify that all the keys are defined
+	table := &fakeTable{
+		attributes: make(map[string]types.ScalarAttributeType),
+		indexes:    make(map[string]*fakeIndex),
+		items:      make(map[string]map[string]types.AttributeValue),
+	}
+
+	for _, attribute := range params.AttributeDefinitions {
+		table.attributes[aws.ToString(attribute.AttributeName)] = attribute.AttributeType
+	}
+
+	// Now, get the keys of the table and each of its indexes, ensuring that they are all defined
+	used := make(map[string]bool)
+	var err error
+	if table.hashKey, table.rangeKey, err = table.parseKeySchema(params.KeySchema, used); err != nil {
+		return nil, operationError("CreateTable", err)
+	}
+
+	for _, index := range params.GlobalSecondaryIndexes {
+		if err := table.addIndex(index.IndexName, true, index.KeySchema, index.Projection, used); err != nil {
+			return nil, operationError("CreateTable", err)
+		}
+	}
+
+	for _, index := range params.LocalSecondaryIndexes {
+		if err := table.addIndex(index.IndexName, false, index.KeySchema, index.Projection, used); err != nil {
+			return nil, operationError("CreateTable", err)
+		}
+	}
+
+	if len(used) != len(table.attributes) {
+		return nil, operationError("CreateTable", validationError("One or more parameter values were invalid: "+
+			"Number of attributes in KeySchema does not exactly match number of attributes defined in AttributeDefinitions"))
+	}
+
+	// Finally, create the description of the table and store it
+	table.description = createTableDescription(params)
+	fake.tables[tableName] = table
+	return &dynamodb.CreateTableOutput{TableDescription: table.describe()}, nil
+}
+
+// DescribeTable describes the table requested, including the number of items it contains
+func (fake *FakeDynamoDB) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	table, err := fake.getTable(params.TableName)
+	if err != nil {
+		return nil, operationError("DescribeTable", err)
+	}
+
+	return &dynamodb.DescribeTableOutput{Table: table.describe()}, nil
+}
+
+// DeleteTable removes the table requested, and all of its items
+func (fake *FakeDynamoDB) DeleteTable(ctx context.Context, params *dynamodb.DeleteTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	table, err := fake.getTable(params.TableName)
+	if err != nil {
+		return nil, operationError("DeleteTable", err)
+	}
+
+	description := table.describe()
+	description.TableStatus = types.TableStatusDeleting
+	delete(fake.tables, aws.ToString(params.TableName))
+	return &dynamodb.DeleteTableOutput{TableDescription: description}, nil
+}
+
+// ListTables lists the names of the tables, in alphabetical order
+func (fake *FakeDynamoDB) ListTables(ctx context.Context, params *dynamodb.ListTablesInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListTablesOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	// First, get the names of all the tables after the start table, in order
+	names := make([]string, 0)
+	for name := range fake.tables {
+		if name > aws.ToString(params.ExclusiveStartTableName) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	// Next, limit the names to the number requested and return them
+	output := &dynamodb.ListTablesOutput{TableNames: names}
+	limit := int(aws.ToInt32(params.Limit))
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+
+	if len(names) > limit {
+		output.TableNames = names[:limit]
+		output.LastEvaluatedTableName = aws.String(names[limit-1])
+	}
+
+	return output, nil
+}
+
+// UpdateTimeToLive records the time-to-live settings for a table. Items will not be expired
+func (fake *FakeDynamoDB) UpdateTimeToLive(ctx context.Context, params *dynamodb.UpdateTimeToLiveInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	table, err := fake.getTable(params.TableName)
+	if err != nil {
+		return nil, operationError("UpdateTimeToLive", err)
+	} else if params.TimeToLiveSpecification == nil {
+		return nil, operationError("UpdateTimeToLive", validationError("TimeToLiveSpecification is required"))
+	}
+
+	table.ttl = &types.TimeToLiveSpecification{
+		AttributeName: params.TimeToLiveSpecification.AttributeName,
+		Enabled:       params.TimeToLiveSpecification.Enabled,
+	}
+
+	return &dynamodb.UpdateTimeToLiveOutput{TimeToLiveSpecification: table.ttl}, nil
+}
+
+// DescribeTimeToLive describes the time-to-live settings for a table
+func (fake *FakeDynamoDB) DescribeTimeToLive(ctx context.Context, params *dynamodb.DescribeTimeToLiveInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	table, err := fake.getTable(params.TableName)
+	if err != nil {
+		return nil, operationError("DescribeTimeToLive", err)
+	}
+
+	description := &types.TimeToLiveDescription{TimeToLiveStatus: types.TimeToLiveStatusDisabled}
+	if table.ttl != nil && aws.ToBool(table.ttl.Enabled) {
+		description.AttributeName = table.ttl.AttributeName
+		description.TimeToLiveStatus = types.TimeToLiveStatusEnabled
+	}
+
+	return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: description}, nil
+}
+
+// Helper function that gets a table by its name, returning an error if it doesn't exist
+func (fake *FakeDynamoDB) getTable(tableName *string) (*fakeTable, error) {
+	table, ok := fake.tables[aws.ToString(tableName)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{
+			Message: aws.String("Requested resource not found: Table: " + aws.ToString(tableName) + " not found")}
+	}
+
+	return table, nil
+}
+
+// Helper function that gets the hash and range keys from a key schema, ensuring that they're defined
+// and recording them as used
+func (table *fakeTable) parseKeySchema(schema []types.KeySchemaElement, used map[string]bool) (string, string, error) {
+	var hash, rangeKey string
+	for _, element := range schema {
+		name := aws.ToString(element.AttributeName)
+		attrType, ok := table.attributes[name]
+		if !ok {
+			return "", "", validationError("One or more parameter values were invalid: "+
+				"Some index key attributes are not defined in AttributeDefinitions. Keys: [%s]", name)
+		} else if attrType != types.ScalarAttributeTypeS && attrType != types.ScalarAttributeTypeN &&
+			attrType != types.ScalarAttributeTypeB {
+			return "", "", validationError("Member must satisfy enum value set: [B, N, S]")
+		}
+
+		switch element.KeyType {
+		case types.KeyTypeHash:
+			hash = name
+		case types.KeyTypeRange:
+			rangeKey = name
+		}
+
+		used[name] = true
+	}
+
+	if hash == "" || len(schema) > 2 || (len(schema) == 2 && rangeKey == "") {
+		return "", "", validationError("Invalid KeySchema: Some index key attribute have no definition")
+	}
+
+	return hash, rangeKey, nil
+}
+
+// Helper function that adds a secondary index to the table
+func (table *fakeTable) addIndex(name *string, global bool, schema []types.KeySchemaElement,
+	projection *types.Projection, used map[string]bool) error {
+
+	// First, ensure that the index doesn't already exist
+	indexName := aws.ToString(name)
+	if _, ok := table.indexes[indexName]; ok {
+		return validationError("One or more parameter values were invalid: Duplicate index name: %s", indexName)
+	}
+
+	// Next, get the keys of the index; local indexes must share the hash key of the table
+	hash, rangeKey, err := table.parseKeySchema(schema, used)
+	if err != nil {
+		return err
+	} else if !global && (hash != table.hashKey || rangeKey == "") {
+		return validationError("One or more parameter values were invalid: Index KeySchema does not have the "+
+			"same leading hash key as table KeySchema for index: %s", indexName)
+	}
+
+	// Finally, create the index and add it to the table
+	if projection == nil {
+		projection = &types.Projection{ProjectionType: types.ProjectionTypeAll}
+	}
+
+	table.indexes[indexName] = &fakeIndex{
+		name:       indexName,
+		global:     global,
+		hashKey:    hash,
+		rangeKey:   rangeKey,
+		projection: projection,
+	}
+
+	return nil
+}
+
+// Helper function that gets the names of the key attributes of the table
+func (table *fakeTable) keyNames() []string {
+	if table.rangeKey == "" {
+		return []string{table.hashKey}
+	}
+
+	return []string{table.hashKey, table.rangeKey}
+}
+
+// Helper function that creates a string that uniquely identifies an item by its key
+func (table *fakeTable) itemKey(item map[string]types.AttributeValue) string {
+	return fingerprint(item, table.keyNames()...)
+}
+
+// Helper function that copies the key attributes of the table, and the index if one is provided, from an item
+func (table *fakeTable) extractKey(item map[string]types.AttributeValue, index *fakeIndex) map[string]types.AttributeValue {
+	names := table.keyNames()
+	if index != nil {
+		names = append(names, index.hashKey, index.rangeKey)
+	}
+
+	key := make(map[string]types.AttributeValue)
+	for _, name := range names {
+		if value, ok := item[name]; ok && name != "" {
+			key[name] = copyValue(value)
+		}
+	}
+
+	return key
+}
+
+// Helper function that ensures that a key contains exactly the key attributes of the table
+func (table *fakeTable) validateKey(key map[string]types.AttributeValue) error {
+	names := table.keyNames()
+	if len(key) != len(names) {
+		return validationError("The provided key element does not match the schema")
+	}
+
+	for _, name := range names {
+		if value, ok := key[name]; !ok || typeName(value) != string(table.attributes[name]) {
+			return validationError("The provided key element does not match the schema")
+		}
+	}
+
+	return nil
+}
+
+// Helper function that ensures that an item contains the key attributes of the table, and that any
+// key attributes of its indexes have the correct types
+func (table *fakeTable) validateItem(item map[string]types.AttributeValue) error {
+
+	// First, ensure that the item has all the key attributes of the table, with the correct types
+	for _, name := range table.keyNames() {
+		value, ok := item[name]
+		if !ok {
+			return validationError("One or more parameter values were invalid: Missing the key %s in the item", name)
+		} else if actual, expected := typeName(value), string(table.attributes[name]); actual != expected {
+			return validationError("One or more parameter values were invalid: "+
+				"Type mismatch for key %s expected: %s actual: %s", name, expected, actual)
+		} else if size, _ := valueSize(value); size == 0 && expected != "N" {
+			return validationError("One or more parameter values are not valid. The AttributeValue for a key "+
+				"attribute cannot contain an empty string value. Key: %s", name)
+		}
+	}
+
+	// Next, ensure that any index key attributes on the item have the correct types
+	for _, index := range table.sortedIndexes() {
+		for _, name := range []string{index.hashKey, index.rangeKey} {
+			value, ok := item[name]
+			if !ok || name == "" {
+				continue
+			}
+
+			if actual, expected := typeName(value), string(table.attributes[name]); actual != expected {
+				return validationError("One or more parameter values were invalid: Type mismatch for Index Key "+
+					"%s Expected: %s Actual: %s IndexName: %s", name, expected, actual, index.name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Helper function that gets the indexes of the table, in order of their names
+func (table *fakeTable) sortedIndexes() []*fakeIndex {
+	indexes := make([]*fakeIndex, 0, len(table.indexes))
+	for _, index := range table.indexes {
+		indexes = append(indexes, index)
+	}
+
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].name < indexes[j].name })
+	return indexes
+}
+
+// Helper function that creates a copy of the table description with the current item counts
+func (table *fakeTable) describe() *types.TableDescription {
+	description := *table.description
+	description.ItemCount = int64(len(table.items))
+	description.GlobalSecondaryIndexes = make([]types.GlobalSecondaryIndexDescription, 0)
+	description.LocalSecondaryIndexes = make([]types.LocalSecondaryIndexDescription, 0)
+	for _, index := range table.sortedIndexes() {
+
+		// First, count the items that have the keys of the index
+		var count int64
+		for _, item := range table.items {
+			if index.contains(item) {
+				count++
+			}
+		}
+
+		// Next, describe the index and add it to the table description
+		arn := fmt.Sprintf("%s/index/%s", aws.ToString(description.TableArn), index.name)
+		schema := []types.KeySchemaElement{{AttributeName: aws.String(index.hashKey), KeyType: types.KeyTypeHash}}
+		if index.rangeKey != "" {
+			schema = append(schema, types.KeySchemaElement{AttributeName: aws.String(index.rangeKey),
+				KeyType: types.KeyTypeRange})
+		}
+
+		if index.global {
+			description.GlobalSecondaryIndexes = append(description.GlobalSecondaryIndexes,
+				types.GlobalSecondaryIndexDescription{
+					IndexArn:              aws.String(arn),
+					IndexName:             aws.String(index.name),
+					IndexStatus:           types.IndexStatusActive,
+					ItemCount:             count,
+					KeySchema:             schema,
+					Projection:            index.projection,
+					ProvisionedThroughput: description.ProvisionedThroughput,
+				})
+		} else {
+			description.LocalSecondaryIndexes = append(description.LocalSecondaryIndexes,
+				types.LocalSecondaryIndexDescription{
+					IndexArn:   aws.String(arn),
+					IndexName:  aws.String(index.name),
+					ItemCount:  count,
+					KeySchema:  schema,
+					Projection: index.projection,
+				})
+		}
+	}
+
+	return &description
+}
+
+// Helper function that determines whether an item has the key attributes of the index
+func (index *fakeIndex) contains(item map[string]types.AttributeValue) bool {
+	if _, ok := item[index.hashKey]; !ok {
+		return false
+	}
+
+	if index.rangeKey != "" {
+		if _, ok := item[index.rangeKey]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Helper function that creates the initial description of a table from the request that created it
+func createTableDescription(params *dynamodb.CreateTableInput) *types.TableDescription {
+	description := &types.TableDescription{
+		AttributeDefinitions: params.AttributeDefinitions,
+		CreationDateTime:     aws.Time(time.Now()),
+		KeySchema:            params.KeySchema,
+		StreamSpecification:  params.StreamSpecification,
+		TableArn:             aws.String("arn:aws:dynamodb:local:000000000000:table/" + aws.ToString(params.TableName)),
+		TableName:            params.TableName,
+		TableSizeBytes:       0,
+		TableStatus:          types.TableStatusActive,
+	}
+
+	billingMode := params.BillingMode
+	if billingMode == "" {
+		billingMode = types.BillingModeProvisioned
+	}
+
+	description.BillingModeSummary = &types.BillingModeSummary{BillingMode: billingMode}
+	if params.ProvisionedThroughput != nil {
+		description.ProvisionedThroughput = &types.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  params.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: params.ProvisionedThroughput.WriteCapacityUnits,
+		}
+	}
+
+	return description
+}
+
+// Helper function that wraps an error in an operation error, as the SDK client does. If the error is
+// nil then nil will be returned
+func operationError(operation string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &smithy.OperationError{
+		ServiceID:     "DynamoDB",
+		OperationName: operation,
+		Err:           err,
+	}
+}
+
+// Helper function that creates an error describing an invalid request
+func validationError(message string, args ...interface{}) error {
+	return &smithy.GenericAPIError{
+		Code:    "ValidationException",
+		Message: fmt.Sprintf(message, args...),
+		Fault:   smithy.FaultClient,
+	}
+}
@@ -0,0 +1,562 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// Create a new test runner we'll use to test all the
+// modules in the testing package
+func TestFakeDynamoDB(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DynamoDB Testing Suite")
+}
+
+var _ = Describe("Fake DynamoDB Tests", func() {
+
+	// Create a new fake, with our test table, before each test
+	var fake *FakeDynamoDB
+	BeforeEach(func() {
+		fake = NewFakeDynamoDB()
+		_, err := fake.CreateTable(context.Background(), createTestTable())
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	// Test that, if the table already exists, then CreateTable will return a resource-in-use error
+	It("CreateTable - Table exists - Error", func() {
+		output, err := fake.CreateTable(context.Background(), createTestTable())
+		Expect(output).Should(BeNil())
+		verifyError(err, "CreateTable", &types.ResourceInUseException{}, "Table already exists: TEST_TABLE")
+	})
+
+	// Test that, if the table doesn't exist, then DescribeTable will return a resource-not-found error
+	It("DescribeTable - Table missing - Error", func() {
+		output, err := fake.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{
+			TableName: aws.String("MISSING_TABLE")})
+		Expect(output).Should(BeNil())
+		verifyError(err, "DescribeTable", &types.ResourceNotFoundException{},
+			"Requested resource not found: Table: MISSING_TABLE not found")
+	})
+
+	// Test that, if the table exists, then DescribeTable will describe it and its indexes, including
+	// the number of items in each
+	It("DescribeTable - Table exists - Described", func() {
+
+		// First, write some items to the table; only the even items will be in the index
+		putTestItems(fake, 4)
+
+		// Next, attempt to describe the table; this should not fail
+		output, err := fake.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{
+			TableName: aws.String("TEST_TABLE")})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the description of the table
+		Expect(*output.Table.TableName).Should(Equal("TEST_TABLE"))
+		Expect(output.Table.TableStatus).Should(Equal(types.TableStatusActive))
+		Expect(output.Table.ItemCount).Should(Equal(int64(4)))
+		Expect(output.Table.GlobalSecondaryIndexes).Should(HaveLen(1))
+		Expect(*output.Table.GlobalSecondaryIndexes[0].IndexName).Should(Equal("ByOwner"))
+		Expect(output.Table.GlobalSecondaryIndexes[0].ItemCount).Should(Equal(int64(2)))
+	})
+
+	// Test that, if an item is written, then GetItem will return it, projected as requested
+	It("PutItem, GetItem - Item written - Item returned", func() {
+
+		// First, write an item to the table
+		putTestItems(fake, 1)
+
+		// Next, attempt to get the entire item; this should not fail
+		output, err := fake.GetItem(context.Background(), &dynamodb.GetItemInput{
+			TableName: aws.String("TEST_TABLE"), Key: createTestKey(0)})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(output.Item).Should(Equal(createTestItem(0)))
+
+		// Now, attempt to get a projection of the item; this should not fail
+		output, err = fake.GetItem(context.Background(), &dynamodb.GetItemInput{
+			TableName:                aws.String("TEST_TABLE"),
+			Key:                      createTestKey(0),
+			ProjectionExpression:     aws.String("#d, details.city, missing"),
+			ExpressionAttributeNames: map[string]string{"#d": "data"}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the projected item
+		Expect(output.Item).Should(Equal(map[string]types.AttributeValue{
+			"data": &types.AttributeValueMemberN{Value: "0"},
+			"details": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"city": &types.AttributeValueMemberS{Value: "Tokyo"}}}}))
+	})
+
+	// Test that, if the key doesn't match the schema of the table, then GetItem will return an error
+	It("GetItem - Invalid key - Error", func() {
+		output, err := fake.GetItem(context.Background(), &dynamodb.GetItemInput{
+			TableName: aws.String("TEST_TABLE"),
+			Key:       map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "test_id|0"}}})
+		Expect(output).Should(BeNil())
+		verifyValidation(err, "GetItem", "The provided key element does not match the schema")
+	})
+
+	// Test that, if the condition on a put fails, then PutItem will return a conditional check failure
+	// and the item will not be modified
+	It("PutItem - Condition fails - Error", func() {
+
+		// First, write an item to the table
+		putTestItems(fake, 1)
+
+		// Next, attempt to overwrite the item, but only if it doesn't exist; this should fail
+		item := createTestItem(0)
+		item["data"] = &types.AttributeValueMemberN{Value: "42"}
+		output, err := fake.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName:                aws.String("TEST_TABLE"),
+			Item:                     item,
+			ConditionExpression:      aws.String("attribute_not_exists(#id)"),
+			ExpressionAttributeNames: map[string]string{"#id": "id"}})
+		Expect(output).Should(BeNil())
+		verifyError(err, "PutItem", &types.ConditionalCheckFailedException{}, "The conditional request failed")
+
+		// Finally, verify that the item was not modified
+		Expect(getTestItem(fake, 0)).Should(Equal(createTestItem(0)))
+	})
+
+	// Test that, if the update expression is valid, then UpdateItem will apply each of its actions to
+	// the item and return the new item
+	It("UpdateItem - Valid expression - Item updated", func() {
+
+		// First, write an item to the table
+		putTestItems(fake, 1)
+
+		// Next, attempt to update the item with every kind of action; this should not fail
+		output, err := fake.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+			TableName: aws.String("TEST_TABLE"),
+			Key:       createTestKey(0),
+			UpdateExpression: aws.String("SET #d = #d + :one, details.city = :city, #l = list_append(if_not_exists(#l, :empty), :list), " +
+				"created = if_not_exists(created, :one) REMOVE details.zip ADD #c :one, #t :tags DELETE #t :gone"),
+			ConditionExpression: aws.String("#d = :zero AND size(tags) > :one"),
+			ExpressionAttributeNames: map[string]string{
+				"#d": "data", "#l": "list", "#c": "counter", "#t": "tags"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":zero":  &types.AttributeValueMemberN{Value: "0"},
+				":one":   &types.AttributeValueMemberN{Value: "1"},
+				":city":  &types.AttributeValueMemberS{Value: "Osaka"},
+				":empty": &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+				":list": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "a"}}},
+				":tags": &types.AttributeValueMemberSS{Value: []string{"c"}},
+				":gone": &types.AttributeValueMemberSS{Value: []string{"a"}}},
+			ReturnValues: types.ReturnValueAllNew})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the updated item
+		Expect(output.Attributes).Should(Equal(map[string]types.AttributeValue{
+			"id":       &types.AttributeValueMemberS{Value: "test_id|0"},
+			"sort_key": &types.AttributeValueMemberN{Value: "0"},
+			"owner":    &types.AttributeValueMemberS{Value: "owner|0"},
+			"data":     &types.AttributeValueMemberN{Value: "1"},
+			"details": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"city": &types.AttributeValueMemberS{Value: "Osaka"}}},
+			"list": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "a"}}},
+			"created": &types.AttributeValueMemberN{Value: "1"},
+			"counter": &types.AttributeValueMemberN{Value: "1"},
+			"tags":    &types.AttributeValueMemberSS{Value: []string{"b", "c"}}}))
+		Expect(getTestItem(fake, 0)).Should(Equal(output.Attributes))
+	})
+
+	// Test that, if the update modifies a key attribute, then UpdateItem will return an error
+	It("UpdateItem - Key modified - Error", func() {
+		output, err := fake.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+			TableName:                 aws.String("TEST_TABLE"),
+			Key:                       createTestKey(0),
+			UpdateExpression:          aws.String("SET id = :id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":id": &types.AttributeValueMemberS{Value: "x"}}})
+		Expect(output).Should(BeNil())
+		verifyValidation(err, "UpdateItem", "One or more parameter values were invalid: "+
+			"Cannot update attribute id. This attribute is part of the key")
+	})
+
+	// Test that, if an expression attribute value isn't used, then the request will be rejected
+	It("DeleteItem - Unused value - Error", func() {
+		output, err := fake.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
+			TableName:                 aws.String("TEST_TABLE"),
+			Key:                       createTestKey(0),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":v": &types.AttributeValueMemberS{Value: "x"}}})
+		Expect(output).Should(BeNil())
+		verifyValidation(err, "DeleteItem", "Value provided in ExpressionAttributeValues unused in expressions: keys: {:v}")
+	})
+
+	// Test that, if a query has a range key condition and limit, then Query will return the matching
+	// items in the order requested, one page at a time
+	It("Query - Range condition with limit - Pages returned", func() {
+
+		// First, write some items to the table
+		putTestItems(fake, 10)
+		for i := 0; i < 10; i++ {
+			item := createTestItem(i)
+			item["id"] = &types.AttributeValueMemberS{Value: "test_id|0"}
+			_, err := fake.PutItem(context.Background(), &dynamodb.PutItemInput{
+				TableName: aws.String("TEST_TABLE"), Item: item})
+			Expect(err).ShouldNot(HaveOccurred())
+		}
+
+		// Next, create a query for the items with a range key between 2 and 8, in descending order,
+		// filtering out the items whose data is divisible by three
+		input := &dynamodb.QueryInput{
+			TableName:                aws.String("TEST_TABLE"),
+			KeyConditionExpression:   aws.String("#id = :id AND sort_key BETWEEN :low AND :high"),
+			FilterExpression:         aws.String("NOT #d IN (:zero, :three, :six, :nine)"),
+			ExpressionAttributeNames: map[string]string{"#id": "id", "#d": "data"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":id":    &types.AttributeValueMemberS{Value: "test_id|0"},
+				":low":   &types.AttributeValueMemberN{Value: "2"},
+				":high":  &types.AttributeValueMemberN{Value: "8"},
+				":zero":  &types.AttributeValueMemberN{Value: "0"},
+				":three": &types.AttributeValueMemberN{Value: "3"},
+				":six":   &types.AttributeValueMemberN{Value: "6"},
+				":nine":  &types.AttributeValueMemberN{Value: "9"}},
+			ScanIndexForward: aws.Bool(false),
+			Limit:            aws.Int32(4),
+		}
+
+		// Now, attempt to query the first page; this should not fail
+		first, err := fake.Query(context.Background(), input)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(first.Count).Should(Equal(int32(3)))
+		Expect(first.ScannedCount).Should(Equal(int32(4)))
+		Expect(sortKeys(first.Items)).Should(Equal([]string{"8", "7", "5"}))
+		Expect(first.LastEvaluatedKey).Should(Equal(map[string]types.AttributeValue{
+			"id":       &types.AttributeValueMemberS{Value: "test_id|0"},
+			"sort_key": &types.AttributeValueMemberN{Value: "5"}}))
+
+		// Finally, attempt to query the second page; this should not fail
+		input.ExclusiveStartKey = first.LastEvaluatedKey
+		second, err := fake.Query(context.Background(), input)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(second.Count).Should(Equal(int32(2)))
+		Expect(second.ScannedCount).Should(Equal(int32(3)))
+		Expect(sortKeys(second.Items)).Should(Equal([]string{"4", "2"}))
+		Expect(second.LastEvaluatedKey).Should(BeNil())
+	})
+
+	// Test that, if a query is made against a global secondary index, then Query will only return the
+	// items in the index, projected with the projection of the index
+	It("Query - Global index - Projected items returned", func() {
+
+		// First, write some items to the table; only the even items will be in the index
+		putTestItems(fake, 4)
+
+		// Next, attempt to query the index; this should not fail
+		output, err := fake.Query(context.Background(), &dynamodb.QueryInput{
+			TableName:                aws.String("TEST_TABLE"),
+			IndexName:                aws.String("ByOwner"),
+			KeyConditionExpression:   aws.String("#o = :o"),
+			ExpressionAttributeNames: map[string]string{"#o": "owner"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":o": &types.AttributeValueMemberS{Value: "owner|2"}}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that only the keys of the item were returned
+		Expect(output.Items).Should(Equal([]map[string]types.AttributeValue{{
+			"id":       &types.AttributeValueMemberS{Value: "test_id|2"},
+			"sort_key": &types.AttributeValueMemberN{Value: "2"},
+			"owner":    &types.AttributeValueMemberS{Value: "owner|2"}}}))
+	})
+
+	// Test that, if the key condition doesn't refer to the hash key, then Query will return an error
+	It("Query - No hash key condition - Error", func() {
+		output, err := fake.Query(context.Background(), &dynamodb.QueryInput{
+			TableName:                 aws.String("TEST_TABLE"),
+			KeyConditionExpression:    aws.String("sort_key = :s"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":s": &types.AttributeValueMemberN{Value: "1"}}})
+		Expect(output).Should(BeNil())
+		verifyValidation(err, "Query", "Query key condition not supported")
+	})
+
+	// Test that, if a scan is split into segments, then each item will be returned by exactly one segment
+	It("Scan - Segments - Every item returned once", func() {
+
+		// First, write some items to the table
+		putTestItems(fake, 20)
+
+		// Next, scan each of the segments and collect the items, filtering out the first five items
+		seen := make(map[string]int)
+		for segment := int32(0); segment < 3; segment++ {
+			output, err := fake.Scan(context.Background(), &dynamodb.ScanInput{
+				TableName:                 aws.String("TEST_TABLE"),
+				FilterExpression:          aws.String("#d >= :d"),
+				ExpressionAttributeNames:  map[string]string{"#d": "data"},
+				ExpressionAttributeValues: map[string]types.AttributeValue{":d": &types.AttributeValueMemberN{Value: "5"}},
+				Segment:                   aws.Int32(segment),
+				TotalSegments:             aws.Int32(3)})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			for _, key := range sortKeys(output.Items) {
+				seen[key]++
+			}
+		}
+
+		// Finally, verify that every item that matched the filter was seen exactly once
+		Expect(seen).Should(HaveLen(15))
+		for _, count := range seen {
+			Expect(count).Should(Equal(1))
+		}
+	})
+
+	// Test that, if items are written with a batch-write, then they can be read with a batch-get
+	It("BatchWriteItem, BatchGetItem - Items written - Items returned", func() {
+
+		// First, write some items to the table and then delete one of them with a batch-write
+		requests := make([]types.WriteRequest, 0)
+		for i := 0; i < 3; i++ {
+			requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: createTestItem(i)}})
+		}
+
+		_, err := fake.BatchWriteItem(context.Background(), &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{"TEST_TABLE": requests}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		_, err = fake.BatchWriteItem(context.Background(), &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{"TEST_TABLE": {
+				{DeleteRequest: &types.DeleteRequest{Key: createTestKey(1)}}}}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Next, attempt to read all the items with a batch-get; this should not fail
+		output, err := fake.BatchGetItem(context.Background(), &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]types.KeysAndAttributes{"TEST_TABLE": {
+				Keys: []map[string]types.AttributeValue{createTestKey(0), createTestKey(1), createTestKey(2)}}}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the items that were returned
+		Expect(output.UnprocessedKeys).Should(BeEmpty())
+		Expect(output.Responses["TEST_TABLE"]).Should(ConsistOf(createTestItem(0), createTestItem(2)))
+	})
+
+	// Test that, if any condition in a transaction fails, then TransactWriteItems will cancel the transaction
+	// without applying any of its operations and return a reason for each operation
+	It("TransactWriteItems - Condition fails - Cancelled", func() {
+
+		// First, write an item to the table
+		putTestItems(fake, 1)
+
+		// Next, attempt to write a transaction that puts a new item and checks a condition that fails
+		output, err := fake.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{Put: &types.Put{TableName: aws.String("TEST_TABLE"), Item: createTestItem(1)}},
+				{ConditionCheck: &types.ConditionCheck{
+					TableName:                           aws.String("TEST_TABLE"),
+					Key:                                 createTestKey(0),
+					ConditionExpression:                 aws.String("attribute_not_exists(id)"),
+					ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld}}}})
+		Expect(output).Should(BeNil())
+
+		// Now, verify the cancellation reasons
+		var cancelled *types.TransactionCanceledException
+		Expect(errors.As(err, &cancelled)).Should(BeTrue())
+		Expect(*cancelled.Message).Should(Equal("Transaction cancelled, please refer cancellation reasons " +
+			"for specific reasons [None, ConditionalCheckFailed]"))
+		Expect(cancelled.CancellationReasons).Should(Equal([]types.CancellationReason{
+			{Code: aws.String("None")},
+			{Code: aws.String("ConditionalCheckFailed"), Message: aws.String("The conditional request failed"),
+				Item: createTestItem(0)}}))
+
+		// Finally, verify that the new item was not written
+		Expect(getTestItem(fake, 1)).Should(BeNil())
+	})
+
+	// Test that, if all the conditions in a transaction are met, then TransactWriteItems will apply all
+	// of its operations, and TransactGetItems can read them
+	It("TransactWriteItems, TransactGetItems - Conditions met - Applied", func() {
+
+		// First, write some items to the table
+		putTestItems(fake, 2)
+
+		// Next, attempt to write a transaction that puts, updates and deletes items; this should not fail
+		_, err := fake.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{
+			TransactItems: []types.TransactWriteItem{
+				{Put: &types.Put{TableName: aws.String("TEST_TABLE"), Item: createTestItem(2)}},
+				{Update: &types.Update{TableName: aws.String("TEST_TABLE"), Key: createTestKey(1),
+					UpdateExpression:          aws.String("SET #d = :d"),
+					ConditionExpression:       aws.String("attribute_exists(#d)"),
+					ExpressionAttributeNames:  map[string]string{"#d": "data"},
+					ExpressionAttributeValues: map[string]types.AttributeValue{":d": &types.AttributeValueMemberN{Value: "42"}}}},
+				{Delete: &types.Delete{TableName: aws.String("TEST_TABLE"), Key: createTestKey(0)}}}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Now, attempt to read all the items in a transaction; this should not fail
+		output, err := fake.TransactGetItems(context.Background(), &dynamodb.TransactGetItemsInput{
+			TransactItems: []types.TransactGetItem{
+				{Get: &types.Get{TableName: aws.String("TEST_TABLE"), Key: createTestKey(0)}},
+				{Get: &types.Get{TableName: aws.String("TEST_TABLE"), Key: createTestKey(1)}},
+				{Get: &types.Get{TableName: aws.String("TEST_TABLE"), Key: createTestKey(2)}}}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the items that were returned
+		Expect(output.Responses[0].Item).Should(BeNil())
+		Expect(output.Responses[1].Item["data"]).Should(Equal(&types.AttributeValueMemberN{Value: "42"}))
+		Expect(output.Responses[2].Item).Should(Equal(createTestItem(2)))
+	})
+
+	// Test that, if the operation isn't supported, then the fake will return an error
+	It("ExecuteStatement - Not supported - Error", func() {
+		output, err := fake.ExecuteStatement(context.Background(), &dynamodb.ExecuteStatementInput{})
+		Expect(output).Should(BeNil())
+		verifyValidation(err, "ExecuteStatement", "ExecuteStatement is not supported by the in-memory DynamoDB")
+	})
+
+	// Test that conditions are evaluated against items as DynamoDB would evaluate them
+	DescribeTable("Conditions - Evaluated",
+		func(expression string, expected bool) {
+
+			// First, create our expression context with some attribute names and values
+			ctx := newExpressionContext(map[string]string{"#d": "data", "#m": "details"},
+				map[string]types.AttributeValue{
+					":s":    &types.AttributeValueMemberS{Value: "Tok"},
+					":n":    &types.AttributeValueMemberN{Value: "10.0"},
+					":t":    &types.AttributeValueMemberS{Value: "SS"},
+					":b":    &types.AttributeValueMemberS{Value: "b"},
+					":two":  &types.AttributeValueMemberN{Value: "2"},
+					":list": &types.AttributeValueMemberS{Value: "x"}})
+
+			// Next, parse the condition; this should not fail
+			cond, err := ctx.parseCondition("ConditionExpression", aws.String(expression))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			// Finally, evaluate the condition against our test item and verify the result
+			item := createTestItem(10)
+			item["list"] = &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "x"}}}
+			Expect(cond.evaluate(item)).Should(Equal(expected))
+		},
+		Entry("Numbers compared by value", "#d = :n", true),
+		Entry("Inequality with missing attribute", "missing <> :n", true),
+		Entry("Comparison with missing attribute", "missing < :n", false),
+		Entry("Comparison with different types", "#d < :s", false),
+		Entry("begins_with on nested path", "begins_with(#m.city, :s)", true),
+		Entry("contains on set", "contains(tags, :b)", true),
+		Entry("contains on list", "contains(list[0], :list) OR contains(list, :list)", true),
+		Entry("attribute_type", "attribute_type(tags, :t)", true),
+		Entry("size of set", "size(tags) = :two", true),
+		Entry("Precedence of AND over OR", "attribute_exists(missing) AND #d = :n OR #d = :n", true),
+		Entry("Parentheses", "attribute_exists(missing) AND (#d = :n OR #d = :n)", false),
+		Entry("NOT", "NOT attribute_exists(missing)", true))
+
+	// Test that invalid expressions are rejected with a description of the problem
+	DescribeTable("Expressions - Invalid - Error",
+		func(expression string, message string) {
+			ctx := newExpressionContext(map[string]string{"#d": "data"},
+				map[string]types.AttributeValue{":v": &types.AttributeValueMemberN{Value: "1"}})
+			_, err := ctx.parseCondition("ConditionExpression", aws.String(expression))
+			verifyValidation(err, "", message)
+		},
+		Entry("Undefined name", "#x = :v",
+			"Invalid ConditionExpression: An expression attribute name used in the document path is not defined; "+
+				"attribute name: #x"),
+		Entry("Undefined value", "#d = :x",
+			"Invalid ConditionExpression: An expression attribute value used in expression is not defined; "+
+				"attribute value: :x"),
+		Entry("Unknown function", "exists(#d)", "Invalid ConditionExpression: Invalid function name; function: exists"),
+		Entry("Unexpected token", "#d = :v :v", "Invalid ConditionExpression: Syntax error; token: \":v\""),
+		Entry("Empty", " ", "Invalid ConditionExpression: The expression can not be empty"))
+})
+
+// Helper function that creates the request for our test table, which has a string hash key, a numeric
+// range key and a global index on the owner, which projects only the keys
+func createTestTable() *dynamodb.CreateTableInput {
+	return &dynamodb.CreateTableInput{
+		TableName: aws.String("TEST_TABLE"),
+		AttributeDefinitions: []types.AttributeDefinition{
+			{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS},
+			{AttributeName: aws.String("sort_key"), AttributeType: types.ScalarAttributeTypeN},
+			{AttributeName: aws.String("owner"), AttributeType: types.ScalarAttributeTypeS}},
+		KeySchema: []types.KeySchemaElement{
+			{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash},
+			{AttributeName: aws.String("sort_key"), KeyType: types.KeyTypeRange}},
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{{
+			IndexName:  aws.String("ByOwner"),
+			KeySchema:  []types.KeySchemaElement{{AttributeName: aws.String("owner"), KeyType: types.KeyTypeHash}},
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly}}},
+		BillingMode: types.BillingModePayPerRequest,
+	}
+}
+
+// Helper function that creates the key of a test item
+func createTestKey(index int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"id":       &types.AttributeValueMemberS{Value: fmt.Sprintf("test_id|%d", index)},
+		"sort_key": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", index)},
+	}
+}
+
+// Helper function that creates a test item. Only the even items have an owner
+func createTestItem(index int) map[string]types.AttributeValue {
+	item := createTestKey(index)
+	item["data"] = &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", index)}
+	item["tags"] = &types.AttributeValueMemberSS{Value: []string{"a", "b"}}
+	item["details"] = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+		"city": &types.AttributeValueMemberS{Value: "Tokyo"},
+		"zip":  &types.AttributeValueMemberS{Value: "100"}}}
+	if index%2 == 0 {
+		item["owner"] = &types.AttributeValueMemberS{Value: fmt.Sprintf("owner|%d", index)}
+	}
+
+	return item
+}
+
+// Helper function that writes a number of test items to the test table
+func putTestItems(fake *FakeDynamoDB, count int) {
+	for i := 0; i < count; i++ {
+		_, err := fake.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName: aws.String("TEST_TABLE"), Item: createTestItem(i)})
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+}
+
+// Helper function that gets a test item from the test table
+func getTestItem(fake *FakeDynamoDB, index int) map[string]types.AttributeValue {
+	output, err := fake.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName: aws.String("TEST_TABLE"), Key: createTestKey(index)})
+	Expect(err).ShouldNot(HaveOccurred())
+	return output.Item
+}
+
+// Helper function that gets the range keys of a list of items
+func sortKeys(items []map[string]types.AttributeValue) []string {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = item["sort_key"].(*types.AttributeValueMemberN).Value
+	}
+
+	return keys
+}
+
+// Helper function that verifies that an error was returned by an operation and has the expected type and message
+func verifyError[T error](err error, operation string, _ T, message string) {
+	var opErr *smithy.OperationError
+	Expect(errors.As(err, &opErr)).Should(BeTrue())
+	Expect(opErr.OperationName).Should(Equal(operation))
+
+	var casted T
+	Expect(errors.As(err, &casted)).Should(BeTrue())
+	Expect(err.Error()).Should(HaveSuffix(message))
+}
+
+// Helper function that verifies that a validation error was returned, by the operation if one is provided
+func verifyValidation(err error, operation string, message string) {
+	if operation != "" {
+		var opErr *smithy.OperationError
+		Expect(errors.As(err, &opErr)).Should(BeTrue())
+		Expect(opErr.OperationName).Should(Equal(operation))
+	}
+
+	var apiErr *smithy.GenericAPIError
+	Expect(errors.As(err, &apiErr)).Should(BeTrue())
+	Expect(apiErr.Code).Should(Equal("ValidationException"))
+	Expect(apiErr.Message).Should(Equal(message))
+}
@@ -0,0 +1,585 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Helper type that describes a write to a single item, from any of the write requests
+type writeRequest struct {
+	kind      string
+	tableName *string
+	key       map[string]types.AttributeValue
+	item      map[string]types.AttributeValue
+	update    *string
+	condition *string
+	names     map[string]string
+	values    map[string]types.AttributeValue
+}
+
+// Helper type that describes a write that has been validated and evaluated but not yet stored
+type preparedWrite struct {
+	kind    string
+	table   *fakeTable
+	key     string
+	old     map[string]types.AttributeValue
+	new     map[string]types.AttributeValue
+	updated []string
+	failed  bool
+}
+
+// GetItem gets an item from a table by its key
+func (fake *FakeDynamoDB) GetItem(ctx context.Context, params *dynamodb.GetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	item, err := fake.getItem(params.TableName, params.Key, params.ProjectionExpression,
+		params.ExpressionAttributeNames)
+	if err != nil {
+		return nil, operationError("GetItem", err)
+	}
+
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+// PutItem writes an item to a table, replacing any existing item with the same key, if its condition is met
+func (fake *FakeDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	// First, ensure that the return values requested are valid for the operation
+	if err := validateReturnValues(params.ReturnValues, false); err != nil {
+		return nil, operationError("PutItem", err)
+	}
+
+	// Next, attempt to write the item; if this fails then return an error
+	write, err := fake.write(&writeRequest{
+		kind:      "PUT",
+		tableName: params.TableName,
+		item:      params.Item,
+		condition: params.ConditionExpression,
+		names:     params.ExpressionAttributeNames,
+		values:    params.ExpressionAttributeValues,
+	})
+
+	if err != nil {
+		return nil, operationError("PutItem", err)
+	}
+
+	// Finally, return the old item, if it was requested
+	return &dynamodb.PutItemOutput{Attributes: write.returnValues(params.ReturnValues)}, nil
+}
+
+// UpdateItem applies an update expression to an item, creating it if it doesn't exist, if its condition is met
+func (fake *FakeDynamoDB) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	// First, ensure that the return values requested are valid for the operation
+	if err := validateReturnValues(params.ReturnValues, true); err != nil {
+		return nil, operationError("UpdateItem", err)
+	}
+
+	// Next, attempt to update the item; if this fails then return an error
+	write, err := fake.write(&writeRequest{
+		kind:      "UPDATE",
+		tableName: params.TableName,
+		key:       params.Key,
+		update:    params.UpdateExpression,
+		condition: params.ConditionExpression,
+		names:     params.ExpressionAttributeNames,
+		values:    params.ExpressionAttributeValues,
+	})
+
+	if err != nil {
+		return nil, operationError("UpdateItem", err)
+	}
+
+	// Finally, return the attributes that were requested
+	return &dynamodb.UpdateItemOutput{Attributes: write.returnValues(params.ReturnValues)}, nil
+}
+
+// DeleteItem removes an item from a table by its key, if its condition is met
+func (fake *FakeDynamoDB) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	// First, ensure that the return values requested are valid for the operation
+	if err := validateReturnValues(params.ReturnValues, false); err != nil {
+		return nil, operationError("DeleteItem", err)
+	}
+
+	// Next, attempt to delete the item; if this fails then return an error
+	write, err := fake.write(&writeRequest{
+		kind:      "DELETE",
+		tableName: params.TableName,
+		key:       params.Key,
+		condition: params.ConditionExpression,
+		names:     params.ExpressionAttributeNames,
+		values:    params.ExpressionAttributeValues,
+	})
+
+	if err != nil {
+		return nil, operationError("DeleteItem", err)
+	}
+
+	// Finally, return the old item, if it was requested
+	return &dynamodb.DeleteItemOutput{Attributes: write.returnValues(params.ReturnValues)}, nil
+}
+
+// BatchGetItem gets up to 100 items, from any number of tables, by their keys. All the keys will be
+// processed so no unprocessed keys will be returned
+func (fake *FakeDynamoDB) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	// First, ensure that the request contains a valid number of keys
+	count := 0
+	for _, request := range params.RequestItems {
+		count += len(request.Keys)
+	}
+
+	if count == 0 || count > 100 {
+		return nil, operationError("BatchGetItem", validationError("Too many items requested for the BatchGetItem call"))
+	}
+
+	// Next, iterate over each table and get all the items requested from it
+	output := &dynamodb.BatchGetItemOutput{
+		Responses:       make(map[string][]map[string]types.AttributeValue),
+		UnprocessedKeys: make(map[string]types.KeysAndAttributes),
+	}
+
+	for tableName, request := range params.RequestItems {
+		seen := make(map[string]bool)
+		output.Responses[tableName] = make([]map[string]types.AttributeValue, 0)
+		for _, key := range request.Keys {
+
+			// First, ensure that we haven't seen the key before
+			if seen[fingerprint(key, sortedNames(key)...)] {
+				return nil, operationError("BatchGetItem",
+					validationError("Provided list of item keys contains duplicates"))
+			}
+
+			seen[fingerprint(key, sortedNames(key)...)] = true
+
+			// Next, attempt to get the item; if it exists then add it to the responses
+			item, err := fake.getItem(aws.String(tableName), key, request.ProjectionExpression,
+				request.ExpressionAttributeNames)
+			if err != nil {
+				return nil, operationError("BatchGetItem", err)
+			} else if item != nil {
+				output.Responses[tableName] = append(output.Responses[tableName], item)
+			}
+		}
+	}
+
+	return output, nil
+}
+
+// BatchWriteItem puts or deletes up to 25 items, in any number of tables. All the requests will be
+// processed so no unprocessed items will be returned
+func (fake *FakeDynamoDB) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	// First, ensure that the request contains a valid number of requests
+	count := 0
+	for _, requests := range params.RequestItems {
+		count += len(requests)
+	}
+
+	if count == 0 || count > 25 {
+		return nil, operationError("BatchWriteItem",
+			validationError("Too many items requested for the BatchWriteItem call"))
+	}
+
+	// Next, prepare each of the writes so that they're all validated before any are stored
+	writes := make([]*preparedWrite, 0, count)
+	seen := make(map[string]bool)
+	for tableName, requests := range params.RequestItems {
+		for _, request := range requests {
+			var write *writeRequest
+			if request.PutRequest != nil {
+				write = &writeRequest{kind: "PUT", tableName: aws.String(tableName), item: request.PutRequest.Item}
+			} else if request.DeleteRequest != nil {
+				write = &writeRequest{kind: "DELETE", tableName: aws.String(tableName), key: request.DeleteRequest.Key}
+			} else {
+				return nil, operationError("BatchWriteItem",
+					validationError("Supplied AttributeValue has more than one datatypes set"))
+			}
+
+			prepared, err := fake.prepareWrite(write)
+			if err != nil {
+				return nil, operationError("BatchWriteItem", err)
+			} else if seen[tableName+"|"+prepared.key] {
+				return nil, operationError("BatchWriteItem",
+					validationError("Provided list of item keys contains duplicates"))
+			}
+
+			seen[tableName+"|"+prepared.key] = true
+			writes = append(writes, prepared)
+		}
+	}
+
+	// Finally, store all the writes
+	for _, write := range writes {
+		write.commit()
+	}
+
+	return &dynamodb.BatchWriteItemOutput{UnprocessedItems: make(map[string][]types.WriteRequest)}, nil
+}
+
+// TransactGetItems gets up to 100 items, from any number of tables, atomically
+func (fake *FakeDynamoDB) TransactGetItems(ctx context.Context, params *dynamodb.TransactGetItemsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	// First, ensure that the request contains a valid number of operations
+	if len(params.TransactItems) == 0 || len(params.TransactItems) > 100 {
+		return nil, operationError("TransactGetItems", validationError("Member must have length less than "+
+			"or equal to 100 and greater than or equal to 1"))
+	}
+
+	// Next, get each of the items requested
+	responses := make([]types.ItemResponse, len(params.TransactItems))
+	for i, request := range params.TransactItems {
+		if request.Get == nil {
+			return nil, operationError("TransactGetItems", validationError("Get is required"))
+		}
+
+		item, err := fake.getItem(request.Get.TableName, request.Get.Key, request.Get.ProjectionExpression,
+			request.Get.ExpressionAttributeNames)
+		if err != nil {
+			return nil, operationError("TransactGetItems", err)
+		}
+
+		responses[i].Item = item
+	}
+
+	return &dynamodb.TransactGetItemsOutput{Responses: responses}, nil
+}
+
+// TransactWriteItems applies up to 100 puts, updates, deletes and condition checks, across any number
+// of tables, atomically. If the condition of any operation fails then none of the operations will be
+// applied and a TransactionCanceledException will be returned with a reason for each operation
+func (fake *FakeDynamoDB) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	// First, ensure that the request contains a valid number of operations
+	if len(params.TransactItems) == 0 || len(params.TransactItems) > 100 {
+		return nil, operationError("TransactWriteItems", validationError("Member must have length less than "+
+			"or equal to 100 and greater than or equal to 1"))
+	}
+
+	// Next, prepare each of the operations so that they're all validated and evaluated before any are stored
+	writes := make([]*preparedWrite, len(params.TransactItems))
+	returnOld := make([]bool, len(params.TransactItems))
+	seen := make(map[string]bool)
+	for i, item := range params.TransactItems {
+
+		// First, convert the operation to a write request
+		var request *writeRequest
+		switch {
+		case item.Put != nil:
+			request = &writeRequest{kind: "PUT", tableName: item.Put.TableName, item: item.Put.Item,
+				condition: item.Put.ConditionExpression, names: item.Put.ExpressionAttributeNames,
+				values: item.Put.ExpressionAttributeValues}
+			returnOld[i] = item.Put.ReturnValuesOnConditionCheckFailure == types.ReturnValuesOnConditionCheckFailureAllOld
+		case item.Update != nil:
+			request = &writeRequest{kind: "UPDATE", tableName: item.Update.TableName, key: item.Update.Key,
+				update: item.Update.UpdateExpression, condition: item.Update.ConditionExpression,
+				names: item.Update.ExpressionAttributeNames, values: item.Update.ExpressionAttributeValues}
+			returnOld[i] = item.Update.ReturnValuesOnConditionCheckFailure == types.ReturnValuesOnConditionCheckFailureAllOld
+		case item.Delete != nil:
+			request = &writeRequest{kind: "DELETE", tableName: item.Delete.TableName, key: item.Delete.Key,
+				condition: item.Delete.ConditionExpression, names: item.Delete.ExpressionAttributeNames,
+				values: item.Delete.ExpressionAttributeValues}
+			returnOld[i] = item.Delete.ReturnValuesOnConditionCheckFailure == types.ReturnValuesOnConditionCheckFailureAllOld
+		case item.ConditionCheck != nil:
+			if item.ConditionCheck.ConditionExpression == nil {
+				return nil, operationError("TransactWriteItems",
+					validationError("ConditionCheck requires a ConditionExpression"))
+			}
+
+			request = &writeRequest{kind: "CONDITION CHECK", tableName: item.ConditionCheck.TableName,
+				key: item.ConditionCheck.Key, condition: item.ConditionCheck.ConditionExpression,
+				names:  item.ConditionCheck.ExpressionAttributeNames,
+				values: item.ConditionCheck.ExpressionAttributeValues}
+			returnOld[i] = item.ConditionCheck.ReturnValuesOnConditionCheckFailure ==
+				types.ReturnValuesOnConditionCheckFailureAllOld
+		default:
+			return nil, operationError("TransactWriteItems",
+				validationError("TransactItems can only contain one of Check, Put, Update or Delete"))
+		}
+
+		// Next, prepare the write; if this fails then return an error
+		prepared, err := fake.prepareWrite(request)
+		if err != nil {
+			return nil, operationError("TransactWriteItems", err)
+		}
+
+		// Finally, ensure that we haven't seen the item before as DynamoDB only allows one operation
+		// on each item in a transaction
+		id := aws.ToString(request.tableName) + "|" + prepared.key
+		if seen[id] {
+			return nil, operationError("TransactWriteItems",
+				validationError("Transaction request cannot include multiple operations on one item"))
+		}
+
+		seen[id] = true
+		writes[i] = prepared
+	}
+
+	// Now, create the cancellation reasons for each operation; if any of the conditions failed then
+	// cancel the transaction and return the reasons
+	reasons := make([]types.CancellationReason, len(writes))
+	codes := make([]string, len(writes))
+	cancelled := false
+	for i, write := range writes {
+		if write.failed {
+			reasons[i] = types.CancellationReason{Code: aws.String("ConditionalCheckFailed"),
+				Message: aws.String("The conditional request failed")}
+			if returnOld[i] {
+				reasons[i].Item = copyItem(write.old)
+			}
+
+			cancelled = true
+		} else {
+			reasons[i] = types.CancellationReason{Code: aws.String("None")}
+		}
+
+		codes[i] = *reasons[i].Code
+	}
+
+	if cancelled {
+		return nil, operationError("TransactWriteItems", &types.TransactionCanceledException{
+			Message: aws.String(fmt.Sprintf("Transaction cancelled, please refer cancellation reasons for "+
+				"specific reasons [%s]", strings.Join(codes, ", "))),
+			CancellationReasons: reasons,
+		})
+	}
+
+	// Finally, store all the writes
+	for _, write := range writes {
+		write.commit()
+	}
+
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+// Helper function that gets a copy of an item from a table, projected with the projection expression.
+// If the item doesn't exist then nil will be returned
+func (fake *FakeDynamoDB) getItem(tableName *string, key map[string]types.AttributeValue,
+	projection *string, names map[string]string) (map[string]types.AttributeValue, error) {
+
+	// First, get the table and ensure that the key is valid for it
+	table, err := fake.getTable(tableName)
+	if err != nil {
+		return nil, err
+	} else if err := table.validateKey(key); err != nil {
+		return nil, err
+	}
+
+	// Next, parse the projection expression and ensure that all the names were used
+	ctx := newExpressionContext(names, nil)
+	paths, err := ctx.parseProjection(projection)
+	if err != nil {
+		return nil, err
+	} else if err := ctx.checkUnused(); err != nil {
+		return nil, err
+	}
+
+	// Finally, get the item and project it, if it exists
+	item, ok := table.items[table.itemKey(key)]
+	if !ok {
+		return nil, nil
+	} else if paths != nil {
+		return projectItem(item, paths), nil
+	}
+
+	return copyItem(item), nil
+}
+
+// Helper function that prepares a write and, if its condition was met, stores it. If the condition
+// failed then a ConditionalCheckFailedException will be returned
+func (fake *FakeDynamoDB) write(request *writeRequest) (*preparedWrite, error) {
+	write, err := fake.prepareWrite(request)
+	if err != nil {
+		return nil, err
+	} else if write.failed {
+		return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	}
+
+	write.commit()
+	return write, nil
+}
+
+// Helper function that validates a write, evaluates its condition and, for puts and updates, creates
+// the new version of the item. Nothing will be stored until the write is committed
+func (fake *FakeDynamoDB) prepareWrite(request *writeRequest) (*preparedWrite, error) {
+
+	// First, get the table the write refers to
+	table, err := fake.getTable(request.tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Next, ensure that the item, or key, is valid for the table
+	key := request.key
+	if request.kind == "PUT" {
+		if err := table.validateItem(request.item); err != nil {
+			return nil, err
+		}
+
+		key = request.item
+	} else if err := table.validateKey(key); err != nil {
+		return nil, err
+	}
+
+	// Now, parse the expressions on the request and ensure that all the names and values were used
+	ctx := newExpressionContext(request.names, request.values)
+	cond, err := ctx.parseCondition("ConditionExpression", request.condition)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := ctx.parseUpdate(request.update)
+	if err != nil {
+		return nil, err
+	} else if request.kind == "UPDATE" && actions == nil {
+		return nil, validationError("Invalid UpdateExpression: The expression can not be empty")
+	}
+
+	if err := ctx.checkUnused(); err != nil {
+		return nil, err
+	}
+
+	// Get the existing item and evaluate the condition against it
+	write := &preparedWrite{kind: request.kind, table: table, key: table.itemKey(key)}
+	write.old = table.items[write.key]
+	existing := write.old
+	if existing == nil {
+		existing = make(map[string]types.AttributeValue)
+	}
+
+	write.failed = cond != nil && !cond.evaluate(existing)
+
+	// Finally, create the new version of the item based on the kind of write
+	switch request.kind {
+	case "PUT":
+		write.new = copyItem(request.item)
+	case "UPDATE":
+		if write.old != nil {
+			write.new = copyItem(write.old)
+		} else {
+			write.new = table.extractKey(key, nil)
+		}
+
+		for _, action := range actions {
+			name := action.path[0].name
+			for _, keyName := range table.keyNames() {
+				if name == keyName {
+					return nil, validationError("One or more parameter values were invalid: Cannot update "+
+						"attribute %s. This attribute is part of the key", name)
+				}
+			}
+
+			if err := action.apply(write.new); err != nil {
+				return nil, err
+			}
+
+			write.updated = append(write.updated, name)
+		}
+
+		if err := table.validateItem(write.new); err != nil {
+			return nil, err
+		}
+	case "CONDITION CHECK":
+		write.new = write.old
+	}
+
+	return write, nil
+}
+
+// Helper function that stores the new version of the item, or removes it if it was deleted
+func (write *preparedWrite) commit() {
+	switch write.kind {
+	case "CONDITION CHECK":
+	case "DELETE":
+		delete(write.table.items, write.key)
+	default:
+		write.table.items[write.key] = write.new
+	}
+}
+
+// Helper function that gets the attributes that should be returned by a write, based on the return
+// values that were requested
+func (write *preparedWrite) returnValues(returnValues types.ReturnValue) map[string]types.AttributeValue {
+	switch returnValues {
+	case types.ReturnValueAllOld:
+		return copyItem(write.old)
+	case types.ReturnValueAllNew:
+		return copyItem(write.new)
+	case types.ReturnValueUpdatedOld, types.ReturnValueUpdatedNew:
+		source := write.new
+		if returnValues == types.ReturnValueUpdatedOld {
+			source = write.old
+		}
+
+		attributes := make(map[string]types.AttributeValue)
+		for _, name := range write.updated {
+			if value, ok := source[name]; ok {
+				attributes[name] = copyValue(value)
+			}
+		}
+
+		if len(attributes) == 0 {
+			return nil
+		}
+
+		return attributes
+	default:
+		return nil
+	}
+}
+
+// Helper function that ensures that the return values requested are valid for a write. Puts and
+// deletes may only return the old item
+func validateReturnValues(returnValues types.ReturnValue, update bool) error {
+	switch returnValues {
+	case "", types.ReturnValueNone, types.ReturnValueAllOld:
+		return nil
+	case types.ReturnValueAllNew, types.ReturnValueUpdatedOld, types.ReturnValueUpdatedNew:
+		if update {
+			return nil
+		}
+	}
+
+	return validationError("Return values set to invalid value")
+}
+
+// Helper function that gets the sorted keys of a map, such as the names of the attributes on an item
+func sortedNames[T any](item map[string]T) []string {
+	names := make([]string, 0, len(item))
+	for name := range item {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
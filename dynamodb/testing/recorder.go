@@ -0,0 +1,583 @@
+package testing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// Helper variable containing the types of all the errors that may be returned by DynamoDB, keyed by their
+// error codes, so that recorded errors can be replayed with the same type
+var errorTypes = registerErrors(&types.BackupInUseException{}, &types.BackupNotFoundException{},
+	&types.ConditionalCheckFailedException{}, &types.ContinuousBackupsUnavailableException{},
+	&types.DuplicateItemException{}, &types.ExportConflictException{}, &types.ExportNotFoundException{},
+	&types.GlobalTableAlreadyExistsException{}, &types.GlobalTableNotFoundException{},
+	&types.IdempotentParameterMismatchException{}, &types.ImportConflictException{},
+	&types.ImportNotFoundException{}, &types.IndexNotFoundException{}, &types.InternalServerError{},
+	&types.InvalidEndpointException{}, &types.InvalidExportTimeException{}, &types.InvalidRestoreTimeException{},
+	&types.ItemCollectionSizeLimitExceededException{}, &types.LimitExceededException{},
+	&types.PointInTimeRecoveryUnavailableException{}, &types.ProvisionedThroughputExceededException{},
+	&types.ReplicaAlreadyExistsException{}, &types.ReplicaNotFoundException{}, &types.RequestLimitExceeded{},
+	&types.ResourceInUseException{}, &types.ResourceNotFoundException{}, &types.TableAlreadyExistsException{},
+	&types.TableInUseException{}, &types.TableNotFoundException{}, &types.TransactionCanceledException{},
+	&types.TransactionConflictException{}, &types.TransactionInProgressException{})
+
+// Fixture describes the contents of a file containing the interactions recorded with DynamoDB
+type Fixture struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction describes a single request made to DynamoDB along with the response, or error, that was
+// returned. Requests and responses are stored as trees of JSON values with their attribute values encoded
+// in the same way as DynamoDB would encode them
+type Interaction struct {
+	Operation string         `json:"operation"`
+	Request   interface{}    `json:"request"`
+	Response  interface{}    `json:"response,omitempty"`
+	Error     *RecordedError `json:"error,omitempty"`
+}
+
+// RecordedError describes an error returned by a request made to DynamoDB. If the error was returned by
+// the service then the code will be set and, if the error has a known type, its fields will also be set
+type RecordedError struct {
+	Operation string      `json:"operation,omitempty"`
+	Code      string      `json:"code,omitempty"`
+	Message   string      `json:"message"`
+	Fields    interface{} `json:"fields,omitempty"`
+}
+
+// Recorder wraps a DynamoDB client, recording every request made through it, along with the response or
+// error returned, so that they can be saved to a fixture file and served by a Replayer later. This allows
+// realistic interactions to be captured once, against DynamoDB Local, and then replayed in unit tests.
+// Requests are recorded in the order in which they complete and the recorder is safe for concurrent use
+type Recorder struct {
+	inner        DynamoDBAPI
+	interactions []*Interaction
+	err          error
+	lock         *sync.Mutex
+}
+
+// NewRecorder creates a new recorder that sends requests to the inner client
+func NewRecorder(inner DynamoDBAPI) *Recorder {
+	return &Recorder{
+		inner:        inner,
+		interactions: make([]*Interaction, 0),
+		lock:         new(sync.Mutex),
+	}
+}
+
+// Interactions returns all the interactions that have been recorded so far
+func (recorder *Recorder) Interactions() []*Interaction {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	return append([]*Interaction{}, recorder.interactions...)
+}
+
+// Save writes all the interactions that have been recorded so far to a fixture file at the path provided.
+// This function will return an error if any interaction could not be recorded or the file could not be written
+func (recorder *Recorder) Save(path string) error {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	// First, check whether any of the interactions failed to record; if this is the case then the
+	// fixture would be incomplete so return an error
+	if recorder.err != nil {
+		return recorder.err
+	}
+
+	// Next, convert the interactions to JSON
+	data, err := json.MarshalIndent(&Fixture{Interactions: recorder.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal recorded interactions, error: %v", err)
+	}
+
+	// Finally, write the JSON to the file
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write recorded interactions to %s, error: %v", path, err)
+	}
+
+	return nil
+}
+
+// Helper function that records a request and its response or error. The error will be returned so that the
+// result of this function can be returned directly by the caller
+func (recorder *Recorder) record(operation string, request interface{}, response interface{}, err error) error {
+
+	// First, create the interaction from the request, response and error; if any of these fail to
+	// encode then save the error so it can be reported when the fixture is saved
+	interaction, encErr := createInteraction(operation, request, response, err)
+
+	// Finally, add the interaction to our list of interactions
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	if encErr != nil {
+		if recorder.err == nil {
+			recorder.err = fmt.Errorf("failed to record %s request, error: %v", operation, encErr)
+		}
+	} else {
+		recorder.interactions = append(recorder.interactions, interaction)
+	}
+
+	return err
+}
+
+// Helper function that creates an interaction from a request and its response or error
+func createInteraction(operation string, request interface{}, response interface{},
+	err error) (*Interaction, error) {
+	interaction := Interaction{Operation: operation}
+
+	// First, encode the request
+	var encErr error
+	if interaction.Request, encErr = encode(request); encErr != nil {
+		return nil, encErr
+	}
+
+	// Next, if the request did not fail then encode the response and return
+	if err == nil {
+		if interaction.Response, encErr = encode(response); encErr != nil {
+			return nil, encErr
+		}
+
+		return &interaction, nil
+	}
+
+	// Now, if the error was wrapped in an operation error then record the operation
+	interaction.Error = &RecordedError{Message: err.Error()}
+	var opErr *smithy.OperationError
+	if errors.As(err, &opErr) {
+		interaction.Error.Operation = opErr.OperationName
+		interaction.Error.Message = opErr.Err.Error()
+	}
+
+	// Finally, if the error was returned by the service then record its code, message and, if it has a
+	// known type, its fields
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		interaction.Error.Code = apiErr.ErrorCode()
+		interaction.Error.Message = apiErr.ErrorMessage()
+		if _, ok := errorTypes[apiErr.ErrorCode()]; ok {
+			if interaction.Error.Fields, encErr = encode(apiErr); encErr != nil {
+				return nil, encErr
+			}
+		}
+	}
+
+	return &interaction, nil
+}
+
+// Helper function that recreates an error from its recording
+func (recorded *RecordedError) toError() error {
+
+	// First, recreate the inner error. If the error has a known type then decode its fields into a new
+	// error of that type. Otherwise, if the error has a code then create a generic API error. If neither
+	// of these is the case then create an error with the same message
+	var err error
+	if errType, ok := errorTypes[recorded.Code]; ok {
+		value := reflect.New(errType.Elem())
+		if decErr := decodeValue(recorded.Fields, value.Elem()); decErr != nil {
+			return fmt.Errorf("failed to replay %s error, error: %v", recorded.Code, decErr)
+		}
+
+		err = value.Interface().(error)
+	} else if recorded.Code != "" {
+		err = &smithy.GenericAPIError{Code: recorded.Code, Message: recorded.Message}
+	} else {
+		err = errors.New(recorded.Message)
+	}
+
+	// Finally, if the error was wrapped in an operation error then wrap it again
+	if recorded.Operation != "" {
+		err = &smithy.OperationError{ServiceID: "DynamoDB", OperationName: recorded.Operation, Err: err}
+	}
+
+	return err
+}
+
+// Helper function that creates a mapping from the error codes of a number of errors to their types
+func registerErrors(errs ...smithy.APIError) map[string]reflect.Type {
+	registry := make(map[string]reflect.Type, len(errs))
+	for _, err := range errs {
+		registry[err.ErrorCode()] = reflect.TypeOf(err)
+	}
+
+	return registry
+}
+
+// BatchExecuteStatement sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) BatchExecuteStatement(ctx context.Context, params *dynamodb.BatchExecuteStatementInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchExecuteStatementOutput, error) {
+	output, err := recorder.inner.BatchExecuteStatement(ctx, params, optFns...)
+	return output, recorder.record("BatchExecuteStatement", params, output, err)
+}
+
+// BatchGetItem sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	output, err := recorder.inner.BatchGetItem(ctx, params, optFns...)
+	return output, recorder.record("BatchGetItem", params, output, err)
+}
+
+// BatchWriteItem sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	output, err := recorder.inner.BatchWriteItem(ctx, params, optFns...)
+	return output, recorder.record("BatchWriteItem", params, output, err)
+}
+
+// CreateBackup sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) CreateBackup(ctx context.Context, params *dynamodb.CreateBackupInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateBackupOutput, error) {
+	output, err := recorder.inner.CreateBackup(ctx, params, optFns...)
+	return output, recorder.record("CreateBackup", params, output, err)
+}
+
+// CreateGlobalTable sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) CreateGlobalTable(ctx context.Context, params *dynamodb.CreateGlobalTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateGlobalTableOutput, error) {
+	output, err := recorder.inner.CreateGlobalTable(ctx, params, optFns...)
+	return output, recorder.record("CreateGlobalTable", params, output, err)
+}
+
+// CreateTable sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) CreateTable(ctx context.Context, params *dynamodb.CreateTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	output, err := recorder.inner.CreateTable(ctx, params, optFns...)
+	return output, recorder.record("CreateTable", params, output, err)
+}
+
+// DeleteBackup sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DeleteBackup(ctx context.Context, params *dynamodb.DeleteBackupInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteBackupOutput, error) {
+	output, err := recorder.inner.DeleteBackup(ctx, params, optFns...)
+	return output, recorder.record("DeleteBackup", params, output, err)
+}
+
+// DeleteItem sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	output, err := recorder.inner.DeleteItem(ctx, params, optFns...)
+	return output, recorder.record("DeleteItem", params, output, err)
+}
+
+// DeleteTable sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DeleteTable(ctx context.Context, params *dynamodb.DeleteTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error) {
+	output, err := recorder.inner.DeleteTable(ctx, params, optFns...)
+	return output, recorder.record("DeleteTable", params, output, err)
+}
+
+// DescribeBackup sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeBackup(ctx context.Context, params *dynamodb.DescribeBackupInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeBackupOutput, error) {
+	output, err := recorder.inner.DescribeBackup(ctx, params, optFns...)
+	return output, recorder.record("DescribeBackup", params, output, err)
+}
+
+// DescribeContinuousBackups sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeContinuousBackups(ctx context.Context, params *dynamodb.DescribeContinuousBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+	output, err := recorder.inner.DescribeContinuousBackups(ctx, params, optFns...)
+	return output, recorder.record("DescribeContinuousBackups", params, output, err)
+}
+
+// DescribeContributorInsights sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeContributorInsights(ctx context.Context, params *dynamodb.DescribeContributorInsightsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeContributorInsightsOutput, error) {
+	output, err := recorder.inner.DescribeContributorInsights(ctx, params, optFns...)
+	return output, recorder.record("DescribeContributorInsights", params, output, err)
+}
+
+// DescribeEndpoints sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeEndpoints(ctx context.Context, params *dynamodb.DescribeEndpointsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeEndpointsOutput, error) {
+	output, err := recorder.inner.DescribeEndpoints(ctx, params, optFns...)
+	return output, recorder.record("DescribeEndpoints", params, output, err)
+}
+
+// DescribeExport sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeExport(ctx context.Context, params *dynamodb.DescribeExportInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeExportOutput, error) {
+	output, err := recorder.inner.DescribeExport(ctx, params, optFns...)
+	return output, recorder.record("DescribeExport", params, output, err)
+}
+
+// DescribeGlobalTable sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeGlobalTable(ctx context.Context, params *dynamodb.DescribeGlobalTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeGlobalTableOutput, error) {
+	output, err := recorder.inner.DescribeGlobalTable(ctx, params, optFns...)
+	return output, recorder.record("DescribeGlobalTable", params, output, err)
+}
+
+// DescribeGlobalTableSettings sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeGlobalTableSettings(ctx context.Context, params *dynamodb.DescribeGlobalTableSettingsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeGlobalTableSettingsOutput, error) {
+	output, err := recorder.inner.DescribeGlobalTableSettings(ctx, params, optFns...)
+	return output, recorder.record("DescribeGlobalTableSettings", params, output, err)
+}
+
+// DescribeImport sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeImport(ctx context.Context, params *dynamodb.DescribeImportInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeImportOutput, error) {
+	output, err := recorder.inner.DescribeImport(ctx, params, optFns...)
+	return output, recorder.record("DescribeImport", params, output, err)
+}
+
+// DescribeKinesisStreamingDestination sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeKinesisStreamingDestination(ctx context.Context, params *dynamodb.DescribeKinesisStreamingDestinationInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeKinesisStreamingDestinationOutput, error) {
+	output, err := recorder.inner.DescribeKinesisStreamingDestination(ctx, params, optFns...)
+	return output, recorder.record("DescribeKinesisStreamingDestination", params, output, err)
+}
+
+// DescribeLimits sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeLimits(ctx context.Context, params *dynamodb.DescribeLimitsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeLimitsOutput, error) {
+	output, err := recorder.inner.DescribeLimits(ctx, params, optFns...)
+	return output, recorder.record("DescribeLimits", params, output, err)
+}
+
+// DescribeTable sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	output, err := recorder.inner.DescribeTable(ctx, params, optFns...)
+	return output, recorder.record("DescribeTable", params, output, err)
+}
+
+// DescribeTableReplicaAutoScaling sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeTableReplicaAutoScaling(ctx context.Context, params *dynamodb.DescribeTableReplicaAutoScalingInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableReplicaAutoScalingOutput, error) {
+	output, err := recorder.inner.DescribeTableReplicaAutoScaling(ctx, params, optFns...)
+	return output, recorder.record("DescribeTableReplicaAutoScaling", params, output, err)
+}
+
+// DescribeTimeToLive sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DescribeTimeToLive(ctx context.Context, params *dynamodb.DescribeTimeToLiveInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	output, err := recorder.inner.DescribeTimeToLive(ctx, params, optFns...)
+	return output, recorder.record("DescribeTimeToLive", params, output, err)
+}
+
+// DisableKinesisStreamingDestination sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) DisableKinesisStreamingDestination(ctx context.Context, params *dynamodb.DisableKinesisStreamingDestinationInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DisableKinesisStreamingDestinationOutput, error) {
+	output, err := recorder.inner.DisableKinesisStreamingDestination(ctx, params, optFns...)
+	return output, recorder.record("DisableKinesisStreamingDestination", params, output, err)
+}
+
+// EnableKinesisStreamingDestination sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) EnableKinesisStreamingDestination(ctx context.Context, params *dynamodb.EnableKinesisStreamingDestinationInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.EnableKinesisStreamingDestinationOutput, error) {
+	output, err := recorder.inner.EnableKinesisStreamingDestination(ctx, params, optFns...)
+	return output, recorder.record("EnableKinesisStreamingDestination", params, output, err)
+}
+
+// ExecuteStatement sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) ExecuteStatement(ctx context.Context, params *dynamodb.ExecuteStatementInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteStatementOutput, error) {
+	output, err := recorder.inner.ExecuteStatement(ctx, params, optFns...)
+	return output, recorder.record("ExecuteStatement", params, output, err)
+}
+
+// ExecuteTransaction sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) ExecuteTransaction(ctx context.Context, params *dynamodb.ExecuteTransactionInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteTransactionOutput, error) {
+	output, err := recorder.inner.ExecuteTransaction(ctx, params, optFns...)
+	return output, recorder.record("ExecuteTransaction", params, output, err)
+}
+
+// ExportTableToPointInTime sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) ExportTableToPointInTime(ctx context.Context, params *dynamodb.ExportTableToPointInTimeInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ExportTableToPointInTimeOutput, error) {
+	output, err := recorder.inner.ExportTableToPointInTime(ctx, params, optFns...)
+	return output, recorder.record("ExportTableToPointInTime", params, output, err)
+}
+
+// GetItem sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) GetItem(ctx context.Context, params *dynamodb.GetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	output, err := recorder.inner.GetItem(ctx, params, optFns...)
+	return output, recorder.record("GetItem", params, output, err)
+}
+
+// ImportTable sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) ImportTable(ctx context.Context, params *dynamodb.ImportTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ImportTableOutput, error) {
+	output, err := recorder.inner.ImportTable(ctx, params, optFns...)
+	return output, recorder.record("ImportTable", params, output, err)
+}
+
+// ListBackups sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) ListBackups(ctx context.Context, params *dynamodb.ListBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListBackupsOutput, error) {
+	output, err := recorder.inner.ListBackups(ctx, params, optFns...)
+	return output, recorder.record("ListBackups", params, output, err)
+}
+
+// ListContributorInsights sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) ListContributorInsights(ctx context.Context, params *dynamodb.ListContributorInsightsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListContributorInsightsOutput, error) {
+	output, err := recorder.inner.ListContributorInsights(ctx, params, optFns...)
+	return output, recorder.record("ListContributorInsights", params, output, err)
+}
+
+// ListExports sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) ListExports(ctx context.Context, params *dynamodb.ListExportsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListExportsOutput, error) {
+	output, err := recorder.inner.ListExports(ctx, params, optFns...)
+	return output, recorder.record("ListExports", params, output, err)
+}
+
+// ListGlobalTables sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) ListGlobalTables(ctx context.Context, params *dynamodb.ListGlobalTablesInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListGlobalTablesOutput, error) {
+	output, err := recorder.inner.ListGlobalTables(ctx, params, optFns...)
+	return output, recorder.record("ListGlobalTables", params, output, err)
+}
+
+// ListImports sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) ListImports(ctx context.Context, params *dynamodb.ListImportsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListImportsOutput, error) {
+	output, err := recorder.inner.ListImports(ctx, params, optFns...)
+	return output, recorder.record("ListImports", params, output, err)
+}
+
+// ListTables sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) ListTables(ctx context.Context, params *dynamodb.ListTablesInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListTablesOutput, error) {
+	output, err := recorder.inner.ListTables(ctx, params, optFns...)
+	return output, recorder.record("ListTables", params, output, err)
+}
+
+// ListTagsOfResource sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) ListTagsOfResource(ctx context.Context, params *dynamodb.ListTagsOfResourceInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListTagsOfResourceOutput, error) {
+	output, err := recorder.inner.ListTagsOfResource(ctx, params, optFns...)
+	return output, recorder.record("ListTagsOfResource", params, output, err)
+}
+
+// PutItem sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) PutItem(ctx context.Context, params *dynamodb.PutItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	output, err := recorder.inner.PutItem(ctx, params, optFns...)
+	return output, recorder.record("PutItem", params, output, err)
+}
+
+// Query sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) Query(ctx context.Context, params *dynamodb.QueryInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	output, err := recorder.inner.Query(ctx, params, optFns...)
+	return output, recorder.record("Query", params, output, err)
+}
+
+// RestoreTableFromBackup sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) RestoreTableFromBackup(ctx context.Context, params *dynamodb.RestoreTableFromBackupInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.RestoreTableFromBackupOutput, error) {
+	output, err := recorder.inner.RestoreTableFromBackup(ctx, params, optFns...)
+	return output, recorder.record("RestoreTableFromBackup", params, output, err)
+}
+
+// RestoreTableToPointInTime sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) RestoreTableToPointInTime(ctx context.Context, params *dynamodb.RestoreTableToPointInTimeInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.RestoreTableToPointInTimeOutput, error) {
+	output, err := recorder.inner.RestoreTableToPointInTime(ctx, params, optFns...)
+	return output, recorder.record("RestoreTableToPointInTime", params, output, err)
+}
+
+// Scan sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) Scan(ctx context.Context, params *dynamodb.ScanInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	output, err := recorder.inner.Scan(ctx, params, optFns...)
+	return output, recorder.record("Scan", params, output, err)
+}
+
+// TagResource sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) TagResource(ctx context.Context, params *dynamodb.TagResourceInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.TagResourceOutput, error) {
+	output, err := recorder.inner.TagResource(ctx, params, optFns...)
+	return output, recorder.record("TagResource", params, output, err)
+}
+
+// TransactGetItems sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) TransactGetItems(ctx context.Context, params *dynamodb.TransactGetItemsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error) {
+	output, err := recorder.inner.TransactGetItems(ctx, params, optFns...)
+	return output, recorder.record("TransactGetItems", params, output, err)
+}
+
+// TransactWriteItems sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	output, err := recorder.inner.TransactWriteItems(ctx, params, optFns...)
+	return output, recorder.record("TransactWriteItems", params, output, err)
+}
+
+// UntagResource sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) UntagResource(ctx context.Context, params *dynamodb.UntagResourceInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UntagResourceOutput, error) {
+	output, err := recorder.inner.UntagResource(ctx, params, optFns...)
+	return output, recorder.record("UntagResource", params, output, err)
+}
+
+// UpdateContinuousBackups sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) UpdateContinuousBackups(ctx context.Context, params *dynamodb.UpdateContinuousBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	output, err := recorder.inner.UpdateContinuousBackups(ctx, params, optFns...)
+	return output, recorder.record("UpdateContinuousBackups", params, output, err)
+}
+
+// UpdateContributorInsights sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) UpdateContributorInsights(ctx context.Context, params *dynamodb.UpdateContributorInsightsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContributorInsightsOutput, error) {
+	output, err := recorder.inner.UpdateContributorInsights(ctx, params, optFns...)
+	return output, recorder.record("UpdateContributorInsights", params, output, err)
+}
+
+// UpdateGlobalTable sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) UpdateGlobalTable(ctx context.Context, params *dynamodb.UpdateGlobalTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateGlobalTableOutput, error) {
+	output, err := recorder.inner.UpdateGlobalTable(ctx, params, optFns...)
+	return output, recorder.record("UpdateGlobalTable", params, output, err)
+}
+
+// UpdateGlobalTableSettings sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) UpdateGlobalTableSettings(ctx context.Context, params *dynamodb.UpdateGlobalTableSettingsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateGlobalTableSettingsOutput, error) {
+	output, err := recorder.inner.UpdateGlobalTableSettings(ctx, params, optFns...)
+	return output, recorder.record("UpdateGlobalTableSettings", params, output, err)
+}
+
+// UpdateItem sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	output, err := recorder.inner.UpdateItem(ctx, params, optFns...)
+	return output, recorder.record("UpdateItem", params, output, err)
+}
+
+// UpdateTable sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) UpdateTable(ctx context.Context, params *dynamodb.UpdateTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+	output, err := recorder.inner.UpdateTable(ctx, params, optFns...)
+	return output, recorder.record("UpdateTable", params, output, err)
+}
+
+// UpdateTableReplicaAutoScaling sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) UpdateTableReplicaAutoScaling(ctx context.Context, params *dynamodb.UpdateTableReplicaAutoScalingInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableReplicaAutoScalingOutput, error) {
+	output, err := recorder.inner.UpdateTableReplicaAutoScaling(ctx, params, optFns...)
+	return output, recorder.record("UpdateTableReplicaAutoScaling", params, output, err)
+}
+
+// UpdateTimeToLive sends the request to the inner client and records it along with its response or error
+func (recorder *Recorder) UpdateTimeToLive(ctx context.Context, params *dynamodb.UpdateTimeToLiveInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	output, err := recorder.inner.UpdateTimeToLive(ctx, params, optFns...)
+	return output, recorder.record("UpdateTimeToLive", params, output, err)
+}
@@ -0,0 +1,143 @@
+package testing
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Record and Replay Tests", func() {
+
+	// Create a recorder, wrapping a fake with our test table, before each test
+	var recorder *Recorder
+	BeforeEach(func() {
+		fake := NewFakeDynamoDB()
+		_, err := fake.CreateTable(context.Background(), createTestTable())
+		Expect(err).ShouldNot(HaveOccurred())
+		recorder = NewRecorder(fake)
+	})
+
+	// Test that, if requests are recorded and saved, then a replayer loaded from the fixture file will
+	// return the same responses and errors, with the same types, for the same requests
+	It("Save, LoadReplayer - Requests recorded - Responses replayed", func() {
+
+		// First, make some requests through the recorder; the last of these should fail
+		putReplayItems(recorder, 2)
+		getInput := &dynamodb.GetItemInput{TableName: aws.String("TEST_TABLE"), Key: createTestKey(1)}
+		getOutput, err := recorder.GetItem(context.Background(), getInput)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		putInput := &dynamodb.PutItemInput{TableName: aws.String("TEST_TABLE"), Item: createTestItem(0),
+			ConditionExpression: aws.String("attribute_not_exists(id)")}
+		_, err = recorder.PutItem(context.Background(), putInput)
+		Expect(err).Should(HaveOccurred())
+
+		// Next, save the recorded interactions to a fixture file; this should not fail
+		path := filepath.Join(GinkgoT().TempDir(), "fixture.json")
+		Expect(recorder.Save(path)).ShouldNot(HaveOccurred())
+		Expect(recorder.Interactions()).Should(HaveLen(4))
+
+		// Now, load a replayer from the fixture file; this should not fail
+		replayer, err := LoadReplayer(path)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, replay the requests and verify that the same responses and errors are returned
+		putReplayItems(replayer, 2)
+		replayed, err := replayer.GetItem(context.Background(), getInput)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(replayed.Item).Should(Equal(getOutput.Item))
+		Expect(replayed.Item).Should(Equal(createTestItem(1)))
+
+		_, err = replayer.PutItem(context.Background(), putInput)
+		verifyError(err, "PutItem", &types.ConditionalCheckFailedException{}, "The conditional request failed")
+		Expect(replayer.Verify()).ShouldNot(HaveOccurred())
+	})
+
+	// Test that, if a transaction was cancelled, then the replayed error will contain the cancellation reasons
+	It("TransactWriteItems - Cancelled - Reasons replayed", func() {
+
+		// First, make a transaction request through the recorder that will be cancelled
+		input := &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{
+			{ConditionCheck: &types.ConditionCheck{TableName: aws.String("TEST_TABLE"), Key: createTestKey(0),
+				ConditionExpression: aws.String("attribute_exists(id)")}}}}
+		_, recErr := recorder.TransactWriteItems(context.Background(), input)
+		Expect(recErr).Should(HaveOccurred())
+
+		// Next, replay the request; this should return the same error
+		replayer := NewReplayer(recorder.Interactions()...)
+		_, err := replayer.TransactWriteItems(context.Background(), input)
+		Expect(err.Error()).Should(Equal(recErr.Error()))
+
+		// Finally, verify that the cancellation reasons were replayed
+		var cancelled *types.TransactionCanceledException
+		Expect(errors.As(err, &cancelled)).Should(BeTrue())
+		Expect(cancelled.CancellationReasons).Should(Equal([]types.CancellationReason{
+			{Code: aws.String("ConditionalCheckFailed"), Message: aws.String("The conditional request failed")}}))
+	})
+
+	// Test that, if a request doesn't match any recorded request, then the replayer will return an error
+	// describing where the request differs from the next recorded request
+	It("GetItem - Request differs - Mismatch described", func() {
+
+		// First, record a request and create a replayer from it
+		_, err := recorder.GetItem(context.Background(), &dynamodb.GetItemInput{
+			TableName: aws.String("TEST_TABLE"), Key: createTestKey(0)})
+		Expect(err).ShouldNot(HaveOccurred())
+		replayer := NewReplayer(recorder.Interactions()...)
+
+		// Next, make a different request to the replayer; this should fail
+		output, err := replayer.GetItem(context.Background(), &dynamodb.GetItemInput{
+			TableName: aws.String("TEST_TABLE"), Key: createTestKey(1)})
+		Expect(output).Should(BeNil())
+
+		// Now, verify the error describing the mismatch
+		var opErr *smithy.OperationError
+		Expect(errors.As(err, &opErr)).Should(BeTrue())
+		Expect(opErr.OperationName).Should(Equal("GetItem"))
+
+		var mismatch *ReplayMismatchError
+		Expect(errors.As(err, &mismatch)).Should(BeTrue())
+		Expect(mismatch.Path).Should(Equal("Key.id.S"))
+		Expect(mismatch.Error()).Should(Equal("no recorded GetItem request matches the request made; it differs " +
+			"from the next recorded GetItem request at Key.id.S; request: " +
+			`{"Key":{"id":{"S":"test_id|1"},"sort_key":{"N":"1"}},"TableName":"TEST_TABLE"}, recorded: ` +
+			`{"Key":{"id":{"S":"test_id|0"},"sort_key":{"N":"0"}},"TableName":"TEST_TABLE"}`))
+
+		// Finally, verify that the recorded request was not replayed
+		Expect(replayer.Verify()).Should(MatchError("1 recorded interactions were not replayed: GetItem (#0)"))
+	})
+
+	// Test that, if all the recorded requests for an operation have been replayed, then the replayer will
+	// return an error stating that none remain
+	It("DeleteItem - No recordings remain - Error", func() {
+		replayer := NewReplayer()
+		_, err := replayer.DeleteItem(context.Background(), &dynamodb.DeleteItemInput{
+			TableName: aws.String("TEST_TABLE"), Key: createTestKey(0)})
+		Expect(err).Should(MatchError("operation error DynamoDB: DeleteItem, no recorded DeleteItem requests remain " +
+			`to be replayed; request: {"Key":{"id":{"S":"test_id|0"},"sort_key":{"N":"0"}},"TableName":"TEST_TABLE"}`))
+	})
+
+	// Test that, if the fixture file doesn't exist, then LoadReplayer will return an error
+	It("LoadReplayer - File missing - Error", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "missing.json")
+		replayer, err := LoadReplayer(path)
+		Expect(replayer).Should(BeNil())
+		Expect(err.Error()).Should(HavePrefix("failed to read recorded interactions from " + path))
+	})
+})
+
+// Helper function that writes a number of test items to the test table through a recorder or replayer
+func putReplayItems(client DynamoDBAPI, count int) {
+	for i := 0; i < count; i++ {
+		_, err := client.PutItem(context.Background(), &dynamodb.PutItemInput{
+			TableName: aws.String("TEST_TABLE"), Item: createTestItem(i)})
+		Expect(err).ShouldNot(HaveOccurred())
+	}
+}
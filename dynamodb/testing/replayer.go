@@ -0,0 +1,750 @@
+package testing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go"
+)
+
+// ReplayMismatchError describes a request made to a Replayer that did not match any of the interactions
+// that remained to be replayed. This error will be wrapped in an operation error, like those returned by
+// the SDK, so that it can be handled in the same way
+type ReplayMismatchError struct {
+	Operation string
+	Request   string
+	Recorded  string
+	Path      string
+}
+
+// Error creates an error message describing the request and how it differs from the next recorded request
+// for the same operation, if there is one
+func (err *ReplayMismatchError) Error() string {
+	if err.Recorded == "" {
+		return fmt.Sprintf("no recorded %s requests remain to be replayed; request: %s", err.Operation, err.Request)
+	}
+
+	return fmt.Sprintf("no recorded %s request matches the request made; it differs from the next recorded "+
+		"%s request at %s; request: %s, recorded: %s", err.Operation, err.Operation, err.Path, err.Request, err.Recorded)
+}
+
+// Replayer serves recorded interactions in place of a DynamoDB client. Each request is matched against the
+// interactions that have not yet been replayed and the response, or error, of the first interaction with
+// the same operation and request is returned. As each interaction is only replayed once, identical requests
+// are served in the order in which they were recorded so the same requests will always receive the same
+// responses. If no interaction matches then a ReplayMismatchError will be returned describing the difference
+// between the request and the next recorded request for the same operation. The replayer is safe for
+// concurrent use
+type Replayer struct {
+	interactions []*Interaction
+	replayed     []bool
+	lock         *sync.Mutex
+}
+
+// NewReplayer creates a new replayer that serves the interactions provided
+func NewReplayer(interactions ...*Interaction) *Replayer {
+	return &Replayer{
+		interactions: interactions,
+		replayed:     make([]bool, len(interactions)),
+		lock:         new(sync.Mutex),
+	}
+}
+
+// LoadReplayer creates a new replayer that serves the interactions saved in the fixture file at the path
+// provided. This function will return an error if the file could not be read or parsed
+func LoadReplayer(path string) (*Replayer, error) {
+
+	// First, read the fixture file
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recorded interactions from %s, error: %v", path, err)
+	}
+
+	// Next, parse the fixture, preserving the precision of numbers so that the requests can be compared
+	// with encoded requests
+	var fixture Fixture
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse recorded interactions from %s, error: %v", path, err)
+	}
+
+	// Finally, create the replayer from the interactions in the fixture
+	return NewReplayer(fixture.Interactions...), nil
+}
+
+// Verify checks that all the recorded interactions have been replayed, returning an error that lists those
+// which have not been if this is not the case
+func (replayer *Replayer) Verify() error {
+	replayer.lock.Lock()
+	defer replayer.lock.Unlock()
+
+	remaining := make([]string, 0)
+	for i, interaction := range replayer.interactions {
+		if !replayer.replayed[i] {
+			remaining = append(remaining, fmt.Sprintf("%s (#%d)", interaction.Operation, i))
+		}
+	}
+
+	if len(remaining) > 0 {
+		return fmt.Errorf("%d recorded interactions were not replayed: %s",
+			len(remaining), strings.Join(remaining, ", "))
+	}
+
+	return nil
+}
+
+// Helper function that finds the interaction matching a request and decodes its response into the output
+// provided, or returns its error
+func (replayer *Replayer) replay(operation string, request interface{}, output interface{}) error {
+
+	// First, encode the request so that it can be compared to the recorded requests
+	encoded, err := encode(request)
+	if err != nil {
+		return &smithy.OperationError{ServiceID: "DynamoDB", OperationName: operation,
+			Err: fmt.Errorf("failed to encode request, error: %v", err)}
+	}
+
+	// Next, find the first interaction, that has not yet been replayed, with the same operation and
+	// request. We'll also keep track of the first interaction with the same operation in case there
+	// is no match so that we can describe the mismatch
+	replayer.lock.Lock()
+	match, candidate := -1, -1
+	for i, interaction := range replayer.interactions {
+		if replayer.replayed[i] || interaction.Operation != operation {
+			continue
+		}
+
+		if candidate < 0 {
+			candidate = i
+		}
+
+		if reflect.DeepEqual(interaction.Request, encoded) {
+			match = i
+			replayer.replayed[i] = true
+			break
+		}
+	}
+
+	replayer.lock.Unlock()
+
+	// Now, if we didn't find a match then return an error describing the difference between the request
+	// and the candidate, if we have one
+	if match < 0 {
+		mismatch := &ReplayMismatchError{Operation: operation, Request: describeTree(encoded)}
+		if candidate >= 0 {
+			recorded := replayer.interactions[candidate].Request
+			mismatch.Recorded = describeTree(recorded)
+			mismatch.Path = firstDifference("", encoded, recorded)
+		}
+
+		return &smithy.OperationError{ServiceID: "DynamoDB", OperationName: operation, Err: mismatch}
+	}
+
+	// Finally, return the recorded error or decode the recorded response into the output
+	interaction := replayer.interactions[match]
+	if interaction.Error != nil {
+		return interaction.Error.toError()
+	} else if err := decode(interaction.Response, output); err != nil {
+		return &smithy.OperationError{ServiceID: "DynamoDB", OperationName: operation,
+			Err: fmt.Errorf("failed to decode recorded response, error: %v", err)}
+	}
+
+	return nil
+}
+
+// Helper function that describes a tree as JSON
+func describeTree(tree interface{}) string {
+	data, _ := json.Marshal(tree)
+	return string(data)
+}
+
+// BatchExecuteStatement replays the response, or error, recorded for the request
+func (replayer *Replayer) BatchExecuteStatement(ctx context.Context, params *dynamodb.BatchExecuteStatementInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchExecuteStatementOutput, error) {
+	output := new(dynamodb.BatchExecuteStatementOutput)
+	if err := replayer.replay("BatchExecuteStatement", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// BatchGetItem replays the response, or error, recorded for the request
+func (replayer *Replayer) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	output := new(dynamodb.BatchGetItemOutput)
+	if err := replayer.replay("BatchGetItem", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// BatchWriteItem replays the response, or error, recorded for the request
+func (replayer *Replayer) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	output := new(dynamodb.BatchWriteItemOutput)
+	if err := replayer.replay("BatchWriteItem", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// CreateBackup replays the response, or error, recorded for the request
+func (replayer *Replayer) CreateBackup(ctx context.Context, params *dynamodb.CreateBackupInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateBackupOutput, error) {
+	output := new(dynamodb.CreateBackupOutput)
+	if err := replayer.replay("CreateBackup", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// CreateGlobalTable replays the response, or error, recorded for the request
+func (replayer *Replayer) CreateGlobalTable(ctx context.Context, params *dynamodb.CreateGlobalTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateGlobalTableOutput, error) {
+	output := new(dynamodb.CreateGlobalTableOutput)
+	if err := replayer.replay("CreateGlobalTable", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// CreateTable replays the response, or error, recorded for the request
+func (replayer *Replayer) CreateTable(ctx context.Context, params *dynamodb.CreateTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	output := new(dynamodb.CreateTableOutput)
+	if err := replayer.replay("CreateTable", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DeleteBackup replays the response, or error, recorded for the request
+func (replayer *Replayer) DeleteBackup(ctx context.Context, params *dynamodb.DeleteBackupInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteBackupOutput, error) {
+	output := new(dynamodb.DeleteBackupOutput)
+	if err := replayer.replay("DeleteBackup", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DeleteItem replays the response, or error, recorded for the request
+func (replayer *Replayer) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	output := new(dynamodb.DeleteItemOutput)
+	if err := replayer.replay("DeleteItem", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DeleteTable replays the response, or error, recorded for the request
+func (replayer *Replayer) DeleteTable(ctx context.Context, params *dynamodb.DeleteTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error) {
+	output := new(dynamodb.DeleteTableOutput)
+	if err := replayer.replay("DeleteTable", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeBackup replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeBackup(ctx context.Context, params *dynamodb.DescribeBackupInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeBackupOutput, error) {
+	output := new(dynamodb.DescribeBackupOutput)
+	if err := replayer.replay("DescribeBackup", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeContinuousBackups replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeContinuousBackups(ctx context.Context, params *dynamodb.DescribeContinuousBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+	output := new(dynamodb.DescribeContinuousBackupsOutput)
+	if err := replayer.replay("DescribeContinuousBackups", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeContributorInsights replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeContributorInsights(ctx context.Context, params *dynamodb.DescribeContributorInsightsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeContributorInsightsOutput, error) {
+	output := new(dynamodb.DescribeContributorInsightsOutput)
+	if err := replayer.replay("DescribeContributorInsights", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeEndpoints replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeEndpoints(ctx context.Context, params *dynamodb.DescribeEndpointsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeEndpointsOutput, error) {
+	output := new(dynamodb.DescribeEndpointsOutput)
+	if err := replayer.replay("DescribeEndpoints", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeExport replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeExport(ctx context.Context, params *dynamodb.DescribeExportInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeExportOutput, error) {
+	output := new(dynamodb.DescribeExportOutput)
+	if err := replayer.replay("DescribeExport", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeGlobalTable replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeGlobalTable(ctx context.Context, params *dynamodb.DescribeGlobalTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeGlobalTableOutput, error) {
+	output := new(dynamodb.DescribeGlobalTableOutput)
+	if err := replayer.replay("DescribeGlobalTable", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeGlobalTableSettings replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeGlobalTableSettings(ctx context.Context, params *dynamodb.DescribeGlobalTableSettingsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeGlobalTableSettingsOutput, error) {
+	output := new(dynamodb.DescribeGlobalTableSettingsOutput)
+	if err := replayer.replay("DescribeGlobalTableSettings", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeImport replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeImport(ctx context.Context, params *dynamodb.DescribeImportInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeImportOutput, error) {
+	output := new(dynamodb.DescribeImportOutput)
+	if err := replayer.replay("DescribeImport", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeKinesisStreamingDestination replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeKinesisStreamingDestination(ctx context.Context, params *dynamodb.DescribeKinesisStreamingDestinationInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeKinesisStreamingDestinationOutput, error) {
+	output := new(dynamodb.DescribeKinesisStreamingDestinationOutput)
+	if err := replayer.replay("DescribeKinesisStreamingDestination", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeLimits replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeLimits(ctx context.Context, params *dynamodb.DescribeLimitsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeLimitsOutput, error) {
+	output := new(dynamodb.DescribeLimitsOutput)
+	if err := replayer.replay("DescribeLimits", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeTable replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	output := new(dynamodb.DescribeTableOutput)
+	if err := replayer.replay("DescribeTable", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeTableReplicaAutoScaling replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeTableReplicaAutoScaling(ctx context.Context, params *dynamodb.DescribeTableReplicaAutoScalingInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableReplicaAutoScalingOutput, error) {
+	output := new(dynamodb.DescribeTableReplicaAutoScalingOutput)
+	if err := replayer.replay("DescribeTableReplicaAutoScaling", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DescribeTimeToLive replays the response, or error, recorded for the request
+func (replayer *Replayer) DescribeTimeToLive(ctx context.Context, params *dynamodb.DescribeTimeToLiveInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	output := new(dynamodb.DescribeTimeToLiveOutput)
+	if err := replayer.replay("DescribeTimeToLive", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DisableKinesisStreamingDestination replays the response, or error, recorded for the request
+func (replayer *Replayer) DisableKinesisStreamingDestination(ctx context.Context, params *dynamodb.DisableKinesisStreamingDestinationInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DisableKinesisStreamingDestinationOutput, error) {
+	output := new(dynamodb.DisableKinesisStreamingDestinationOutput)
+	if err := replayer.replay("DisableKinesisStreamingDestination", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// EnableKinesisStreamingDestination replays the response, or error, recorded for the request
+func (replayer *Replayer) EnableKinesisStreamingDestination(ctx context.Context, params *dynamodb.EnableKinesisStreamingDestinationInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.EnableKinesisStreamingDestinationOutput, error) {
+	output := new(dynamodb.EnableKinesisStreamingDestinationOutput)
+	if err := replayer.replay("EnableKinesisStreamingDestination", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// ExecuteStatement replays the response, or error, recorded for the request
+func (replayer *Replayer) ExecuteStatement(ctx context.Context, params *dynamodb.ExecuteStatementInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteStatementOutput, error) {
+	output := new(dynamodb.ExecuteStatementOutput)
+	if err := replayer.replay("ExecuteStatement", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// ExecuteTransaction replays the response, or error, recorded for the request
+func (replayer *Replayer) ExecuteTransaction(ctx context.Context, params *dynamodb.ExecuteTransactionInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteTransactionOutput, error) {
+	output := new(dynamodb.ExecuteTransactionOutput)
+	if err := replayer.replay("ExecuteTransaction", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// ExportTableToPointInTime replays the response, or error, recorded for the request
+func (replayer *Replayer) ExportTableToPointInTime(ctx context.Context, params *dynamodb.ExportTableToPointInTimeInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ExportTableToPointInTimeOutput, error) {
+	output := new(dynamodb.ExportTableToPointInTimeOutput)
+	if err := replayer.replay("ExportTableToPointInTime", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// GetItem replays the response, or error, recorded for the request
+func (replayer *Replayer) GetItem(ctx context.Context, params *dynamodb.GetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	output := new(dynamodb.GetItemOutput)
+	if err := replayer.replay("GetItem", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// ImportTable replays the response, or error, recorded for the request
+func (replayer *Replayer) ImportTable(ctx context.Context, params *dynamodb.ImportTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ImportTableOutput, error) {
+	output := new(dynamodb.ImportTableOutput)
+	if err := replayer.replay("ImportTable", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// ListBackups replays the response, or error, recorded for the request
+func (replayer *Replayer) ListBackups(ctx context.Context, params *dynamodb.ListBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListBackupsOutput, error) {
+	output := new(dynamodb.ListBackupsOutput)
+	if err := replayer.replay("ListBackups", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// ListContributorInsights replays the response, or error, recorded for the request
+func (replayer *Replayer) ListContributorInsights(ctx context.Context, params *dynamodb.ListContributorInsightsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListContributorInsightsOutput, error) {
+	output := new(dynamodb.ListContributorInsightsOutput)
+	if err := replayer.replay("ListContributorInsights", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// ListExports replays the response, or error, recorded for the request
+func (replayer *Replayer) ListExports(ctx context.Context, params *dynamodb.ListExportsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListExportsOutput, error) {
+	output := new(dynamodb.ListExportsOutput)
+	if err := replayer.replay("ListExports", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// ListGlobalTables replays the response, or error, recorded for the request
+func (replayer *Replayer) ListGlobalTables(ctx context.Context, params *dynamodb.ListGlobalTablesInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListGlobalTablesOutput, error) {
+	output := new(dynamodb.ListGlobalTablesOutput)
+	if err := replayer.replay("ListGlobalTables", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// ListImports replays the response, or error, recorded for the request
+func (replayer *Replayer) ListImports(ctx context.Context, params *dynamodb.ListImportsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListImportsOutput, error) {
+	output := new(dynamodb.ListImportsOutput)
+	if err := replayer.replay("ListImports", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// ListTables replays the response, or error, recorded for the request
+func (replayer *Replayer) ListTables(ctx context.Context, params *dynamodb.ListTablesInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListTablesOutput, error) {
+	output := new(dynamodb.ListTablesOutput)
+	if err := replayer.replay("ListTables", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// ListTagsOfResource replays the response, or error, recorded for the request
+func (replayer *Replayer) ListTagsOfResource(ctx context.Context, params *dynamodb.ListTagsOfResourceInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListTagsOfResourceOutput, error) {
+	output := new(dynamodb.ListTagsOfResourceOutput)
+	if err := replayer.replay("ListTagsOfResource", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// PutItem replays the response, or error, recorded for the request
+func (replayer *Replayer) PutItem(ctx context.Context, params *dynamodb.PutItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	output := new(dynamodb.PutItemOutput)
+	if err := replayer.replay("PutItem", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// Query replays the response, or error, recorded for the request
+func (replayer *Replayer) Query(ctx context.Context, params *dynamodb.QueryInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	output := new(dynamodb.QueryOutput)
+	if err := replayer.replay("Query", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// RestoreTableFromBackup replays the response, or error, recorded for the request
+func (replayer *Replayer) RestoreTableFromBackup(ctx context.Context, params *dynamodb.RestoreTableFromBackupInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.RestoreTableFromBackupOutput, error) {
+	output := new(dynamodb.RestoreTableFromBackupOutput)
+	if err := replayer.replay("RestoreTableFromBackup", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// RestoreTableToPointInTime replays the response, or error, recorded for the request
+func (replayer *Replayer) RestoreTableToPointInTime(ctx context.Context, params *dynamodb.RestoreTableToPointInTimeInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.RestoreTableToPointInTimeOutput, error) {
+	output := new(dynamodb.RestoreTableToPointInTimeOutput)
+	if err := replayer.replay("RestoreTableToPointInTime", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// Scan replays the response, or error, recorded for the request
+func (replayer *Replayer) Scan(ctx context.Context, params *dynamodb.ScanInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	output := new(dynamodb.ScanOutput)
+	if err := replayer.replay("Scan", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// TagResource replays the response, or error, recorded for the request
+func (replayer *Replayer) TagResource(ctx context.Context, params *dynamodb.TagResourceInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.TagResourceOutput, error) {
+	output := new(dynamodb.TagResourceOutput)
+	if err := replayer.replay("TagResource", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// TransactGetItems replays the response, or error, recorded for the request
+func (replayer *Replayer) TransactGetItems(ctx context.Context, params *dynamodb.TransactGetItemsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error) {
+	output := new(dynamodb.TransactGetItemsOutput)
+	if err := replayer.replay("TransactGetItems", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// TransactWriteItems replays the response, or error, recorded for the request
+func (replayer *Replayer) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	output := new(dynamodb.TransactWriteItemsOutput)
+	if err := replayer.replay("TransactWriteItems", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// UntagResource replays the response, or error, recorded for the request
+func (replayer *Replayer) UntagResource(ctx context.Context, params *dynamodb.UntagResourceInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UntagResourceOutput, error) {
+	output := new(dynamodb.UntagResourceOutput)
+	if err := replayer.replay("UntagResource", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// UpdateContinuousBackups replays the response, or error, recorded for the request
+func (replayer *Replayer) UpdateContinuousBackups(ctx context.Context, params *dynamodb.UpdateContinuousBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	output := new(dynamodb.UpdateContinuousBackupsOutput)
+	if err := replayer.replay("UpdateContinuousBackups", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// UpdateContributorInsights replays the response, or error, recorded for the request
+func (replayer *Replayer) UpdateContributorInsights(ctx context.Context, params *dynamodb.UpdateContributorInsightsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContributorInsightsOutput, error) {
+	output := new(dynamodb.UpdateContributorInsightsOutput)
+	if err := replayer.replay("UpdateContributorInsights", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// UpdateGlobalTable replays the response, or error, recorded for the request
+func (replayer *Replayer) UpdateGlobalTable(ctx context.Context, params *dynamodb.UpdateGlobalTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateGlobalTableOutput, error) {
+	output := new(dynamodb.UpdateGlobalTableOutput)
+	if err := replayer.replay("UpdateGlobalTable", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// UpdateGlobalTableSettings replays the response, or error, recorded for the request
+func (replayer *Replayer) UpdateGlobalTableSettings(ctx context.Context, params *dynamodb.UpdateGlobalTableSettingsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateGlobalTableSettingsOutput, error) {
+	output := new(dynamodb.UpdateGlobalTableSettingsOutput)
+	if err := replayer.replay("UpdateGlobalTableSettings", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// UpdateItem replays the response, or error, recorded for the request
+func (replayer *Replayer) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	output := new(dynamodb.UpdateItemOutput)
+	if err := replayer.replay("UpdateItem", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// UpdateTable replays the response, or error, recorded for the request
+func (replayer *Replayer) UpdateTable(ctx context.Context, params *dynamodb.UpdateTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+	output := new(dynamodb.UpdateTableOutput)
+	if err := replayer.replay("UpdateTable", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// UpdateTableReplicaAutoScaling replays the response, or error, recorded for the request
+func (replayer *Replayer) UpdateTableReplicaAutoScaling(ctx context.Context, params *dynamodb.UpdateTableReplicaAutoScalingInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableReplicaAutoScalingOutput, error) {
+	output := new(dynamodb.UpdateTableReplicaAutoScalingOutput)
+	if err := replayer.replay("UpdateTableReplicaAutoScaling", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// UpdateTimeToLive replays the response, or error, recorded for the request
+func (replayer *Replayer) UpdateTimeToLive(ctx context.Context, params *dynamodb.UpdateTimeToLiveInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	output := new(dynamodb.UpdateTimeToLiveOutput)
+	if err := replayer.replay("UpdateTimeToLive", params, output); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
@@ -0,0 +1,358 @@
+package testing
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Helper type that describes a query or scan against a table or index
+type searchRequest struct {
+	table      *fakeTable
+	index      *fakeIndex
+	filter     condition
+	projection []documentPath
+	limit      int32
+	start      map[string]types.AttributeValue
+	selection  types.Select
+}
+
+// Helper type that describes the results of a query or scan
+type searchResult struct {
+	items   []map[string]types.AttributeValue
+	count   int32
+	scanned int32
+	lastKey map[string]types.AttributeValue
+}
+
+// Query gets the items from a table, or index, with the hash key and range key conditions described
+// by the key condition expression, in the order of their range keys
+func (fake *FakeDynamoDB) Query(ctx context.Context, params *dynamodb.QueryInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	// First, create the search from the request; if this fails then return an error
+	exprCtx := newExpressionContext(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	request, err := fake.createSearch(exprCtx, params.TableName, params.IndexName, params.FilterExpression,
+		params.ProjectionExpression, params.Limit, params.ExclusiveStartKey, params.Select, params.ConsistentRead)
+	if err != nil {
+		return nil, operationError("Query", err)
+	}
+
+	// Next, parse the key condition and ensure that it refers to the keys of the table, or index
+	if params.KeyConditionExpression == nil {
+		return nil, operationError("Query", validationError("Either the KeyConditions or "+
+			"KeyConditionExpression parameter must be specified in the request."))
+	}
+
+	keyCond, err := exprCtx.parseCondition("KeyConditionExpression", params.KeyConditionExpression)
+	if err != nil {
+		return nil, operationError("Query", err)
+	}
+
+	hash, rangeKey := request.keys()
+	if err := validateKeyCondition(keyCond, hash, rangeKey); err != nil {
+		return nil, operationError("Query", err)
+	} else if err := exprCtx.checkUnused(); err != nil {
+		return nil, operationError("Query", err)
+	}
+
+	// Now, get all the items that match the key condition
+	items := make([]map[string]types.AttributeValue, 0)
+	for _, item := range request.candidates() {
+		if keyCond.evaluate(item) {
+			items = append(items, item)
+		}
+	}
+
+	// Finally, sort the items by their range keys and search them
+	forward := params.ScanIndexForward == nil || *params.ScanIndexForward
+	result := request.search(items, func(first map[string]types.AttributeValue, second map[string]types.AttributeValue) int {
+		if rangeKey != "" {
+			if result, _ := compareValues(first[rangeKey], second[rangeKey]); result != 0 {
+				return result
+			}
+		}
+
+		return strings.Compare(request.table.itemKey(first), request.table.itemKey(second))
+	}, forward)
+
+	return &dynamodb.QueryOutput{
+		Items:            result.items,
+		Count:            result.count,
+		ScannedCount:     result.scanned,
+		LastEvaluatedKey: result.lastKey,
+	}, nil
+}
+
+// Scan gets all the items from a table, or index, that match the filter expression. If the scan has
+// more than one segment then only the items that belong to the segment requested will be returned
+func (fake *FakeDynamoDB) Scan(ctx context.Context, params *dynamodb.ScanInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+
+	// First, create the search from the request; if this fails then return an error
+	exprCtx := newExpressionContext(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	request, err := fake.createSearch(exprCtx, params.TableName, params.IndexName, params.FilterExpression,
+		params.ProjectionExpression, params.Limit, params.ExclusiveStartKey, params.Select, params.ConsistentRead)
+	if err != nil {
+		return nil, operationError("Scan", err)
+	} else if err := exprCtx.checkUnused(); err != nil {
+		return nil, operationError("Scan", err)
+	}
+
+	// Next, ensure that the segment requested is valid
+	total := aws.ToInt32(params.TotalSegments)
+	segment := aws.ToInt32(params.Segment)
+	if (params.Segment == nil) != (params.TotalSegments == nil) {
+		return nil, operationError("Scan", validationError("The TotalSegments parameter is required "+
+			"but was not present in the request when Segment parameter is present"))
+	} else if params.TotalSegments != nil && (total < 1 || segment < 0 || segment >= total) {
+		return nil, operationError("Scan", validationError("The Segment parameter is zero-based and must be "+
+			"less than parameter TotalSegments: Segment: %d is not less than TotalSegments: %d", segment, total))
+	}
+
+	// Now, get all the items that belong to the segment
+	items := make([]map[string]types.AttributeValue, 0)
+	for _, item := range request.candidates() {
+		if total <= 1 || segmentOf(request.table.itemKey(item), total) == segment {
+			items = append(items, item)
+		}
+	}
+
+	// Finally, sort the items by their keys and search them
+	result := request.search(items, func(first map[string]types.AttributeValue, second map[string]types.AttributeValue) int {
+		return strings.Compare(request.table.itemKey(first), request.table.itemKey(second))
+	}, true)
+
+	return &dynamodb.ScanOutput{
+		Items:            result.items,
+		Count:            result.count,
+		ScannedCount:     result.scanned,
+		LastEvaluatedKey: result.lastKey,
+	}, nil
+}
+
+// Helper function that creates a search from the parameters shared by queries and scans
+func (fake *FakeDynamoDB) createSearch(ctx *expressionContext, tableName *string, indexName *string,
+	filter *string, projection *string, limit *int32, start map[string]types.AttributeValue,
+	selection types.Select, consistent *bool) (*searchRequest, error) {
+
+	// First, get the table and index that should be searched
+	table, err := fake.getTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &searchRequest{table: table, start: start, selection: selection, limit: aws.ToInt32(limit)}
+	if indexName != nil {
+		index, ok := table.indexes[*indexName]
+		if !ok {
+			return nil, validationError("The table does not have the specified index: %s", *indexName)
+		} else if index.global && aws.ToBool(consistent) {
+			return nil, validationError("Consistent reads are not supported on global secondary indexes")
+		}
+
+		request.index = index
+	}
+
+	// Next, ensure that the limit is valid
+	if limit != nil && *limit < 1 {
+		return nil, validationError("1 validation error detected: Value '%d' at 'limit' failed to satisfy "+
+			"constraint: Member must have value greater than or equal to 1", *limit)
+	}
+
+	// Finally, parse the filter and projection expressions
+	if request.filter, err = ctx.parseCondition("FilterExpression", filter); err != nil {
+		return nil, err
+	} else if request.projection, err = ctx.parseProjection(projection); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// Helper function that gets the hash and range keys of the table or index being searched
+func (request *searchRequest) keys() (string, string) {
+	if request.index != nil {
+		return request.index.hashKey, request.index.rangeKey
+	}
+
+	return request.table.hashKey, request.table.rangeKey
+}
+
+// Helper function that gets all the items in the table or, if an index is being searched, all the
+// items that have the keys of the index
+func (request *searchRequest) candidates() []map[string]types.AttributeValue {
+	items := make([]map[string]types.AttributeValue, 0, len(request.table.items))
+	for _, item := range request.table.items {
+		if request.index == nil || request.index.contains(item) {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Helper function that sorts the items, skips those before the start key and evaluates the rest
+// against the filter until the limit is reached
+func (request *searchRequest) search(items []map[string]types.AttributeValue,
+	compare func(map[string]types.AttributeValue, map[string]types.AttributeValue) int, forward bool) *searchResult {
+
+	// First, sort the items in the direction requested
+	direction := 1
+	if !forward {
+		direction = -1
+	}
+
+	sort.Slice(items, func(i, j int) bool { return direction*compare(items[i], items[j]) < 0 })
+
+	// Next, if we have a start key then skip all the items up to and including it
+	if request.start != nil {
+		position := sort.Search(len(items), func(i int) bool {
+			return direction*compare(items[i], request.start) > 0
+		})
+
+		items = items[position:]
+	}
+
+	// Now, iterate over the items, evaluating each against the filter, until we reach the limit
+	result := &searchResult{items: make([]map[string]types.AttributeValue, 0)}
+	for i, item := range items {
+		result.scanned++
+		if request.filter == nil || request.filter.evaluate(item) {
+			result.count++
+			if request.selection != types.SelectCount {
+				result.items = append(result.items, request.project(item))
+			}
+		}
+
+		if request.limit > 0 && result.scanned == request.limit && i < len(items)-1 {
+			result.lastKey = request.table.extractKey(item, request.index)
+			break
+		}
+	}
+
+	// Finally, if we only wanted the count then remove the items
+	if request.selection == types.SelectCount {
+		result.items = nil
+	}
+
+	return result
+}
+
+// Helper function that projects an item with the projection of the index being searched, if there is
+// one, and then with the projection expression, if there is one
+func (request *searchRequest) project(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+
+	// First, if we're searching an index that doesn't project all attributes then remove the attributes
+	// that aren't projected into the index
+	if request.index != nil && request.index.projection.ProjectionType != types.ProjectionTypeAll {
+		projected := request.table.extractKey(item, request.index)
+		if request.index.projection.ProjectionType == types.ProjectionTypeInclude {
+			for _, name := range request.index.projection.NonKeyAttributes {
+				if value, ok := item[name]; ok {
+					projected[name] = copyValue(value)
+				}
+			}
+		}
+
+		item = projected
+	}
+
+	// Next, apply the projection expression to the item, if we have one
+	if request.projection != nil {
+		return projectItem(item, request.projection)
+	}
+
+	return copyItem(item)
+}
+
+// Helper function that ensures that a key condition contains an equality condition on the hash key
+// and, optionally, a single condition on the range key
+func validateKeyCondition(cond condition, hash string, rangeKey string) error {
+
+	// First, split the condition into the conditions joined by AND
+	conditions := make([]condition, 0)
+	var flatten func(condition)
+	flatten = func(current condition) {
+		if logical, ok := current.(*logicalCondition); ok && logical.and {
+			flatten(logical.left)
+			flatten(logical.right)
+		} else {
+			conditions = append(conditions, current)
+		}
+	}
+
+	flatten(cond)
+	if len(conditions) > 2 {
+		return validationError("Query key condition not supported")
+	}
+
+	// Next, iterate over the conditions and determine which key each refers to
+	hasHash := false
+	for _, current := range conditions {
+		var name string
+		var isEquality bool
+		switch casted := current.(type) {
+		case *comparison:
+			name = keyConditionName(casted.left, casted.right)
+			isEquality = casted.operator == "="
+			if casted.operator == "<>" {
+				return validationError("Unsupported operator in KeyConditionExpression: <>")
+			}
+		case *betweenCondition:
+			name = keyConditionName(casted.value, casted.low, casted.high)
+		case *functionCondition:
+			if casted.name != "begins_with" {
+				return validationError("Invalid operator used in KeyConditionExpression: %s", casted.name)
+			} else if len(casted.path) == 1 {
+				name = casted.path[0].name
+			}
+		default:
+			return validationError("Query key condition not supported")
+		}
+
+		// Finally, ensure that the condition refers to one of the keys in a way that is permitted
+		switch {
+		case name == hash && isEquality && !hasHash:
+			hasHash = true
+		case name == rangeKey && rangeKey != "" && len(conditions) == 2:
+		default:
+			return validationError("Query key condition not supported")
+		}
+	}
+
+	if !hasHash {
+		return validationError("Query condition missed key schema element: %s", hash)
+	}
+
+	return nil
+}
+
+// Helper function that gets the name of the key referenced by a key condition, which should have a
+// single path operand referring to a top-level attribute and all other operands should be values
+func keyConditionName(operands ...operand) string {
+	name := ""
+	for _, op := range operands {
+		switch casted := op.(type) {
+		case *pathOperand:
+			if name != "" || len(casted.path) != 1 {
+				return ""
+			}
+
+			name = casted.path[0].name
+		case *valueOperand:
+		default:
+			return ""
+		}
+	}
+
+	return name
+}
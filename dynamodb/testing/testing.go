@@ -75,6 +75,43 @@ func EnsureTableExists(ctx context.Context, cfg aws.Config, table *dynamodb.Crea
 	return nil
 }
 
+// TableSchema describes a table that can be created for testing purposes, such as the TableDefinition
+// derived from the schema tags on a struct type by the dynamodb package
+type TableSchema interface {
+	CreateTableInput() *dynamodb.CreateTableInput
+	TimeToLive() *dynamodb.UpdateTimeToLiveInput
+}
+
+// EnsureSchemaExists ensures that the table described by the schema exists for testing purposes and, if
+// the schema has a TTL attribute, that TTL is enabled on the table
+func EnsureSchemaExists(ctx context.Context, cfg aws.Config, schema TableSchema) error {
+
+	// First, ensure that the table exists; if this fails then return an error
+	if err := EnsureTableExists(ctx, cfg, schema.CreateTableInput()); err != nil {
+		return err
+	}
+
+	// Next, if the schema doesn't have a TTL attribute then we're done
+	ttl := schema.TimeToLive()
+	if ttl == nil {
+		return nil
+	}
+
+	// Now, check whether TTL is already enabled on the table; if it is then there's nothing to do
+	client := dynamodb.NewFromConfig(cfg)
+	output, err := client.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: ttl.TableName})
+	if err != nil {
+		return err
+	} else if desc := output.TimeToLiveDescription; desc != nil &&
+		desc.TimeToLiveStatus != types.TimeToLiveStatusDisabled {
+		return nil
+	}
+
+	// Finally, enable TTL on the table; return any error that occurs
+	_, err = client.UpdateTimeToLive(ctx, ttl)
+	return err
+}
+
 // EmptyTable ensures that the table is in pristine condition for testing
 func EmptyTable(ctx context.Context, cfg aws.Config, table *dynamodb.CreateTableInput) error {
 
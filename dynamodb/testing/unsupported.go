@@ -0,0 +1,234 @@
+package testing
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// BatchExecuteStatement is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) BatchExecuteStatement(ctx context.Context, params *dynamodb.BatchExecuteStatementInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.BatchExecuteStatementOutput, error) {
+	return nil, unsupported("BatchExecuteStatement")
+}
+
+// CreateBackup is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) CreateBackup(ctx context.Context, params *dynamodb.CreateBackupInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateBackupOutput, error) {
+	return nil, unsupported("CreateBackup")
+}
+
+// CreateGlobalTable is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) CreateGlobalTable(ctx context.Context, params *dynamodb.CreateGlobalTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.CreateGlobalTableOutput, error) {
+	return nil, unsupported("CreateGlobalTable")
+}
+
+// DeleteBackup is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DeleteBackup(ctx context.Context, params *dynamodb.DeleteBackupInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteBackupOutput, error) {
+	return nil, unsupported("DeleteBackup")
+}
+
+// DescribeBackup is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DescribeBackup(ctx context.Context, params *dynamodb.DescribeBackupInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeBackupOutput, error) {
+	return nil, unsupported("DescribeBackup")
+}
+
+// DescribeContinuousBackups is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DescribeContinuousBackups(ctx context.Context, params *dynamodb.DescribeContinuousBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+	return nil, unsupported("DescribeContinuousBackups")
+}
+
+// DescribeContributorInsights is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DescribeContributorInsights(ctx context.Context, params *dynamodb.DescribeContributorInsightsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeContributorInsightsOutput, error) {
+	return nil, unsupported("DescribeContributorInsights")
+}
+
+// DescribeEndpoints is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DescribeEndpoints(ctx context.Context, params *dynamodb.DescribeEndpointsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeEndpointsOutput, error) {
+	return nil, unsupported("DescribeEndpoints")
+}
+
+// DescribeExport is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DescribeExport(ctx context.Context, params *dynamodb.DescribeExportInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeExportOutput, error) {
+	return nil, unsupported("DescribeExport")
+}
+
+// DescribeGlobalTable is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DescribeGlobalTable(ctx context.Context, params *dynamodb.DescribeGlobalTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeGlobalTableOutput, error) {
+	return nil, unsupported("DescribeGlobalTable")
+}
+
+// DescribeGlobalTableSettings is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DescribeGlobalTableSettings(ctx context.Context, params *dynamodb.DescribeGlobalTableSettingsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeGlobalTableSettingsOutput, error) {
+	return nil, unsupported("DescribeGlobalTableSettings")
+}
+
+// DescribeImport is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DescribeImport(ctx context.Context, params *dynamodb.DescribeImportInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeImportOutput, error) {
+	return nil, unsupported("DescribeImport")
+}
+
+// DescribeKinesisStreamingDestination is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DescribeKinesisStreamingDestination(ctx context.Context, params *dynamodb.DescribeKinesisStreamingDestinationInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeKinesisStreamingDestinationOutput, error) {
+	return nil, unsupported("DescribeKinesisStreamingDestination")
+}
+
+// DescribeLimits is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DescribeLimits(ctx context.Context, params *dynamodb.DescribeLimitsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeLimitsOutput, error) {
+	return nil, unsupported("DescribeLimits")
+}
+
+// DescribeTableReplicaAutoScaling is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DescribeTableReplicaAutoScaling(ctx context.Context, params *dynamodb.DescribeTableReplicaAutoScalingInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableReplicaAutoScalingOutput, error) {
+	return nil, unsupported("DescribeTableReplicaAutoScaling")
+}
+
+// DisableKinesisStreamingDestination is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) DisableKinesisStreamingDestination(ctx context.Context, params *dynamodb.DisableKinesisStreamingDestinationInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DisableKinesisStreamingDestinationOutput, error) {
+	return nil, unsupported("DisableKinesisStreamingDestination")
+}
+
+// EnableKinesisStreamingDestination is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) EnableKinesisStreamingDestination(ctx context.Context, params *dynamodb.EnableKinesisStreamingDestinationInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.EnableKinesisStreamingDestinationOutput, error) {
+	return nil, unsupported("EnableKinesisStreamingDestination")
+}
+
+// ExecuteStatement is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) ExecuteStatement(ctx context.Context, params *dynamodb.ExecuteStatementInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteStatementOutput, error) {
+	return nil, unsupported("ExecuteStatement")
+}
+
+// ExecuteTransaction is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) ExecuteTransaction(ctx context.Context, params *dynamodb.ExecuteTransactionInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteTransactionOutput, error) {
+	return nil, unsupported("ExecuteTransaction")
+}
+
+// ExportTableToPointInTime is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) ExportTableToPointInTime(ctx context.Context, params *dynamodb.ExportTableToPointInTimeInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ExportTableToPointInTimeOutput, error) {
+	return nil, unsupported("ExportTableToPointInTime")
+}
+
+// ImportTable is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) ImportTable(ctx context.Context, params *dynamodb.ImportTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ImportTableOutput, error) {
+	return nil, unsupported("ImportTable")
+}
+
+// ListBackups is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) ListBackups(ctx context.Context, params *dynamodb.ListBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListBackupsOutput, error) {
+	return nil, unsupported("ListBackups")
+}
+
+// ListContributorInsights is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) ListContributorInsights(ctx context.Context, params *dynamodb.ListContributorInsightsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListContributorInsightsOutput, error) {
+	return nil, unsupported("ListContributorInsights")
+}
+
+// ListExports is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) ListExports(ctx context.Context, params *dynamodb.ListExportsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListExportsOutput, error) {
+	return nil, unsupported("ListExports")
+}
+
+// ListGlobalTables is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) ListGlobalTables(ctx context.Context, params *dynamodb.ListGlobalTablesInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListGlobalTablesOutput, error) {
+	return nil, unsupported("ListGlobalTables")
+}
+
+// ListImports is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) ListImports(ctx context.Context, params *dynamodb.ListImportsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListImportsOutput, error) {
+	return nil, unsupported("ListImports")
+}
+
+// ListTagsOfResource is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) ListTagsOfResource(ctx context.Context, params *dynamodb.ListTagsOfResourceInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ListTagsOfResourceOutput, error) {
+	return nil, unsupported("ListTagsOfResource")
+}
+
+// RestoreTableFromBackup is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) RestoreTableFromBackup(ctx context.Context, params *dynamodb.RestoreTableFromBackupInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.RestoreTableFromBackupOutput, error) {
+	return nil, unsupported("RestoreTableFromBackup")
+}
+
+// RestoreTableToPointInTime is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) RestoreTableToPointInTime(ctx context.Context, params *dynamodb.RestoreTableToPointInTimeInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.RestoreTableToPointInTimeOutput, error) {
+	return nil, unsupported("RestoreTableToPointInTime")
+}
+
+// TagResource is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) TagResource(ctx context.Context, params *dynamodb.TagResourceInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.TagResourceOutput, error) {
+	return nil, unsupported("TagResource")
+}
+
+// UntagResource is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) UntagResource(ctx context.Context, params *dynamodb.UntagResourceInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UntagResourceOutput, error) {
+	return nil, unsupported("UntagResource")
+}
+
+// UpdateContinuousBackups is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) UpdateContinuousBackups(ctx context.Context, params *dynamodb.UpdateContinuousBackupsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	return nil, unsupported("UpdateContinuousBackups")
+}
+
+// UpdateContributorInsights is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) UpdateContributorInsights(ctx context.Context, params *dynamodb.UpdateContributorInsightsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateContributorInsightsOutput, error) {
+	return nil, unsupported("UpdateContributorInsights")
+}
+
+// UpdateGlobalTable is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) UpdateGlobalTable(ctx context.Context, params *dynamodb.UpdateGlobalTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateGlobalTableOutput, error) {
+	return nil, unsupported("UpdateGlobalTable")
+}
+
+// UpdateGlobalTableSettings is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) UpdateGlobalTableSettings(ctx context.Context, params *dynamodb.UpdateGlobalTableSettingsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateGlobalTableSettingsOutput, error) {
+	return nil, unsupported("UpdateGlobalTableSettings")
+}
+
+// UpdateTable is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) UpdateTable(ctx context.Context, params *dynamodb.UpdateTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+	return nil, unsupported("UpdateTable")
+}
+
+// UpdateTableReplicaAutoScaling is not supported by the fake and will always return an error
+func (fake *FakeDynamoDB) UpdateTableReplicaAutoScaling(ctx context.Context, params *dynamodb.UpdateTableReplicaAutoScalingInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableReplicaAutoScalingOutput, error) {
+	return nil, unsupported("UpdateTableReplicaAutoScaling")
+}
+
+// Helper function that creates an error describing an operation that the fake does not support
+func unsupported(operation string) error {
+	return operationError(operation, validationError("%s is not supported by the in-memory DynamoDB", operation))
+}
@@ -0,0 +1,402 @@
+package testing
+
+import (
+	"bytes"
+	"encoding/base64"
+	"hash/fnv"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Helper function that creates a deep copy of an item so that items stored by the fake cannot be
+// modified by the caller, and vice versa
+func copyItem(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	if item == nil {
+		return nil
+	}
+
+	copied := make(map[string]types.AttributeValue, len(item))
+	for name, value := range item {
+		copied[name] = copyValue(value)
+	}
+
+	return copied
+}
+
+// Helper function that creates a deep copy of an attribute value
+func copyValue(value types.AttributeValue) types.AttributeValue {
+	switch casted := value.(type) {
+	case *types.AttributeValueMemberS:
+		return &types.AttributeValueMemberS{Value: casted.Value}
+	case *types.AttributeValueMemberN:
+		return &types.AttributeValueMemberN{Value: casted.Value}
+	case *types.AttributeValueMemberB:
+		return &types.AttributeValueMemberB{Value: append([]byte{}, casted.Value...)}
+	case *types.AttributeValueMemberBOOL:
+		return &types.AttributeValueMemberBOOL{Value: casted.Value}
+	case *types.AttributeValueMemberNULL:
+		return &types.AttributeValueMemberNULL{Value: casted.Value}
+	case *types.AttributeValueMemberSS:
+		return &types.AttributeValueMemberSS{Value: append([]string{}, casted.Value...)}
+	case *types.AttributeValueMemberNS:
+		return &types.AttributeValueMemberNS{Value: append([]string{}, casted.Value...)}
+	case *types.AttributeValueMemberBS:
+		values := make([][]byte, len(casted.Value))
+		for i, value := range casted.Value {
+			values[i] = append([]byte{}, value...)
+		}
+
+		return &types.AttributeValueMemberBS{Value: values}
+	case *types.AttributeValueMemberL:
+		values := make([]types.AttributeValue, len(casted.Value))
+		for i, value := range casted.Value {
+			values[i] = copyValue(value)
+		}
+
+		return &types.AttributeValueMemberL{Value: values}
+	case *types.AttributeValueMemberM:
+		return &types.AttributeValueMemberM{Value: copyItem(casted.Value)}
+	default:
+		return value
+	}
+}
+
+// Helper function that gets the DynamoDB type descriptor of an attribute value
+func typeName(value types.AttributeValue) string {
+	switch value.(type) {
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	default:
+		return ""
+	}
+}
+
+// Helper function that parses the string representation of a DynamoDB number
+func parseNumber(value string) (*big.Float, bool) {
+	return new(big.Float).SetPrec(256).SetString(strings.TrimSpace(value))
+}
+
+// Helper function that formats a number so that it can be stored as a DynamoDB number
+func formatNumber(value *big.Float) string {
+	return value.Text('f', -1)
+}
+
+// Helper function that compares two scalar values of the same type. The second return value will be
+// false if the values cannot be compared because they aren't scalars or have different types
+func compareValues(first types.AttributeValue, second types.AttributeValue) (int, bool) {
+	switch casted := first.(type) {
+	case *types.AttributeValueMemberS:
+		if other, ok := second.(*types.AttributeValueMemberS); ok {
+			return strings.Compare(casted.Value, other.Value), true
+		}
+	case *types.AttributeValueMemberN:
+		if other, ok := second.(*types.AttributeValueMemberN); ok {
+			left, lok := parseNumber(casted.Value)
+			right, rok := parseNumber(other.Value)
+			if lok && rok {
+				return left.Cmp(right), true
+			}
+		}
+	case *types.AttributeValueMemberB:
+		if other, ok := second.(*types.AttributeValueMemberB); ok {
+			return bytes.Compare(casted.Value, other.Value), true
+		}
+	}
+
+	return 0, false
+}
+
+// Helper function that determines whether two attribute values are equal. Numbers are compared by
+// value and sets are compared without regard to the order of their elements
+func valuesEqual(first types.AttributeValue, second types.AttributeValue) bool {
+	if first == nil || second == nil {
+		return first == nil && second == nil
+	}
+
+	switch casted := first.(type) {
+	case *types.AttributeValueMemberS, *types.AttributeValueMemberN, *types.AttributeValueMemberB:
+		result, ok := compareValues(first, second)
+		return ok && result == 0
+	case *types.AttributeValueMemberBOOL:
+		other, ok := second.(*types.AttributeValueMemberBOOL)
+		return ok && casted.Value == other.Value
+	case *types.AttributeValueMemberNULL:
+		_, ok := second.(*types.AttributeValueMemberNULL)
+		return ok
+	case *types.AttributeValueMemberSS, *types.AttributeValueMemberNS, *types.AttributeValueMemberBS:
+		if typeName(first) != typeName(second) {
+			return false
+		}
+
+		left, right := setElements(first), setElements(second)
+		if len(left) != len(right) {
+			return false
+		}
+
+		for i := range left {
+			if left[i] != right[i] {
+				return false
+			}
+		}
+
+		return true
+	case *types.AttributeValueMemberL:
+		other, ok := second.(*types.AttributeValueMemberL)
+		if !ok || len(casted.Value) != len(other.Value) {
+			return false
+		}
+
+		for i := range casted.Value {
+			if !valuesEqual(casted.Value[i], other.Value[i]) {
+				return false
+			}
+		}
+
+		return true
+	case *types.AttributeValueMemberM:
+		other, ok := second.(*types.AttributeValueMemberM)
+		if !ok || len(casted.Value) != len(other.Value) {
+			return false
+		}
+
+		for name, value := range casted.Value {
+			if !valuesEqual(value, other.Value[name]) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return false
+	}
+}
+
+// Helper function that gets the size of an attribute value, as defined by the size function in
+// DynamoDB expressions. The second return value will be false if the value has no size
+func valueSize(value types.AttributeValue) (int, bool) {
+	switch casted := value.(type) {
+	case *types.AttributeValueMemberS:
+		return len(casted.Value), true
+	case *types.AttributeValueMemberB:
+		return len(casted.Value), true
+	case *types.AttributeValueMemberSS:
+		return len(casted.Value), true
+	case *types.AttributeValueMemberNS:
+		return len(casted.Value), true
+	case *types.AttributeValueMemberBS:
+		return len(casted.Value), true
+	case *types.AttributeValueMemberL:
+		return len(casted.Value), true
+	case *types.AttributeValueMemberM:
+		return len(casted.Value), true
+	default:
+		return 0, false
+	}
+}
+
+// Helper function that converts the elements of a set into sorted, comparable strings. Numbers are
+// normalized so that equivalent numbers produce the same string
+func setElements(value types.AttributeValue) []string {
+	var elements []string
+	switch casted := value.(type) {
+	case *types.AttributeValueMemberSS:
+		elements = append(elements, casted.Value...)
+	case *types.AttributeValueMemberNS:
+		for _, element := range casted.Value {
+			if number, ok := parseNumber(element); ok {
+				element = formatNumber(number)
+			}
+
+			elements = append(elements, element)
+		}
+	case *types.AttributeValueMemberBS:
+		for _, element := range casted.Value {
+			elements = append(elements, base64.StdEncoding.EncodeToString(element))
+		}
+	}
+
+	sort.Strings(elements)
+	return elements
+}
+
+// Helper function that determines whether a set, or list, contains an element
+func containsElement(collection types.AttributeValue, element types.AttributeValue) bool {
+	switch casted := collection.(type) {
+	case *types.AttributeValueMemberS:
+		other, ok := element.(*types.AttributeValueMemberS)
+		return ok && strings.Contains(casted.Value, other.Value)
+	case *types.AttributeValueMemberSS:
+		other, ok := element.(*types.AttributeValueMemberS)
+		return ok && containsString(setElements(collection), other.Value)
+	case *types.AttributeValueMemberNS:
+		other, ok := element.(*types.AttributeValueMemberN)
+		if !ok {
+			return false
+		}
+
+		number, ok := parseNumber(other.Value)
+		return ok && containsString(setElements(collection), formatNumber(number))
+	case *types.AttributeValueMemberBS:
+		other, ok := element.(*types.AttributeValueMemberB)
+		return ok && containsString(setElements(collection), base64.StdEncoding.EncodeToString(other.Value))
+	case *types.AttributeValueMemberL:
+		for _, value := range casted.Value {
+			if valuesEqual(value, element) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Helper function that combines two sets of the same type, either adding the elements of the second
+// set to the first or removing them from it. The second return value will be false if the values are
+// not sets of the same type
+func combineSets(first types.AttributeValue, second types.AttributeValue, add bool) (types.AttributeValue, bool) {
+	if typeName(first) != typeName(second) {
+		return nil, false
+	}
+
+	// First, create a lookup of the elements in the second set, keyed by their normalized form
+	lookup := make(map[string]bool)
+	for _, element := range setElements(second) {
+		lookup[element] = true
+	}
+
+	// Next, define a function that determines whether an element from the first set should be kept
+	keep := func(normalized string) bool {
+		if add {
+			delete(lookup, normalized)
+			return true
+		}
+
+		return !lookup[normalized]
+	}
+
+	// Finally, combine the sets based on their type
+	switch casted := first.(type) {
+	case *types.AttributeValueMemberSS:
+		result := make([]string, 0)
+		for _, element := range casted.Value {
+			if keep(element) {
+				result = append(result, element)
+			}
+		}
+
+		if add {
+			for _, element := range second.(*types.AttributeValueMemberSS).Value {
+				if lookup[element] {
+					result = append(result, element)
+					delete(lookup, element)
+				}
+			}
+		}
+
+		return &types.AttributeValueMemberSS{Value: result}, true
+	case *types.AttributeValueMemberNS:
+		result := make([]string, 0)
+		for _, element := range casted.Value {
+			if keep(normalizeNumber(element)) {
+				result = append(result, element)
+			}
+		}
+
+		if add {
+			for _, element := range second.(*types.AttributeValueMemberNS).Value {
+				if normalized := normalizeNumber(element); lookup[normalized] {
+					result = append(result, element)
+					delete(lookup, normalized)
+				}
+			}
+		}
+
+		return &types.AttributeValueMemberNS{Value: result}, true
+	case *types.AttributeValueMemberBS:
+		result := make([][]byte, 0)
+		for _, element := range casted.Value {
+			if keep(base64.StdEncoding.EncodeToString(element)) {
+				result = append(result, element)
+			}
+		}
+
+		if add {
+			for _, element := range second.(*types.AttributeValueMemberBS).Value {
+				if encoded := base64.StdEncoding.EncodeToString(element); lookup[encoded] {
+					result = append(result, element)
+					delete(lookup, encoded)
+				}
+			}
+		}
+
+		return &types.AttributeValueMemberBS{Value: result}, true
+	default:
+		return nil, false
+	}
+}
+
+// Helper function that converts a number to its normalized form so that it can be compared as a string
+func normalizeNumber(value string) string {
+	if number, ok := parseNumber(value); ok {
+		return formatNumber(number)
+	}
+
+	return value
+}
+
+// Helper function that determines whether a sorted list of strings contains a value
+func containsString(values []string, value string) bool {
+	index := sort.SearchStrings(values, value)
+	return index < len(values) && values[index] == value
+}
+
+// Helper function that creates a string uniquely identifying an item by the values of the attributes
+// with the names provided
+func fingerprint(item map[string]types.AttributeValue, names ...string) string {
+	var builder strings.Builder
+	for _, name := range names {
+		builder.WriteString(name)
+		switch casted := item[name].(type) {
+		case *types.AttributeValueMemberS:
+			builder.WriteString("|S|" + casted.Value)
+		case *types.AttributeValueMemberN:
+			builder.WriteString("|N|" + normalizeNumber(casted.Value))
+		case *types.AttributeValueMemberB:
+			builder.WriteString("|B|" + base64.StdEncoding.EncodeToString(casted.Value))
+		default:
+			builder.WriteString("|?|")
+		}
+
+		builder.WriteString("|")
+	}
+
+	return builder.String()
+}
+
+// Helper function that assigns an item to one of the segments of a parallel scan
+func segmentOf(key string, total int32) int32 {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int32(hash.Sum32() % uint32(total))
+}
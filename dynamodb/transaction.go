@@ -0,0 +1,291 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Woody1193/goutils/collections"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MaxTransactionItems is the maximum number of operations that DynamoDB allows in a single transaction
+const MaxTransactionItems = 100
+
+// Helper type that describes a single operation in a transaction so that failures can be reported
+type transactOperation struct {
+	tableName string
+	verb      string
+}
+
+// TransactWriteBuilder collects Put, Update, Delete and ConditionCheck operations, across any number of
+// tables, so that they can be written to DynamoDB atomically by TransactWrite
+type TransactWriteBuilder struct {
+	items      []types.TransactWriteItem
+	operations []*transactOperation
+	token      *string
+}
+
+// NewTransactWrite creates a new, empty transaction write builder
+func NewTransactWrite() *TransactWriteBuilder {
+	return &TransactWriteBuilder{
+		items:      make([]types.TransactWriteItem, 0),
+		operations: make([]*transactOperation, 0),
+	}
+}
+
+// Put adds an operation to the transaction that writes an item to a table
+func (builder *TransactWriteBuilder) Put(put *types.Put) *TransactWriteBuilder {
+	return builder.add(types.TransactWriteItem{Put: put}, put.TableName, "PUT")
+}
+
+// Update adds an operation to the transaction that updates fields on an item in a table
+func (builder *TransactWriteBuilder) Update(update *types.Update) *TransactWriteBuilder {
+	return builder.add(types.TransactWriteItem{Update: update}, update.TableName, "UPDATE")
+}
+
+// Delete adds an operation to the transaction that removes an item from a table
+func (builder *TransactWriteBuilder) Delete(del *types.Delete) *TransactWriteBuilder {
+	return builder.add(types.TransactWriteItem{Delete: del}, del.TableName, "DELETE")
+}
+
+// ConditionCheck adds an operation to the transaction that checks a condition on an item in a table
+// without modifying it. If the condition fails then the entire transaction will be cancelled
+func (builder *TransactWriteBuilder) ConditionCheck(check *types.ConditionCheck) *TransactWriteBuilder {
+	return builder.add(types.TransactWriteItem{ConditionCheck: check}, check.TableName, "CONDITION CHECK")
+}
+
+// WithClientRequestToken sets the token used to make the transaction idempotent. If the same token
+// is submitted again within ten minutes of the original request then DynamoDB will not apply the
+// transaction a second time
+func (builder *TransactWriteBuilder) WithClientRequestToken(token string) *TransactWriteBuilder {
+	builder.token = aws.String(token)
+	return builder
+}
+
+// Len returns the number of operations that have been added to the transaction
+func (builder *TransactWriteBuilder) Len() int {
+	return len(builder.items)
+}
+
+// Build creates the input for a TransactWriteItems request from the builder. An error will be returned
+// if the transaction contains no operations or more operations than DynamoDB allows
+func (builder *TransactWriteBuilder) Build() (*dynamodb.TransactWriteItemsInput, error) {
+	if err := validateTransactionSize(len(builder.items)); err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.TransactWriteItemsInput{
+		TransactItems:               builder.items,
+		ClientRequestToken:          builder.token,
+		ReturnConsumedCapacity:      types.ReturnConsumedCapacityNone,
+		ReturnItemCollectionMetrics: types.ReturnItemCollectionMetricsNone,
+	}, nil
+}
+
+// Helper function that adds a write item, and a description of its operation, to the builder
+func (builder *TransactWriteBuilder) add(item types.TransactWriteItem,
+	tableName *string, verb string) *TransactWriteBuilder {
+	builder.items = append(builder.items, item)
+	builder.operations = append(builder.operations, &transactOperation{
+		tableName: aws.ToString(tableName),
+		verb:      verb,
+	})
+
+	return builder
+}
+
+// TransactGetBuilder collects Get operations, across any number of tables, so that the items can be
+// read from DynamoDB atomically by TransactGet
+type TransactGetBuilder struct {
+	items      []types.TransactGetItem
+	operations []*transactOperation
+}
+
+// NewTransactGet creates a new, empty transaction get builder
+func NewTransactGet() *TransactGetBuilder {
+	return &TransactGetBuilder{
+		items:      make([]types.TransactGetItem, 0),
+		operations: make([]*transactOperation, 0),
+	}
+}
+
+// Get adds an operation to the transaction that reads an item from a table
+func (builder *TransactGetBuilder) Get(get *types.Get) *TransactGetBuilder {
+	builder.items = append(builder.items, types.TransactGetItem{Get: get})
+	builder.operations = append(builder.operations, &transactOperation{
+		tableName: aws.ToString(get.TableName),
+		verb:      "GET",
+	})
+
+	return builder
+}
+
+// Len returns the number of operations that have been added to the transaction
+func (builder *TransactGetBuilder) Len() int {
+	return len(builder.items)
+}
+
+// Build creates the input for a TransactGetItems request from the builder. An error will be returned
+// if the transaction contains no operations or more operations than DynamoDB allows
+func (builder *TransactGetBuilder) Build() (*dynamodb.TransactGetItemsInput, error) {
+	if err := validateTransactionSize(len(builder.items)); err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.TransactGetItemsInput{
+		TransactItems:          builder.items,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityNone,
+	}, nil
+}
+
+// TransactWrite atomically applies all the operations collected by the builder to DynamoDB. If
+// DynamoDB cancels the transaction then a TransactionCanceledError will be returned, describing
+// which of the operations caused the cancellation and why
+func (conn *DatabaseConnection) TransactWrite(ctx context.Context,
+	builder *TransactWriteBuilder) (*dynamodb.TransactWriteItemsOutput, error) {
+	tableName := transactionTableNames(builder.operations)
+
+	// First, attempt to create the request from the builder; if this fails then return an error
+	input, err := builder.Build()
+	if err != nil {
+		return nil, conn.NewError(err, tableName, "Failed to create transaction for %s", tableName)
+	}
+
+	// Next, attempt to retry the operation to write the transaction to DynamoDB; if this fails
+	// then convert the error so that any cancellation reasons are included
+	var output *dynamodb.TransactWriteItemsOutput
+	input.ReturnConsumedCapacity = conn.capacityMode(input.ReturnConsumedCapacity)
+	err = conn.doRetry(ctx, tableName, "TRANSACT WRITE", func() error {
+		var inner error
+		if output, inner = conn.db.TransactWriteItems(ctx, input); inner == nil {
+			conn.recordCapacity("TRANSACT WRITE", output.ConsumedCapacity...)
+		}
+
+		return inner
+	})
+
+	conn.cacheTransaction(input.TransactItems)
+	if err != nil {
+		return nil, fromTransactionError(err, builder.operations)
+	}
+
+	return output, nil
+}
+
+// TransactGet atomically reads all the items requested by the builder from DynamoDB. The items will
+// be returned in the same order as the operations that requested them, with a nil entry for every item
+// that could not be found. If DynamoDB cancels the transaction then a TransactionCanceledError will be
+// returned, describing which of the operations caused the cancellation and why
+func (conn *DatabaseConnection) TransactGet(ctx context.Context,
+	builder *TransactGetBuilder) ([]map[string]types.AttributeValue, error) {
+	tableName := transactionTableNames(builder.operations)
+
+	// First, attempt to create the request from the builder; if this fails then return an error
+	input, err := builder.Build()
+	if err != nil {
+		return nil, conn.NewError(err, tableName, "Failed to create transaction for %s", tableName)
+	}
+
+	// Next, attempt to retry the operation to read the transaction from DynamoDB; if this fails
+	// then convert the error so that any cancellation reasons are included
+	var output *dynamodb.TransactGetItemsOutput
+	input.ReturnConsumedCapacity = conn.capacityMode(input.ReturnConsumedCapacity)
+	err = conn.doRetry(ctx, tableName, "TRANSACT GET", func() error {
+		var inner error
+		if output, inner = conn.db.TransactGetItems(ctx, input); inner == nil {
+			conn.recordCapacity("TRANSACT GET", output.ConsumedCapacity...)
+		}
+
+		return inner
+	})
+
+	if err != nil {
+		return nil, fromTransactionError(err, builder.operations)
+	}
+
+	// Finally, extract the items from the responses and return them
+	items := make([]map[string]types.AttributeValue, len(builder.items))
+	for i, response := range output.Responses {
+		if len(response.Item) > 0 {
+			items[i] = response.Item
+		}
+	}
+
+	return items, nil
+}
+
+// Helper function that converts an error returned by a transaction into a TransactionCanceledError if
+// DynamoDB cancelled the transaction. Otherwise, the error will be returned as-is
+func fromTransactionError(err error, operations []*transactOperation) error {
+
+	// First, check if the error was a cancellation; if it wasn't then return it
+	casted, ok := err.(*Error)
+	if !ok {
+		return err
+	}
+
+	var canceled *types.TransactionCanceledException
+	if !errors.As(casted.Inner, &canceled) {
+		return err
+	}
+
+	// Next, iterate over all the cancellation reasons and convert each that describes a failure,
+	// associating it with the operation that caused it
+	reasons := make([]*CancellationReason, 0)
+	for i, reason := range canceled.CancellationReasons {
+		code := aws.ToString(reason.Code)
+		if code == "" || code == "None" {
+			continue
+		}
+
+		converted := CancellationReason{
+			Index:   i,
+			Code:    code,
+			Message: aws.ToString(reason.Message),
+			Item:    reason.Item,
+		}
+
+		if i < len(operations) {
+			converted.TableName = operations[i].tableName
+			converted.Operation = operations[i].verb
+		}
+
+		reasons = append(reasons, &converted)
+	}
+
+	// Finally, create our cancellation error from the original error and the reasons
+	return &TransactionCanceledError{
+		GError:    casted.GError,
+		TableName: casted.TableName,
+		Reasons:   reasons,
+	}
+}
+
+// Helper function that ensures that a transaction has a valid number of operations
+func validateTransactionSize(size int) error {
+	if size == 0 {
+		return fmt.Errorf("transaction contains no operations")
+	} else if size > MaxTransactionItems {
+		return fmt.Errorf("transaction contains %d operations but no more than %d are allowed",
+			size, MaxTransactionItems)
+	}
+
+	return nil
+}
+
+// Helper function that creates a description of the tables included in a transaction
+func transactionTableNames(operations []*transactOperation) string {
+	names := make(map[string]bool)
+	for _, operation := range operations {
+		names[operation.tableName] = true
+	}
+
+	keys := collections.Keys(names)
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
@@ -0,0 +1,190 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transaction Tests", func() {
+
+	// Test that, if no failures occur, then TransactWrite will send every operation in the builder,
+	// along with the client request token
+	It("TransactWrite - No failures - Request sent", func() {
+
+		// First, create our test connection with a client that records transactions
+		client := &transactDynamoDBClient{}
+		conn := createMockConnection(client)
+
+		// Next, create a transaction with one of each operation type
+		builder := NewTransactWrite().
+			Put(&types.Put{TableName: aws.String("TABLE_A"), Item: createBatchItem(1)}).
+			Update(&types.Update{TableName: aws.String("TABLE_B"), Key: createBatchKey(2),
+				UpdateExpression: aws.String("SET #d = :d")}).
+			Delete(&types.Delete{TableName: aws.String("TABLE_A"), Key: createBatchKey(3)}).
+			ConditionCheck(&types.ConditionCheck{TableName: aws.String("TABLE_C"), Key: createBatchKey(4),
+				ConditionExpression: aws.String("attribute_exists(id)")}).
+			WithClientRequestToken("test_token")
+		Expect(builder.Len()).Should(Equal(4))
+
+		// Now, attempt to write the transaction; this should not fail
+		_, err := conn.TransactWrite(context.Background(), builder)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the request that was sent to DynamoDB
+		Expect(client.writes).Should(HaveLen(1))
+		Expect(*client.writes[0].ClientRequestToken).Should(Equal("test_token"))
+		Expect(client.writes[0].TransactItems).Should(HaveLen(4))
+		Expect(client.writes[0].TransactItems[0].Put).ShouldNot(BeNil())
+		Expect(client.writes[0].TransactItems[1].Update).ShouldNot(BeNil())
+		Expect(client.writes[0].TransactItems[2].Delete).ShouldNot(BeNil())
+		Expect(client.writes[0].TransactItems[3].ConditionCheck).ShouldNot(BeNil())
+	})
+
+	// Test that, if the transaction contains too many operations, then TransactWrite will return
+	// an error without sending the request
+	It("TransactWrite - Too many operations - Error", func() {
+
+		// First, create our test connection with a client that records transactions
+		client := &transactDynamoDBClient{}
+		conn := createMockConnection(client)
+
+		// Next, create a transaction with more operations than DynamoDB allows
+		builder := NewTransactWrite()
+		for i := 0; i <= MaxTransactionItems; i++ {
+			builder.Put(&types.Put{TableName: aws.String("TEST_TABLE"), Item: createBatchItem(i)})
+		}
+
+		// Now, attempt to write the transaction; this should fail
+		output, err := conn.TransactWrite(context.Background(), builder)
+
+		// Finally, verify the details of the error and that no request was sent
+		casted := err.(*Error)
+		Expect(output).Should(BeNil())
+		Expect(casted.TableName).Should(Equal("TEST_TABLE"))
+		Expect(casted.Message).Should(Equal("Failed to create transaction for TEST_TABLE"))
+		Expect(casted.Inner.Error()).Should(Equal("transaction contains 101 operations but no more than 100 are allowed"))
+		Expect(client.writes).Should(BeEmpty())
+	})
+
+	// Test that, if DynamoDB cancels the transaction, then TransactWrite will return an error that
+	// describes which operations caused the cancellation
+	It("TransactWrite - Cancelled - Reasons returned", func() {
+
+		// First, create our test connection with a client that cancels transactions
+		client := &transactDynamoDBClient{reasons: []types.CancellationReason{
+			{Code: aws.String("None")},
+			{Code: aws.String("ConditionalCheckFailed"), Message: aws.String("The conditional request failed"),
+				Item: createBatchItem(2)},
+			{Code: aws.String("TransactionConflict"), Message: aws.String("Conflict")},
+		}}
+
+		conn := createMockConnection(client)
+
+		// Next, create a transaction with three operations
+		builder := NewTransactWrite().
+			Put(&types.Put{TableName: aws.String("TABLE_A"), Item: createBatchItem(1)}).
+			Update(&types.Update{TableName: aws.String("TABLE_B"), Key: createBatchKey(2)}).
+			Delete(&types.Delete{TableName: aws.String("TABLE_C"), Key: createBatchKey(3)})
+
+		// Now, attempt to write the transaction; this should fail
+		output, err := conn.TransactWrite(context.Background(), builder)
+		Expect(output).Should(BeNil())
+
+		// Finally, verify the details of the error
+		casted := err.(*TransactionCanceledError)
+		Expect(casted.TableName).Should(Equal("TABLE_A, TABLE_B, TABLE_C"))
+		Expect(casted.Message).Should(Equal("TRANSACT WRITE request to TABLE_A, TABLE_B, TABLE_C in DynamoDB failed"))
+		Expect(casted.Reasons).Should(Equal([]*CancellationReason{
+			{Index: 1, TableName: "TABLE_B", Operation: "UPDATE", Code: "ConditionalCheckFailed",
+				Message: "The conditional request failed", Item: createBatchItem(2)},
+			{Index: 2, TableName: "TABLE_C", Operation: "DELETE", Code: "TransactionConflict",
+				Message: "Conflict"}}))
+		Expect(casted.ConditionFailed()).Should(Equal(casted.Reasons[:1]))
+	})
+
+	// Test that, if no failures occur, then TransactGet will return the items in the order requested
+	It("TransactGet - No failures - Items returned", func() {
+
+		// First, create our test connection with a client that returns items for even keys
+		conn := createMockConnection(&transactDynamoDBClient{})
+
+		// Next, create a transaction that reads three items
+		builder := NewTransactGet()
+		for i := 0; i < 3; i++ {
+			builder.Get(&types.Get{TableName: aws.String("TEST_TABLE"), Key: createBatchKey(i)})
+		}
+
+		// Now, attempt to read the transaction; this should not fail
+		items, err := conn.TransactGet(context.Background(), builder)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the items that were returned
+		Expect(items).Should(Equal([]map[string]types.AttributeValue{
+			createBatchItem(0), nil, createBatchItem(2)}))
+	})
+
+	// Test that, if the transaction contains no operations, then TransactGet will return an error
+	It("TransactGet - No operations - Error", func() {
+
+		// First, create our test connection with a client that records transactions
+		conn := createMockConnection(&transactDynamoDBClient{})
+
+		// Next, attempt to read an empty transaction; this should fail
+		items, err := conn.TransactGet(context.Background(), NewTransactGet())
+
+		// Finally, verify the details of the error
+		casted := err.(*Error)
+		Expect(items).Should(BeNil())
+		Expect(casted.Inner.Error()).Should(Equal("transaction contains no operations"))
+	})
+})
+
+// Helper type that records transactions and cancels them if it has been given cancellation reasons
+type transactDynamoDBClient struct {
+	DynamoDBAPI
+	reasons []types.CancellationReason
+	writes  []*dynamodb.TransactWriteItemsInput
+}
+
+// Mocks out the TransactWriteItems function so that it records the request and, if the client has
+// cancellation reasons, returns a cancellation error
+func (client *transactDynamoDBClient) TransactWriteItems(ctx context.Context,
+	params *dynamodb.TransactWriteItemsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	client.writes = append(client.writes, params)
+	if client.reasons != nil {
+		return nil, &smithy.OperationError{
+			ServiceID:     "DynamoDB",
+			OperationName: "TransactWriteItems",
+			Err: &types.TransactionCanceledException{
+				Message:             aws.String("Transaction cancelled"),
+				CancellationReasons: client.reasons,
+			},
+		}
+	}
+
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+// Mocks out the TransactGetItems function so that it returns an item for every even-numbered key
+func (client *transactDynamoDBClient) TransactGetItems(ctx context.Context,
+	params *dynamodb.TransactGetItemsInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.TransactGetItemsOutput, error) {
+	responses := make([]types.ItemResponse, len(params.TransactItems))
+	for i, item := range params.TransactItems {
+		var index int
+		fmt.Sscanf(item.Get.Key["id"].(*types.AttributeValueMemberS).Value, "test_id|%d", &index)
+		if index%2 == 0 {
+			responses[i].Item = createBatchItem(index)
+		}
+	}
+
+	return &dynamodb.TransactGetItemsOutput{Responses: responses}, nil
+}
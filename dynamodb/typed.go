@@ -0,0 +1,160 @@
+package dynamodb
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// GetAs retrieves an item from DynamoDB and unmarshals it into an object of the type provided. The
+// attribute names will be determined from the tag key set on the connection. If the item does not
+// exist in the table then nil will be returned
+func GetAs[T any](ctx context.Context, conn *DatabaseConnection, input *dynamodb.GetItemInput) (*T, error) {
+
+	// First, attempt to get the item from DynamoDB; if this fails then return an error
+	output, err := conn.GetItem(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Next, check if the item was actually found; if it wasn't then return nil
+	if len(output.Item) == 0 {
+		return nil, nil
+	}
+
+	// Finally, attempt to unmarshal the item into our object and return it
+	return unmarshalItem[T](conn, *input.TableName, output.Item)
+}
+
+// PutObject marshals an object into a DynamoDB item and writes it to the table described by the
+// input, overwriting an existing item if there is one. Any item set on the input will be replaced
+// with the marshalled object, but all other fields, such as condition expressions, will be preserved.
+// If the object has a version field then the item will only be written if the version stored in
+// DynamoDB matches the version on the object, and the version will be incremented. If the versions do
+// not match then a VersionConflictError will be returned and the version on the object will be unchanged.
+// If the write fails because of another condition on the input then that error will be returned instead
+func PutObject[T any](ctx context.Context, conn *DatabaseConnection, input *dynamodb.PutItemInput,
+	obj *T) (*dynamodb.PutItemOutput, error) {
+
+	// First, if the object is versioned then add the version condition to the input and increment
+	// the version on the object so that the new version is written
+	version, err := getVersionField[T](conn)
+	if err != nil {
+		return nil, conn.NewError(err, *input.TableName, "Failed to get the version of %T", obj)
+	}
+
+	var expected int64
+	if version != nil {
+		expected = version.get(obj)
+		version.addCondition(expected, &input.ConditionExpression,
+			&input.ExpressionAttributeNames, &input.ExpressionAttributeValues)
+		version.set(obj, expected+1)
+	}
+
+	// Next, attempt to marshal the object into a DynamoDB item; if this fails then return an error
+	item, err := marshalItem(conn, *input.TableName, obj)
+	if err != nil {
+		if version != nil {
+			version.set(obj, expected)
+		}
+
+		return nil, err
+	}
+
+	// Now, set the item on the input and attempt to write it to the table
+	input.Item = item
+	output, err := conn.PutItem(ctx, input)
+
+	// Finally, if the write failed and the object is versioned then restore the original version and
+	// check whether the failure was caused by a version conflict
+	if err != nil && version != nil {
+		version.set(obj, expected)
+		if _, ok := isConditionFailed(err); !ok {
+			return nil, err
+		}
+
+		key, keyErr := itemKey(ctx, conn, *input.TableName, item)
+		if keyErr != nil {
+			return nil, keyErr
+		}
+
+		return nil, version.fromError(ctx, conn, *input.TableName, key, err, expected)
+	}
+
+	return output, err
+}
+
+// QueryAs makes a search on a DynamoDB table and unmarshals the results into a list of objects of
+// the type provided. Like Query, this function will retrieve every page of results before returning
+func QueryAs[T any](ctx context.Context, conn *DatabaseConnection, input *dynamodb.QueryInput) ([]*T, error) {
+
+	// First, attempt to query the table; if this fails then return an error
+	items, err := conn.Query(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Next, attempt to unmarshal the items into our list of objects and return it
+	return unmarshalList[T](conn, *input.TableName, items)
+}
+
+// ScanAs reads every item from a DynamoDB table or index and unmarshals the results into a list of
+// objects of the type provided. Like Scan, this function will retrieve every page of results before
+// returning so it should not be used on very large tables
+func ScanAs[T any](ctx context.Context, conn *DatabaseConnection, input *dynamodb.ScanInput) ([]*T, error) {
+
+	// First, attempt to scan the table; if this fails then return an error
+	items, err := conn.Scan(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Next, attempt to unmarshal the items into our list of objects and return it
+	return unmarshalList[T](conn, *input.TableName, items)
+}
+
+// Helper function that marshals an object into a DynamoDB item using the tag key set on the connection
+func marshalItem[T any](conn *DatabaseConnection, tableName string,
+	obj *T) (map[string]types.AttributeValue, error) {
+	item, err := attributevalue.MarshalMapWithOptions(obj, conn.encoderOptions)
+	if err != nil {
+		return nil, conn.NewError(err, tableName, "Failed to marshal %T to a DynamoDB item", obj)
+	}
+
+	return item, nil
+}
+
+// Helper function that unmarshals a DynamoDB item into an object using the tag key set on the connection
+func unmarshalItem[T any](conn *DatabaseConnection, tableName string,
+	item map[string]types.AttributeValue) (*T, error) {
+	var obj T
+	if err := attributevalue.UnmarshalMapWithOptions(item, &obj, conn.decoderOptions); err != nil {
+		return nil, conn.NewError(err, tableName, "Failed to unmarshal DynamoDB item to %T", obj)
+	}
+
+	return &obj, nil
+}
+
+// Helper function that unmarshals a list of DynamoDB items into a list of objects using the tag
+// key set on the connection
+func unmarshalList[T any](conn *DatabaseConnection, tableName string,
+	items []map[string]types.AttributeValue) ([]*T, error) {
+	objs := make([]*T, 0, len(items))
+	if err := attributevalue.UnmarshalListOfMapsWithOptions(items, &objs, conn.decoderOptions); err != nil {
+		return nil, conn.NewError(err, tableName, "Failed to unmarshal DynamoDB items to %T", objs)
+	}
+
+	return objs, nil
+}
+
+// Helper function that sets the encoder options used by the connection when marshalling objects
+func (conn *DatabaseConnection) encoderOptions(opts *attributevalue.EncoderOptions) {
+	opts.TagKey = conn.tagKey
+}
+
+// Helper function that sets the decoder options used by the connection when unmarshalling objects
+func (conn *DatabaseConnection) decoderOptions(opts *attributevalue.DecoderOptions) {
+	opts.TagKey = conn.tagKey
+}
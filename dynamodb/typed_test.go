@@ -0,0 +1,230 @@
+package dynamodb
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Woody1193/goutils/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Typed Item Tests", func() {
+
+	// Test that, if the item exists, then calling GetAs will return the unmarshalled object
+	It("GetAs - Item exists - Object returned", func() {
+
+		// First, create our test connection with a client that returns a canned item
+		client := &pagedDynamoDBClient{item: map[string]types.AttributeValue{
+			"id":       &types.AttributeValueMemberS{Value: "test_id"},
+			"sort_key": &types.AttributeValueMemberS{Value: "test|sort|key"},
+			"data":     &types.AttributeValueMemberN{Value: "42"}}}
+		conn := createMockConnection(client)
+
+		// Next, attempt to get the item as a test object; this should not fail
+		obj, err := GetAs[testObject](context.Background(), conn, &dynamodb.GetItemInput{
+			TableName: aws.String("TEST_TABLE"),
+			Key: map[string]types.AttributeValue{
+				"id":       &types.AttributeValueMemberS{Value: "test_id"},
+				"sort_key": &types.AttributeValueMemberS{Value: "test|sort|key"}}})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the data on the test object
+		Expect(obj.ID).Should(Equal("test_id"))
+		Expect(obj.SortKey).Should(Equal("test|sort|key"))
+		Expect(obj.Data).Should(Equal(42))
+	})
+
+	// Test that, if the item does not exist, then calling GetAs will return nil
+	It("GetAs - Item missing - Nil returned", func() {
+
+		// First, create our test connection with a client that returns no item
+		conn := createMockConnection(&pagedDynamoDBClient{})
+
+		// Next, attempt to get the item as a test object; this should not fail
+		obj, err := GetAs[testObject](context.Background(), conn, &dynamodb.GetItemInput{
+			TableName: aws.String("TEST_TABLE"),
+			Key: map[string]types.AttributeValue{
+				"id":       &types.AttributeValueMemberS{Value: "test_id"},
+				"sort_key": &types.AttributeValueMemberS{Value: "test|sort|key"}}})
+
+		// Finally, verify that no object was returned
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(obj).Should(BeNil())
+	})
+
+	// Test that, if the item cannot be unmarshalled, then calling GetAs will return an error
+	It("GetAs - Unmarshal fails - Error", func() {
+
+		// First, create our test connection with a client that returns an item with a bad data type
+		client := &pagedDynamoDBClient{item: map[string]types.AttributeValue{
+			"id":   &types.AttributeValueMemberS{Value: "test_id"},
+			"data": &types.AttributeValueMemberS{Value: "derp"}}}
+		conn := createMockConnection(client)
+
+		// Next, attempt to get the item as a test object; this should fail
+		obj, err := GetAs[testObject](context.Background(), conn, &dynamodb.GetItemInput{
+			TableName: aws.String("TEST_TABLE"),
+			Key: map[string]types.AttributeValue{
+				"id":       &types.AttributeValueMemberS{Value: "test_id"},
+				"sort_key": &types.AttributeValueMemberS{Value: "test|sort|key"}}})
+
+		// Finally, verify the details of the error
+		casted := err.(*Error)
+		Expect(obj).Should(BeNil())
+		Expect(casted.TableName).Should(Equal("TEST_TABLE"))
+		Expect(casted.Function).Should(Equal("GetAs"))
+		Expect(casted.Message).Should(Equal("Failed to unmarshal DynamoDB item to dynamodb.testObject"))
+	})
+
+	// Test that calling PutObject will marshal the object into the item on the request
+	It("PutObject - No failures - Item written", func() {
+
+		// First, create our test connection with a client that records its requests
+		client := &pagedDynamoDBClient{}
+		conn := createMockConnection(client)
+
+		// Next, attempt to write our test object to the table; this should not fail
+		_, err := PutObject(context.Background(), conn, &dynamodb.PutItemInput{
+			TableName:           aws.String("TEST_TABLE"),
+			ConditionExpression: aws.String("attribute_not_exists(id)"),
+		}, &testObject{ID: "test_id", SortKey: "test|sort|key", Data: 1})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the request that was sent to DynamoDB
+		Expect(client.puts).Should(HaveLen(1))
+		Expect(*client.puts[0].ConditionExpression).Should(Equal("attribute_not_exists(id)"))
+		Expect(client.puts[0].Item).Should(Equal(map[string]types.AttributeValue{
+			"id":       &types.AttributeValueMemberS{Value: "test_id"},
+			"sort_key": &types.AttributeValueMemberS{Value: "test|sort|key"},
+			"data":     &types.AttributeValueMemberN{Value: "1"}}))
+	})
+
+	// Test that calling QueryAs will read every page of results and unmarshal the items
+	It("QueryAs - Multiple pages - All objects returned", func() {
+
+		// First, create our test connection with a client that returns multiple pages
+		conn := createMockConnection(&pagedDynamoDBClient{pages: createTestPages(3, 4)})
+
+		// Next, attempt to query the table; this should not fail
+		objs, err := QueryAs[testObject](context.Background(), conn, &dynamodb.QueryInput{
+			TableName: aws.String("TEST_TABLE")})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the objects that were returned
+		Expect(objs).Should(HaveLen(12))
+		for i, obj := range objs {
+			Expect(obj.ID).Should(Equal("test_id"))
+			Expect(obj.Data).Should(Equal(i))
+		}
+	})
+
+	// Test that calling ScanAs will read every page of results and unmarshal the items
+	It("ScanAs - Multiple pages - All objects returned", func() {
+
+		// First, create our test connection with a client that returns multiple pages
+		conn := createMockConnection(&pagedDynamoDBClient{pages: createTestPages(2, 5)})
+
+		// Next, attempt to scan the table; this should not fail
+		objs, err := ScanAs[testObject](context.Background(), conn, &dynamodb.ScanInput{
+			TableName: aws.String("TEST_TABLE")})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the objects that were returned
+		Expect(objs).Should(HaveLen(10))
+		for i, obj := range objs {
+			Expect(obj.ID).Should(Equal("test_id"))
+			Expect(obj.Data).Should(Equal(i))
+		}
+	})
+})
+
+// Helper function that creates a test connection from a mocked DynamoDB client
+func createMockConnection(client DynamoDBAPI, opts ...IDynamoDBOption) *DatabaseConnection {
+	logger := utils.NewLogger("testd", "test")
+	logger.Discard()
+	opts = append([]IDynamoDBOption{WithBackoffStart(1), WithBackoffEnd(5),
+		WithBackoffMaxElapsed(10), WithTagKey("json")}, opts...)
+	return FromClient(client, logger, opts...)
+}
+
+// Helper function that creates a number of pages of test items, each of which has a data value
+// equal to its overall position in the results
+func createTestPages(pages int, size int) [][]map[string]types.AttributeValue {
+	results := make([][]map[string]types.AttributeValue, pages)
+	for i := 0; i < pages; i++ {
+		results[i] = make([]map[string]types.AttributeValue, size)
+		for j := 0; j < size; j++ {
+			results[i][j] = map[string]types.AttributeValue{
+				"id":       &types.AttributeValueMemberS{Value: "test_id"},
+				"sort_key": &types.AttributeValueMemberS{Value: "test|sort|key"},
+				"data":     &types.AttributeValueMemberN{Value: strconv.Itoa(i*size + j)}}
+		}
+	}
+
+	return results
+}
+
+// Helper type that returns canned items and pages so that functionality built on top of the
+// connection can be tested without a DynamoDB instance
+type pagedDynamoDBClient struct {
+	DynamoDBAPI
+	item  map[string]types.AttributeValue
+	pages [][]map[string]types.AttributeValue
+	puts  []*dynamodb.PutItemInput
+}
+
+// Mocks out the GetItem function so that it returns the canned item
+func (client *pagedDynamoDBClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return &dynamodb.GetItemOutput{Item: client.item}, nil
+}
+
+// Mocks out the PutItem function so that it records the request
+func (client *pagedDynamoDBClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	client.puts = append(client.puts, params)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// Mocks out the Query function so that it returns the page associated with the exclusive start key
+func (client *pagedDynamoDBClient) Query(ctx context.Context, params *dynamodb.QueryInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	items, last := client.page(params.ExclusiveStartKey)
+	return &dynamodb.QueryOutput{Items: items, LastEvaluatedKey: last}, nil
+}
+
+// Mocks out the Scan function so that it returns the page associated with the exclusive start key
+func (client *pagedDynamoDBClient) Scan(ctx context.Context, params *dynamodb.ScanInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	items, last := client.page(params.ExclusiveStartKey)
+	return &dynamodb.ScanOutput{Items: items, LastEvaluatedKey: last}, nil
+}
+
+// Helper function that gets the page of items following the exclusive start key, along with the
+// last-evaluated key that should be returned with it
+func (client *pagedDynamoDBClient) page(start map[string]types.AttributeValue) (
+	[]map[string]types.AttributeValue, map[string]types.AttributeValue) {
+
+	// First, determine the index of the page from the exclusive start key
+	index := 0
+	if start != nil {
+		index, _ = strconv.Atoi(start["page"].(*types.AttributeValueMemberN).Value)
+	}
+
+	// Next, if there are no more pages then return nothing
+	if index >= len(client.pages) {
+		return nil, nil
+	}
+
+	// Finally, return the page and, if there is a page after it, a key pointing to it
+	var last map[string]types.AttributeValue
+	if index+1 < len(client.pages) {
+		last = map[string]types.AttributeValue{"page": &types.AttributeValueMemberN{Value: strconv.Itoa(index + 1)}}
+	}
+
+	return client.pages[index], last
+}
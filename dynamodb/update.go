@@ -0,0 +1,469 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Woody1193/goutils/collections"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// UpdateExpression builds a DynamoDB update expression from SET, REMOVE, ADD and DELETE actions. Every
+// attribute name and value referenced by the expression is replaced with a placeholder so that the
+// expression is safe to use with reserved words and arbitrary values. Attribute names are provided as
+// a path of names so that nested map attributes can be referenced
+type UpdateExpression struct {
+	names   map[string]string
+	lookup  map[string]string
+	values  map[string]types.AttributeValue
+	sets    []string
+	removes []string
+	adds    []string
+	deletes []string
+}
+
+// NewUpdateExpression creates a new, empty update expression
+func NewUpdateExpression() *UpdateExpression {
+	return &UpdateExpression{
+		names:   make(map[string]string),
+		lookup:  make(map[string]string),
+		values:  make(map[string]types.AttributeValue),
+		sets:    make([]string, 0),
+		removes: make([]string, 0),
+		adds:    make([]string, 0),
+		deletes: make([]string, 0),
+	}
+}
+
+// DiffUpdateExpression creates an update expression that will transform an item with the old attributes
+// into an item with the new attributes. Attributes that were added or modified will be SET, attributes
+// that were removed will be REMOVEd, nested maps will be compared attribute-by-attribute, lists that were
+// extended will be appended to, and elements added to or removed from sets will be ADDed or DELETEd. Any
+// attributes included in the keys will be ignored as DynamoDB does not allow key attributes to be updated.
+// If any fields are provided then only those attributes, given as paths separated by periods, will be
+// compared, allowing a partial object to be used to update an item
+func DiffUpdateExpression(old map[string]types.AttributeValue, new map[string]types.AttributeValue,
+	keys map[string]types.AttributeValue, fields ...string) *UpdateExpression {
+
+	// First, create our expression and convert our fields into a list of paths
+	expr := NewUpdateExpression()
+	masks := make([][]string, len(fields))
+	for i, field := range fields {
+		masks[i] = strings.Split(field, ".")
+	}
+
+	// Next, get all the top-level attribute names from both items, ignoring any key attributes
+	for _, name := range unionKeys(old, new) {
+		if _, ok := keys[name]; !ok {
+			expr.diff([]string{name}, old[name], new[name], masks)
+		}
+	}
+
+	// Finally, return the expression
+	return expr
+}
+
+// Set adds an action to the expression that sets the attribute at the path to the value provided
+func (expr *UpdateExpression) Set(value types.AttributeValue, path ...string) *UpdateExpression {
+	expr.sets = append(expr.sets, fmt.Sprintf("%s = %s", expr.path(path), expr.value(value)))
+	return expr
+}
+
+// SetIfNotExists adds an action to the expression that sets the attribute at the path to the value
+// provided, but only if the attribute does not already exist on the item
+func (expr *UpdateExpression) SetIfNotExists(value types.AttributeValue, path ...string) *UpdateExpression {
+	name := expr.path(path)
+	expr.sets = append(expr.sets, fmt.Sprintf("%s = if_not_exists(%s, %s)", name, name, expr.value(value)))
+	return expr
+}
+
+// Append adds an action to the expression that appends the values provided to the list at the path
+func (expr *UpdateExpression) Append(values []types.AttributeValue, path ...string) *UpdateExpression {
+	name := expr.path(path)
+	expr.sets = append(expr.sets, fmt.Sprintf("%s = list_append(%s, %s)", name, name,
+		expr.value(&types.AttributeValueMemberL{Value: values})))
+	return expr
+}
+
+// Remove adds an action to the expression that removes the attribute at the path from the item
+func (expr *UpdateExpression) Remove(path ...string) *UpdateExpression {
+	expr.removes = append(expr.removes, expr.path(path))
+	return expr
+}
+
+// Add adds an action to the expression that adds the value provided to the number, or set, at the path
+func (expr *UpdateExpression) Add(value types.AttributeValue, path ...string) *UpdateExpression {
+	expr.adds = append(expr.adds, fmt.Sprintf("%s %s", expr.path(path), expr.value(value)))
+	return expr
+}
+
+// Delete adds an action to the expression that removes the elements provided from the set at the path
+func (expr *UpdateExpression) Delete(value types.AttributeValue, path ...string) *UpdateExpression {
+	expr.deletes = append(expr.deletes, fmt.Sprintf("%s %s", expr.path(path), expr.value(value)))
+	return expr
+}
+
+// IsEmpty returns true if the expression contains no actions
+func (expr *UpdateExpression) IsEmpty() bool {
+	return len(expr.sets)+len(expr.removes)+len(expr.adds)+len(expr.deletes) == 0
+}
+
+// Expression creates the update expression string from the actions that have been added to it
+func (expr *UpdateExpression) Expression() string {
+	clauses := make([]string, 0, 4)
+	for _, clause := range []struct {
+		verb    string
+		actions []string
+	}{{"SET", expr.sets}, {"REMOVE", expr.removes}, {"ADD", expr.adds}, {"DELETE", expr.deletes}} {
+		if len(clause.actions) > 0 {
+			clauses = append(clauses, clause.verb+" "+strings.Join(clause.actions, ", "))
+		}
+	}
+
+	return strings.Join(clauses, " ")
+}
+
+// Names returns a mapping of the attribute name placeholders used by the expression to their names
+func (expr *UpdateExpression) Names() map[string]string {
+	return expr.names
+}
+
+// Values returns a mapping of the attribute value placeholders used by the expression to their values
+func (expr *UpdateExpression) Values() map[string]types.AttributeValue {
+	return expr.values
+}
+
+// Apply sets the update expression on the input and adds the attribute names and values it references
+// to those already on the input. All the placeholders generated by the expression are prefixed with
+// "#u" or ":u" so placeholders with these prefixes should not be used elsewhere on the input
+func (expr *UpdateExpression) Apply(input *dynamodb.UpdateItemInput) {
+
+	// First, set the update expression on the input
+	input.UpdateExpression = aws.String(expr.Expression())
+
+	// Next, copy all the attribute names from the expression to the input
+	if len(expr.names) > 0 {
+		if input.ExpressionAttributeNames == nil {
+			input.ExpressionAttributeNames = make(map[string]string)
+		}
+
+		for placeholder, name := range expr.names {
+			input.ExpressionAttributeNames[placeholder] = name
+		}
+	}
+
+	// Finally, copy all the attribute values from the expression to the input
+	if len(expr.values) > 0 {
+		if input.ExpressionAttributeValues == nil {
+			input.ExpressionAttributeValues = make(map[string]types.AttributeValue)
+		}
+
+		for placeholder, value := range expr.values {
+			input.ExpressionAttributeValues[placeholder] = value
+		}
+	}
+}
+
+// UpdateFromDiff marshals the old and new objects into DynamoDB items, generates an update expression
+// from the differences between them and applies it to the item described by the input. The table name,
+// key, condition expression and return values should be set on the input; the update expression will be
+// generated. If the old object is nil then the new object will be treated as a partial object and only
+// the fields provided will be written. See DiffUpdateExpression for more information. If there are no
+// differences between the objects then no request will be made and an empty output will be returned.
+// If the objects have a version field then the item will only be updated if the version stored in
+// DynamoDB matches the version on the old object (or the new object, if there is no old object), and
+// the version will be incremented on both the item and the new object. If the versions do not match
+// then a VersionConflictError will be returned. If the update fails because of another condition on the
+// input then that error will be returned instead
+func UpdateFromDiff[T any](ctx context.Context, conn *DatabaseConnection, input *dynamodb.UpdateItemInput,
+	old *T, new *T, fields ...string) (*dynamodb.UpdateItemOutput, error) {
+
+	// First, attempt to marshal the old object, if we have one; if this fails then return an error
+	oldAttrs := make(map[string]types.AttributeValue)
+	if old != nil {
+		var err error
+		if oldAttrs, err = marshalItem(conn, *input.TableName, old); err != nil {
+			return nil, err
+		}
+	}
+
+	// Next, attempt to marshal the new object; if this fails then return an error
+	newAttrs, err := marshalItem(conn, *input.TableName, new)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the objects aren't versioned then update the item from the differences between the two items
+	version, err := getVersionField[T](conn)
+	if err != nil {
+		return nil, conn.NewError(err, *input.TableName, "Failed to get the version of %T", new)
+	} else if version == nil {
+		return conn.UpdateItemDiff(ctx, input, oldAttrs, newAttrs, fields...)
+	}
+
+	// Now, get the expected version and remove the version attribute from both items so that it won't
+	// be compared. Then, generate the update expression; if there were no differences then there's
+	// nothing to update so return here
+	expected := version.get(new)
+	if old != nil {
+		expected = version.get(old)
+	}
+
+	delete(oldAttrs, version.name)
+	delete(newAttrs, version.name)
+	expr := DiffUpdateExpression(oldAttrs, newAttrs, input.Key, fields...)
+	if expr.IsEmpty() {
+		conn.logger.Log("No changes to update on %s in DynamoDB", *input.TableName)
+		return &dynamodb.UpdateItemOutput{}, nil
+	}
+
+	// Finally, increment the version as part of the update, add the version condition to the input and
+	// attempt to update the item. If this succeeds then set the new version on the new object
+	expr.Set(&types.AttributeValueMemberN{Value: strconv.FormatInt(expected+1, 10)}, version.name)
+	expr.Apply(input)
+	version.addCondition(expected, &input.ConditionExpression,
+		&input.ExpressionAttributeNames, &input.ExpressionAttributeValues)
+	output, err := conn.UpdateItem(ctx, input)
+	if err != nil {
+		return nil, version.fromError(ctx, conn, *input.TableName, input.Key, err, expected)
+	}
+
+	version.set(new, expected+1)
+	return output, nil
+}
+
+// UpdateItemDiff generates an update expression from the differences between the old and new attributes
+// and applies it to the item described by the input. The table name, key, condition expression and return
+// values should be set on the input; the update expression will be generated. See DiffUpdateExpression
+// for more information. If there are no differences between the attributes then no request will be made
+// and an empty output will be returned
+func (conn *DatabaseConnection) UpdateItemDiff(ctx context.Context, input *dynamodb.UpdateItemInput,
+	old map[string]types.AttributeValue, new map[string]types.AttributeValue,
+	fields ...string) (*dynamodb.UpdateItemOutput, error) {
+
+	// First, generate the update expression from the differences between the two items. If there
+	// were no differences then there's nothing to update so return here
+	expr := DiffUpdateExpression(old, new, input.Key, fields...)
+	if expr.IsEmpty() {
+		conn.logger.Log("No changes to update on %s in DynamoDB", *input.TableName)
+		return &dynamodb.UpdateItemOutput{}, nil
+	}
+
+	// Next, apply the expression to the input and attempt to update the item
+	expr.Apply(input)
+	return conn.UpdateItem(ctx, input)
+}
+
+// Helper function that compares the old and new values of the attribute at the path and adds actions
+// to the expression that transform the old value into the new value
+func (expr *UpdateExpression) diff(path []string, old types.AttributeValue,
+	new types.AttributeValue, masks [][]string) {
+
+	// First, check whether the path should be compared at all. If the path is covered by one of the
+	// masks then compare it normally. If it is an ancestor of a mask then we'll only compare the
+	// masked attributes within it, if we can. Otherwise, ignore it
+	covered, ancestor := checkMasks(path, masks)
+	if !covered && !ancestor {
+		return
+	}
+
+	// Next, handle the cases where the attribute was added, removed or not modified
+	if new == nil {
+		if old != nil {
+			expr.Remove(path...)
+		}
+
+		return
+	} else if old == nil {
+		if ancestor && !covered {
+			if newMap, ok := new.(*types.AttributeValueMemberM); ok {
+				new = &types.AttributeValueMemberM{Value: filterMasked(path, newMap.Value, masks)}
+			}
+		}
+
+		expr.Set(new, path...)
+		return
+	} else if reflect.DeepEqual(old, new) {
+		return
+	}
+
+	// Now, handle the cases where the old and new values have the same collection type
+	switch newCasted := new.(type) {
+	case *types.AttributeValueMemberM:
+		if oldCasted, ok := old.(*types.AttributeValueMemberM); ok {
+			for _, name := range unionKeys(oldCasted.Value, newCasted.Value) {
+				expr.diff(append(append([]string{}, path...), name),
+					oldCasted.Value[name], newCasted.Value[name], masks)
+			}
+
+			return
+		}
+	case *types.AttributeValueMemberL:
+		if oldCasted, ok := old.(*types.AttributeValueMemberL); ok && len(newCasted.Value) > len(oldCasted.Value) &&
+			reflect.DeepEqual(oldCasted.Value, newCasted.Value[:len(oldCasted.Value)]) {
+			expr.Append(newCasted.Value[len(oldCasted.Value):], path...)
+			return
+		}
+	case *types.AttributeValueMemberSS:
+		if oldCasted, ok := old.(*types.AttributeValueMemberSS); ok {
+			added, removed := diffSets(oldCasted.Value, newCasted.Value)
+			if len(added) == 0 || len(removed) == 0 {
+				expr.updateSet(path, &types.AttributeValueMemberSS{Value: added},
+					&types.AttributeValueMemberSS{Value: removed}, len(added), len(removed))
+				return
+			}
+		}
+	case *types.AttributeValueMemberNS:
+		if oldCasted, ok := old.(*types.AttributeValueMemberNS); ok {
+			added, removed := diffSets(oldCasted.Value, newCasted.Value)
+			if len(added) == 0 || len(removed) == 0 {
+				expr.updateSet(path, &types.AttributeValueMemberNS{Value: added},
+					&types.AttributeValueMemberNS{Value: removed}, len(added), len(removed))
+				return
+			}
+		}
+	}
+
+	// Finally, if we reached this point then the value was modified in a way that we can't describe
+	// more precisely so replace it entirely
+	expr.Set(new, path...)
+}
+
+// Helper function that adds the elements added to a set, or removes the elements removed from a set
+func (expr *UpdateExpression) updateSet(path []string, added types.AttributeValue,
+	removed types.AttributeValue, numAdded int, numRemoved int) {
+	if numAdded > 0 {
+		expr.Add(added, path...)
+	} else if numRemoved > 0 {
+		expr.Delete(removed, path...)
+	}
+}
+
+// Helper function that creates a placeholder for each name in the path and joins them together
+func (expr *UpdateExpression) path(path []string) string {
+	parts := make([]string, len(path))
+	for i, name := range path {
+		placeholder, ok := expr.lookup[name]
+		if !ok {
+			placeholder = fmt.Sprintf("#u%d", len(expr.lookup))
+			expr.lookup[name] = placeholder
+			expr.names[placeholder] = name
+		}
+
+		parts[i] = placeholder
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// Helper function that creates a placeholder for the value
+func (expr *UpdateExpression) value(value types.AttributeValue) string {
+	placeholder := fmt.Sprintf(":u%d", len(expr.values))
+	expr.values[placeholder] = value
+	return placeholder
+}
+
+// Helper function that determines whether a path is covered by one of the masks, meaning that the
+// mask is equal to the path or one of its ancestors, or whether the path is an ancestor of one of the
+// masks. If there are no masks then every path is covered
+func checkMasks(path []string, masks [][]string) (bool, bool) {
+	if len(masks) == 0 {
+		return true, false
+	}
+
+	covered, ancestor := false, false
+	for _, mask := range masks {
+		if isPrefix(mask, path) {
+			covered = true
+		} else if isPrefix(path, mask) {
+			ancestor = true
+		}
+	}
+
+	return covered, ancestor
+}
+
+// Helper function that removes all the attributes from a map that are not covered by the masks
+func filterMasked(path []string, attrs map[string]types.AttributeValue,
+	masks [][]string) map[string]types.AttributeValue {
+	filtered := make(map[string]types.AttributeValue)
+	for name, value := range attrs {
+		child := append(append([]string{}, path...), name)
+		if covered, ancestor := checkMasks(child, masks); covered {
+			filtered[name] = value
+		} else if casted, ok := value.(*types.AttributeValueMemberM); ok && ancestor {
+			filtered[name] = &types.AttributeValueMemberM{Value: filterMasked(child, casted.Value, masks)}
+		}
+	}
+
+	return filtered
+}
+
+// Helper function that determines whether the prefix is a prefix of the path
+func isPrefix(prefix []string, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+
+	for i, name := range prefix {
+		if path[i] != name {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Helper function that gets the elements that were added to, and removed from, a set
+func diffSets[T comparable](old []T, new []T) ([]T, []T) {
+
+	// First, index the elements in both sets
+	oldSet, newSet := make(map[T]bool), make(map[T]bool)
+	for _, element := range old {
+		oldSet[element] = true
+	}
+
+	for _, element := range new {
+		newSet[element] = true
+	}
+
+	// Next, find the elements that are in the new set but not the old set
+	added := make([]T, 0)
+	for _, element := range new {
+		if !oldSet[element] {
+			added = append(added, element)
+		}
+	}
+
+	// Finally, find the elements that are in the old set but not the new set
+	removed := make([]T, 0)
+	for _, element := range old {
+		if !newSet[element] {
+			removed = append(removed, element)
+		}
+	}
+
+	return added, removed
+}
+
+// Helper function that gets the sorted union of the keys of two maps
+func unionKeys[T any](first map[string]T, second map[string]T) []string {
+	names := make(map[string]bool)
+	for _, name := range collections.Keys(first) {
+		names[name] = true
+	}
+
+	for _, name := range collections.Keys(second) {
+		names[name] = true
+	}
+
+	keys := collections.Keys(names)
+	sort.Strings(keys)
+	return keys
+}
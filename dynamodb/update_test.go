@@ -0,0 +1,144 @@
+package dynamodb
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Update Expression Tests", func() {
+
+	// Test that DiffUpdateExpression will generate SET, REMOVE and ADD actions from the differences
+	// between two items, including nested map paths and list appends, and ignore the key attributes
+	It("DiffUpdateExpression - Items differ - Expression generated", func() {
+
+		// First, create the old and new items
+		old := map[string]types.AttributeValue{
+			"id":   &types.AttributeValueMemberS{Value: "test_id"},
+			"name": &types.AttributeValueMemberS{Value: "old"},
+			"tags": &types.AttributeValueMemberSS{Value: []string{"a", "b"}},
+			"list": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberN{Value: "1"}}},
+			"address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"city": &types.AttributeValueMemberS{Value: "Tokyo"},
+				"zip":  &types.AttributeValueMemberS{Value: "100"}}},
+			"gone": &types.AttributeValueMemberS{Value: "removed"}}
+		new := map[string]types.AttributeValue{
+			"id":   &types.AttributeValueMemberS{Value: "new_id"},
+			"name": &types.AttributeValueMemberS{Value: "new"},
+			"tags": &types.AttributeValueMemberSS{Value: []string{"a", "b", "c"}},
+			"list": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberN{Value: "1"}, &types.AttributeValueMemberN{Value: "2"}}},
+			"address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"city": &types.AttributeValueMemberS{Value: "Osaka"}}}}
+
+		// Next, generate the update expression from the differences between the items
+		expr := DiffUpdateExpression(old, new, map[string]types.AttributeValue{"id": old["id"]})
+
+		// Finally, verify the expression, names and values
+		Expect(expr.IsEmpty()).Should(BeFalse())
+		Expect(expr.Expression()).Should(Equal("SET #u0.#u1 = :u0, #u4 = list_append(#u4, :u1), #u5 = :u2 " +
+			"REMOVE #u0.#u2, #u3 ADD #u6 :u3"))
+		Expect(expr.Names()).Should(Equal(map[string]string{"#u0": "address", "#u1": "city", "#u2": "zip",
+			"#u3": "gone", "#u4": "list", "#u5": "name", "#u6": "tags"}))
+		Expect(expr.Values()).Should(Equal(map[string]types.AttributeValue{
+			":u0": &types.AttributeValueMemberS{Value: "Osaka"},
+			":u1": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberN{Value: "2"}}},
+			":u2": &types.AttributeValueMemberS{Value: "new"},
+			":u3": &types.AttributeValueMemberSS{Value: []string{"c"}}}))
+	})
+
+	// Test that, if fields are provided, then DiffUpdateExpression will only compare those fields
+	It("DiffUpdateExpression - Fields provided - Only fields compared", func() {
+
+		// First, create a partial item with a nested map
+		new := map[string]types.AttributeValue{
+			"name": &types.AttributeValueMemberS{Value: "new"},
+			"data": &types.AttributeValueMemberN{Value: "42"},
+			"address": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"city": &types.AttributeValueMemberS{Value: "Osaka"},
+				"zip":  &types.AttributeValueMemberS{Value: "100"}}}}
+
+		// Next, generate the update expression for only some of the fields
+		expr := DiffUpdateExpression(nil, new, nil, "data", "address.city", "missing")
+
+		// Finally, verify the expression, names and values
+		Expect(expr.Expression()).Should(Equal("SET #u0 = :u0, #u1 = :u1"))
+		Expect(expr.Names()).Should(Equal(map[string]string{"#u0": "address", "#u1": "data"}))
+		Expect(expr.Values()).Should(Equal(map[string]types.AttributeValue{
+			":u0": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"city": &types.AttributeValueMemberS{Value: "Osaka"}}},
+			":u1": &types.AttributeValueMemberN{Value: "42"}}))
+	})
+
+	// Test that, if the objects differ, then UpdateFromDiff will send an update request containing the
+	// generated expression, merged with the names and values already on the input
+	It("UpdateFromDiff - Objects differ - Update sent", func() {
+
+		// First, create our test connection with a client that records updates
+		client := &updateDynamoDBClient{}
+		conn := createMockConnection(client)
+
+		// Next, create an update input with a condition expression
+		input := &dynamodb.UpdateItemInput{
+			TableName: aws.String("TEST_TABLE"),
+			Key: map[string]types.AttributeValue{
+				"id":       &types.AttributeValueMemberS{Value: "test_id"},
+				"sort_key": &types.AttributeValueMemberS{Value: "test|sort|key"}},
+			ConditionExpression:       aws.String("#c = :c"),
+			ExpressionAttributeNames:  map[string]string{"#c": "data"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":c": &types.AttributeValueMemberN{Value: "1"}},
+		}
+
+		// Now, attempt to update the item from the differences between two objects; this should not fail
+		_, err := UpdateFromDiff(context.Background(), conn, input,
+			&testObject{ID: "test_id", SortKey: "test|sort|key", Data: 1},
+			&testObject{ID: "test_id", SortKey: "test|sort|key", Data: 2})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the request that was sent to DynamoDB
+		Expect(client.updates).Should(HaveLen(1))
+		Expect(*client.updates[0].UpdateExpression).Should(Equal("SET #u0 = :u0"))
+		Expect(client.updates[0].ExpressionAttributeNames).Should(Equal(map[string]string{
+			"#c": "data", "#u0": "data"}))
+		Expect(client.updates[0].ExpressionAttributeValues).Should(Equal(map[string]types.AttributeValue{
+			":c":  &types.AttributeValueMemberN{Value: "1"},
+			":u0": &types.AttributeValueMemberN{Value: "2"}}))
+	})
+
+	// Test that, if the objects are the same, then UpdateFromDiff will not send an update request
+	It("UpdateFromDiff - No differences - No update sent", func() {
+
+		// First, create our test connection with a client that records updates
+		client := &updateDynamoDBClient{}
+		conn := createMockConnection(client)
+
+		// Next, attempt to update the item from two identical objects; this should not fail
+		obj := &testObject{ID: "test_id", SortKey: "test|sort|key", Data: 1}
+		output, err := UpdateFromDiff(context.Background(), conn, &dynamodb.UpdateItemInput{
+			TableName: aws.String("TEST_TABLE")}, obj, obj)
+
+		// Finally, verify that no request was sent to DynamoDB
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(output).ShouldNot(BeNil())
+		Expect(client.updates).Should(BeEmpty())
+	})
+})
+
+// Helper type that records the update requests sent to it
+type updateDynamoDBClient struct {
+	DynamoDBAPI
+	updates []*dynamodb.UpdateItemInput
+}
+
+// Mocks out the UpdateItem function so that it records the request
+func (client *updateDynamoDBClient) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	client.updates = append(client.updates, params)
+	return &dynamodb.UpdateItemOutput{}, nil
+}
@@ -0,0 +1,255 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Woody1193/goutils/reflection"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// SchemaTag is the struct tag used to describe how a field should be treated by DynamoDB beyond how it
+// is marshalled. For example, tagging an integer field with `dynamodb:"version"` opts its type into
+// optimistic locking, so that PutObject and UpdateFromDiff will only write the object if the version
+// stored in DynamoDB matches the version on the object, incrementing the version as they do so. The version
+// field must be an integer, or a pointer to one, or these functions will return an error
+const SchemaTag = "dynamodb"
+
+// Helper type that describes the integer field on an object used for optimistic locking
+type versionField struct {
+	index int
+	name  string
+}
+
+// Helper function that gets the version field from the type, if it has one. The name of the field will
+// be taken from the tag key used by the connection so that it matches the name of the marshalled attribute.
+// An error will be returned if the version field is not an integer, or a pointer to one
+func getVersionField[T any](conn *DatabaseConnection) (*versionField, error) {
+
+	// First, check that we have a struct type; if we don't then it can't have a version field
+	tType := reflect.TypeOf((*T)(nil)).Elem()
+	if tType.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	// Next, iterate over all the fields on the type and find the one tagged as the version
+	for i, field := range reflection.GetTypeInfo[T]().Fields {
+		if tag, ok := field.Tags[SchemaTag]; !ok || !hasTagValue(tag, "version") {
+			continue
+		}
+
+		// Now, check that the field is an integer; if it isn't then we can't increment it so return an error
+		if !isIntegerType(field.Type) {
+			return nil, fmt.Errorf("field %s on %s: type %s cannot be used as a version as it is not an integer",
+				field.Name, tType, field.Type)
+		}
+
+		// Finally, get the attribute name of the field and return it
+		name := field.Name
+		if tag, ok := field.Tags[conn.tagKey]; ok && tag.Name != "" && tag.Name != "-" {
+			name = tag.Name
+		}
+
+		return &versionField{index: i, name: name}, nil
+	}
+
+	return nil, nil
+}
+
+// Helper function that gets the version from the object. If the version field is a nil pointer then the
+// object has no version so zero will be returned
+func (field *versionField) get(obj interface{}) int64 {
+	value := reflect.ValueOf(obj).Elem().Field(field.index)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return 0
+		}
+
+		value = value.Elem()
+	}
+
+	if value.CanInt() {
+		return value.Int()
+	}
+
+	return int64(value.Uint())
+}
+
+// Helper function that sets the version on the object, allocating the version if the field is a nil pointer
+func (field *versionField) set(obj interface{}, version int64) {
+	value := reflect.ValueOf(obj).Elem().Field(field.index)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+
+		value = value.Elem()
+	}
+
+	if value.CanInt() {
+		value.SetInt(version)
+	} else {
+		value.SetUint(uint64(version))
+	}
+}
+
+// Helper function that adds a condition to a request requiring that the version stored in DynamoDB is
+// equal to the version expected. If the expected version is zero then the item should not exist. The
+// condition will be combined with any condition already on the request, and the placeholders it uses
+// will be chosen so that they don't conflict with any placeholders already on the request
+func (field *versionField) addCondition(expected int64, condition **string,
+	names *map[string]string, values *map[string]types.AttributeValue) {
+
+	// First, add the version attribute name to the request
+	if *names == nil {
+		*names = make(map[string]string)
+	}
+
+	name := unusedPlaceholder("#ver", func(placeholder string) bool {
+		_, ok := (*names)[placeholder]
+		return ok
+	})
+
+	(*names)[name] = field.name
+
+	// Next, create the version condition and add its attribute value to the request if it requires one
+	check := fmt.Sprintf("attribute_not_exists(%s)", name)
+	if expected != 0 {
+		if *values == nil {
+			*values = make(map[string]types.AttributeValue)
+		}
+
+		value := unusedPlaceholder(":ver", func(placeholder string) bool {
+			_, ok := (*values)[placeholder]
+			return ok
+		})
+
+		check = fmt.Sprintf("%s = %s", name, value)
+		(*values)[value] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expected, 10)}
+	}
+
+	// Finally, combine the version condition with any existing condition
+	if existing := aws.ToString(*condition); existing != "" {
+		check = fmt.Sprintf("(%s) AND %s", existing, check)
+	}
+
+	*condition = aws.String(check)
+}
+
+// Helper function that converts an error returned by a versioned write into a VersionConflictError if
+// the write failed because the version stored in DynamoDB did not match the version expected. Since the
+// condition on the write may also include conditions provided by the caller, the stored version will be
+// read from the item with the key provided and, if it matches, the error will be returned as-is
+func (field *versionField) fromError(ctx context.Context, conn *DatabaseConnection, tableName string,
+	key map[string]types.AttributeValue, err error, expected int64) error {
+
+	// First, check whether the write failed because of its condition; if it didn't then return the error
+	casted, ok := isConditionFailed(err)
+	if !ok {
+		return err
+	}
+
+	// Next, read the version currently stored on the item; if this fails then return an error
+	stored, readErr := field.read(ctx, conn, tableName, key)
+	if readErr != nil {
+		return readErr
+	}
+
+	// Finally, if the stored version matches the version expected then the condition failed for some
+	// other reason so return the error as-is. Otherwise, return a version conflict
+	if stored == expected {
+		return err
+	}
+
+	return &VersionConflictError{
+		GError:    casted.GError,
+		TableName: casted.TableName,
+		Expected:  expected,
+	}
+}
+
+// Helper function that reads the version stored on the item with the key provided, using a consistent
+// read. If the item doesn't exist, or doesn't have a version, then zero will be returned
+func (field *versionField) read(ctx context.Context, conn *DatabaseConnection, tableName string,
+	key map[string]types.AttributeValue) (int64, error) {
+
+	// First, attempt to read the version attribute from the item; if this fails then return an error
+	output, err := conn.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:                aws.String(tableName),
+		Key:                      key,
+		ConsistentRead:           aws.Bool(true),
+		ProjectionExpression:     aws.String("#ver"),
+		ExpressionAttributeNames: map[string]string{"#ver": field.name},
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	// Next, if the item doesn't have a version then return zero
+	value, ok := output.Item[field.name].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, nil
+	}
+
+	// Finally, attempt to parse the version; if this fails then return an error
+	version, err := strconv.ParseInt(value.Value, 10, 64)
+	if err != nil {
+		return 0, conn.NewError(err, tableName, "Failed to parse version %q stored on %s", value.Value, tableName)
+	}
+
+	return version, nil
+}
+
+// Helper function that extracts the key of an item written to a table. The table will be described so
+// that the names of its key attributes are known
+func itemKey(ctx context.Context, conn *DatabaseConnection, tableName string,
+	item map[string]types.AttributeValue) (map[string]types.AttributeValue, error) {
+
+	// First, describe the table; if this fails or the table doesn't exist then return an error
+	table, err := conn.describeTable(ctx, tableName)
+	if err != nil {
+		return nil, err
+	} else if table == nil {
+		return nil, conn.NewError(nil, tableName, "Table %s does not exist", tableName)
+	}
+
+	// Next, copy each of the key attributes from the item and return them
+	key := make(map[string]types.AttributeValue, len(table.KeySchema))
+	for _, element := range table.KeySchema {
+		name := aws.ToString(element.AttributeName)
+		key[name] = item[name]
+	}
+
+	return key, nil
+}
+
+// Helper function that finds a placeholder, starting with the prefix provided, that isn't already used
+func unusedPlaceholder(prefix string, used func(string) bool) string {
+	placeholder := prefix
+	for i := 1; used(placeholder); i++ {
+		placeholder = fmt.Sprintf("%s%d", prefix, i)
+	}
+
+	return placeholder
+}
+
+// Helper function that determines whether a tag contains a value, either as its name or a modifier
+func hasTagValue(tag *reflection.TagInfo, value string) bool {
+	if strings.TrimSpace(tag.Name) == value {
+		return true
+	}
+
+	for _, modifier := range tag.Modifiers {
+		if strings.TrimSpace(modifier) == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,302 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Optimistic Locking Tests", func() {
+
+	// Test that, if a new versioned object is written, then PutObject will require that the item does
+	// not exist and will write the item with the first version
+	It("PutObject - New versioned object - Version created", func() {
+
+		// First, create our test connection with a client that records writes
+		client := &versionDynamoDBClient{}
+		conn := createMockConnection(client)
+
+		// Next, attempt to put a versioned object that has never been written; this should not fail
+		obj := &versionedObject{ID: "test_id", Data: 1}
+		_, err := PutObject(context.Background(), conn, &dynamodb.PutItemInput{
+			TableName: aws.String("TEST_TABLE")}, obj)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the request that was sent to DynamoDB and the version on the object
+		Expect(obj.Version).Should(Equal(1))
+		Expect(client.puts).Should(HaveLen(1))
+		Expect(*client.puts[0].ConditionExpression).Should(Equal("attribute_not_exists(#ver)"))
+		Expect(client.puts[0].ExpressionAttributeNames).Should(Equal(map[string]string{"#ver": "version"}))
+		Expect(client.puts[0].ExpressionAttributeValues).Should(BeNil())
+		Expect(client.puts[0].Item["version"]).Should(Equal(&types.AttributeValueMemberN{Value: "1"}))
+	})
+
+	// Test that, if the stored version does not match, then PutObject will return a version conflict
+	// error, combine the version condition with the existing condition and leave the version unchanged
+	It("PutObject - Version conflict - Error returned", func() {
+
+		// First, create our test connection with a client that fails every condition and has a newer
+		// version of the object stored
+		client := &versionDynamoDBClient{conflict: true, stored: 4}
+		conn := createMockConnection(client)
+
+		// Next, attempt to put a versioned object with an existing condition; this should fail
+		obj := &versionedObject{ID: "test_id", Data: 1, Version: 3}
+		output, err := PutObject(context.Background(), conn, &dynamodb.PutItemInput{
+			TableName:           aws.String("TEST_TABLE"),
+			ConditionExpression: aws.String("attribute_exists(id)")}, obj)
+		Expect(output).Should(BeNil())
+
+		// Now, verify the details of the error
+		casted := err.(*VersionConflictError)
+		Expect(casted.TableName).Should(Equal("TEST_TABLE"))
+		Expect(casted.Expected).Should(Equal(int64(3)))
+		Expect(casted.Message).Should(Equal("PUT request to TEST_TABLE in DynamoDB failed"))
+
+		// Finally, verify the request that was sent to DynamoDB and the version on the object
+		Expect(obj.Version).Should(Equal(3))
+		Expect(*client.puts[0].ConditionExpression).Should(Equal("(attribute_exists(id)) AND #ver = :ver"))
+		Expect(client.puts[0].ExpressionAttributeValues).Should(Equal(map[string]types.AttributeValue{
+			":ver": &types.AttributeValueMemberN{Value: "3"}}))
+		Expect(client.puts[0].Item["version"]).Should(Equal(&types.AttributeValueMemberN{Value: "4"}))
+		Expect(client.gets).Should(HaveLen(1))
+		Expect(*client.gets[0].ConsistentRead).Should(BeTrue())
+		Expect(client.gets[0].Key).Should(Equal(map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: "test_id"}}))
+	})
+
+	// Test that, if the stored version matches but a condition provided by the caller fails, then PutObject
+	// will return the original error rather than a version conflict
+	It("PutObject - Caller condition failed - Error returned", func() {
+
+		// First, create our test connection with a client that fails every condition but has the same
+		// version of the object stored
+		client := &versionDynamoDBClient{conflict: true, stored: 3}
+		conn := createMockConnection(client)
+
+		// Next, attempt to put a versioned object with an existing condition; this should fail
+		obj := &versionedObject{ID: "test_id", Data: 1, Version: 3}
+		output, err := PutObject(context.Background(), conn, &dynamodb.PutItemInput{
+			TableName:           aws.String("TEST_TABLE"),
+			ConditionExpression: aws.String("attribute_exists(id)")}, obj)
+
+		// Finally, verify that the error was not converted to a version conflict and that the version
+		// on the object was unchanged
+		Expect(output).Should(BeNil())
+		Expect(err).ShouldNot(BeAssignableToTypeOf(&VersionConflictError{}))
+		Expect(err.(*Error).Message).Should(Equal("PUT request to TEST_TABLE in DynamoDB failed"))
+		Expect(obj.Version).Should(Equal(3))
+	})
+
+	// Test that, if the caller's condition already uses the placeholders that the version condition would
+	// use, then PutObject will choose different placeholders rather than overwriting them
+	It("PutObject - Placeholders in use - Unused placeholders chosen", func() {
+
+		// First, create our test connection with a client that records writes
+		client := &versionDynamoDBClient{}
+		conn := createMockConnection(client)
+
+		// Next, put a versioned object with a condition that uses the version placeholders; this should not fail
+		obj := &versionedObject{ID: "test_id", Data: 1, Version: 2}
+		_, err := PutObject(context.Background(), conn, &dynamodb.PutItemInput{
+			TableName:                aws.String("TEST_TABLE"),
+			ConditionExpression:      aws.String("#ver <> :ver"),
+			ExpressionAttributeNames: map[string]string{"#ver": "data"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":ver": &types.AttributeValueMemberN{Value: "10"}}}, obj)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify that the caller's placeholders were preserved
+		Expect(*client.puts[0].ConditionExpression).Should(Equal("(#ver <> :ver) AND #ver1 = :ver1"))
+		Expect(client.puts[0].ExpressionAttributeNames).Should(Equal(map[string]string{
+			"#ver": "data", "#ver1": "version"}))
+		Expect(client.puts[0].ExpressionAttributeValues).Should(Equal(map[string]types.AttributeValue{
+			":ver":  &types.AttributeValueMemberN{Value: "10"},
+			":ver1": &types.AttributeValueMemberN{Value: "2"}}))
+	})
+
+	// Test that, if the object type is an interface, then it will not be treated as having a version field
+	It("getVersionField - Interface type - Nil", func() {
+		Expect(getVersionField[fmt.Stringer](createMockConnection(&versionDynamoDBClient{}))).Should(BeNil())
+	})
+
+	// Test that, if the version field is a pointer to an integer, then PutObject will set the version on it,
+	// allocating the version if it wasn't set
+	It("PutObject - Pointer version - Version created", func() {
+
+		// First, create our test connection with a client that records writes
+		client := &versionDynamoDBClient{}
+		conn := createMockConnection(client)
+
+		// Next, attempt to put a versioned object that has never been written; this should not fail
+		obj := &pointerVersionObject{ID: "test_id"}
+		_, err := PutObject(context.Background(), conn, &dynamodb.PutItemInput{
+			TableName: aws.String("TEST_TABLE")}, obj)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the request that was sent to DynamoDB and the version on the object
+		Expect(*obj.Version).Should(Equal(int64(1)))
+		Expect(*client.puts[0].ConditionExpression).Should(Equal("attribute_not_exists(#ver)"))
+		Expect(client.puts[0].Item["version"]).Should(Equal(&types.AttributeValueMemberN{Value: "1"}))
+	})
+
+	// Test that, if the version field is not an integer, then PutObject will return an error rather than
+	// attempting to increment it
+	It("PutObject - String version - Error", func() {
+
+		// First, create our test connection with a client that records writes
+		client := &versionDynamoDBClient{}
+		conn := createMockConnection(client)
+
+		// Next, attempt to put an object with a string version; this should fail
+		obj := &stringVersionObject{ID: "test_id", Version: "1"}
+		output, err := PutObject(context.Background(), conn, &dynamodb.PutItemInput{
+			TableName: aws.String("TEST_TABLE")}, obj)
+
+		// Finally, verify the details of the error and that nothing was written
+		Expect(output).Should(BeNil())
+		casted := err.(*Error)
+		Expect(casted.Message).Should(Equal("Failed to get the version of *dynamodb.stringVersionObject"))
+		Expect(casted.Inner).Should(MatchError("field Version on dynamodb.stringVersionObject: type string " +
+			"cannot be used as a version as it is not an integer"))
+		Expect(client.puts).Should(BeEmpty())
+		Expect(obj.Version).Should(Equal("1"))
+	})
+
+	// Test that, if a versioned object is modified, then UpdateFromDiff will require that the stored
+	// version matches the old object and will increment the version on the item and new object
+	It("UpdateFromDiff - Versioned object - Version incremented", func() {
+
+		// First, create our test connection with a client that records writes
+		client := &versionDynamoDBClient{}
+		conn := createMockConnection(client)
+
+		// Next, attempt to update a versioned object; this should not fail
+		old := &versionedObject{ID: "test_id", Data: 1, Version: 5}
+		new := &versionedObject{ID: "test_id", Data: 2, Version: 5}
+		_, err := UpdateFromDiff(context.Background(), conn, &dynamodb.UpdateItemInput{
+			TableName: aws.String("TEST_TABLE"),
+			Key:       map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "test_id"}}},
+			old, new)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		// Finally, verify the request that was sent to DynamoDB and the versions on the objects
+		Expect(old.Version).Should(Equal(5))
+		Expect(new.Version).Should(Equal(6))
+		Expect(client.updates).Should(HaveLen(1))
+		Expect(*client.updates[0].UpdateExpression).Should(Equal("SET #u0 = :u0, #u1 = :u1"))
+		Expect(*client.updates[0].ConditionExpression).Should(Equal("#ver = :ver"))
+		Expect(client.updates[0].ExpressionAttributeNames).Should(Equal(map[string]string{
+			"#u0": "data", "#u1": "version", "#ver": "version"}))
+		Expect(client.updates[0].ExpressionAttributeValues).Should(Equal(map[string]types.AttributeValue{
+			":u0":  &types.AttributeValueMemberN{Value: "2"},
+			":u1":  &types.AttributeValueMemberN{Value: "6"},
+			":ver": &types.AttributeValueMemberN{Value: "5"}}))
+	})
+
+	// Test that, if the stored version does not match, then UpdateFromDiff will return a version
+	// conflict error and leave the version on the new object unchanged
+	It("UpdateFromDiff - Version conflict - Error returned", func() {
+
+		// First, create our test connection with a client that fails every condition and has a newer
+		// version of the object stored
+		conn := createMockConnection(&versionDynamoDBClient{conflict: true, stored: 6})
+
+		// Next, attempt to update a versioned object; this should fail
+		old := &versionedObject{ID: "test_id", Data: 1, Version: 5}
+		new := &versionedObject{ID: "test_id", Data: 2, Version: 5}
+		output, err := UpdateFromDiff(context.Background(), conn, &dynamodb.UpdateItemInput{
+			TableName: aws.String("TEST_TABLE"),
+			Key:       map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "test_id"}}},
+			old, new)
+
+		// Finally, verify the details of the error and the version on the new object
+		casted := err.(*VersionConflictError)
+		Expect(output).Should(BeNil())
+		Expect(casted.Expected).Should(Equal(int64(5)))
+		Expect(new.Version).Should(Equal(5))
+	})
+})
+
+// Helper type that can be used to test optimistic locking
+type versionedObject struct {
+	ID      string `json:"id"`
+	Data    int    `json:"data"`
+	Version int    `json:"version" dynamodb:"version"`
+}
+
+// Helper type with a version field that is a pointer to an integer
+type pointerVersionObject struct {
+	ID      string `json:"id"`
+	Version *int64 `json:"version" dynamodb:"version"`
+}
+
+// Helper type with a version field that is not an integer
+type stringVersionObject struct {
+	ID      string `json:"id"`
+	Version string `json:"version" dynamodb:"version"`
+}
+
+// Helper type that records the writes sent to it and fails their conditions if it is in conflict
+type versionDynamoDBClient struct {
+	DynamoDBAPI
+	conflict bool
+	stored   int
+	puts     []*dynamodb.PutItemInput
+	updates  []*dynamodb.UpdateItemInput
+	gets     []*dynamodb.GetItemInput
+}
+
+// Mocks out the DescribeTable function so that it describes a table keyed on the ID of the object
+func (client *versionDynamoDBClient) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{
+		TableName: params.TableName, KeySchema: keySchema("id", "")}}, nil
+}
+
+// Mocks out the GetItem function so that it records the request and returns the stored version
+func (client *versionDynamoDBClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	client.gets = append(client.gets, params)
+	return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+		"version": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", client.stored)}}}, nil
+}
+
+// Mocks out the PutItem function so that it records the request and returns a conditional check
+// failure if the client is in conflict
+func (client *versionDynamoDBClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	client.puts = append(client.puts, params)
+	if client.conflict {
+		return nil, client.conditionFailed("PutItem")
+	}
+
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// Mocks out the UpdateItem function so that it records the request and returns a conditional check
+// failure if the client is in conflict
+func (client *versionDynamoDBClient) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput,
+	optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	client.updates = append(client.updates, params)
+	if client.conflict {
+		return nil, client.conditionFailed("UpdateItem")
+	}
+
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+// Helper function that creates a conditional check failure for an operation
+func (client *versionDynamoDBClient) conditionFailed(operation string) error {
+	return &smithy.OperationError{
+		ServiceID:     "DynamoDB",
+		OperationName: operation,
+		Err:           &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")},
+	}
+}